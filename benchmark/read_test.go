@@ -23,6 +23,18 @@ func BenchmarkGoFuseMemoryRead(b *testing.B) {
 	benchmarkRead(mnt, b, 32, "direct")
 }
 
+func BenchmarkGoFuseMemoryReadCloned(b *testing.B) {
+	root := &readFS{}
+	mnt := setupFSOptions(root, b.N, b, clonedOptions())
+	benchmarkRead(mnt, b, 32, "direct")
+}
+
+func BenchmarkGoFuseMemoryReadVmsplice(b *testing.B) {
+	root := &readFS{vmsplice: true}
+	mnt := setupFS(root, b.N, b)
+	benchmarkRead(mnt, b, 32, "direct")
+}
+
 const blockSize = 64 * 1024
 
 func benchmarkRead(mnt string, b *testing.B, readers int, ddflag string) {
@@ -19,13 +19,16 @@ const fileSize = 2 << 60
 // operations. Useful when benchmarking the raw throughput with go-fuse.
 type readFS struct {
 	fs.Inode
+
+	// vmsplice returns the data with ReadResultVmsplice.
+	vmsplice bool
 }
 
 var _ = (fs.NodeLookuper)((*readFS)(nil))
 
 func (n *readFS) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	sattr := fs.StableAttr{Mode: fuse.S_IFREG}
-	return n.NewInode(ctx, &readFS{}, sattr), fs.OK
+	return n.NewInode(ctx, &readFS{vmsplice: n.vmsplice}, sattr), fs.OK
 }
 
 var _ = (fs.NodeGetattrer)((*readFS)(nil))
@@ -39,11 +42,14 @@ func (n *readFS) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut
 var _ = (fs.NodeOpener)((*readFS)(nil))
 
 func (n *readFS) Open(ctx context.Context, flags uint32) (fh fs.FileHandle, fuseFlags uint32, errno syscall.Errno) {
-	return &readFS{}, fuse.FOPEN_DIRECT_IO, fs.OK
+	return &readFS{vmsplice: n.vmsplice}, fuse.FOPEN_DIRECT_IO, fs.OK
 }
 
 var _ = (fs.FileReader)((*readFS)(nil))
 
 func (n *readFS) Read(ctx context.Context, dest []byte, offset int64) (fuse.ReadResult, syscall.Errno) {
+	if n.vmsplice {
+		return fuse.ReadResultVmsplice(dest, nil), fs.OK
+	}
 	return fuse.ReadResultData(dest), fs.OK
 }
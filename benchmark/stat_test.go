@@ -22,7 +22,20 @@ import (
 )
 
 func setupFS(node fs.InodeEmbedder, N int, tb testing.TB) string {
-	opts := &fs.Options{}
+	return setupFSOptions(node, N, tb, &fs.Options{})
+}
+
+// clonedOptions returns mount options that read requests from one
+// FUSE device channel per CPU.
+func clonedOptions() *fs.Options {
+	return &fs.Options{
+		MountOptions: fuse.MountOptions{
+			CloneQueues: runtime.GOMAXPROCS(0),
+		},
+	}
+}
+
+func setupFSOptions(node fs.InodeEmbedder, N int, tb testing.TB, opts *fs.Options) string {
 	opts.Debug = testutil.VerboseTest()
 	mountPoint := tb.TempDir()
 	server, err := fs.Mount(mountPoint, node, opts)
@@ -107,6 +120,14 @@ func TestNewStatFs(t *testing.T) {
 }
 
 func BenchmarkGoFuseStat(b *testing.B) {
+	benchmarkGoFuseStat(b, &fs.Options{})
+}
+
+func BenchmarkGoFuseStatCloned(b *testing.B) {
+	benchmarkGoFuseStat(b, clonedOptions())
+}
+
+func benchmarkGoFuseStat(b *testing.B, opts *fs.Options) {
 	b.StopTimer()
 	fs := &StatFS{}
 
@@ -117,7 +138,7 @@ func BenchmarkGoFuseStat(b *testing.B) {
 		fs.AddFile(fn, fuse.Attr{Mode: syscall.S_IFREG})
 	}
 
-	mnt := setupFS(fs, b.N, b)
+	mnt := setupFSOptions(fs, b.N, b, opts)
 
 	for i, l := range files {
 		files[i] = filepath.Join(mnt, l)
@@ -0,0 +1,89 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This program prints FUSE traces written by fuse.Recorder (eg. with
+// the -record option of example/loopback), and can replay them
+// against a loopback file system.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func printTrace(r io.Reader, times bool) error {
+	tr, err := fuse.NewTraceReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		f, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if times {
+			fmt.Printf("%12.6f ", f.Time.Seconds())
+		}
+		fmt.Println(f)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	times := flag.Bool("t", false, "print the time of each frame")
+	loopback := flag.String("replay", "", "replay the trace against a loopback of this directory, and print differing replies")
+	debug := flag.Bool("debug", false, "print debugging messages while replaying")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Printf("usage: %s TRACE\n", path.Base(os.Args[0]))
+		fmt.Printf("\noptions:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	if *loopback == "" {
+		if err := printTrace(f, *times); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	root, err := fs.NewLoopbackRoot(*loopback)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Use the timeouts of example/loopback.
+	sec := time.Second
+	opts := &fs.Options{
+		AttrTimeout:  &sec,
+		EntryTimeout: &sec,
+	}
+	opts.Debug = *debug
+	diffs, err := fuse.Replay(f, fs.NewNodeFS(root, opts), &opts.MountOptions)
+	for _, d := range diffs {
+		fmt.Println(d.String())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(diffs) > 0 {
+		os.Exit(1)
+	}
+}
@@ -53,6 +53,7 @@ func main() {
 	directmountstrict := flag.Bool("directmountstrict", false, "like directmount, but don't fall back to fusermount")
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to this file")
 	memprofile := flag.String("memprofile", "", "write memory profile to this file")
+	record := flag.String("record", "", "record FUSE traffic to this file; print it with example/fusetrace")
 	flag.Parse()
 	if flag.NArg() < 2 {
 		fmt.Printf("usage: %s MOUNTPOINT ORIGINAL\n", path.Base(os.Args[0]))
@@ -118,6 +119,18 @@ func main() {
 	if *ro {
 		opts.MountOptions.Options = append(opts.MountOptions.Options, "ro")
 	}
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		rec, err := fuse.NewRecorder(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.MountOptions.Recorder = rec
+	}
 	// Enable diagnostics logging
 	if !*quiet {
 		opts.Logger = log.New(os.Stderr, "", 0)
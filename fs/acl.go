@@ -0,0 +1,213 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"encoding/binary"
+	"syscall"
+)
+
+// Extended attributes that hold POSIX ACLs.
+const (
+	XATTR_NAME_POSIX_ACL_ACCESS  = "system.posix_acl_access"
+	XATTR_NAME_POSIX_ACL_DEFAULT = "system.posix_acl_default"
+)
+
+// Tags of ACL entries.
+const (
+	ACL_USER_OBJ  = 0x01
+	ACL_USER      = 0x02
+	ACL_GROUP_OBJ = 0x04
+	ACL_GROUP     = 0x08
+	ACL_MASK      = 0x10
+	ACL_OTHER     = 0x20
+)
+
+// aclVersion is the version of the xattr format.
+const aclVersion = 2
+
+// aclUndefinedID is the ID of entries that are not for a named user
+// or group.
+const aclUndefinedID = 0xffffffff
+
+// ACLEntry is an entry of a POSIX ACL.
+type ACLEntry struct {
+	// Tag is one of the ACL_* tags.
+	Tag uint16
+
+	// Perm holds the permissions, a combination of R_OK, W_OK
+	// and X_OK.
+	Perm uint16
+
+	// ID is the UID for ACL_USER, and the GID for ACL_GROUP
+	// entries. It is unused for other entries.
+	ID uint32
+}
+
+// ACL is a POSIX access control list, as stored in the
+// system.posix_acl_access and system.posix_acl_default extended
+// attributes. The entries are sorted by tag, and by ID for named
+// users and groups.
+//
+// The ACL_USER_OBJ, ACL_OTHER and ACL_MASK entries (or ACL_GROUP_OBJ
+// if there is no mask) of an access ACL correspond to the permission
+// bits of the file mode.
+type ACL []ACLEntry
+
+// ParseACL decodes the value of an ACL extended attribute. It fails
+// with EINVAL if the data is malformed or the ACL is not valid.
+func ParseACL(data []byte) (ACL, error) {
+	if len(data) < 4 || (len(data)-4)%8 != 0 || binary.LittleEndian.Uint32(data) != aclVersion {
+		return nil, syscall.EINVAL
+	}
+	var acl ACL
+	for data = data[4:]; len(data) > 0; data = data[8:] {
+		e := ACLEntry{
+			Tag:  binary.LittleEndian.Uint16(data),
+			Perm: binary.LittleEndian.Uint16(data[2:]),
+		}
+		if e.Tag == ACL_USER || e.Tag == ACL_GROUP {
+			e.ID = binary.LittleEndian.Uint32(data[4:])
+		}
+		acl = append(acl, e)
+	}
+	if err := acl.Valid(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+// Bytes encodes the ACL for storing it in an extended attribute.
+func (a ACL) Bytes() []byte {
+	data := make([]byte, 4, 4+8*len(a))
+	binary.LittleEndian.PutUint32(data, aclVersion)
+	for _, e := range a {
+		id := e.ID
+		if e.Tag != ACL_USER && e.Tag != ACL_GROUP {
+			id = aclUndefinedID
+		}
+		data = binary.LittleEndian.AppendUint16(data, e.Tag)
+		data = binary.LittleEndian.AppendUint16(data, e.Perm)
+		data = binary.LittleEndian.AppendUint32(data, id)
+	}
+	return data
+}
+
+// Valid returns EINVAL unless the ACL has exactly one ACL_USER_OBJ,
+// ACL_GROUP_OBJ and ACL_OTHER entry, a mask if it has entries for
+// named users or groups, and is sorted without duplicates.
+func (a ACL) Valid() error {
+	var seen uint16
+	named := false
+	for i, e := range a {
+		if e.Perm&^7 != 0 {
+			return syscall.EINVAL
+		}
+		switch e.Tag {
+		case ACL_USER, ACL_GROUP:
+			named = true
+			if i > 0 && a[i-1].Tag == e.Tag && a[i-1].ID >= e.ID {
+				return syscall.EINVAL
+			}
+		case ACL_USER_OBJ, ACL_GROUP_OBJ, ACL_MASK, ACL_OTHER:
+			if seen&e.Tag != 0 {
+				return syscall.EINVAL
+			}
+		default:
+			return syscall.EINVAL
+		}
+		if seen&^(e.Tag<<1-1) != 0 {
+			return syscall.EINVAL
+		}
+		seen |= e.Tag
+	}
+	required := uint16(ACL_USER_OBJ | ACL_GROUP_OBJ | ACL_OTHER)
+	if seen&required != required || named && seen&ACL_MASK == 0 {
+		return syscall.EINVAL
+	}
+	return nil
+}
+
+// find returns the entry with the given tag, which must not be
+// ACL_USER or ACL_GROUP, or nil.
+func (a ACL) find(tag uint16) *ACLEntry {
+	for i := range a {
+		if a[i].Tag == tag {
+			return &a[i]
+		}
+	}
+	return nil
+}
+
+// groupClass returns the entry that holds the group permission bits
+// of the file mode: the mask, or the owning group if there is none.
+func (a ACL) groupClass() *ACLEntry {
+	if e := a.find(ACL_MASK); e != nil {
+		return e
+	}
+	return a.find(ACL_GROUP_OBJ)
+}
+
+// Mode returns the permission bits of the file mode that correspond
+// to the ACL.
+func (a ACL) Mode() uint32 {
+	var mode uint32
+	if e := a.find(ACL_USER_OBJ); e != nil {
+		mode |= uint32(e.Perm) << 6
+	}
+	if e := a.groupClass(); e != nil {
+		mode |= uint32(e.Perm) << 3
+	}
+	if e := a.find(ACL_OTHER); e != nil {
+		mode |= uint32(e.Perm)
+	}
+	return mode
+}
+
+// Extended returns true if the ACL cannot be represented by the file
+// mode alone, because it has entries for named users or groups.
+func (a ACL) Extended() bool {
+	return len(a) > 3
+}
+
+// Chmod returns a copy of the ACL, with the entries that correspond
+// to the file mode set from the permission bits of mode. This
+// is how chmod(2) changes an access ACL.
+func (a ACL) Chmod(mode uint32) ACL {
+	a = append(ACL(nil), a...)
+	if e := a.find(ACL_USER_OBJ); e != nil {
+		e.Perm = uint16(mode>>6) & 7
+	}
+	if e := a.groupClass(); e != nil {
+		e.Perm = uint16(mode>>3) & 7
+	}
+	if e := a.find(ACL_OTHER); e != nil {
+		e.Perm = uint16(mode) & 7
+	}
+	return a
+}
+
+// Inherit computes the access ACL of a new file in a directory that
+// has default ACL a, and which is created with the given mode. It
+// returns the ACL, and the mode for the file, whose permission bits
+// are those of the ACL. A new directory also gets a as its default
+// ACL.
+func (a ACL) Inherit(mode uint32) (ACL, uint32) {
+	acl := append(ACL(nil), a...)
+	for _, e := range []*ACLEntry{acl.find(ACL_USER_OBJ), acl.groupClass(), acl.find(ACL_OTHER)} {
+		if e == nil {
+			continue
+		}
+		var shift uint32
+		switch e.Tag {
+		case ACL_USER_OBJ:
+			shift = 6
+		case ACL_GROUP_OBJ, ACL_MASK:
+			shift = 3
+		}
+		e.Perm &= uint16(mode>>shift) & 7
+	}
+	return acl, mode&^0777 | acl.Mode()
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestParseACL(t *testing.T) {
+	acl := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 7},
+		{Tag: ACL_USER, Perm: 6, ID: 1000},
+		{Tag: ACL_USER, Perm: 4, ID: 1001},
+		{Tag: ACL_GROUP_OBJ, Perm: 5},
+		{Tag: ACL_GROUP, Perm: 1, ID: 100},
+		{Tag: ACL_MASK, Perm: 7},
+		{Tag: ACL_OTHER, Perm: 0},
+	}
+	data := acl.Bytes()
+	if len(data) != 4+8*len(acl) {
+		t.Fatalf("got %d bytes", len(data))
+	}
+	got, err := ParseACL(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, acl) {
+		t.Errorf("got %v, want %v", got, acl)
+	}
+	if got, want := acl.Mode(), uint32(0770); got != want {
+		t.Errorf("Mode: got %o, want %o", got, want)
+	}
+	if !acl.Extended() {
+		t.Error("not Extended")
+	}
+
+	minimal := ACL{{Tag: ACL_USER_OBJ, Perm: 6}, {Tag: ACL_GROUP_OBJ, Perm: 4}, {Tag: ACL_OTHER, Perm: 4}}
+	if got, want := minimal.Mode(), uint32(0644); got != want {
+		t.Errorf("Mode: got %o, want %o", got, want)
+	}
+	if minimal.Extended() {
+		t.Error("minimal ACL is Extended")
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":   nil,
+		"version": {1, 0, 0, 0},
+		"short":   acl.Bytes()[:10],
+	} {
+		if _, err := ParseACL(data); err != syscall.EINVAL {
+			t.Errorf("%s: got %v, want EINVAL", name, err)
+		}
+	}
+
+	for name, acl := range map[string]ACL{
+		"no entries": {},
+		"no other":   minimal[:2],
+		"no mask": {
+			{Tag: ACL_USER_OBJ, Perm: 7},
+			{Tag: ACL_USER, Perm: 6, ID: 1000},
+			{Tag: ACL_GROUP_OBJ, Perm: 5},
+			{Tag: ACL_OTHER, Perm: 0},
+		},
+		"duplicate": {
+			{Tag: ACL_USER_OBJ, Perm: 7},
+			{Tag: ACL_USER, Perm: 6, ID: 1000},
+			{Tag: ACL_USER, Perm: 4, ID: 1000},
+			{Tag: ACL_GROUP_OBJ, Perm: 5},
+			{Tag: ACL_MASK, Perm: 7},
+			{Tag: ACL_OTHER, Perm: 0},
+		},
+		"order": {
+			{Tag: ACL_GROUP_OBJ, Perm: 5},
+			{Tag: ACL_USER_OBJ, Perm: 7},
+			{Tag: ACL_OTHER, Perm: 0},
+		},
+		"perm": {
+			{Tag: ACL_USER_OBJ, Perm: 8},
+			{Tag: ACL_GROUP_OBJ, Perm: 5},
+			{Tag: ACL_OTHER, Perm: 0},
+		},
+		"tag": {
+			{Tag: ACL_USER_OBJ, Perm: 7},
+			{Tag: ACL_GROUP_OBJ, Perm: 5},
+			{Tag: ACL_OTHER, Perm: 0},
+			{Tag: 0x40, Perm: 0},
+		},
+	} {
+		if err := acl.Valid(); err != syscall.EINVAL {
+			t.Errorf("%s: got %v, want EINVAL", name, err)
+		}
+		if _, err := ParseACL(acl.Bytes()); err != syscall.EINVAL {
+			t.Errorf("%s: ParseACL: got %v, want EINVAL", name, err)
+		}
+	}
+}
+
+func TestACLChmod(t *testing.T) {
+	acl := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 7},
+		{Tag: ACL_USER, Perm: 6, ID: 1000},
+		{Tag: ACL_GROUP_OBJ, Perm: 5},
+		{Tag: ACL_MASK, Perm: 7},
+		{Tag: ACL_OTHER, Perm: 5},
+	}
+	got := acl.Chmod(0640)
+	want := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 6},
+		{Tag: ACL_USER, Perm: 6, ID: 1000},
+		{Tag: ACL_GROUP_OBJ, Perm: 5},
+		{Tag: ACL_MASK, Perm: 4},
+		{Tag: ACL_OTHER, Perm: 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if acl[0].Perm != 7 {
+		t.Error("Chmod modified its receiver")
+	}
+
+	minimal := ACL{{Tag: ACL_USER_OBJ, Perm: 7}, {Tag: ACL_GROUP_OBJ, Perm: 7}, {Tag: ACL_OTHER, Perm: 7}}
+	if got := minimal.Chmod(0751).Mode(); got != 0751 {
+		t.Errorf("got %o, want 751", got)
+	}
+}
+
+func TestACLInherit(t *testing.T) {
+	def := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 7},
+		{Tag: ACL_USER, Perm: 7, ID: 1000},
+		{Tag: ACL_GROUP_OBJ, Perm: 5},
+		{Tag: ACL_MASK, Perm: 7},
+		{Tag: ACL_OTHER, Perm: 5},
+	}
+	acl, mode := def.Inherit(syscall.S_IFREG | 0640)
+	if want := uint32(syscall.S_IFREG | 0640); mode != want {
+		t.Errorf("mode: got %o, want %o", mode, want)
+	}
+	want := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 6},
+		{Tag: ACL_USER, Perm: 7, ID: 1000},
+		{Tag: ACL_GROUP_OBJ, Perm: 5},
+		{Tag: ACL_MASK, Perm: 4},
+		{Tag: ACL_OTHER, Perm: 0},
+	}
+	if !reflect.DeepEqual(acl, want) {
+		t.Errorf("got %v, want %v", acl, want)
+	}
+
+	// The default ACL limits the mode.
+	minimal := ACL{{Tag: ACL_USER_OBJ, Perm: 7}, {Tag: ACL_GROUP_OBJ, Perm: 0}, {Tag: ACL_OTHER, Perm: 0}}
+	if _, mode := minimal.Inherit(syscall.S_ISGID | 0777); mode != syscall.S_ISGID|0700 {
+		t.Errorf("mode: got %o, want 2700", mode)
+	}
+}
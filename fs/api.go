@@ -230,6 +230,19 @@
 // them. This is done by instantiating "persistent" inodes from the
 // OnAdd method of the root node.  See the ZipFS example for a
 // runnable example of how to do this.
+//
+// # Wrapping trees
+//
+// Some constructors, such as NewReadonlyRoot, return a root that
+// wraps another tree, and forwards operations to it, changing some
+// of them on the way. The wrapped tree is served by a bridge of its
+// own, and is initialized when the wrapper is created, so it must not
+// be mounted or be part of another tree; the constructors panic if
+// it is. The wrapper itself can be mounted, or added to another
+// tree. Its nodes report the inode numbers of the nodes they wrap.
+// The wrapped tree hands out automatic inode numbers from a range
+// below 1<<63, which does not overlap with other wrapped trees, or
+// with the default FirstAutomaticIno of the tree it is added to.
 package fs
 
 import (
@@ -437,6 +450,14 @@ type NodeSetlkwer interface {
 	Setlkw(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno
 }
 
+// ReleaseLocks drops the locks held by a lock owner. It is called
+// when a file is closed: on Flush with the owner of the POSIX locks
+// of the calling process, and on Release with the owner of the flock
+// locks of the file, if they were used. See LockManager.
+type NodeLockReleaser interface {
+	ReleaseLocks(ctx context.Context, owner uint64)
+}
+
 // OnForget is called when the node becomes unreachable. This can
 // happen because the kernel issues a FORGET request,
 // ForgetPersistent() is called on the inode, or the last child of the
@@ -448,6 +469,15 @@ type NodeOnForgetter interface {
 	OnForget()
 }
 
+// Discard is called on a node returned from Lookup, Create, Mkdir,
+// Mknod, Symlink or Link if it is not added to the tree, because the
+// tree already has a live node with the same StableAttr. Nodes that
+// acquire resources when they are created should release them here;
+// OnForget is not called for discarded nodes.
+type NodeDiscarder interface {
+	Discard()
+}
+
 // DirStream lists directory entries.
 type DirStream interface {
 	// HasNext indicates if there are further entries. HasNext
@@ -625,6 +655,15 @@ type FileWriter interface {
 	Write(ctx context.Context, data []byte, off int64) (written uint32, errno syscall.Errno)
 }
 
+// FileSpliceWriter is an optional variant of FileWriter. If the
+// mount has fuse.MountOptions.EnableSpliceWrite set, the data of large
+// writes is passed in a pipe, which can be spliced into a file
+// descriptor with fuse.WritePipe.SpliceTo. Other writes, and writes to
+// nodes implementing NodeWriter, go to FileWriter.Write.
+type FileSpliceWriter interface {
+	SpliceWrite(ctx context.Context, data *fuse.WritePipe, off int64) (written uint32, errno syscall.Errno)
+}
+
 // See NodeGetlker.
 type FileGetlker interface {
 	Getlk(ctx context.Context, owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) syscall.Errno
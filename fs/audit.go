@@ -0,0 +1,410 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// AuditRecord describes an operation that modified the tree. Fields
+// that do not apply to the operation are left empty.
+type AuditRecord struct {
+	// Time is when the operation completed.
+	Time time.Time `json:"time"`
+
+	// Op is "create", "mkdir", "mknod", "symlink", "link",
+	// "open" (with O_TRUNC), "write", "allocate",
+	// "copy_file_range", "setattr", "setxattr", "removexattr",
+	// "rename", "unlink" or "rmdir".
+	Op string `json:"op"`
+
+	// Path is the slash-separated path relative to the root. For
+	// copy_file_range, it is the destination.
+	Path string `json:"path"`
+
+	// NewPath is the destination of rename.
+	NewPath string `json:"new_path,omitempty"`
+
+	// Target is the existing path for link, the contents of the
+	// link for symlink, and the source for copy_file_range.
+	Target string `json:"target,omitempty"`
+
+	// Offset and Length give the written range for write,
+	// allocate and copy_file_range.
+	Offset int64 `json:"offset,omitempty"`
+	Length int64 `json:"length,omitempty"`
+
+	// Flags of open, rename, setxattr and allocate.
+	Flags uint32 `json:"flags,omitempty"`
+
+	// Mode of created nodes, or set by setattr.
+	Mode uint32 `json:"mode,omitempty"`
+
+	// The attributes changed by setattr.
+	Size   *uint64    `json:"size,omitempty"`
+	NewUid *uint32    `json:"new_uid,omitempty"`
+	NewGid *uint32    `json:"new_gid,omitempty"`
+	Atime  *time.Time `json:"atime,omitempty"`
+	Mtime  *time.Time `json:"mtime,omitempty"`
+
+	// Xattr is the attribute name for setxattr and removexattr.
+	Xattr string `json:"xattr,omitempty"`
+
+	// The caller of the operation, from fuse.Caller. Cmdline is
+	// read from /proc, and is empty if the process has exited.
+	Uid     uint32   `json:"uid"`
+	Gid     uint32   `json:"gid"`
+	Pid     uint32   `json:"pid"`
+	Cmdline []string `json:"cmdline,omitempty"`
+
+	// Errno is the result of the operation.
+	Errno syscall.Errno `json:"errno"`
+}
+
+// AuditSink receives the records of a wrapper made with
+// NewAuditRoot. Record is called concurrently, after the operation
+// completed. Errors are logged, and do not change the result of the
+// operation.
+type AuditSink interface {
+	Record(r *AuditRecord) error
+}
+
+// NewAuditRoot returns a root for the tree at root, which sends a
+// record of every operation that modifies the tree to sink,
+// including failed ones.
+//
+// Files are opened without passthrough, so all writes are seen.
+// They are attributed to the process that issued them, unless the
+// kernel caches them (Options.WritebackCache), in which case they
+// may be sent later, by a kernel thread.
+//
+// Root must not be mounted or be part of another tree; see "Wrapping
+// trees" in the package documentation.
+func NewAuditRoot(root InodeEmbedder, sink AuditSink) InodeEmbedder {
+	cmdlines := &auditCmdlines{}
+	t := newWrapTree(root, func() wrapper { return &auditNode{sink: sink, cmdlines: cmdlines} })
+	return t.wrapRoot(root.embed())
+}
+
+type auditNode struct {
+	wrapNode
+
+	sink     AuditSink
+	cmdlines *auditCmdlines
+}
+
+// maxAuditCmdlines bounds the number of cached command lines.
+const maxAuditCmdlines = 256
+
+// auditCmdlines caches command lines by PID, so they are not read
+// from /proc for every write. Entries are dropped when the process
+// opens or releases a file, so a reused PID is not reported with the
+// command line of the process that had it before.
+type auditCmdlines struct {
+	mu sync.Mutex
+	m  map[uint32][]string
+}
+
+// get returns the command line of pid. It is read from /proc, unless
+// cached is set and it is in the cache.
+func (c *auditCmdlines) get(pid uint32, cached bool) []string {
+	c.mu.Lock()
+	cmdline, ok := c.m[pid]
+	c.mu.Unlock()
+	if ok && cached {
+		return cmdline
+	}
+
+	cmdline = procCmdline(pid)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.m == nil || len(c.m) >= maxAuditCmdlines {
+		c.m = map[uint32][]string{}
+	}
+	c.m[pid] = cmdline
+	return cmdline
+}
+
+// forget drops the command line of the caller from the cache.
+func (c *auditCmdlines) forget(ctx context.Context) {
+	if caller, ok := fuse.FromContext(ctx); ok {
+		c.mu.Lock()
+		delete(c.m, caller.Pid)
+		c.mu.Unlock()
+	}
+}
+
+// record completes r with the caller and result, and sends it to
+// the sink.
+func (n *auditNode) record(ctx context.Context, r *AuditRecord, errno syscall.Errno) {
+	r.Time = time.Now()
+	r.Errno = errno
+	if c, ok := fuse.FromContext(ctx); ok {
+		r.Uid = c.Uid
+		r.Gid = c.Gid
+		r.Pid = c.Pid
+		r.Cmdline = n.cmdlines.get(c.Pid, r.Op == "write")
+	}
+	if err := n.sink.Record(r); err != nil {
+		n.bridge.logf("audit: %s %q: %v", r.Op, r.Path, err)
+	}
+}
+
+// nodePath returns the path of a node of the same wrapper.
+func (n *auditNode) nodePath(ops InodeEmbedder) string {
+	if w, ok := ops.(wrapper); ok && w.wrap().tree == n.tree {
+		return w.wrap().relPath()
+	}
+	return ""
+}
+
+func (n *auditNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	child, errno := n.wrapNode.Mkdir(ctx, name, mode, out)
+	n.record(ctx, &AuditRecord{Op: "mkdir", Path: n.childPath(name), Mode: mode}, errno)
+	return child, errno
+}
+
+func (n *auditNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	child, errno := n.wrapNode.Mknod(ctx, name, mode, dev, out)
+	n.record(ctx, &AuditRecord{Op: "mknod", Path: n.childPath(name), Mode: mode}, errno)
+	return child, errno
+}
+
+func (n *auditNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	child, errno := n.wrapNode.Link(ctx, target, name, out)
+	n.record(ctx, &AuditRecord{Op: "link", Path: n.childPath(name), Target: n.nodePath(target)}, errno)
+	return child, errno
+}
+
+func (n *auditNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	child, errno := n.wrapNode.Symlink(ctx, target, name, out)
+	n.record(ctx, &AuditRecord{Op: "symlink", Path: n.childPath(name), Target: target}, errno)
+	return child, errno
+}
+
+func (n *auditNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	child, fh, fuseFlags, errno := n.wrapNode.Create(ctx, name, flags, mode, out)
+	n.record(ctx, &AuditRecord{Op: "create", Path: n.childPath(name), Flags: flags, Mode: mode}, errno)
+	return child, hideFile(fh), fuseFlags, errno
+}
+
+func (n *auditNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	p := n.childPath(name)
+	errno := n.wrapNode.Unlink(ctx, name)
+	n.record(ctx, &AuditRecord{Op: "unlink", Path: p}, errno)
+	return errno
+}
+
+func (n *auditNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	p := n.childPath(name)
+	errno := n.wrapNode.Rmdir(ctx, name)
+	n.record(ctx, &AuditRecord{Op: "rmdir", Path: p}, errno)
+	return errno
+}
+
+func (n *auditNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	r := &AuditRecord{Op: "rename", Path: n.childPath(name), Flags: flags}
+	if p, ok := newParent.(*auditNode); ok {
+		r.NewPath = p.childPath(newName)
+	}
+	errno := n.wrapNode.Rename(ctx, name, newParent, newName, flags)
+	n.record(ctx, r, errno)
+	return errno
+}
+
+// Open records opening with O_TRUNC, which truncates the file.
+func (n *auditNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	n.cmdlines.forget(ctx)
+	fh, fuseFlags, errno := n.wrapNode.Open(ctx, flags)
+	if flags&syscall.O_TRUNC != 0 {
+		n.record(ctx, &AuditRecord{Op: "open", Path: n.relPath(), Flags: flags}, errno)
+	}
+	return hideFile(fh), fuseFlags, errno
+}
+
+func (n *auditNode) Release(ctx context.Context, f FileHandle) syscall.Errno {
+	n.cmdlines.forget(ctx)
+	return n.wrapNode.Release(ctx, f)
+}
+
+func (n *auditNode) Write(ctx context.Context, f FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	written, errno := n.wrapNode.Write(ctx, f, data, off)
+	n.record(ctx, &AuditRecord{Op: "write", Path: n.relPath(), Offset: off, Length: int64(written)}, errno)
+	return written, errno
+}
+
+func (n *auditNode) spliceWrite(ctx context.Context, f FileHandle, data *fuse.WritePipe, off int64) (uint32, syscall.Errno, bool) {
+	written, errno, ok := n.wrapNode.spliceWrite(ctx, f, data, off)
+	if ok {
+		n.record(ctx, &AuditRecord{Op: "write", Path: n.relPath(), Offset: off, Length: int64(written)}, errno)
+	}
+	return written, errno, ok
+}
+
+func (n *auditNode) Allocate(ctx context.Context, f FileHandle, off uint64, size uint64, mode uint32) syscall.Errno {
+	errno := n.wrapNode.Allocate(ctx, f, off, size, mode)
+	n.record(ctx, &AuditRecord{Op: "allocate", Path: n.relPath(), Offset: int64(off), Length: int64(size), Flags: mode}, errno)
+	return errno
+}
+
+func (n *auditNode) CopyFileRange(ctx context.Context, fhIn FileHandle, offIn uint64, out *Inode, fhOut FileHandle, offOut uint64, len uint64, flags uint64) (uint32, syscall.Errno) {
+	written, errno := n.wrapNode.CopyFileRange(ctx, fhIn, offIn, out, fhOut, offOut, len, flags)
+	n.record(ctx, &AuditRecord{
+		Op:     "copy_file_range",
+		Path:   n.nodePath(out.ops),
+		Target: n.relPath(),
+		Offset: int64(offOut),
+		Length: int64(written),
+	}, errno)
+	return written, errno
+}
+
+func (n *auditNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	errno := n.wrapNode.Setattr(ctx, f, in, out)
+	r := &AuditRecord{Op: "setattr", Path: n.relPath()}
+	if m, ok := in.GetMode(); ok {
+		r.Mode = m
+	}
+	if sz, ok := in.GetSize(); ok {
+		r.Size = &sz
+	}
+	if uid, ok := in.GetUID(); ok {
+		r.NewUid = &uid
+	}
+	if gid, ok := in.GetGID(); ok {
+		r.NewGid = &gid
+	}
+	if a, ok := in.GetATime(); ok {
+		r.Atime = &a
+	}
+	if m, ok := in.GetMTime(); ok {
+		r.Mtime = &m
+	}
+	n.record(ctx, r, errno)
+	return errno
+}
+
+func (n *auditNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	errno := n.wrapNode.Setxattr(ctx, attr, data, flags)
+	n.record(ctx, &AuditRecord{Op: "setxattr", Path: n.relPath(), Xattr: attr, Flags: flags, Length: int64(len(data))}, errno)
+	return errno
+}
+
+func (n *auditNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	errno := n.wrapNode.Removexattr(ctx, attr)
+	n.record(ctx, &AuditRecord{Op: "removexattr", Path: n.relPath(), Xattr: attr}, errno)
+	return errno
+}
+
+// AuditFile is an AuditSink that writes records as JSON, one per
+// line. When the file would grow beyond MaxSize, it is rotated: it
+// is renamed to Name.1, and older files to Name.2 and so on, up to
+// Name.MaxBackups. Older files are removed.
+type AuditFile struct {
+	Name       string
+	MaxSize    int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+var _ = (AuditSink)((*AuditFile)(nil))
+
+// NewAuditFile opens the file name for appending records. A MaxSize
+// of 0 disables rotation.
+func NewAuditFile(name string, maxSize int64, maxBackups int) (*AuditFile, error) {
+	a := &AuditFile{Name: name, MaxSize: maxSize, MaxBackups: maxBackups}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AuditFile) open() error {
+	f, err := os.OpenFile(a.Name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	a.f = f
+	a.size = st.Size()
+	return nil
+}
+
+// rotate renames the current file away, and opens a new one.
+func (a *AuditFile) rotate() error {
+	if err := a.f.Close(); err != nil {
+		return err
+	}
+	a.f = nil
+	if a.MaxBackups <= 0 {
+		if err := os.Remove(a.Name); err != nil {
+			return err
+		}
+		return a.open()
+	}
+	for i := a.MaxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", a.Name, i), fmt.Sprintf("%s.%d", a.Name, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(a.Name, a.Name+".1"); err != nil {
+		return err
+	}
+	return a.open()
+}
+
+// Record writes r as a line of JSON.
+func (a *AuditFile) Record(r *AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		// A previous rotation failed.
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	if a.MaxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.MaxSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.f.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+// Close closes the file.
+func (a *AuditFile) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		return nil
+	}
+	err := a.f.Close()
+	a.f = nil
+	return err
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestAuditRootXattr(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.WriteFile(filepath.Join(orig, "file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &auditRecorder{}
+	mnt, _ := testMount(t, NewAuditRoot(loopback, rec), nil)
+
+	file := filepath.Join(mnt, "file")
+	if err := unix.Setxattr(file, "user.attr", []byte("value"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Removexattr(file, "user.attr"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []AuditRecord{
+		{Op: "setxattr", Path: "file", Xattr: "user.attr", Length: 5},
+		{Op: "removexattr", Path: "file", Xattr: "user.attr"},
+	} {
+		got := rec.find(want.Op, want.Path)
+		if got == nil {
+			t.Errorf("no record for %s %q", want.Op, want.Path)
+			continue
+		}
+		if got.Xattr != want.Xattr || got.Length != want.Length || got.Errno != 0 {
+			t.Errorf("%s %q: got %+v, want %+v", want.Op, want.Path, got, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+type auditRecorder struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (r *auditRecorder) Record(rec *AuditRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, *rec)
+	return nil
+}
+
+// find returns the first record for op on path.
+func (r *auditRecorder) find(op, path string) *AuditRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.records {
+		if rec := &r.records[i]; rec.Op == op && rec.Path == path {
+			return rec
+		}
+	}
+	return nil
+}
+
+func TestAuditRoot(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(orig, "full/sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &auditRecorder{}
+	mnt, _ := testMount(t, NewAuditRoot(loopback, rec), nil)
+
+	file := filepath.Join(mnt, "file")
+	if err := os.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(mnt, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(file, filepath.Join(mnt, "dir/file")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(mnt, "dir/file"), filepath.Join(mnt, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir/file", filepath.Join(mnt, "symlink")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(mnt, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Rmdir(filepath.Join(mnt, "full")); err != syscall.ENOTEMPTY {
+		t.Fatalf("rmdir: got %v, want ENOTEMPTY", err)
+	}
+
+	for _, want := range []AuditRecord{
+		{Op: "create", Path: "file", Mode: 0644},
+		{Op: "write", Path: "file", Length: 5},
+		{Op: "setattr", Path: "file", Mode: 0600},
+		{Op: "mkdir", Path: "dir", Mode: 0755},
+		{Op: "rename", Path: "file", NewPath: "dir/file"},
+		{Op: "link", Path: "link", Target: "dir/file"},
+		{Op: "symlink", Path: "symlink", Target: "dir/file"},
+		{Op: "unlink", Path: "link"},
+		{Op: "rmdir", Path: "full", Errno: syscall.ENOTEMPTY},
+	} {
+		got := rec.find(want.Op, want.Path)
+		if got == nil {
+			t.Errorf("no record for %s %q", want.Op, want.Path)
+			continue
+		}
+		if got.NewPath != want.NewPath || got.Target != want.Target || got.Length != want.Length ||
+			got.Xattr != want.Xattr || got.Mode&07777 != want.Mode || got.Errno != want.Errno {
+			t.Errorf("%s %q: got %+v, want %+v", want.Op, want.Path, got, want)
+		}
+		if got.Uid != uint32(os.Getuid()) || got.Pid == 0 || got.Time.IsZero() {
+			t.Errorf("%s %q: got caller %d/%d/%d at %v", want.Op, want.Path, got.Uid, got.Gid, got.Pid, got.Time)
+		}
+		if len(got.Cmdline) == 0 || got.Cmdline[0] != os.Args[0] {
+			t.Errorf("%s %q: got cmdline %q, want %q", want.Op, want.Path, got.Cmdline, os.Args[0])
+		}
+	}
+}
+
+func TestAuditFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+	a, err := NewAuditFile(name, 300, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := a.Record(&AuditRecord{Op: "write", Path: fmt.Sprintf("file%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(name + ".3"); !os.IsNotExist(err) {
+		t.Errorf("got %v for third backup, want ENOENT", err)
+	}
+	var last string
+	for _, nm := range []string{name + ".2", name + ".1", name} {
+		f, err := os.Open(nm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		st, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Size() > 300 {
+			t.Errorf("%s: got size %d, want at most 300", nm, st.Size())
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			var r AuditRecord
+			if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+				t.Fatalf("%s: %q: %v", nm, s.Text(), err)
+			}
+			last = r.Path
+		}
+	}
+	if last != "file19" {
+		t.Errorf("got last record %q", last)
+	}
+}
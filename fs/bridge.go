@@ -29,6 +29,11 @@ type fileEntry struct {
 	// Handle number which we communicate to the kernel.
 	fh uint32
 
+	// The flags passed to open, and the backing ID if the file
+	// is opened in passthrough mode. Protected by bridge.mu.
+	flags     uint32
+	backingID int32
+
 	// Protects directory fields. Must be acquired before bridge.mu
 	mu sync.Mutex
 
@@ -71,6 +76,14 @@ type serverBackingFdCallbacks interface {
 	UnregisterBackingFd(id int32) syscall.Errno
 }
 
+type serverContexter interface {
+	Context(cancel <-chan struct{}, header *fuse.InHeader) *fuse.Context
+}
+
+type serverExpireCallbacks interface {
+	EntryNotifyExpire(parent uint64, name string) fuse.Status
+}
+
 type rawBridge struct {
 	options Options
 	root    *Inode
@@ -239,9 +252,25 @@ func (b *rawBridge) addNewChild(parent *Inode, name string, child *Inode, file F
 	b.mu.Unlock()
 	unlockNodes(parent, child)
 
+	if child != orig {
+		if d, ok := orig.ops.(NodeDiscarder); ok {
+			d.Discard()
+		}
+	}
 	return child, fe
 }
 
+// discardChild drops a node returned by a lookup, which is not added
+// to the tree. Nodes that are not in the tree already are discarded.
+func (b *rawBridge) discardChild(child *Inode) {
+	if old, _ := b._getStableNode(child.stableAttr); old == child {
+		return
+	}
+	if d, ok := child.ops.(NodeDiscarder); ok {
+		d.Discard()
+	}
+}
+
 func (b *rawBridge) setEntryOutTimeout(out *fuse.EntryOut) {
 	b.setAttr(&out.Attr)
 	if b.options.AttrTimeout != nil && out.AttrTimeout() == 0 {
@@ -374,7 +403,7 @@ func (b *rawBridge) getFile(fh uint64) *fileEntry {
 
 func (b *rawBridge) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
 	parent := b.getNode(header.NodeId)
-	ctx := &fuse.Context{Caller: header.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, header)
 	child, errno := b.lookup(ctx, parent, name, out)
 
 	if errno != 0 {
@@ -391,7 +420,7 @@ func (b *rawBridge) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name s
 	return fuse.OK
 }
 
-func (b *rawBridge) lookup(ctx *fuse.Context, parent *Inode, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+func (b *rawBridge) lookup(ctx context.Context, parent *Inode, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
 	if lu, ok := parent.ops.(NodeLookuper); ok {
 		return lu.Lookup(ctx, name, out)
 	}
@@ -416,7 +445,7 @@ func (b *rawBridge) Rmdir(cancel <-chan struct{}, header *fuse.InHeader, name st
 	parent := b.getNode(header.NodeId)
 	var errno syscall.Errno
 	if mops, ok := parent.ops.(NodeRmdirer); ok {
-		errno = mops.Rmdir(&fuse.Context{Caller: header.Caller, Cancel: cancel}, name)
+		errno = mops.Rmdir(b.newContext(cancel, header), name)
 	}
 
 	// TODO - this should not succeed silently.
@@ -431,7 +460,7 @@ func (b *rawBridge) Unlink(cancel <-chan struct{}, header *fuse.InHeader, name s
 	parent := b.getNode(header.NodeId)
 	var errno syscall.Errno
 	if mops, ok := parent.ops.(NodeUnlinker); ok {
-		errno = mops.Unlink(&fuse.Context{Caller: header.Caller, Cancel: cancel}, name)
+		errno = mops.Unlink(b.newContext(cancel, header), name)
 	}
 
 	// TODO - this should not succeed silently.
@@ -445,7 +474,7 @@ func (b *rawBridge) Unlink(cancel <-chan struct{}, header *fuse.InHeader, name s
 func (b *rawBridge) Mkdir(cancel <-chan struct{}, input *fuse.MkdirIn, name string, out *fuse.EntryOut) fuse.Status {
 	parent := b.getNode(input.NodeId)
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	mops, ok := parent.ops.(NodeMkdirer)
 	if !ok {
 		return fuse.ENOTSUP
@@ -477,7 +506,7 @@ func (b *rawBridge) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name stri
 	if !ok {
 		return fuse.ENOTSUP
 	}
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	child, errno := mops.Mknod(ctx, name, input.Mode, input.Rdev, out)
 	if errno != 0 {
 		return errnoToStatus(errno)
@@ -496,7 +525,7 @@ func (b *rawBridge) Create(cancel <-chan struct{}, input *fuse.CreateIn, name st
 	if !ok {
 		return fuse.EROFS
 	}
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	child, f, flags, errno := mops.Create(ctx, name, input.Flags, input.Mode, &out.EntryOut)
 
 	if errno != 0 {
@@ -509,7 +538,12 @@ func (b *rawBridge) Create(cancel <-chan struct{}, input *fuse.CreateIn, name st
 	}
 	out.OpenFlags = flags
 
+	b.mu.Lock()
 	b.addBackingID(child, f, &out.OpenOut)
+	if fe != nil {
+		fe.backingID = out.BackingID
+	}
+	b.mu.Unlock()
 	child.setEntryOut(&out.EntryOut)
 	b.setEntryOutTimeout(&out.EntryOut)
 	return fuse.OK
@@ -538,7 +572,7 @@ func (b *rawBridge) GetAttr(cancel <-chan struct{}, input *fuse.GetAttrIn, out *
 		}
 		b.mu.Unlock()
 	}
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	return errnoToStatus(b.getattr(ctx, n, f, out))
 }
 
@@ -566,7 +600,7 @@ func (b *rawBridge) getattr(ctx context.Context, n *Inode, f FileHandle, out *fu
 }
 
 func (b *rawBridge) SetAttr(cancel <-chan struct{}, in *fuse.SetAttrIn, out *fuse.AttrOut) fuse.Status {
-	ctx := &fuse.Context{Caller: in.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &in.InHeader)
 
 	fh, _ := in.GetFh()
 
@@ -589,7 +623,7 @@ func (b *rawBridge) Rename(cancel <-chan struct{}, input *fuse.RenameIn, oldName
 	p2 := b.getNode(input.Newdir)
 
 	if mops, ok := p1.ops.(NodeRenamer); ok {
-		errno := mops.Rename(&fuse.Context{Caller: input.Caller, Cancel: cancel}, oldName, p2.ops, newName, input.Flags)
+		errno := mops.Rename(b.newContext(cancel, &input.InHeader), oldName, p2.ops, newName, input.Flags)
 		if errno == 0 {
 			if input.Flags&RENAME_EXCHANGE != 0 {
 				p1.ExchangeChild(oldName, p2, newName)
@@ -612,7 +646,7 @@ func (b *rawBridge) Link(cancel <-chan struct{}, input *fuse.LinkIn, name string
 		return fuse.ENOTSUP
 	}
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	child, errno := mops.Link(ctx, target.ops, name, out)
 	if errno != 0 {
 		return errnoToStatus(errno)
@@ -631,7 +665,7 @@ func (b *rawBridge) Symlink(cancel <-chan struct{}, header *fuse.InHeader, targe
 	if !ok {
 		return fuse.ENOTSUP
 	}
-	ctx := &fuse.Context{Caller: header.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, header)
 	child, status := mops.Symlink(ctx, target, name, out)
 	if status != 0 {
 		return errnoToStatus(status)
@@ -650,7 +684,7 @@ func (b *rawBridge) Readlink(cancel <-chan struct{}, header *fuse.InHeader) (out
 	if !ok {
 		return nil, fuse.ENOTSUP
 	}
-	ctx := &fuse.Context{Caller: header.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, header)
 	result, errno := linker.Readlink(ctx)
 	if errno != 0 {
 		return nil, errnoToStatus(errno)
@@ -662,23 +696,25 @@ func (b *rawBridge) Readlink(cancel <-chan struct{}, header *fuse.InHeader) (out
 func (b *rawBridge) Access(cancel <-chan struct{}, input *fuse.AccessIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
+	return errnoToStatus(b.access(ctx, n, &input.Caller, input.Mask))
+}
+
+func (b *rawBridge) access(ctx context.Context, n *Inode, caller *fuse.Caller, mask uint32) syscall.Errno {
 	if a, ok := n.ops.(NodeAccesser); ok {
-		return errnoToStatus(a.Access(ctx, input.Mask))
+		return a.Access(ctx, mask)
 	}
 
 	// default: check attributes.
-	caller := input.Caller
-
 	var out fuse.AttrOut
 	if s := b.getattr(ctx, n, nil, &out); s != 0 {
-		return errnoToStatus(s)
+		return s
 	}
 
-	if !internal.HasAccess(caller.Uid, caller.Gid, out.Uid, out.Gid, out.Mode, input.Mask) {
-		return fuse.EACCES
+	if !internal.HasAccessGroups(caller.Uid, caller.Gid, caller.Groups, out.Uid, out.Gid, out.Mode, mask) {
+		return syscall.EACCES
 	}
-	return fuse.OK
+	return OK
 }
 
 // Extended attributes.
@@ -687,7 +723,7 @@ func (b *rawBridge) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr
 	n := b.getNode(header.NodeId)
 
 	if xops, ok := n.ops.(NodeGetxattrer); ok {
-		nb, errno := xops.Getxattr(&fuse.Context{Caller: header.Caller, Cancel: cancel}, attr, data)
+		nb, errno := xops.Getxattr(b.newContext(cancel, header), attr, data)
 		return nb, errnoToStatus(errno)
 	}
 
@@ -697,7 +733,7 @@ func (b *rawBridge) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr
 func (b *rawBridge) ListXAttr(cancel <-chan struct{}, header *fuse.InHeader, dest []byte) (sz uint32, status fuse.Status) {
 	n := b.getNode(header.NodeId)
 	if xops, ok := n.ops.(NodeListxattrer); ok {
-		sz, errno := xops.Listxattr(&fuse.Context{Caller: header.Caller, Cancel: cancel}, dest)
+		sz, errno := xops.Listxattr(b.newContext(cancel, header), dest)
 		return sz, errnoToStatus(errno)
 	}
 	return 0, fuse.OK
@@ -706,7 +742,7 @@ func (b *rawBridge) ListXAttr(cancel <-chan struct{}, header *fuse.InHeader, des
 func (b *rawBridge) SetXAttr(cancel <-chan struct{}, input *fuse.SetXAttrIn, attr string, data []byte) fuse.Status {
 	n := b.getNode(input.NodeId)
 	if xops, ok := n.ops.(NodeSetxattrer); ok {
-		return errnoToStatus(xops.Setxattr(&fuse.Context{Caller: input.Caller, Cancel: cancel}, attr, data, input.Flags))
+		return errnoToStatus(xops.Setxattr(b.newContext(cancel, &input.InHeader), attr, data, input.Flags))
 	}
 	return fuse.ENOATTR
 }
@@ -714,7 +750,7 @@ func (b *rawBridge) SetXAttr(cancel <-chan struct{}, input *fuse.SetXAttrIn, att
 func (b *rawBridge) RemoveXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr string) fuse.Status {
 	n := b.getNode(header.NodeId)
 	if xops, ok := n.ops.(NodeRemovexattrer); ok {
-		return errnoToStatus(xops.Removexattr(&fuse.Context{Caller: header.Caller, Cancel: cancel}, attr))
+		return errnoToStatus(xops.Removexattr(b.newContext(cancel, header), attr))
 	}
 	return fuse.ENOATTR
 }
@@ -726,7 +762,7 @@ func (b *rawBridge) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.O
 	if !ok {
 		return fuse.ENOTSUP
 	}
-	f, flags, errno := op.Open(&fuse.Context{Caller: input.Caller, Cancel: cancel}, input.Flags)
+	f, flags, errno := op.Open(b.newContext(cancel, &input.InHeader), input.Flags)
 	if errno != 0 {
 		return errnoToStatus(errno)
 	}
@@ -739,6 +775,7 @@ func (b *rawBridge) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.O
 		out.Fh = uint64(fe.fh)
 
 		b.addBackingID(n, f, out)
+		fe.backingID = out.BackingID
 	}
 	return fuse.OK
 }
@@ -822,6 +859,7 @@ func (b *rawBridge) registerFile(n *Inode, f FileHandle, flags uint32) *fileEntr
 	}
 	fe.nodeIndex = len(n.openFiles)
 	fe.file = f
+	fe.flags = flags
 	n.openFiles = append(n.openFiles, fe.fh)
 
 	return fe
@@ -831,7 +869,7 @@ func (b *rawBridge) Read(cancel <-chan struct{}, input *fuse.ReadIn, buf []byte)
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if fops, ok := n.ops.(NodeReader); ok {
 		res, errno := fops.Read(ctx, f.file, buf, int64(input.Offset))
 		return res, errnoToStatus(errno)
@@ -848,7 +886,7 @@ func (b *rawBridge) GetLk(cancel <-chan struct{}, input *fuse.LkIn, out *fuse.Lk
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if lops, ok := n.ops.(NodeGetlker); ok {
 		return errnoToStatus(lops.Getlk(ctx, f.file, input.Owner, &input.Lk, input.LkFlags, &out.Lk))
 	}
@@ -861,7 +899,7 @@ func (b *rawBridge) GetLk(cancel <-chan struct{}, input *fuse.LkIn, out *fuse.Lk
 func (b *rawBridge) SetLk(cancel <-chan struct{}, input *fuse.LkIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if lops, ok := n.ops.(NodeSetlker); ok {
 		return errnoToStatus(lops.Setlk(ctx, f.file, input.Owner, &input.Lk, input.LkFlags))
 	}
@@ -873,7 +911,7 @@ func (b *rawBridge) SetLk(cancel <-chan struct{}, input *fuse.LkIn) fuse.Status
 func (b *rawBridge) SetLkw(cancel <-chan struct{}, input *fuse.LkIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if lops, ok := n.ops.(NodeSetlkwer); ok {
 		return errnoToStatus(lops.Setlkw(ctx, f.file, input.Owner, &input.Lk, input.LkFlags))
 	}
@@ -885,13 +923,16 @@ func (b *rawBridge) SetLkw(cancel <-chan struct{}, input *fuse.LkIn) fuse.Status
 
 func (b *rawBridge) Release(cancel <-chan struct{}, input *fuse.ReleaseIn) {
 	n, f := b.releaseFileEntry(input.NodeId, input.Fh)
+	ctx := b.newContext(cancel, &input.InHeader)
+	if lr, ok := n.ops.(NodeLockReleaser); ok && input.ReleaseFlags&fuse.FUSE_RELEASE_FLOCK_UNLOCK != 0 {
+		lr.ReleaseLocks(ctx, input.LockOwner)
+	}
 	if f == nil {
 		return
 	}
 
 	f.wg.Wait()
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
 	if r, ok := n.ops.(NodeReleaser); ok {
 		r.Release(ctx, f.file)
 	} else if r, ok := f.file.(FileReleaser); ok {
@@ -946,23 +987,79 @@ func (b *rawBridge) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []by
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
+	return b.write(ctx, n, f.file, data, int64(input.Offset))
+}
+
+func (b *rawBridge) write(ctx context.Context, n *Inode, f FileHandle, data []byte, off int64) (written uint32, status fuse.Status) {
 	if wr, ok := n.ops.(NodeWriter); ok {
-		w, errno := wr.Write(ctx, f.file, data, int64(input.Offset))
+		w, errno := wr.Write(ctx, f, data, off)
 		return w, errnoToStatus(errno)
 	}
-	if fr, ok := f.file.(FileWriter); ok {
-		w, errno := fr.Write(ctx, data, int64(input.Offset))
+	if fr, ok := f.(FileWriter); ok {
+		w, errno := fr.Write(ctx, data, off)
 		return w, errnoToStatus(errno)
 	}
 
 	return 0, fuse.ENOTSUP
 }
 
+// nodeSpliceWriter is implemented by nodes that implement NodeWriter,
+// but can still pass on a pipe, such as wrapNode. If spliceWrite
+// returns false, the data is passed to Write instead.
+type nodeSpliceWriter interface {
+	spliceWrite(ctx context.Context, f FileHandle, data *fuse.WritePipe, off int64) (uint32, syscall.Errno, bool)
+}
+
+// spliceWrite passes the pipe of a write to ops or f, if they take
+// it. It returns false if the data must be passed to Write.
+func spliceWrite(ctx context.Context, ops InodeEmbedder, f FileHandle, data *fuse.WritePipe, off int64) (uint32, syscall.Errno, bool) {
+	if sw, ok := ops.(nodeSpliceWriter); ok {
+		return sw.spliceWrite(ctx, f, data, off)
+	}
+	if _, ok := ops.(NodeWriter); ok {
+		return 0, 0, false
+	}
+	if sw, ok := f.(FileSpliceWriter); ok {
+		w, errno := sw.SpliceWrite(ctx, data, off)
+		return w, errno, true
+	}
+	return 0, 0, false
+}
+
+// spliceWriteBufs holds buffers for the data of splice writes that
+// are passed to Write.
+var spliceWriteBufs sync.Pool
+
+func (b *rawBridge) SpliceWrite(cancel <-chan struct{}, input *fuse.WriteIn, data *fuse.WritePipe) (written uint32, status fuse.Status) {
+	n := b.getNode(input.NodeId)
+	f := b.getFile(input.Fh)
+
+	ctx := b.newContext(cancel, &input.InHeader)
+	if w, errno, ok := spliceWrite(ctx, n.ops, f.file, data, int64(input.Offset)); ok {
+		return w, errnoToStatus(errno)
+	}
+
+	bufp, _ := spliceWriteBufs.Get().(*[]byte)
+	if bufp == nil {
+		bufp = new([]byte)
+	}
+	defer spliceWriteBufs.Put(bufp)
+	buf, err := data.Bytes(*bufp)
+	if err != nil {
+		return 0, fuse.ToStatus(err)
+	}
+	*bufp = buf
+	return b.write(ctx, n, f.file, buf, int64(input.Offset))
+}
+
 func (b *rawBridge) Flush(cancel <-chan struct{}, input *fuse.FlushIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
+	if lr, ok := n.ops.(NodeLockReleaser); ok {
+		lr.ReleaseLocks(ctx, input.LockOwner)
+	}
 	if fl, ok := n.ops.(NodeFlusher); ok {
 		return errnoToStatus(fl.Flush(ctx, f.file))
 	}
@@ -975,7 +1072,7 @@ func (b *rawBridge) Flush(cancel <-chan struct{}, input *fuse.FlushIn) fuse.Stat
 func (b *rawBridge) Fsync(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if fs, ok := n.ops.(NodeFsyncer); ok {
 		return errnoToStatus(fs.Fsync(ctx, f.file, input.FsyncFlags))
 	}
@@ -988,7 +1085,7 @@ func (b *rawBridge) Fsync(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.Stat
 func (b *rawBridge) Fallocate(cancel <-chan struct{}, input *fuse.FallocateIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if a, ok := n.ops.(NodeAllocater); ok {
 		return errnoToStatus(a.Allocate(ctx, f.file, input.Offset, input.Length, input.Mode))
 	}
@@ -1001,40 +1098,10 @@ func (b *rawBridge) Fallocate(cancel <-chan struct{}, input *fuse.FallocateIn) f
 func (b *rawBridge) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) fuse.Status {
 	n := b.getNode(input.NodeId)
 
-	var fh FileHandle
-	var fuseFlags uint32
-	var errno syscall.Errno
-
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
-
-	nod, _ := n.ops.(NodeOpendirer)
-	nrd, _ := n.ops.(NodeReaddirer)
-
-	if odh, ok := n.ops.(NodeOpendirHandler); ok {
-		fh, fuseFlags, errno = odh.OpendirHandle(ctx, input.Flags)
-
-		if errno != 0 {
-			return errnoToStatus(errno)
-		}
-	} else {
-		if nod != nil {
-			errno = nod.Opendir(ctx)
-			if errno != 0 {
-				return errnoToStatus(errno)
-			}
-		}
-
-		var ctor func(context.Context) (DirStream, syscall.Errno)
-		if nrd != nil {
-			ctor = func(ctx context.Context) (DirStream, syscall.Errno) {
-				return nrd.Readdir(ctx)
-			}
-		} else {
-			ctor = func(ctx context.Context) (DirStream, syscall.Errno) {
-				return n.childrenAsDirstream(), 0
-			}
-		}
-		fh = &dirStreamAsFile{creator: ctor}
+	ctx := b.newContext(cancel, &input.InHeader)
+	fh, fuseFlags, errno := b.opendir(ctx, n, input.Flags)
+	if errno != 0 {
+		return errnoToStatus(errno)
 	}
 
 	if fuseFlags&(fuse.FOPEN_CACHE_DIR|fuse.FOPEN_KEEP_CACHE) != 0 {
@@ -1048,6 +1115,30 @@ func (b *rawBridge) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fus
 	return fuse.OK
 }
 
+func (b *rawBridge) opendir(ctx context.Context, n *Inode, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if odh, ok := n.ops.(NodeOpendirHandler); ok {
+		return odh.OpendirHandle(ctx, flags)
+	}
+
+	if nod, ok := n.ops.(NodeOpendirer); ok {
+		if errno := nod.Opendir(ctx); errno != 0 {
+			return nil, 0, errno
+		}
+	}
+
+	var ctor func(context.Context) (DirStream, syscall.Errno)
+	if nrd, ok := n.ops.(NodeReaddirer); ok {
+		ctor = func(ctx context.Context) (DirStream, syscall.Errno) {
+			return nrd.Readdir(ctx)
+		}
+	} else {
+		ctor = func(ctx context.Context) (DirStream, syscall.Errno) {
+			return n.childrenAsDirstream(), 0
+		}
+	}
+	return &dirStreamAsFile{creator: ctor}, 0, 0
+}
+
 func (n *Inode) childrenAsDirstream() DirStream {
 	lst := n.childrenList()
 	r := make([]fuse.DirEntry, 0, len(lst))
@@ -1080,7 +1171,7 @@ func (b *rawBridge) readDirMaybeLookup(cancel <-chan struct{}, input *fuse.ReadI
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	interruptedRead := false
 	if input.Offset != f.dirOffset {
 		// If the last readdir(plus) was interrupted, the
@@ -1211,7 +1302,7 @@ func (b *rawBridge) readDirMaybeLookup(cancel <-chan struct{}, input *fuse.ReadI
 func (b *rawBridge) FsyncDir(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.Status {
 	n := b.getNode(input.NodeId)
 	f := b.getFile(input.Fh)
-	ctx := &fuse.Context{Caller: input.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &input.InHeader)
 	if fsd, ok := f.file.(FileFsyncdirer); ok {
 		return errnoToStatus(fsd.Fsyncdir(ctx, input.FsyncFlags))
 	} else if fs, ok := n.ops.(NodeFsyncer); ok {
@@ -1224,13 +1315,21 @@ func (b *rawBridge) FsyncDir(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.S
 func (b *rawBridge) StatFs(cancel <-chan struct{}, input *fuse.InHeader, out *fuse.StatfsOut) fuse.Status {
 	n := b.getNode(input.NodeId)
 	if sf, ok := n.ops.(NodeStatfser); ok {
-		return errnoToStatus(sf.Statfs(&fuse.Context{Caller: input.Caller, Cancel: cancel}, out))
+		return errnoToStatus(sf.Statfs(b.newContext(cancel, input), out))
 	}
 
 	// leave zeroed out
 	return fuse.OK
 }
 
+// newContext returns the context for a request from the kernel.
+func (b *rawBridge) newContext(cancel <-chan struct{}, header *fuse.InHeader) *fuse.Context {
+	if s, ok := b.server.(serverContexter); ok {
+		return s.Context(cancel, header)
+	}
+	return &fuse.Context{Caller: header.Caller, Cancel: cancel}
+}
+
 func (b *rawBridge) Init(s *fuse.Server) {
 	b.server = s
 }
@@ -1244,7 +1343,7 @@ func (b *rawBridge) CopyFileRange(cancel <-chan struct{}, in *fuse.CopyFileRange
 
 	n2 := b.getNode(in.NodeIdOut)
 
-	sz, errno := cfr.CopyFileRange(&fuse.Context{Caller: in.Caller, Cancel: cancel},
+	sz, errno := cfr.CopyFileRange(b.newContext(cancel, &in.InHeader),
 		b.files[in.FhIn].file, in.OffIn, n2, b.files[in.FhOut].file, in.OffOut, in.Len, in.Flags)
 	return sz, errnoToStatus(errno)
 }
@@ -1252,39 +1351,37 @@ func (b *rawBridge) CopyFileRange(cancel <-chan struct{}, in *fuse.CopyFileRange
 func (b *rawBridge) Lseek(cancel <-chan struct{}, in *fuse.LseekIn, out *fuse.LseekOut) fuse.Status {
 	n := b.getNode(in.NodeId)
 
-	ctx := &fuse.Context{Caller: in.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &in.InHeader)
 
-	ls, ok := n.ops.(NodeLseeker)
-	if ok {
-		off, errno := ls.Lseek(ctx,
-			b.files[in.Fh].file, in.Offset, in.Whence)
-		out.Offset = off
-		return errnoToStatus(errno)
+	off, errno := b.lseek(ctx, n, b.files[in.Fh].file, in.Offset, in.Whence)
+	out.Offset = off
+	return errnoToStatus(errno)
+}
+
+func (b *rawBridge) lseek(ctx context.Context, n *Inode, f FileHandle, off uint64, whence uint32) (uint64, syscall.Errno) {
+	if ls, ok := n.ops.(NodeLseeker); ok {
+		return ls.Lseek(ctx, f, off, whence)
 	}
-	if fs, ok := b.files[in.Fh].file.(FileLseeker); ok {
-		off, errno := fs.Lseek(ctx, in.Offset, in.Whence)
-		out.Offset = off
-		return errnoToStatus(errno)
+	if fs, ok := f.(FileLseeker); ok {
+		return fs.Lseek(ctx, off, whence)
 	}
 	var attr fuse.AttrOut
 	if s := b.getattr(ctx, n, nil, &attr); s != 0 {
-		return errnoToStatus(s)
+		return 0, s
 	}
-	if in.Whence == _SEEK_DATA {
-		if in.Offset >= attr.Size {
-			return errnoToStatus(syscall.ENXIO)
+	if whence == _SEEK_DATA {
+		if off >= attr.Size {
+			return 0, syscall.ENXIO
 		}
-		out.Offset = in.Offset
-		return fuse.OK
+		return off, 0
 	}
 
-	if in.Whence == _SEEK_HOLE {
-		if in.Offset > attr.Size {
-			return errnoToStatus(syscall.ENXIO)
+	if whence == _SEEK_HOLE {
+		if off > attr.Size {
+			return 0, syscall.ENXIO
 		}
-		out.Offset = attr.Size
-		return fuse.OK
+		return attr.Size, 0
 	}
 
-	return fuse.ENOTSUP
+	return 0, syscall.ENOTSUP
 }
@@ -0,0 +1,554 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// bridgeFS presents a node tree as an io/fs.FS, by calling the
+// rawBridge like the kernel would.
+type bridgeFS struct {
+	b      *rawBridge
+	caller fuse.Caller
+}
+
+var _ = (iofs.StatFS)((*bridgeFS)(nil))
+var _ = (iofs.ReadDirFS)((*bridgeFS)(nil))
+var _ = (readLinkFS)((*bridgeFS)(nil))
+var _ = (iofs.ReadDirFile)((*bridgeFile)(nil))
+var _ = (io.ReaderAt)((*bridgeFile)(nil))
+var _ = (io.Seeker)((*bridgeFile)(nil))
+
+// NewIOFS returns an io/fs.FS for the tree rooted at root, so it can
+// be used with fstest.TestFS, fs.WalkDir or http.FileServer without
+// mounting it. The requests go through the same code as the requests
+// of the kernel, including lookups and forgets, with the credentials
+// of the current process. Symlinks are followed within the tree, and
+// the result also has ReadLink and Lstat methods, like
+// io/fs.ReadLinkFS.
+//
+// If root was mounted, opts is ignored, and the FS works on the
+// mounted tree. Otherwise, opts is used as for NewNodeFS.
+func NewIOFS(root InodeEmbedder, opts *Options) iofs.FS {
+	b := root.embed().bridge
+	if b == nil {
+		if opts == nil {
+			opts = &Options{}
+		}
+		b = NewNodeFS(root, opts).(*rawBridge)
+	}
+	return &bridgeFS{
+		b: b,
+		caller: fuse.Caller{
+			Owner: fuse.Owner{
+				Uid: uint32(os.Getuid()),
+				Gid: uint32(os.Getgid()),
+			},
+			Pid: uint32(os.Getpid()),
+		},
+	}
+}
+
+func (f *bridgeFS) header(nodeID uint64) fuse.InHeader {
+	return fuse.InHeader{NodeId: nodeID, Caller: f.caller}
+}
+
+// bridgePath holds the lookups for the directories leading to a node,
+// and for the node itself.
+type bridgePath []uint64
+
+func (p bridgePath) nodeID() uint64 {
+	if len(p) == 0 {
+		return fuse.FUSE_ROOT_ID
+	}
+	return p[len(p)-1]
+}
+
+// forget drops the lookups, children first, like the kernel.
+func (f *bridgeFS) forget(p bridgePath) {
+	for i := len(p) - 1; i >= 0; i-- {
+		f.b.Forget(p[i], 1)
+	}
+}
+
+// maxSymlinks is the number of symlinks followed in a path, as in
+// Linux.
+const maxSymlinks = 40
+
+// walk looks up the components of name. The result holds lookups that
+// must be released with forget. Symlinks are followed, except for the
+// last component if follow is not set. Symlinks pointing outside the
+// tree are treated as dangling.
+func (f *bridgeFS) walk(name string, follow bool) (bridgePath, fuse.Attr, syscall.Errno) {
+	var p bridgePath
+	var attr fuse.Attr
+	haveAttr := false
+	hops := 0
+
+	todo := strings.Split(name, "/")
+	for len(todo) > 0 {
+		c := todo[0]
+		todo = todo[1:]
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if len(p) == 0 {
+				f.forget(p)
+				return nil, attr, syscall.ENOENT
+			}
+			f.b.Forget(p.nodeID(), 1)
+			p = p[:len(p)-1]
+			haveAttr = false
+			continue
+		}
+		if haveAttr && attr.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+			f.forget(p)
+			return nil, attr, syscall.ENOTDIR
+		}
+
+		var out fuse.EntryOut
+		h := f.header(p.nodeID())
+		if st := f.b.Lookup(nil, &h, c, &out); !st.Ok() {
+			f.forget(p)
+			return nil, attr, syscall.Errno(st)
+		} else if out.NodeId == 0 {
+			// A negative entry.
+			f.forget(p)
+			return nil, attr, syscall.ENOENT
+		}
+		p = append(p, out.NodeId)
+		attr = out.Attr
+		haveAttr = true
+
+		if attr.Mode&syscall.S_IFMT != syscall.S_IFLNK || (len(todo) == 0 && !follow) {
+			continue
+		}
+		if hops++; hops > maxSymlinks {
+			f.forget(p)
+			return nil, attr, syscall.ELOOP
+		}
+		h = f.header(out.NodeId)
+		target, st := f.b.Readlink(nil, &h)
+		f.b.Forget(out.NodeId, 1)
+		p = p[:len(p)-1]
+		haveAttr = false
+		if !st.Ok() {
+			f.forget(p)
+			return nil, attr, syscall.Errno(st)
+		}
+		if path.IsAbs(string(target)) {
+			f.forget(p)
+			return nil, attr, syscall.ENOENT
+		}
+		todo = append(strings.Split(string(target), "/"), todo...)
+	}
+
+	if !haveAttr {
+		in := fuse.GetAttrIn{InHeader: f.header(p.nodeID())}
+		var out fuse.AttrOut
+		if st := f.b.GetAttr(nil, &in, &out); !st.Ok() {
+			f.forget(p)
+			return nil, attr, syscall.Errno(st)
+		}
+		attr = out.Attr
+	}
+	return p, attr, 0
+}
+
+func (f *bridgeFS) stat(op, name string, follow bool) (iofs.FileInfo, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+	p, attr, errno := f.walk(name, follow)
+	if errno != 0 {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: errno}
+	}
+	f.forget(p)
+	return &bridgeFileInfo{name: path.Base(name), attr: attr}, nil
+}
+
+func (f *bridgeFS) Stat(name string) (iofs.FileInfo, error) {
+	return f.stat("stat", name, true)
+}
+
+// Lstat is like Stat, but does not follow a symlink in the last
+// component of name.
+func (f *bridgeFS) Lstat(name string) (iofs.FileInfo, error) {
+	return f.stat("lstat", name, false)
+}
+
+// ReadLink returns the target of the symlink name.
+func (f *bridgeFS) ReadLink(name string) (string, error) {
+	if !iofs.ValidPath(name) {
+		return "", &iofs.PathError{Op: "readlink", Path: name, Err: iofs.ErrInvalid}
+	}
+	p, attr, errno := f.walk(name, false)
+	if errno != 0 {
+		return "", &iofs.PathError{Op: "readlink", Path: name, Err: errno}
+	}
+	defer f.forget(p)
+	if attr.Mode&syscall.S_IFMT != syscall.S_IFLNK {
+		return "", &iofs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	h := f.header(p.nodeID())
+	target, st := f.b.Readlink(nil, &h)
+	if !st.Ok() {
+		return "", &iofs.PathError{Op: "readlink", Path: name, Err: syscall.Errno(st)}
+	}
+	return string(target), nil
+}
+
+func (f *bridgeFS) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrInvalid}
+	}
+	p, attr, errno := f.walk(name, true)
+	if errno != 0 {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: errno}
+	}
+
+	dir := attr.Mode&syscall.S_IFMT == syscall.S_IFDIR
+	in := fuse.OpenIn{InHeader: f.header(p.nodeID()), Flags: syscall.O_RDONLY}
+	var out fuse.OpenOut
+	var st fuse.Status
+	if dir {
+		in.Flags |= syscall.O_DIRECTORY
+		st = f.b.OpenDir(nil, &in, &out)
+	} else {
+		st = f.b.Open(nil, &in, &out)
+	}
+	if !st.Ok() {
+		f.forget(p)
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: syscall.Errno(st)}
+	}
+	return &bridgeFile{
+		fs:   f,
+		name: name,
+		path: p,
+		fh:   out.Fh,
+		dir:  dir,
+	}, nil
+}
+
+func (f *bridgeFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	dir, ok := file.(*bridgeFile)
+	if !ok || !dir.dir {
+		return nil, &iofs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	entries, err := dir.ReadDir(-1)
+	slices.SortFunc(entries, func(a, b iofs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, err
+}
+
+// bridgeFile is an open file or directory of a bridgeFS.
+type bridgeFile struct {
+	fs   *bridgeFS
+	name string
+	path bridgePath
+	fh   uint64
+	dir  bool
+
+	mu     sync.Mutex
+	closed bool
+	// off is the offset for Read, or the READDIR offset for
+	// ReadDir.
+	off uint64
+	// entries are read but not yet returned directory entries.
+	entries []fuse.DirEntry
+	eof     bool
+}
+
+func (f *bridgeFile) pathError(op string, err error) error {
+	return &iofs.PathError{Op: op, Path: f.name, Err: err}
+}
+
+func (f *bridgeFile) Stat() (iofs.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, f.pathError("stat", iofs.ErrClosed)
+	}
+	in := fuse.GetAttrIn{InHeader: f.fs.header(f.path.nodeID())}
+	if !f.dir {
+		in.Fh_ = f.fh
+		in.Flags_ = fuse.FUSE_GETATTR_FH
+	}
+	var out fuse.AttrOut
+	if st := f.fs.b.GetAttr(nil, &in, &out); !st.Ok() {
+		return nil, f.pathError("stat", syscall.Errno(st))
+	}
+	return &bridgeFileInfo{name: path.Base(f.name), attr: out.Attr}, nil
+}
+
+// readAt issues a single READ.
+func (f *bridgeFile) readAt(b []byte, off int64) (int, error) {
+	in := fuse.ReadIn{
+		InHeader: f.fs.header(f.path.nodeID()),
+		Fh:       f.fh,
+		Offset:   uint64(off),
+		Size:     uint32(len(b)),
+	}
+	res, st := f.fs.b.Read(nil, &in, b)
+	if !st.Ok() {
+		return 0, syscall.Errno(st)
+	}
+	if res == nil {
+		return 0, nil
+	}
+	defer res.Done()
+	data, st := res.Bytes(b)
+	if !st.Ok() {
+		return 0, syscall.Errno(st)
+	}
+	return copy(b, data), nil
+}
+
+func (f *bridgeFile) Read(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, f.pathError("read", iofs.ErrClosed)
+	}
+	if f.dir {
+		return 0, f.pathError("read", syscall.EISDIR)
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := f.readAt(b, int64(f.off))
+	f.off += uint64(n)
+	if err != nil {
+		return n, f.pathError("read", err)
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (f *bridgeFile) ReadAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, f.pathError("read", iofs.ErrClosed)
+	}
+	if f.dir {
+		return 0, f.pathError("read", syscall.EISDIR)
+	}
+	if off < 0 {
+		return 0, f.pathError("read", iofs.ErrInvalid)
+	}
+	total := 0
+	for total < len(b) {
+		n, err := f.readAt(b[total:], off+int64(total))
+		total += n
+		if err != nil {
+			return total, f.pathError("read", err)
+		}
+		if n == 0 {
+			return total, io.EOF
+		}
+	}
+	return total, nil
+}
+
+func (f *bridgeFile) Seek(offset int64, whence int) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dir {
+		return 0, f.pathError("seek", syscall.EISDIR)
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += int64(f.off)
+	case io.SeekEnd:
+		offset += fi.Size()
+	}
+	if offset < 0 {
+		return 0, f.pathError("seek", iofs.ErrInvalid)
+	}
+	f.off = uint64(offset)
+	return offset, nil
+}
+
+// ReadDir reads the directory like io/fs.ReadDirFile. It does not
+// sort the entries.
+func (f *bridgeFile) ReadDir(count int) ([]iofs.DirEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, f.pathError("readdir", iofs.ErrClosed)
+	}
+	if !f.dir {
+		return nil, f.pathError("readdir", syscall.ENOTDIR)
+	}
+
+	var result []iofs.DirEntry
+	for count <= 0 || len(result) < count {
+		if len(f.entries) == 0 {
+			if f.eof {
+				break
+			}
+			if err := f.readDirents(); err != nil {
+				return result, f.pathError("readdir", err)
+			}
+			continue
+		}
+		e := f.entries[0]
+		f.entries = f.entries[1:]
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		result = append(result, &bridgeDirEntry{
+			fs:   f.fs,
+			dir:  f.name,
+			name: e.Name,
+			mode: e.Mode,
+		})
+	}
+	if count > 0 && len(result) == 0 {
+		return nil, io.EOF
+	}
+	if count <= 0 && result == nil {
+		result = []iofs.DirEntry{}
+	}
+	return result, nil
+}
+
+// readDirents issues a READDIR to fill f.entries.
+func (f *bridgeFile) readDirents() error {
+	in := fuse.ReadIn{
+		InHeader: f.fs.header(f.path.nodeID()),
+		Fh:       f.fh,
+		Offset:   f.off,
+		Size:     64 * 1024,
+	}
+	list := fuse.NewDirEntryList(make([]byte, in.Size), f.off)
+	if st := f.fs.b.ReadDir(nil, &in, list); !st.Ok() {
+		return syscall.Errno(st)
+	}
+	f.entries = list.Entries()
+	if len(f.entries) == 0 {
+		f.eof = true
+	} else {
+		f.off = f.entries[len(f.entries)-1].Off
+	}
+	return nil
+}
+
+func (f *bridgeFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return f.pathError("close", iofs.ErrClosed)
+	}
+	f.closed = true
+
+	in := fuse.ReleaseIn{
+		InHeader: f.fs.header(f.path.nodeID()),
+		Fh:       f.fh,
+	}
+	if f.dir {
+		f.fs.b.ReleaseDir(&in)
+	} else {
+		h := in.InHeader
+		f.fs.b.Flush(nil, &fuse.FlushIn{InHeader: h, Fh: f.fh})
+		f.fs.b.Release(nil, &in)
+	}
+	f.fs.forget(f.path)
+	return nil
+}
+
+// bridgeDirEntry is a directory entry of a bridgeFile.
+type bridgeDirEntry struct {
+	fs   *bridgeFS
+	dir  string
+	name string
+	mode uint32
+}
+
+func (e *bridgeDirEntry) Name() string        { return e.name }
+func (e *bridgeDirEntry) IsDir() bool         { return e.Type().IsDir() }
+func (e *bridgeDirEntry) Type() iofs.FileMode { return ioFSMode(e.mode).Type() }
+func (e *bridgeDirEntry) Info() (iofs.FileInfo, error) {
+	return e.fs.Lstat(path.Join(e.dir, e.name))
+}
+
+func (e *bridgeDirEntry) String() string {
+	return iofs.FormatDirEntry(e)
+}
+
+// bridgeFileInfo is the io/fs.FileInfo for a fuse.Attr.
+type bridgeFileInfo struct {
+	name string
+	attr fuse.Attr
+}
+
+func (fi *bridgeFileInfo) Name() string        { return fi.name }
+func (fi *bridgeFileInfo) Size() int64         { return int64(fi.attr.Size) }
+func (fi *bridgeFileInfo) Mode() iofs.FileMode { return ioFSMode(fi.attr.Mode) }
+func (fi *bridgeFileInfo) ModTime() time.Time {
+	return time.Unix(int64(fi.attr.Mtime), int64(fi.attr.Mtimensec))
+}
+func (fi *bridgeFileInfo) IsDir() bool { return fi.Mode().IsDir() }
+
+// Sys returns the *fuse.Attr.
+func (fi *bridgeFileInfo) Sys() any { return &fi.attr }
+
+func (fi *bridgeFileInfo) String() string {
+	return iofs.FormatFileInfo(fi)
+}
+
+// ioFSMode converts a unix mode to an io/fs.FileMode. It is the
+// inverse of unixMode.
+func ioFSMode(mode uint32) iofs.FileMode {
+	m := iofs.FileMode(mode & 0777)
+	switch mode & syscall.S_IFMT {
+	case syscall.S_IFDIR:
+		m |= iofs.ModeDir
+	case syscall.S_IFLNK:
+		m |= iofs.ModeSymlink
+	case syscall.S_IFIFO:
+		m |= iofs.ModeNamedPipe
+	case syscall.S_IFSOCK:
+		m |= iofs.ModeSocket
+	case syscall.S_IFCHR:
+		m |= iofs.ModeDevice | iofs.ModeCharDevice
+	case syscall.S_IFBLK:
+		m |= iofs.ModeDevice
+	}
+	if mode&syscall.S_ISUID != 0 {
+		m |= iofs.ModeSetuid
+	}
+	if mode&syscall.S_ISGID != 0 {
+		m |= iofs.ModeSetgid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		m |= iofs.ModeSticky
+	}
+	return m
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// iofsTreeRoot builds a tree from files on OnAdd. Values starting
+// with "->" are symlinks.
+type iofsTreeRoot struct {
+	Inode
+	files map[string]string
+}
+
+func (r *iofsTreeRoot) OnAdd(ctx context.Context) {
+	for name, content := range r.files {
+		dir, base := filepath.Split(name)
+		p := &r.Inode
+		for _, c := range strings.Split(dir, "/") {
+			if c == "" {
+				continue
+			}
+			ch := p.GetChild(c)
+			if ch == nil {
+				ch = p.NewPersistentInode(ctx, &Inode{}, StableAttr{Mode: syscall.S_IFDIR})
+				p.AddChild(c, ch, true)
+			}
+			p = ch
+		}
+		var ch *Inode
+		if target, ok := strings.CutPrefix(content, "->"); ok {
+			ch = p.NewPersistentInode(ctx, &MemSymlink{Data: []byte(target)}, StableAttr{Mode: syscall.S_IFLNK})
+		} else {
+			ch = p.NewPersistentInode(ctx, &MemRegularFile{Data: []byte(content), Attr: fuse.Attr{Mode: 0644}}, StableAttr{})
+		}
+		p.AddChild(base, ch, true)
+	}
+}
+
+func TestNewIOFS(t *testing.T) {
+	root := &iofsTreeRoot{files: map[string]string{
+		"file":       "hello",
+		"dir/sub/x":  strings.Repeat("x", 200000),
+		"dir/link":   "->../file",
+		"dir/sublnk": "->sub",
+	}}
+	fsys := NewIOFS(root, nil)
+	if err := fstest.TestFS(fsys, "file", "dir/sub/x", "dir/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := iofs.ReadFile(fsys, "dir/sublnk/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 200000 {
+		t.Errorf("got %d bytes, want 200000", len(data))
+	}
+	rl := fsys.(readLinkFS)
+	if target, err := rl.ReadLink("dir/link"); err != nil || target != "../file" {
+		t.Errorf("ReadLink: got %q, %v", target, err)
+	}
+	if fi, err := rl.Lstat("dir/link"); err != nil || fi.Mode().Type() != iofs.ModeSymlink {
+		t.Errorf("Lstat: got %v, %v", fi, err)
+	}
+	if _, err := fsys.Open("nonexistent"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Errorf("Open nonexistent: got %v", err)
+	}
+	if _, err := fsys.Open("file/x"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Errorf("Open file/x: got %v, want ENOTDIR", err)
+	}
+
+	// All lookups were forgotten.
+	if n := root.bridge.kernelNodeIds.Size(); n != 1 {
+		t.Errorf("%d node IDs in use, want only the root", n)
+	}
+}
+
+func TestNewIOFSSymlinkLoop(t *testing.T) {
+	root := &iofsTreeRoot{files: map[string]string{
+		"loop":   "->loop",
+		"escape": "->/etc/passwd",
+		"up":     "->../file",
+	}}
+	fsys := NewIOFS(root, nil)
+	if _, err := iofs.Stat(fsys, "loop"); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("loop: got %v, want ELOOP", err)
+	}
+	for _, name := range []string{"escape", "up"} {
+		if _, err := iofs.Stat(fsys, name); !errors.Is(err, iofs.ErrNotExist) {
+			t.Errorf("%s: got %v, want ErrNotExist", name, err)
+		}
+	}
+	if n := root.bridge.kernelNodeIds.Size(); n != 1 {
+		t.Errorf("%d node IDs in use, want only the root", n)
+	}
+}
+
+func TestNewIOFSLoopback(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a":       "aaa",
+		"b/c":     "ccc",
+		"b/d/e/f": "fff",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("c", filepath.Join(dir, "b/link")); err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := NewLoopbackRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := NewIOFS(root, nil)
+	if err := fstest.TestFS(fsys, "a", "b/c", "b/d/e/f", "b/link"); err != nil {
+		t.Fatal(err)
+	}
+	if n := root.embed().bridge.kernelNodeIds.Size(); n != 1 {
+		t.Errorf("%d node IDs in use, want only the root", n)
+	}
+}
+
+func TestNewIOFSMounted(t *testing.T) {
+	root := &iofsTreeRoot{files: map[string]string{
+		"dir/file": "hello",
+	}}
+	mnt, _ := testMount(t, root, nil)
+
+	fsys := NewIOFS(root, nil)
+	got, err := iofs.ReadFile(fsys, "dir/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+
+	// The mount is unaffected.
+	got, err = os.ReadFile(filepath.Join(mnt, "dir/file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Errorf("mount: got %q, want %q", got, "hello")
+	}
+}
@@ -38,7 +38,7 @@ func (b *rawBridge) Statx(cancel <-chan struct{}, in *fuse.StatxIn, out *fuse.St
 		fh = fe.file
 	}
 
-	ctx := &fuse.Context{Caller: in.Caller, Cancel: cancel}
+	ctx := b.newContext(cancel, &in.InHeader)
 
 	errno := syscall.ENOSYS
 	if sx, ok := n.ops.(NodeStatxer); ok {
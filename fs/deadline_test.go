@@ -0,0 +1,57 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// stuckGetattrNode blocks Getattr until its context is canceled, once
+// stuck is set.
+type stuckGetattrNode struct {
+	Inode
+	stuck  atomic.Bool
+	result chan error
+}
+
+func (n *stuckGetattrNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	if !n.stuck.Load() {
+		return 0
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		n.result <- nil
+		return 0
+	}
+	<-ctx.Done()
+	n.result <- ctx.Err()
+	return 0
+}
+
+func TestRequestTimeout(t *testing.T) {
+	root := &stuckGetattrNode{result: make(chan error, 1)}
+	zero := time.Duration(0)
+	opts := &Options{AttrTimeout: &zero}
+	opts.OpcodeTimeouts = map[string]time.Duration{"GETATTR": 100 * time.Millisecond}
+	mnt, _ := testMount(t, root, opts)
+
+	root.stuck.Store(true)
+	_, err := os.Stat(mnt)
+	if !errors.Is(err, syscall.EIO) {
+		t.Errorf("got %v, want EIO", err)
+	}
+	if err := <-root.result; err != context.DeadlineExceeded {
+		t.Errorf("got context error %v, want DeadlineExceeded", err)
+	}
+	root.stuck.Store(false)
+}
@@ -0,0 +1,755 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"golang.org/x/sys/unix"
+)
+
+// FaultRule describes a fault to inject. The selectors (Op, Path,
+// Pid, Probability) decide whether the rule applies to an operation,
+// and the actions (Delay, Hang, Errno, Short, Corrupt) what happens
+// to it. All zero fields are ignored, so a rule with only Errno set
+// fails all operations.
+type FaultRule struct {
+	// Op is the name of the operation: "statfs", "access",
+	// "getattr", "setattr", "statx", "getxattr", "setxattr",
+	// "removexattr", "listxattr", "readlink", "open", "read",
+	// "write", "fsync", "flush", "allocate", "copy_file_range",
+	// "lseek", "getlk", "setlk", "setlkw", "lookup", "opendir",
+	// "readdir", "mkdir", "mknod", "link", "symlink", "create",
+	// "unlink", "rmdir" or "rename". Readdir is applied to every
+	// directory entry.
+	Op string
+
+	// Path is a path.Match pattern for the slash-separated path
+	// relative to the root. Like for GlobFilter, patterns without
+	// slash are matched against the last component. Operations on
+	// a name in a directory, such as lookup and create, use the
+	// path of the name.
+	Path string
+
+	// Pid selects operations by the process ID of the caller. It
+	// matches all threads of the process.
+	Pid uint32
+
+	// Probability is the chance that a matching operation is
+	// selected, if it is between 0 and 1.
+	Probability float64
+
+	// Count is the number of times the rule applies. After that,
+	// it is removed. Note that the kernel may retry failed reads
+	// from its cache.
+	Count int
+
+	// Delay is added before the operation.
+	Delay time.Duration
+
+	// Hang blocks the operation until it is interrupted, which
+	// fails it with EINTR, or until the rules are replaced, which
+	// lets it continue.
+	Hang bool
+
+	// Errno is returned instead of running the operation.
+	Errno syscall.Errno
+
+	// Short is the maximum number of bytes transferred by reads
+	// and writes.
+	Short int
+
+	// Corrupt inverts the bits of the data read or written.
+	Corrupt bool
+}
+
+// String returns the rule in the format of ParseFaultRule.
+func (r FaultRule) String() string {
+	var fields []string
+	add := func(k string, v interface{}) {
+		fields = append(fields, fmt.Sprintf("%s=%v", k, v))
+	}
+	if r.Op != "" {
+		add("op", r.Op)
+	}
+	if r.Path != "" {
+		add("path", r.Path)
+	}
+	if r.Pid != 0 {
+		add("pid", r.Pid)
+	}
+	if r.Probability != 0 {
+		add("prob", r.Probability)
+	}
+	if r.Count != 0 {
+		add("count", r.Count)
+	}
+	if r.Delay != 0 {
+		add("delay", r.Delay)
+	}
+	if r.Hang {
+		fields = append(fields, "hang")
+	}
+	if r.Errno != 0 {
+		name := unix.ErrnoName(r.Errno)
+		if name == "" {
+			name = strconv.Itoa(int(r.Errno))
+		}
+		add("errno", name)
+	}
+	if r.Short != 0 {
+		add("short", r.Short)
+	}
+	if r.Corrupt {
+		fields = append(fields, "corrupt")
+	}
+	return strings.Join(fields, " ")
+}
+
+// ParseFaultRule parses a rule from space separated fields, for
+// example
+//
+//	op=read path=*.db pid=1234 prob=0.5 count=3 delay=10ms hang errno=EIO short=100 corrupt
+//
+// Errno is a name or a number, and delay uses the syntax of
+// time.ParseDuration.
+func ParseFaultRule(s string) (FaultRule, error) {
+	var r FaultRule
+	for _, f := range strings.Fields(s) {
+		k, v, _ := strings.Cut(f, "=")
+		var err error
+		switch k {
+		case "op":
+			r.Op = v
+		case "path":
+			_, err = path.Match(v, "")
+			r.Path = v
+		case "pid":
+			var pid uint64
+			pid, err = strconv.ParseUint(v, 10, 32)
+			r.Pid = uint32(pid)
+		case "prob":
+			r.Probability, err = strconv.ParseFloat(v, 64)
+		case "count":
+			r.Count, err = strconv.Atoi(v)
+		case "delay":
+			r.Delay, err = time.ParseDuration(v)
+		case "hang":
+			r.Hang = true
+		case "errno":
+			r.Errno, err = parseErrno(v)
+		case "short":
+			r.Short, err = strconv.Atoi(v)
+		case "corrupt":
+			r.Corrupt = true
+		default:
+			err = errors.New("unknown field")
+		}
+		if err != nil {
+			return FaultRule{}, fmt.Errorf("fault rule %q: %s: %v", s, f, err)
+		}
+	}
+	return r, nil
+}
+
+func parseErrno(s string) (syscall.Errno, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return syscall.Errno(n), nil
+	}
+	for e := syscall.Errno(1); e < 4096; e++ {
+		if unix.ErrnoName(e) == s {
+			return e, nil
+		}
+	}
+	return 0, errors.New("unknown errno")
+}
+
+// FaultInjector holds the rules of a wrapper made with NewFaultRoot.
+// The rules can be changed while the file system is mounted. For
+// each operation, the first rule that applies is used.
+type FaultInjector struct {
+	// ControlFile is the name of a file in the root, through which
+	// the rules can be read and written. Reading it returns the
+	// rules, one per line. Each line written to it adds a rule,
+	// and truncating it removes all rules, so
+	//
+	//	echo op=read errno=EIO > $MNT/.faults
+	//
+	// replaces the rules. The file is not listed in the root
+	// directory. It must be set before calling NewFaultRoot.
+	ControlFile string
+
+	mu    sync.Mutex
+	rules []*FaultRule
+
+	// replaced is closed when the rules are replaced.
+	replaced chan struct{}
+}
+
+// AddRule adds a rule after the existing ones.
+func (fi *FaultInjector) AddRule(r FaultRule) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.rules = append(fi.rules, &r)
+}
+
+// SetRules replaces the rules. Operations that hang are released.
+func (fi *FaultInjector) SetRules(rules ...FaultRule) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.rules = nil
+	for _, r := range rules {
+		r := r
+		fi.rules = append(fi.rules, &r)
+	}
+	if fi.replaced != nil {
+		close(fi.replaced)
+		fi.replaced = nil
+	}
+}
+
+// Rules returns the current rules. The Count of a rule is what
+// remains of it.
+func (fi *FaultInjector) Rules() []FaultRule {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	var rules []FaultRule
+	for _, r := range fi.rules {
+		rules = append(rules, *r)
+	}
+	return rules
+}
+
+// match returns the first rule that applies, and a channel that is
+// closed when the rules are replaced. The path is only computed if
+// needed.
+func (fi *FaultInjector) match(op string, pid uint32, relPath func() string) (*FaultRule, <-chan struct{}) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	p := ""
+	var tgid uint32
+	for i, r := range fi.rules {
+		if r.Op != "" && r.Op != op {
+			continue
+		}
+		if r.Pid != 0 && r.Pid != pid {
+			if tgid == 0 {
+				tgid = threadGroup(pid)
+			}
+			if r.Pid != tgid {
+				continue
+			}
+		}
+		if r.Path != "" {
+			if p == "" {
+				p = relPath()
+			}
+			if !matchGlob(r.Path, p) {
+				continue
+			}
+		}
+		if r.Probability > 0 && r.Probability < 1 && rand.Float64() >= r.Probability {
+			continue
+		}
+		result := *r
+		if r.Count > 0 {
+			r.Count--
+			if r.Count == 0 {
+				fi.rules = append(fi.rules[:i:i], fi.rules[i+1:]...)
+			}
+		}
+		if fi.replaced == nil {
+			fi.replaced = make(chan struct{})
+		}
+		return &result, fi.replaced
+	}
+	return nil, nil
+}
+
+// NewFaultRoot returns a root for the tree at root, which injects
+// faults into operations according to the rules of fi.
+//
+// Files are opened without passthrough, so reads and writes can be
+// changed, but the kernel may still serve them from its cache.
+//
+// Root must not be mounted or be part of another tree; see "Wrapping
+// trees" in the package documentation.
+func NewFaultRoot(root InodeEmbedder, fi *FaultInjector) InodeEmbedder {
+	ft := &faultTree{injector: fi}
+	t := newWrapTree(root, func() wrapper { return &faultNode{faults: ft} })
+	return t.wrapRoot(root.embed())
+}
+
+// faultTree is shared by the nodes of a fault injection wrapper.
+type faultTree struct {
+	injector *FaultInjector
+
+	mu      sync.Mutex
+	control *Inode
+}
+
+type faultNode struct {
+	wrapNode
+
+	faults *faultTree
+}
+
+// inject runs the rule that applies to the operation on the child
+// name of n, or on n if name is empty. It returns the rule, or nil
+// if the operation should continue unchanged.
+func (n *faultNode) inject(ctx context.Context, op string, name string) (*FaultRule, syscall.Errno) {
+	var pid uint32
+	if c, ok := fuse.FromContext(ctx); ok {
+		pid = c.Pid
+	}
+	r, replaced := n.faults.injector.match(op, pid, func() string {
+		if name == "" {
+			return n.relPath()
+		}
+		return n.childPath(name)
+	})
+	if r == nil {
+		return nil, 0
+	}
+	if r.Delay > 0 {
+		select {
+		case <-time.After(r.Delay):
+		case <-ctx.Done():
+			return nil, syscall.EINTR
+		}
+	}
+	if r.Hang {
+		select {
+		case <-replaced:
+			return nil, 0
+		case <-ctx.Done():
+			return nil, syscall.EINTR
+		}
+	}
+	return r, r.Errno
+}
+
+// injectErrno is inject for operations that do not transfer data.
+func (n *faultNode) injectErrno(ctx context.Context, op string, name string) syscall.Errno {
+	_, errno := n.inject(ctx, op, name)
+	return errno
+}
+
+// corrupt returns data with its bits inverted.
+func corrupt(data []byte) []byte {
+	c := make([]byte, len(data))
+	for i, b := range data {
+		c[i] = ^b
+	}
+	return c
+}
+
+func (n *faultNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	if errno := n.injectErrno(ctx, "statfs", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Statfs(ctx, out)
+}
+
+func (n *faultNode) Access(ctx context.Context, mask uint32) syscall.Errno {
+	if errno := n.injectErrno(ctx, "access", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Access(ctx, mask)
+}
+
+func (n *faultNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if errno := n.injectErrno(ctx, "getattr", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Getattr(ctx, f, out)
+}
+
+func (n *faultNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if errno := n.injectErrno(ctx, "setattr", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Setattr(ctx, f, in, out)
+}
+
+func (n *faultNode) Statx(ctx context.Context, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	if errno := n.injectErrno(ctx, "statx", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Statx(ctx, f, flags, mask, out)
+}
+
+func (n *faultNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "getxattr", ""); errno != 0 {
+		return 0, errno
+	}
+	return n.wrapNode.Getxattr(ctx, attr, dest)
+}
+
+func (n *faultNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	if errno := n.injectErrno(ctx, "setxattr", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Setxattr(ctx, attr, data, flags)
+}
+
+func (n *faultNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	if errno := n.injectErrno(ctx, "removexattr", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Removexattr(ctx, attr)
+}
+
+func (n *faultNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "listxattr", ""); errno != 0 {
+		return 0, errno
+	}
+	return n.wrapNode.Listxattr(ctx, dest)
+}
+
+func (n *faultNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "readlink", ""); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Readlink(ctx)
+}
+
+func (n *faultNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "open", ""); errno != 0 {
+		return nil, 0, errno
+	}
+	fh, fuseFlags, errno := n.wrapNode.Open(ctx, flags)
+	return hideFile(fh), fuseFlags, errno
+}
+
+func (n *faultNode) Read(ctx context.Context, f FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	r, errno := n.inject(ctx, "read", "")
+	if errno != 0 {
+		return nil, errno
+	}
+	if r != nil && r.Short > 0 && r.Short < len(dest) {
+		dest = dest[:r.Short]
+	}
+	res, errno := n.wrapNode.Read(ctx, f, dest, off)
+	if errno != 0 || r == nil || !r.Corrupt {
+		return res, errno
+	}
+	data, status := res.Bytes(dest)
+	res.Done()
+	if !status.Ok() {
+		return nil, syscall.Errno(status)
+	}
+	return fuse.ReadResultData(corrupt(data)), 0
+}
+
+func (n *faultNode) Write(ctx context.Context, f FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	r, errno := n.inject(ctx, "write", "")
+	if errno != 0 {
+		return 0, errno
+	}
+	if r != nil && r.Short > 0 && r.Short < len(data) {
+		data = data[:r.Short]
+	}
+	if r != nil && r.Corrupt {
+		data = corrupt(data)
+	}
+	return n.wrapNode.Write(ctx, f, data, off)
+}
+
+// spliceWrite passes the data to Write, which applies the faults.
+func (n *faultNode) spliceWrite(ctx context.Context, f FileHandle, data *fuse.WritePipe, off int64) (uint32, syscall.Errno, bool) {
+	return 0, 0, false
+}
+
+func (n *faultNode) Fsync(ctx context.Context, f FileHandle, flags uint32) syscall.Errno {
+	if errno := n.injectErrno(ctx, "fsync", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Fsync(ctx, f, flags)
+}
+
+func (n *faultNode) Flush(ctx context.Context, f FileHandle) syscall.Errno {
+	if errno := n.injectErrno(ctx, "flush", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Flush(ctx, f)
+}
+
+// Release does not inject faults, because its result is ignored, and
+// the file handle must be released anyway.
+func (n *faultNode) Release(ctx context.Context, f FileHandle) syscall.Errno {
+	return n.wrapNode.Release(ctx, f)
+}
+
+func (n *faultNode) Allocate(ctx context.Context, f FileHandle, off uint64, size uint64, mode uint32) syscall.Errno {
+	if errno := n.injectErrno(ctx, "allocate", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Allocate(ctx, f, off, size, mode)
+}
+
+func (n *faultNode) CopyFileRange(ctx context.Context, fhIn FileHandle, offIn uint64, out *Inode, fhOut FileHandle, offOut uint64, len uint64, flags uint64) (uint32, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "copy_file_range", ""); errno != 0 {
+		return 0, errno
+	}
+	return n.wrapNode.CopyFileRange(ctx, fhIn, offIn, out, fhOut, offOut, len, flags)
+}
+
+func (n *faultNode) Lseek(ctx context.Context, f FileHandle, off uint64, whence uint32) (uint64, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "lseek", ""); errno != 0 {
+		return 0, errno
+	}
+	return n.wrapNode.Lseek(ctx, f, off, whence)
+}
+
+func (n *faultNode) Getlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) syscall.Errno {
+	if errno := n.injectErrno(ctx, "getlk", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Getlk(ctx, f, owner, lk, flags, out)
+}
+
+func (n *faultNode) Setlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	if errno := n.injectErrno(ctx, "setlk", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Setlk(ctx, f, owner, lk, flags)
+}
+
+func (n *faultNode) Setlkw(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	if errno := n.injectErrno(ctx, "setlkw", ""); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Setlkw(ctx, f, owner, lk, flags)
+}
+
+// isControl returns true if name is the control file in n.
+func (n *faultNode) isControl(name string) bool {
+	fi := n.faults.injector
+	return fi.ControlFile != "" && name == fi.ControlFile && &n.wrapNode == n.tree.root.wrap()
+}
+
+func (n *faultNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if n.isControl(name) {
+		ft := n.faults
+		ft.mu.Lock()
+		defer ft.mu.Unlock()
+		if ft.control == nil {
+			// The automatic inode number comes from the
+			// tree the wrapper is in, whose range differs
+			// from that of the wrapped tree; see
+			// newWrapTree.
+			ft.control = n.NewPersistentInode(ctx, &faultControl{injector: ft.injector}, StableAttr{Mode: syscall.S_IFREG})
+		}
+		out.Mode = syscall.S_IFREG | 0644
+		return ft.control, 0
+	}
+	if errno := n.injectErrno(ctx, "lookup", name); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Lookup(ctx, name, out)
+}
+
+func (n *faultNode) OpendirHandle(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "opendir", ""); errno != 0 {
+		return nil, 0, errno
+	}
+	fh, fuseFlags, errno := n.wrapNode.OpendirHandle(ctx, flags)
+	if errno != 0 {
+		return nil, 0, errno
+	}
+	return &faultDir{node: n, inner: fh}, fuseFlags, 0
+}
+
+func (n *faultNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "mkdir", name); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Mkdir(ctx, name, mode, out)
+}
+
+func (n *faultNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "mknod", name); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Mknod(ctx, name, mode, dev, out)
+}
+
+func (n *faultNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "link", name); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Link(ctx, target, name, out)
+}
+
+func (n *faultNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.injectErrno(ctx, "symlink", name); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Symlink(ctx, target, name, out)
+}
+
+func (n *faultNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	if n.isControl(name) {
+		return nil, nil, 0, syscall.EEXIST
+	}
+	if errno := n.injectErrno(ctx, "create", name); errno != 0 {
+		return nil, nil, 0, errno
+	}
+	child, fh, fuseFlags, errno := n.wrapNode.Create(ctx, name, flags, mode, out)
+	return child, hideFile(fh), fuseFlags, errno
+}
+
+func (n *faultNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	if n.isControl(name) {
+		return syscall.EPERM
+	}
+	if errno := n.injectErrno(ctx, "unlink", name); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Unlink(ctx, name)
+}
+
+func (n *faultNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if errno := n.injectErrno(ctx, "rmdir", name); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Rmdir(ctx, name)
+}
+
+func (n *faultNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if p, ok := newParent.(*faultNode); n.isControl(name) || ok && p.isControl(newName) {
+		return syscall.EPERM
+	}
+	if errno := n.injectErrno(ctx, "rename", name); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Rename(ctx, name, newParent, newName, flags)
+}
+
+// faultDir is a directory handle that injects faults into reading
+// entries.
+type faultDir struct {
+	node  *faultNode
+	inner FileHandle
+}
+
+var _ = (FileReaddirenter)((*faultDir)(nil))
+var _ = (FileSeekdirer)((*faultDir)(nil))
+var _ = (FileReleasedirer)((*faultDir)(nil))
+var _ = (FileFsyncdirer)((*faultDir)(nil))
+
+func (d *faultDir) Readdirent(ctx context.Context) (*fuse.DirEntry, syscall.Errno) {
+	if errno := d.node.injectErrno(ctx, "readdir", ""); errno != 0 {
+		return nil, errno
+	}
+	rd, ok := d.inner.(FileReaddirenter)
+	if !ok {
+		return nil, 0
+	}
+	return rd.Readdirent(ctx)
+}
+
+func (d *faultDir) Seekdir(ctx context.Context, off uint64) syscall.Errno {
+	if sd, ok := d.inner.(FileSeekdirer); ok {
+		return sd.Seekdir(ctx, off)
+	}
+	return syscall.ENOTSUP
+}
+
+func (d *faultDir) Releasedir(ctx context.Context, releaseFlags uint32) {
+	if rd, ok := d.inner.(FileReleasedirer); ok {
+		rd.Releasedir(ctx, releaseFlags)
+	}
+}
+
+func (d *faultDir) Fsyncdir(ctx context.Context, flags uint32) syscall.Errno {
+	if errno := d.node.injectErrno(ctx, "fsync", ""); errno != 0 {
+		return errno
+	}
+	if fsd, ok := d.inner.(FileFsyncdirer); ok {
+		return fsd.Fsyncdir(ctx, flags)
+	}
+	return d.node.wrapNode.Fsync(ctx, d.inner, flags)
+}
+
+// faultControl is the control file of a FaultInjector.
+type faultControl struct {
+	Inode
+
+	injector *FaultInjector
+}
+
+var _ = (NodeOpener)((*faultControl)(nil))
+var _ = (NodeReader)((*faultControl)(nil))
+var _ = (NodeWriter)((*faultControl)(nil))
+var _ = (NodeGetattrer)((*faultControl)(nil))
+var _ = (NodeSetattrer)((*faultControl)(nil))
+
+func (c *faultControl) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if flags&syscall.O_TRUNC != 0 {
+		c.injector.SetRules()
+	}
+	return nil, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (c *faultControl) Read(ctx context.Context, f FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	var b strings.Builder
+	for _, r := range c.injector.Rules() {
+		b.WriteString(r.String())
+		b.WriteString("\n")
+	}
+	data := b.String()
+	if off >= int64(len(data)) {
+		return fuse.ReadResultData(nil), 0
+	}
+	end := min(int(off)+len(dest), len(data))
+	return fuse.ReadResultData([]byte(data[off:end])), 0
+}
+
+// Write adds the rules in data. Each write must contain complete
+// lines.
+func (c *faultControl) Write(ctx context.Context, f FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	var rules []FaultRule
+	for _, l := range strings.Split(string(data), "\n") {
+		if l = strings.TrimSpace(l); l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		r, err := ParseFaultRule(l)
+		if err != nil {
+			return 0, syscall.EINVAL
+		}
+		rules = append(rules, r)
+	}
+	for _, r := range rules {
+		c.injector.AddRule(r)
+	}
+	return uint32(len(data)), 0
+}
+
+func (c *faultControl) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = syscall.S_IFREG | 0644
+	return 0
+}
+
+// Setattr removes all rules when the file is truncated.
+func (c *faultControl) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if sz, ok := in.GetSize(); ok && sz == 0 {
+		c.injector.SetRules()
+	}
+	out.Mode = syscall.S_IFREG | 0644
+	return 0
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/posixtest"
+)
+
+func TestParseFaultRule(t *testing.T) {
+	want := FaultRule{
+		Op:          "read",
+		Path:        "*.db",
+		Pid:         1234,
+		Probability: 0.5,
+		Count:       3,
+		Delay:       10 * time.Millisecond,
+		Hang:        true,
+		Errno:       syscall.EIO,
+		Short:       100,
+		Corrupt:     true,
+	}
+	s := want.String()
+	if s != "op=read path=*.db pid=1234 prob=0.5 count=3 delay=10ms hang errno=EIO short=100 corrupt" {
+		t.Errorf("String: got %q", s)
+	}
+	got, err := ParseFaultRule(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, err := ParseFaultRule("errno=5"); err != nil || got.Errno != syscall.EIO {
+		t.Errorf("got %v, %v", got, err)
+	}
+	for _, s := range []string{"errno=EBOGUS", "path=[", "count=x", "bogus"} {
+		if _, err := ParseFaultRule(s); err == nil {
+			t.Errorf("ParseFaultRule(%q) succeeded", s)
+		}
+	}
+}
+
+func TestFaultRoot(t *testing.T) {
+	orig := t.TempDir()
+	for _, f := range []string{"a.dat", "b.txt", "short.txt"} {
+		if err := os.WriteFile(filepath.Join(orig, f), []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi := &FaultInjector{ControlFile: ".faults"}
+	mnt, _ := testMount(t, NewFaultRoot(loopback, fi), nil)
+
+	fi.AddRule(FaultRule{Op: "read", Path: "*.dat", Errno: syscall.EIO})
+	if _, err := os.ReadFile(filepath.Join(mnt, "b.txt")); err != nil {
+		t.Errorf("b.txt: %v", err)
+	}
+	if _, err := os.ReadFile(filepath.Join(mnt, "a.dat")); !errors.Is(err, syscall.EIO) {
+		t.Errorf("a.dat: got %v, want EIO", err)
+	}
+
+	fi.SetRules(FaultRule{Op: "open", Errno: syscall.EIO, Count: 1})
+	if _, err := os.Open(filepath.Join(mnt, "a.dat")); !errors.Is(err, syscall.EIO) {
+		t.Errorf("open: got %v, want EIO", err)
+	}
+	if got := fi.Rules(); len(got) != 0 {
+		t.Errorf("got rules %v after count", got)
+	}
+	fi.SetRules()
+	if got, err := os.ReadFile(filepath.Join(mnt, "a.dat")); err != nil || string(got) != "hello" {
+		t.Errorf("a.dat: got %q, %v", got, err)
+	}
+
+	fi.SetRules(FaultRule{Op: "open", Pid: uint32(os.Getpid()), Errno: syscall.EACCES})
+	if f, err := os.Open(filepath.Join(mnt, "b.txt")); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("open: got %v, want EACCES", err)
+		if err == nil {
+			f.Close()
+		}
+	}
+	fi.SetRules(FaultRule{Op: "open", Pid: 1, Errno: syscall.EACCES})
+	if f, err := os.Open(filepath.Join(mnt, "b.txt")); err != nil {
+		t.Errorf("open: %v", err)
+	} else {
+		f.Close()
+	}
+
+	fi.SetRules(FaultRule{Op: "read", Short: 2})
+	if got, err := os.ReadFile(filepath.Join(mnt, "short.txt")); err != nil || string(got) != "he" {
+		t.Errorf("short read: got %q, %v", got, err)
+	}
+
+	fi.SetRules(FaultRule{Op: "write", Corrupt: true})
+	if err := os.WriteFile(filepath.Join(mnt, "corrupt"), []byte{0, 1}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(orig, "corrupt")); err != nil || string(got) != "\xff\xfe" {
+		t.Errorf("corrupt write: got %q, %v", got, err)
+	}
+
+	fi.SetRules(FaultRule{Op: "lookup", Path: "slow", Delay: 50 * time.Millisecond})
+	start := time.Now()
+	os.Lstat(filepath.Join(mnt, "slow"))
+	if dt := time.Since(start); dt < 50*time.Millisecond {
+		t.Errorf("delay: lookup took %v", dt)
+	}
+
+	fi.SetRules(FaultRule{Op: "mkdir", Hang: true})
+	done := make(chan error, 1)
+	go func() {
+		done <- os.Mkdir(filepath.Join(mnt, "dir"), 0755)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("mkdir did not hang: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	fi.SetRules()
+	if err := <-done; err != nil {
+		t.Errorf("mkdir after release: %v", err)
+	}
+}
+
+func TestFaultRootControlFile(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.WriteFile(filepath.Join(orig, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi := &FaultInjector{ControlFile: ".faults"}
+	mnt, _ := testMount(t, NewFaultRoot(loopback, fi), nil)
+	ctl := filepath.Join(mnt, ".faults")
+
+	entries, err := os.ReadDir(mnt)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir: got %v, %v", entries, err)
+	}
+
+	if err := os.WriteFile(ctl, []byte("op=unlink errno=EBUSY\n# comment\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(mnt, "file")); !errors.Is(err, syscall.EBUSY) {
+		t.Errorf("unlink: got %v, want EBUSY", err)
+	}
+	if got, err := os.ReadFile(ctl); err != nil || string(got) != "op=unlink errno=EBUSY\n" {
+		t.Errorf("read control: got %q, %v", got, err)
+	}
+
+	f, err := os.OpenFile(ctl, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("op=bogus count=x\n")); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("bad rule: got %v, want EINVAL", err)
+	}
+	if _, err := f.Write([]byte("op=rmdir errno=EIO\n")); err != nil {
+		t.Error(err)
+	}
+	f.Close()
+	if got := fi.Rules(); len(got) != 2 {
+		t.Errorf("got rules %v, want 2", got)
+	}
+
+	if err := os.WriteFile(ctl, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := fi.Rules(); len(got) != 0 {
+		t.Errorf("got rules %v after truncate", got)
+	}
+	if err := os.Remove(filepath.Join(mnt, "file")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFaultRootControlFileIno(t *testing.T) {
+	ctx := context.Background()
+	inner := &Inode{}
+	root := NewFaultRoot(inner, &FaultInjector{ControlFile: ".faults"})
+	// Both the control file and this file get automatic inode
+	// numbers.
+	inner.AddChild("file", inner.NewPersistentInode(ctx, &MemRegularFile{Data: []byte("data")}, StableAttr{}), false)
+	mnt, _ := testMount(t, root, &Options{})
+
+	if got, err := os.ReadFile(filepath.Join(mnt, ".faults")); err != nil || len(got) != 0 {
+		t.Errorf("read control: got %q, %v", got, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(mnt, "file")); err != nil || string(got) != "data" {
+		t.Errorf("read file: got %q, %v", got, err)
+	}
+}
+
+func TestFaultRootPosix(t *testing.T) {
+	for nm, fn := range posixtest.All {
+		t.Run(nm, func(t *testing.T) {
+			loopback, err := NewLoopbackRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &Options{}
+			opts.EnableLocks = true
+			mnt, _ := testMount(t, NewFaultRoot(loopback, &FaultInjector{}), opts)
+			fn(t, mnt)
+		})
+	}
+}
@@ -44,3 +44,20 @@ func (f *loopbackFile) Statx(ctx context.Context, flags uint32, mask uint32, out
 
 	return OK
 }
+
+var _ = (FileSpliceWriter)((*loopbackFile)(nil))
+
+func (f *loopbackFile) SpliceWrite(ctx context.Context, data *fuse.WritePipe, off int64) (uint32, syscall.Errno) {
+	f.mu.Lock()
+	n, err := data.SpliceTo(f.fd, off)
+	f.mu.Unlock()
+	if err == syscall.EINVAL && n == 0 {
+		// The backing file system does not support splice.
+		buf, err := data.Bytes(nil)
+		if err != nil {
+			return 0, ToErrno(err)
+		}
+		return f.Write(ctx, buf, off)
+	}
+	return uint32(n), ToErrno(err)
+}
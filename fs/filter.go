@@ -0,0 +1,194 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewFilterRoot returns a root for a view of the tree at root that
+// hides the entries for which hide returns true. It is called with
+// slash-separated paths relative to root, without leading slash. A
+// regular expression can be used by passing its MatchString method,
+// and globs by using GlobFilter.
+//
+// Hidden entries are left out of directory listings, and looking
+// them up fails with ENOENT. Creating or renaming to a hidden name
+// fails with EACCES. Hiding a directory hides everything below it.
+//
+// Root must not be mounted or be part of another tree; see "Wrapping
+// trees" in the package documentation.
+func NewFilterRoot(root InodeEmbedder, hide func(path string) bool) InodeEmbedder {
+	t := newWrapTree(root, func() wrapper { return &filterNode{hide: hide} })
+	return t.wrapRoot(root.embed())
+}
+
+// GlobFilter returns a function for NewFilterRoot that matches the
+// given path.Match patterns. Patterns with a slash are matched
+// against the full path, and others against the last component, so
+// ".git" hides all .git directories, and "secrets/*.key" only keys
+// at the top level.
+func GlobFilter(patterns ...string) (func(path string) bool, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+	return func(name string) bool {
+		for _, p := range patterns {
+			if matchGlob(p, name) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// matchGlob matches a slash-separated path against a path.Match
+// pattern. Patterns without slash are matched against the last
+// component.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+type filterNode struct {
+	wrapNode
+
+	hide func(path string) bool
+}
+
+// hidden returns true if the child name of n is hidden.
+func (n *filterNode) hidden(name string) bool {
+	return n.hide(n.childPath(name))
+}
+
+func (n *filterNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, syscall.ENOENT
+	}
+	return n.wrapNode.Lookup(ctx, name, out)
+}
+
+func (n *filterNode) OpendirHandle(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	fh, fuseFlags, errno := n.wrapNode.OpendirHandle(ctx, flags)
+	if errno != 0 {
+		return nil, 0, errno
+	}
+	return &filterDir{node: n, inner: fh}, fuseFlags, 0
+}
+
+func (n *filterNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, syscall.EACCES
+	}
+	return n.wrapNode.Mkdir(ctx, name, mode, out)
+}
+
+func (n *filterNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, syscall.EACCES
+	}
+	return n.wrapNode.Mknod(ctx, name, mode, dev, out)
+}
+
+func (n *filterNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, syscall.EACCES
+	}
+	return n.wrapNode.Link(ctx, target, name, out)
+}
+
+func (n *filterNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, syscall.EACCES
+	}
+	return n.wrapNode.Symlink(ctx, target, name, out)
+}
+
+func (n *filterNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	if n.hidden(name) {
+		return nil, nil, 0, syscall.EACCES
+	}
+	return n.wrapNode.Create(ctx, name, flags, mode, out)
+}
+
+func (n *filterNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if p, ok := newParent.(*filterNode); ok && p.hidden(newName) {
+		return syscall.EACCES
+	}
+	return n.wrapNode.Rename(ctx, name, newParent, newName, flags)
+}
+
+// filterDir is a directory handle that leaves out hidden entries.
+type filterDir struct {
+	node  *filterNode
+	inner FileHandle
+
+	// pos is the offset of the next entry of inner. It is used
+	// for entries without offset, so seeking works on the offsets
+	// of inner.
+	pos uint64
+}
+
+var _ = (FileReaddirenter)((*filterDir)(nil))
+var _ = (FileSeekdirer)((*filterDir)(nil))
+var _ = (FileReleasedirer)((*filterDir)(nil))
+var _ = (FileFsyncdirer)((*filterDir)(nil))
+
+func (d *filterDir) Readdirent(ctx context.Context) (*fuse.DirEntry, syscall.Errno) {
+	rd, ok := d.inner.(FileReaddirenter)
+	if !ok {
+		return nil, 0
+	}
+	for {
+		de, errno := rd.Readdirent(ctx)
+		if de == nil || errno != 0 {
+			return de, errno
+		}
+		d.pos++
+		if de.Off == 0 {
+			de.Off = d.pos
+		} else {
+			d.pos = de.Off
+		}
+		if de.Name == "." || de.Name == ".." || !d.node.hidden(de.Name) {
+			return de, 0
+		}
+	}
+}
+
+func (d *filterDir) Seekdir(ctx context.Context, off uint64) syscall.Errno {
+	sd, ok := d.inner.(FileSeekdirer)
+	if !ok {
+		return syscall.ENOTSUP
+	}
+	errno := sd.Seekdir(ctx, off)
+	if errno == 0 {
+		d.pos = off
+	}
+	return errno
+}
+
+func (d *filterDir) Releasedir(ctx context.Context, releaseFlags uint32) {
+	if rd, ok := d.inner.(FileReleasedirer); ok {
+		rd.Releasedir(ctx, releaseFlags)
+	}
+}
+
+func (d *filterDir) Fsyncdir(ctx context.Context, flags uint32) syscall.Errno {
+	if fsd, ok := d.inner.(FileFsyncdirer); ok {
+		return fsd.Fsyncdir(ctx, flags)
+	}
+	return d.node.Fsync(ctx, d.inner, flags)
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestGlobFilter(t *testing.T) {
+	if _, err := GlobFilter("["); err == nil {
+		t.Error("GlobFilter accepted bad pattern")
+	}
+	hide, err := GlobFilter(".git", "*.key", "etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]bool{
+		".git":           true,
+		"a/b/.git":       true,
+		"a/.gitignore":   false,
+		"id.key":         true,
+		"a/id.key":       true,
+		"etc/passwd":     true,
+		"a/etc/passwd":   false,
+		"etc/passwd.txt": false,
+	} {
+		if got := hide(p); got != want {
+			t.Errorf("hide(%q): got %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestFilterRoot(t *testing.T) {
+	orig := t.TempDir()
+	for _, d := range []string{"src/.git/objects", "src/dir"} {
+		if err := os.MkdirAll(filepath.Join(orig, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"src/main.go", "src/id.key", "src/.git/config", "src/dir/x.key", "top.key"} {
+		if err := os.WriteFile(filepath.Join(orig, f), []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(filepath.Join(orig, "src/main.go"), filepath.Join(orig, "src/dir/main.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	hide, err := GlobFilter(".git", "*.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := NewSubtreeRoot(loopback, "src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{}
+	opts.EnableLocks = true
+	mnt, _ := testMount(t, NewFilterRoot(sub, hide), opts)
+
+	for dir, want := range map[string][]string{
+		"":    {"dir", "main.go"},
+		"dir": {"main.go"},
+	} {
+		entries, err := os.ReadDir(filepath.Join(mnt, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		sort.Strings(got)
+		if len(got) != len(want) || got[0] != want[0] || got[len(got)-1] != want[len(want)-1] {
+			t.Errorf("ReadDir(%q): got %v, want %v", dir, got, want)
+		}
+	}
+
+	for _, p := range []string{".git", ".git/config", "id.key", "dir/x.key"} {
+		if _, err := os.Lstat(filepath.Join(mnt, p)); !os.IsNotExist(err) {
+			t.Errorf("Lstat(%q): got %v, want ENOENT", p, err)
+		}
+	}
+
+	var st1, st2 syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(mnt, "main.go"), &st1); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(filepath.Join(mnt, "dir/main.go"), &st2); err != nil {
+		t.Fatal(err)
+	}
+	if st1.Ino != st2.Ino {
+		t.Errorf("hard link: got inodes %d, %d", st1.Ino, st2.Ino)
+	}
+
+	for nm, fn := range map[string]func() error{
+		"create": func() error {
+			_, err := unix.Open(mnt+"/new.key", unix.O_CREAT|unix.O_WRONLY, 0644)
+			return err
+		},
+		"mkdir":   func() error { return unix.Mkdir(mnt+"/dir/.git", 0755) },
+		"symlink": func() error { return unix.Symlink("main.go", mnt+"/s.key") },
+		"link":    func() error { return unix.Link(mnt+"/main.go", mnt+"/l.key") },
+		"rename":  func() error { return unix.Rename(mnt+"/main.go", mnt+"/dir/main.key") },
+	} {
+		if err := fn(); err != syscall.EACCES {
+			t.Errorf("%s: got %v, want EACCES", nm, err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(mnt, "dir/new.go"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(orig, "src/dir/new.go")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFilterRootRegexp(t *testing.T) {
+	orig := t.TempDir()
+	for _, f := range []string{"a.txt", "b.txt", "secret.txt"} {
+		if err := os.WriteFile(filepath.Join(orig, f), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`^secret`)
+	mnt, _ := testMount(t, NewFilterRoot(loopback, re.MatchString), nil)
+
+	entries, err := os.ReadDir(mnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	sort.Strings(got)
+	if len(got) != 2 || got[0] != "a.txt" || got[1] != "b.txt" {
+		t.Errorf("got %v, want [a.txt b.txt]", got)
+	}
+}
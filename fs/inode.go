@@ -745,6 +745,25 @@ func (n *Inode) NotifyContent(off, sz int64) syscall.Errno {
 	return syscall.Errno(n.bridge.server.InodeNotify(n.nodeId, off, sz))
 }
 
+// NotifyExpireEntry marks the entry for the given name as expired.
+// The next access revalidates it with a LOOKUP, but unlike
+// NotifyEntry, the kernel does not drop it, so eg. mounts on top of
+// it stay. This needs Linux 6.2; older kernels return ENOSYS.
+func (n *Inode) NotifyExpireEntry(name string) syscall.Errno {
+	s, ok := n.bridge.server.(serverExpireCallbacks)
+	if !ok {
+		return syscall.ENOSYS
+	}
+	return syscall.Errno(s.EntryNotifyExpire(n.nodeId, name))
+}
+
+// NotifyExpireAttr marks the attributes of the inode as expired, so
+// the next stat calls Getattr. Unlike NotifyContent, the data cache
+// is kept.
+func (n *Inode) NotifyExpireAttr() syscall.Errno {
+	return syscall.Errno(n.bridge.server.InodeNotify(n.nodeId, -1, 0))
+}
+
 // WriteCache stores data in the kernel cache.
 func (n *Inode) WriteCache(offset int64, data []byte) syscall.Errno {
 	return syscall.Errno(n.bridge.server.InodeNotifyStoreCache(n.nodeId, offset, data))
@@ -0,0 +1,139 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// BridgeState is a snapshot of the nodes and files that the
+// kernel knows about. It is meant to be marshaled to JSON.
+type BridgeState struct {
+	Nodes []NodeState
+	Files []FileState
+
+	// Server is the state of the FUSE server, if the tree is
+	// mounted by a *fuse.Server.
+	Server *fuse.ServerState `json:",omitempty"`
+}
+
+// NodeState describes an Inode known to the kernel.
+type NodeState struct {
+	NodeId      uint64
+	StableAttr  StableAttr
+	LookupCount uint64
+	Persistent  bool
+
+	// Paths lists a path relative to the root for each parent
+	// of the node. A hard-linked file has multiple paths.
+	Paths []string
+
+	// OpenFiles holds the file handles opened on the node.
+	OpenFiles []uint32 `json:",omitempty"`
+	BackingID int32    `json:",omitempty"`
+
+	// Type is the Go type of the InodeEmbedder.
+	Type string
+}
+
+// FileState describes an open file handle.
+type FileState struct {
+	Fh     uint32
+	NodeId uint64
+
+	// Flags are the flags passed to open(2).
+	Flags     uint32
+	BackingID int32 `json:",omitempty"`
+
+	// Type is the Go type of the FileHandle.
+	Type string
+}
+
+// Introspect returns a snapshot of the nodes and open files of the
+// tree rooted at root, which must be mounted. It takes the same
+// locks as the file system operations, so it should not be called
+// from the methods of a node.
+func Introspect(root InodeEmbedder) (*BridgeState, error) {
+	b := root.EmbeddedInode().bridge
+	if b == nil {
+		return nil, fmt.Errorf("root is not mounted")
+	}
+
+	var nodes []*Inode
+	b.kernelNodeIds.Range(func(id uint64, n *Inode) bool {
+		nodes = append(nodes, n)
+		return true
+	})
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].nodeId < nodes[j].nodeId })
+
+	st := &BridgeState{}
+	for _, n := range nodes {
+		n.mu.Lock()
+		ns := NodeState{
+			NodeId:      n.nodeId,
+			StableAttr:  n.stableAttr,
+			LookupCount: n.lookupCount,
+			Persistent:  n.persistent,
+			Type:        fmt.Sprintf("%T", n.ops),
+		}
+		parents := n.parents.all()
+		n.mu.Unlock()
+
+		for _, pd := range parents {
+			ns.Paths = append(ns.Paths, path.Join(pd.parent.Path(nil), pd.name))
+		}
+		if n == b.root {
+			ns.Paths = []string{""}
+		}
+
+		b.mu.Lock()
+		ns.OpenFiles = append([]uint32(nil), n.openFiles...)
+		ns.BackingID = n.backingID
+		for _, fh := range n.openFiles {
+			fe := b.files[fh]
+			st.Files = append(st.Files, FileState{
+				Fh:        fe.fh,
+				NodeId:    n.nodeId,
+				Flags:     fe.flags,
+				BackingID: fe.backingID,
+				Type:      fmt.Sprintf("%T", fe.file),
+			})
+		}
+		b.mu.Unlock()
+
+		st.Nodes = append(st.Nodes, ns)
+	}
+	sort.Slice(st.Files, func(i, j int) bool { return st.Files[i].Fh < st.Files[j].Fh })
+
+	if srv, ok := b.server.(*fuse.Server); ok {
+		st.Server = srv.DebugState()
+	}
+	return st, nil
+}
+
+// NewIntrospectionHandler returns a HTTP handler that serves the
+// result of Introspect as JSON. This is useful for finding leaked
+// nodes and file handles in a running file system, eg.
+//
+//	http.Handle("/debug/fuse", fs.NewIntrospectionHandler(root))
+func NewIntrospectionHandler(root InodeEmbedder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st, err := Introspect(root)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(st)
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestIntrospectionHandler(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.Mkdir(filepath.Join(orig, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(orig, "dir/file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mntDir, _ := testMount(t, root, nil)
+
+	f, err := os.Open(filepath.Join(mntDir, "dir/file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	rec := httptest.NewRecorder()
+	NewIntrospectionHandler(root).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 200 {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	var st BridgeState
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	var fileNode *NodeState
+	for i, n := range st.Nodes {
+		if len(n.Paths) == 1 && n.Paths[0] == "dir/file" {
+			fileNode = &st.Nodes[i]
+		}
+	}
+	if fileNode == nil {
+		t.Fatalf("dir/file missing in %+v", st.Nodes)
+	}
+	if fileNode.LookupCount == 0 || fileNode.Persistent || len(fileNode.OpenFiles) != 1 {
+		t.Errorf("got node %+v", fileNode)
+	}
+	if len(st.Files) != 1 {
+		t.Fatalf("got files %+v, want 1", st.Files)
+	}
+	if fs := st.Files[0]; fs.NodeId != fileNode.NodeId || fs.Fh != fileNode.OpenFiles[0] || fs.Flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		t.Errorf("got file %+v", fs)
+	}
+
+	if st.Server == nil || st.Server.Minor == 0 || len(st.Server.Capabilities) == 0 {
+		t.Errorf("got server state %+v", st.Server)
+	}
+}
+
+func TestIntrospectUnmounted(t *testing.T) {
+	if _, err := Introspect(&Inode{}); err == nil {
+		t.Error("Introspect succeeded on an unmounted tree")
+	}
+}
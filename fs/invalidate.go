@@ -0,0 +1,63 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Invalidator batches cache invalidations for Inodes, for file
+// systems that mirror a backing store with many changes. See
+// fuse.Invalidator.
+type Invalidator struct {
+	iv *fuse.Invalidator
+}
+
+// NewInvalidator starts an Invalidator for the server returned by
+// Mount. Call Close to stop it.
+func NewInvalidator(server *fuse.Server, opts *fuse.InvalidatorOptions) *Invalidator {
+	return &Invalidator{server.NewInvalidator(opts)}
+}
+
+// Entry queues NotifyEntry for parent.
+func (iv *Invalidator) Entry(parent *Inode, name string) {
+	iv.iv.Entry(parent.nodeId, name)
+}
+
+// ExpireEntry queues NotifyExpireEntry for parent. If the kernel
+// does not support it, the entry is invalidated instead.
+func (iv *Invalidator) ExpireEntry(parent *Inode, name string) {
+	iv.iv.ExpireEntry(parent.nodeId, name)
+}
+
+// Delete queues NotifyDelete for parent.
+func (iv *Invalidator) Delete(parent *Inode, name string, child *Inode) {
+	iv.iv.Delete(parent.nodeId, child.nodeId, name)
+}
+
+// Content queues NotifyContent for n.
+func (iv *Invalidator) Content(n *Inode, off, sz int64) {
+	iv.iv.Inode(n.nodeId, off, sz)
+}
+
+// ExpireAttr queues NotifyExpireAttr for n.
+func (iv *Invalidator) ExpireAttr(n *Inode) {
+	iv.iv.ExpireInode(n.nodeId)
+}
+
+// Flush waits until all invalidations queued so far are sent.
+func (iv *Invalidator) Flush() {
+	iv.iv.Flush()
+}
+
+// Close sends the pending invalidations, and stops the Invalidator.
+func (iv *Invalidator) Close() {
+	iv.iv.Close()
+}
+
+// Stats returns counters for the invalidations so far.
+func (iv *Invalidator) Stats() fuse.InvalidatorStats {
+	return iv.iv.Stats()
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// expireRoot counts lookups of its children, which report the size
+// in the size field.
+type expireRoot struct {
+	Inode
+	lookups atomic.Int64
+	size    atomic.Int64
+}
+
+var _ = (NodeLookuper)((*expireRoot)(nil))
+
+func (r *expireRoot) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	r.lookups.Add(1)
+	ch := r.NewInode(ctx, &expireFile{root: r}, StableAttr{Ino: 2})
+	out.Size = uint64(r.size.Load())
+	out.Mode = fuse.S_IFREG | 0644
+	return ch, 0
+}
+
+type expireFile struct {
+	Inode
+	root *expireRoot
+}
+
+var _ = (NodeGetattrer)((*expireFile)(nil))
+
+func (f *expireFile) Getattr(ctx context.Context, fh FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Size = uint64(f.root.size.Load())
+	out.Mode = fuse.S_IFREG | 0644
+	return 0
+}
+
+func TestNotifyExpire(t *testing.T) {
+	root := &expireRoot{}
+	root.size.Store(1)
+	hour := time.Hour
+	mnt, server := testMount(t, root, &Options{
+		EntryTimeout: &hour,
+		AttrTimeout:  &hour,
+	})
+	if !server.KernelSettings().SupportsNotify(fuse.NOTIFY_INVAL_ENTRY) ||
+		server.KernelSettings().Flags64()&fuse.CAP_HAS_EXPIRE_ONLY == 0 {
+		t.Skip("kernel does not support FUSE_EXPIRE_ONLY")
+	}
+
+	stat := func() int64 {
+		t.Helper()
+		fi, err := os.Stat(mnt + "/file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+	stat()
+	root.size.Store(2)
+	if sz := stat(); sz != 1 || root.lookups.Load() != 1 {
+		t.Fatalf("got size %d, %d lookups; want cached size 1, 1 lookup", sz, root.lookups.Load())
+	}
+
+	child := root.GetChild("file")
+	if errno := child.NotifyExpireAttr(); errno != 0 {
+		t.Fatalf("NotifyExpireAttr: %v", errno)
+	}
+	if sz := stat(); sz != 2 || root.lookups.Load() != 1 {
+		t.Errorf("got size %d, %d lookups; want size 2, 1 lookup", sz, root.lookups.Load())
+	}
+
+	if errno := root.NotifyExpireEntry("file"); errno != 0 {
+		t.Fatalf("NotifyExpireEntry: %v", errno)
+	}
+	stat()
+	if n := root.lookups.Load(); n != 2 {
+		t.Errorf("got %d lookups after expiring the entry, want 2", n)
+	}
+
+	iv := NewInvalidator(server, nil)
+	defer iv.Close()
+	root.size.Store(3)
+	for i := 0; i < 100; i++ {
+		iv.ExpireEntry(root.EmbeddedInode(), "file")
+		iv.ExpireAttr(child)
+	}
+	iv.Flush()
+	if sz := stat(); sz != 3 || root.lookups.Load() != 3 {
+		t.Errorf("got size %d, %d lookups; want size 3, 3 lookups", sz, root.lookups.Load())
+	}
+	if st := iv.Stats(); st.Sent > 4 || st.Errors != 0 {
+		t.Errorf("got stats %+v, want at most 4 notifications", st)
+	}
+}
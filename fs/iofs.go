@@ -0,0 +1,334 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	iofs "io/fs"
+	"path"
+	"slices"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// readLinkFS is io/fs.ReadLinkFS (Go 1.25), declared here so older Go
+// versions can use file systems that implement it.
+type readLinkFS interface {
+	iofs.FS
+	ReadLink(name string) (string, error)
+	Lstat(name string) (iofs.FileInfo, error)
+}
+
+// NewIOFSRoot returns a read-only file system serving fsys, eg. an
+// embed.FS, a *zip.Reader or a fstest.MapFS. If fsys implements
+// ReadLink and Lstat (like io/fs.ReadLinkFS), symlinks are shown as
+// such; otherwise they are followed. Files are read through
+// io.ReaderAt or io.Seeker if the files of fsys implement them, and
+// sequentially otherwise. Inode numbers are derived from the paths,
+// so they are stable across mounts.
+//
+// The contents of fsys must not change: directory listings and
+// attributes are cached once read.
+func NewIOFSRoot(fsys iofs.FS) InodeEmbedder {
+	return &ioFSNode{fsys: fsys, path: "."}
+}
+
+// ioFSNode is a file or directory in an io/fs.FS.
+type ioFSNode struct {
+	Inode
+	fsys iofs.FS
+	path string
+
+	mu sync.Mutex
+	// info is the FileInfo for path, set on first use.
+	info iofs.FileInfo
+	// entries is the directory listing, set on first use.
+	entries []iofs.DirEntry
+}
+
+var _ = (NodeLookuper)((*ioFSNode)(nil))
+var _ = (NodeReaddirer)((*ioFSNode)(nil))
+var _ = (NodeGetattrer)((*ioFSNode)(nil))
+var _ = (NodeReadlinker)((*ioFSNode)(nil))
+var _ = (NodeOpener)((*ioFSNode)(nil))
+
+// stat returns the FileInfo for a path, without following symlinks
+// if fsys supports them.
+func (n *ioFSNode) stat(p string) (iofs.FileInfo, error) {
+	if rl, ok := n.fsys.(readLinkFS); ok {
+		return rl.Lstat(p)
+	}
+	return iofs.Stat(n.fsys, p)
+}
+
+func (n *ioFSNode) fileInfo() (iofs.FileInfo, syscall.Errno) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.info == nil {
+		fi, err := n.stat(n.path)
+		if err != nil {
+			return nil, ioFSErrno(err)
+		}
+		n.info = fi
+	}
+	return n.info, 0
+}
+
+func (n *ioFSNode) readDir() ([]iofs.DirEntry, syscall.Errno) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.entries == nil {
+		entries, err := iofs.ReadDir(n.fsys, n.path)
+		if err != nil {
+			return nil, ioFSErrno(err)
+		}
+		if entries == nil {
+			entries = []iofs.DirEntry{}
+		}
+		n.entries = entries
+	}
+	return n.entries, 0
+}
+
+// ioFSErrno converts an error from an io/fs.FS. Unlike ToErrno, it
+// maps errors it does not know to EIO, as io/fs implementations have
+// their own.
+func ioFSErrno(err error) syscall.Errno {
+	var errno syscall.Errno
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &errno):
+		return errno
+	case errors.Is(err, iofs.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, iofs.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, iofs.ErrInvalid):
+		return syscall.EINVAL
+	}
+	return syscall.EIO
+}
+
+// ioFSIno returns the inode number for a path.
+func ioFSIno(p string) uint64 {
+	if p == "." {
+		return 1
+	}
+	h := fnv.New64a()
+	io.WriteString(h, p)
+	// Avoid 0 and the root.
+	return h.Sum64() | 2
+}
+
+func (n *ioFSNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	p := path.Join(n.path, name)
+
+	n.mu.Lock()
+	entries := n.entries
+	n.mu.Unlock()
+	_, symlinks := n.fsys.(readLinkFS)
+
+	var fi iofs.FileInfo
+	var err error
+	if entries != nil {
+		// The listing is cheaper than a Stat for many io/fs
+		// implementations.
+		i := slices.IndexFunc(entries, func(e iofs.DirEntry) bool { return e.Name() == name })
+		if i < 0 {
+			return nil, syscall.ENOENT
+		}
+		if e := entries[i]; symlinks || e.Type()&iofs.ModeSymlink == 0 {
+			fi, err = e.Info()
+		}
+	}
+	if fi == nil && err == nil {
+		fi, err = n.stat(p)
+	}
+	if err != nil {
+		return nil, ioFSErrno(err)
+	}
+
+	child := &ioFSNode{fsys: n.fsys, path: p, info: fi}
+	setIOFSAttr(&out.Attr, p, fi)
+	return n.NewInode(ctx, child, StableAttr{Mode: out.Attr.Mode & syscall.S_IFMT, Ino: out.Attr.Ino}), 0
+}
+
+func (n *ioFSNode) Readdir(ctx context.Context) (DirStream, syscall.Errno) {
+	entries, errno := n.readDir()
+	if errno != 0 {
+		return nil, errno
+	}
+	_, symlinks := n.fsys.(readLinkFS)
+	result := make([]fuse.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		de := fuse.DirEntry{
+			Name: e.Name(),
+			Mode: unixMode(e.Type()),
+			Ino:  ioFSIno(path.Join(n.path, e.Name())),
+		}
+		if e.Type()&iofs.ModeSymlink != 0 && !symlinks {
+			// Lookup follows the link, so we don't know
+			// the type.
+			de.Mode = 0
+		}
+		result = append(result, de)
+	}
+	return NewListDirStream(result), 0
+}
+
+func (n *ioFSNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	fi, errno := n.fileInfo()
+	if errno != 0 {
+		return errno
+	}
+	setIOFSAttr(&out.Attr, n.path, fi)
+	return 0
+}
+
+func (n *ioFSNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	rl, ok := n.fsys.(readLinkFS)
+	if !ok {
+		return nil, syscall.EINVAL
+	}
+	target, err := rl.ReadLink(n.path)
+	if err != nil {
+		return nil, ioFSErrno(err)
+	}
+	return []byte(target), 0
+}
+
+func (n *ioFSNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR|syscall.O_TRUNC) != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	f, err := n.fsys.Open(n.path)
+	if err != nil {
+		return nil, 0, ioFSErrno(err)
+	}
+	// The data does not change, so the kernel may keep it cached.
+	return &ioFSFile{fsys: n.fsys, path: n.path, file: f}, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+// setIOFSAttr fills out from a FileInfo.
+func setIOFSAttr(out *fuse.Attr, p string, fi iofs.FileInfo) {
+	out.Ino = ioFSIno(p)
+	out.Mode = unixMode(fi.Mode())
+	out.Size = uint64(fi.Size())
+	out.Nlink = 1
+	if fi.IsDir() {
+		out.Size = 4096
+		out.Nlink = 2
+	}
+	out.Blocks = (out.Size + 511) / 512
+	mtime := fi.ModTime()
+	out.SetTimes(&mtime, &mtime, &mtime)
+}
+
+// unixMode converts an io/fs.FileMode to a unix mode.
+func unixMode(m iofs.FileMode) uint32 {
+	mode := uint32(m.Perm())
+	switch {
+	case m&iofs.ModeDir != 0:
+		mode |= syscall.S_IFDIR
+	case m&iofs.ModeSymlink != 0:
+		mode |= syscall.S_IFLNK
+	case m&iofs.ModeNamedPipe != 0:
+		mode |= syscall.S_IFIFO
+	case m&iofs.ModeSocket != 0:
+		mode |= syscall.S_IFSOCK
+	case m&iofs.ModeCharDevice != 0:
+		mode |= syscall.S_IFCHR
+	case m&iofs.ModeDevice != 0:
+		mode |= syscall.S_IFBLK
+	default:
+		mode |= syscall.S_IFREG
+	}
+	if m&iofs.ModeSetuid != 0 {
+		mode |= syscall.S_ISUID
+	}
+	if m&iofs.ModeSetgid != 0 {
+		mode |= syscall.S_ISGID
+	}
+	if m&iofs.ModeSticky != 0 {
+		mode |= syscall.S_ISVTX
+	}
+	return mode
+}
+
+// ioFSFile is an open file of an io/fs.FS.
+type ioFSFile struct {
+	fsys iofs.FS
+	path string
+
+	mu   sync.Mutex
+	file iofs.File
+	// pos is the offset of file, if it can only be read
+	// sequentially.
+	pos int64
+}
+
+var _ = (FileReader)((*ioFSFile)(nil))
+var _ = (FileReleaser)((*ioFSFile)(nil))
+
+func (f *ioFSFile) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var n int
+	var err error
+	switch r := f.file.(type) {
+	case io.ReaderAt:
+		n, err = r.ReadAt(dest, off)
+	case io.Seeker:
+		if _, err = r.Seek(off, io.SeekStart); err == nil {
+			n, err = io.ReadFull(f.file, dest)
+		}
+	default:
+		n, err = f.readSequential(dest, off)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return nil, ioFSErrno(err)
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// readSequential reads from a file that can only be read from start
+// to end. Reading backwards reopens the file.
+func (f *ioFSFile) readSequential(dest []byte, off int64) (int, error) {
+	if off < f.pos {
+		nf, err := f.fsys.Open(f.path)
+		if err != nil {
+			return 0, err
+		}
+		f.file.Close()
+		f.file = nf
+		f.pos = 0
+	}
+	if off > f.pos {
+		skipped, err := io.CopyN(io.Discard, f.file, off-f.pos)
+		f.pos += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := io.ReadFull(f.file, dest)
+	f.pos += int64(n)
+	return n, err
+}
+
+func (f *ioFSFile) Release(ctx context.Context) syscall.Errno {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return ioFSErrno(f.file.Close())
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testIOFSData() fstest.MapFS {
+	big := make([]byte, 300*1024)
+	for i := range big {
+		big[i] = byte(i * 13)
+	}
+	mtime := time.Unix(1700000000, 0)
+	return fstest.MapFS{
+		"hello.txt":       {Data: []byte("hello world\n"), Mode: 0644, ModTime: mtime},
+		"dir/big":         {Data: big, Mode: 0600, ModTime: mtime},
+		"dir/sub/exec.sh": {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"link":            {Data: []byte("hello.txt"), Mode: iofs.ModeSymlink | 0777},
+	}
+}
+
+func TestIOFS(t *testing.T) {
+	data := testIOFSData()
+	mnt, _ := testMount(t, NewIOFSRoot(data), nil)
+
+	entries, err := os.ReadDir(mnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"dir", "hello.txt", "link"}; !slices.Equal(names, want) {
+		t.Errorf("ReadDir: got %v, want %v", names, want)
+	}
+
+	for name, f := range data {
+		if f.Mode&iofs.ModeSymlink != 0 {
+			continue
+		}
+		p := filepath.Join(mnt, name)
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, f.Data) {
+			t.Errorf("%s: got %d bytes, want %d", name, len(got), len(f.Data))
+		}
+
+		var st syscall.Stat_t
+		if err := syscall.Stat(p, &st); err != nil {
+			t.Fatal(err)
+		}
+		if st.Mode != syscall.S_IFREG|uint32(f.Mode) {
+			t.Errorf("%s: got mode %o, want %o", name, st.Mode, syscall.S_IFREG|uint32(f.Mode))
+		}
+		if st.Size != int64(len(f.Data)) {
+			t.Errorf("%s: got size %d, want %d", name, st.Size, len(f.Data))
+		}
+		if st.Ino != ioFSIno(name) {
+			t.Errorf("%s: got ino %d, want %d", name, st.Ino, ioFSIno(name))
+		}
+		if !f.ModTime.IsZero() && st.Mtim.Sec != f.ModTime.Unix() {
+			t.Errorf("%s: got mtime %d, want %d", name, st.Mtim.Sec, f.ModTime.Unix())
+		}
+	}
+
+	if fi, err := os.Stat(filepath.Join(mnt, "dir/sub")); err != nil {
+		t.Fatal(err)
+	} else if !fi.IsDir() {
+		t.Errorf("dir/sub: got mode %v, want directory", fi.Mode())
+	}
+
+	if _, err := os.OpenFile(filepath.Join(mnt, "hello.txt"), os.O_WRONLY, 0); !errors.Is(err, syscall.EROFS) {
+		t.Errorf("open for writing: got %v, want EROFS", err)
+	}
+	if _, err := os.Stat(filepath.Join(mnt, "nonexistent")); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("Stat nonexistent: got %v, want ENOENT", err)
+	}
+
+	if _, ok := iofs.FS(data).(readLinkFS); ok {
+		target, err := os.Readlink(filepath.Join(mnt, "link"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "hello.txt" {
+			t.Errorf("Readlink: got %q, want %q", target, "hello.txt")
+		}
+	}
+}
+
+// sequentialFS hides the io.ReaderAt and io.Seeker methods of the
+// files of an io/fs.FS.
+type sequentialFS struct {
+	iofs.FS
+}
+
+type sequentialFile struct {
+	f iofs.File
+}
+
+func (f *sequentialFile) Stat() (iofs.FileInfo, error) { return f.f.Stat() }
+func (f *sequentialFile) Read(b []byte) (int, error)   { return f.f.Read(b) }
+func (f *sequentialFile) Close() error                 { return f.f.Close() }
+
+func (fsys sequentialFS) Open(name string) (iofs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sequentialFile{f}, nil
+}
+
+func TestIOFSSequentialRead(t *testing.T) {
+	data := testIOFSData()
+	want := data["dir/big"].Data
+	fsys := sequentialFS{data}
+	f, err := fsys.Open("dir/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &ioFSFile{fsys: fsys, path: "dir/big", file: f}
+	defer file.Release(context.Background())
+
+	// Forward, skipping ahead, backwards and past the end.
+	for _, off := range []int64{0, 100, 200000, 50, int64(len(want)) - 10, int64(len(want)) + 10} {
+		dest := make([]byte, 1000)
+		res, errno := file.Read(context.Background(), dest, off)
+		if errno != 0 {
+			t.Fatalf("Read at %d: %v", off, errno)
+		}
+		got, _ := res.Bytes(dest)
+		end := min(off+int64(len(dest)), int64(len(want)))
+		if off > end {
+			end = off
+		}
+		var wantData []byte
+		if off < int64(len(want)) {
+			wantData = want[off:end]
+		}
+		if !bytes.Equal(got, wantData) {
+			t.Errorf("Read at %d: got %d bytes, want %d", off, len(got), len(wantData))
+		}
+	}
+}
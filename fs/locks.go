@@ -0,0 +1,342 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// LockManager keeps the POSIX (fcntl) and BSD (flock) locks of a file
+// in memory. Embed it in a node to implement NodeGetlker, NodeSetlker,
+// NodeSetlkwer and NodeLockReleaser, and mount with
+// Options.EnableLocks:
+//
+//	type myFile struct {
+//		fs.MemRegularFile
+//		fs.LockManager
+//	}
+//
+// POSIX locks cover byte ranges, and belong to a lock owner, which
+// is the process, or the open file for OFD locks. The locks of an
+// owner do not conflict with each other; setting a lock replaces the
+// range it covers, splitting and merging the locks of the owner.
+// Setlkw fails with EDEADLK if waiting would deadlock, also for
+// locks on different files, and with EINTR if it is interrupted.
+// The locks of a process are released when it closes the file.
+//
+// Flock locks cover the whole file, and belong to the open file.
+// They do not conflict with POSIX locks, and are released when the
+// file is closed. OFD locks are only released on close if flock
+// was also used on the file, because the kernel does not report
+// their owner otherwise.
+//
+// The zero value is ready for use.
+type LockManager struct {
+	// posix holds the POSIX locks, sorted by owner and start.
+	posix []posixLock
+
+	// flocks holds the flock lock type by owner.
+	flocks map[uint64]uint32
+
+	// changed is closed when locks are released.
+	changed chan struct{}
+}
+
+var _ = (NodeGetlker)((*LockManager)(nil))
+var _ = (NodeSetlker)((*LockManager)(nil))
+var _ = (NodeSetlkwer)((*LockManager)(nil))
+var _ = (NodeLockReleaser)((*LockManager)(nil))
+
+// posixLock is a lock on the bytes start to end, inclusive.
+type posixLock struct {
+	owner uint64
+	start uint64
+	end   uint64
+	typ   uint32
+	pid   uint32
+}
+
+// conflicts returns true if l and other cannot be held at the same
+// time.
+func (l *posixLock) conflicts(other *posixLock) bool {
+	return l.owner != other.owner && l.start <= other.end && other.start <= l.end &&
+		(l.typ == syscall.F_WRLCK || other.typ == syscall.F_WRLCK)
+}
+
+// lockWaiter is a Setlkw call that waits for a POSIX lock.
+type lockWaiter struct {
+	m  *LockManager
+	lk posixLock
+}
+
+// lockMu protects all LockManagers, and lockWaiters. Detecting
+// deadlocks needs a consistent view of the locks of all files.
+var lockMu sync.Mutex
+
+// lockWaiters holds the blocked Setlkw calls.
+var lockWaiters = map[*lockWaiter]struct{}{}
+
+func newPosixLock(owner uint64, lk *fuse.FileLock) (posixLock, syscall.Errno) {
+	switch lk.Typ {
+	case syscall.F_RDLCK, syscall.F_WRLCK, syscall.F_UNLCK:
+	default:
+		return posixLock{}, syscall.EINVAL
+	}
+	if lk.Start > lk.End {
+		return posixLock{}, syscall.EINVAL
+	}
+	return posixLock{owner: owner, start: lk.Start, end: lk.End, typ: lk.Typ, pid: lk.Pid}, 0
+}
+
+// notify wakes up the waiters for m. Must hold lockMu.
+func (m *LockManager) notify() {
+	if m.changed != nil {
+		close(m.changed)
+		m.changed = nil
+	}
+}
+
+// wait returns a channel that is closed when locks of m are
+// released. Must hold lockMu.
+func (m *LockManager) wait() <-chan struct{} {
+	if m.changed == nil {
+		m.changed = make(chan struct{})
+	}
+	return m.changed
+}
+
+// conflict returns a POSIX lock that conflicts with lk, or nil. Must
+// hold lockMu.
+func (m *LockManager) conflict(lk *posixLock) *posixLock {
+	for i := range m.posix {
+		if m.posix[i].conflicts(lk) {
+			return &m.posix[i]
+		}
+	}
+	return nil
+}
+
+// deadlock returns true if waiting for lk would deadlock, because an
+// owner it waits for waits, directly or indirectly, for the owner of
+// lk. Must hold lockMu.
+func (m *LockManager) deadlock(lk *posixLock) bool {
+	seen := map[uint64]bool{}
+	var blocks func(m *LockManager, req *posixLock) bool
+	blocks = func(m *LockManager, req *posixLock) bool {
+		for i := range m.posix {
+			held := &m.posix[i]
+			if !held.conflicts(req) {
+				continue
+			}
+			if held.owner == lk.owner {
+				return true
+			}
+			if seen[held.owner] {
+				continue
+			}
+			seen[held.owner] = true
+			for w := range lockWaiters {
+				if w.lk.owner == held.owner && blocks(w.m, &w.lk) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return blocks(m, lk)
+}
+
+// setPosix replaces the range of lk in the locks of its owner. Must
+// hold lockMu.
+func (m *LockManager) setPosix(lk *posixLock) {
+	var locks []posixLock
+	released := false
+	for _, l := range m.posix {
+		if l.owner != lk.owner || l.end < lk.start || l.start > lk.end {
+			locks = append(locks, l)
+			continue
+		}
+		if l.start < lk.start {
+			left := l
+			left.end = lk.start - 1
+			locks = append(locks, left)
+		}
+		if l.end > lk.end {
+			right := l
+			right.start = lk.end + 1
+			locks = append(locks, right)
+		}
+		if lk.typ == syscall.F_UNLCK || l.typ == syscall.F_WRLCK && lk.typ == syscall.F_RDLCK {
+			released = true
+		}
+	}
+	if lk.typ != syscall.F_UNLCK {
+		locks = append(locks, *lk)
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].owner != locks[j].owner {
+			return locks[i].owner < locks[j].owner
+		}
+		return locks[i].start < locks[j].start
+	})
+
+	// Merge adjacent locks of the same owner and type.
+	m.posix = locks[:0]
+	for _, l := range locks {
+		if k := len(m.posix) - 1; k >= 0 {
+			prev := &m.posix[k]
+			if prev.owner == l.owner && prev.typ == l.typ && prev.end != math.MaxUint64 && prev.end+1 == l.start {
+				prev.end = l.end
+				continue
+			}
+		}
+		m.posix = append(m.posix, l)
+	}
+	if released {
+		m.notify()
+	}
+}
+
+// flockConflict returns true if owner cannot take a flock lock of
+// type typ. Must hold lockMu.
+func (m *LockManager) flockConflict(owner uint64, typ uint32) bool {
+	for o, t := range m.flocks {
+		if o != owner && (t == syscall.F_WRLCK || typ == syscall.F_WRLCK) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFlock sets or removes the flock lock of owner. Must hold lockMu.
+func (m *LockManager) setFlock(owner uint64, typ uint32) {
+	old, ok := m.flocks[owner]
+	if typ == syscall.F_UNLCK {
+		delete(m.flocks, owner)
+	} else {
+		if m.flocks == nil {
+			m.flocks = map[uint64]uint32{}
+		}
+		m.flocks[owner] = typ
+	}
+	if ok && (typ == syscall.F_UNLCK || old == syscall.F_WRLCK && typ == syscall.F_RDLCK) {
+		m.notify()
+	}
+}
+
+// Getlk returns a POSIX lock that conflicts with lk, or lk with type
+// F_UNLCK if there is none.
+func (m *LockManager) Getlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) syscall.Errno {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+
+	*out = *lk
+	if flags&fuse.FUSE_LK_FLOCK != 0 {
+		if m.flockConflict(owner, lk.Typ) {
+			out.Typ = syscall.F_WRLCK
+		} else {
+			out.Typ = syscall.F_UNLCK
+		}
+		return 0
+	}
+
+	req, errno := newPosixLock(owner, lk)
+	if errno != 0 {
+		return errno
+	}
+	if l := m.conflict(&req); l != nil {
+		*out = fuse.FileLock{Start: l.start, End: l.end, Typ: l.typ, Pid: l.pid}
+	} else {
+		out.Typ = syscall.F_UNLCK
+	}
+	return 0
+}
+
+// Setlk sets or removes a lock, or fails with EAGAIN if it conflicts
+// with the locks of other owners.
+func (m *LockManager) Setlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	return m.setlk(ctx, owner, lk, flags, false)
+}
+
+// Setlkw sets or removes a lock, waiting for conflicting locks to be
+// released.
+func (m *LockManager) Setlkw(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	return m.setlk(ctx, owner, lk, flags, true)
+}
+
+func (m *LockManager) setlk(ctx context.Context, owner uint64, lk *fuse.FileLock, flags uint32, block bool) syscall.Errno {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+
+	if flags&fuse.FUSE_LK_FLOCK != 0 {
+		if _, errno := newPosixLock(owner, lk); errno != 0 {
+			return errno
+		}
+		for lk.Typ != syscall.F_UNLCK && m.flockConflict(owner, lk.Typ) {
+			if !block {
+				return syscall.EAGAIN
+			}
+			if errno := m.waitLocked(ctx, nil); errno != 0 {
+				return errno
+			}
+		}
+		m.setFlock(owner, lk.Typ)
+		return 0
+	}
+
+	req, errno := newPosixLock(owner, lk)
+	if errno != 0 {
+		return errno
+	}
+	for req.typ != syscall.F_UNLCK && m.conflict(&req) != nil {
+		if !block {
+			return syscall.EAGAIN
+		}
+		if m.deadlock(&req) {
+			return syscall.EDEADLK
+		}
+		if errno := m.waitLocked(ctx, &lockWaiter{m: m, lk: req}); errno != 0 {
+			return errno
+		}
+	}
+	m.setPosix(&req)
+	return 0
+}
+
+// waitLocked waits until locks of m are released, registering w as
+// waiter while doing so. It returns EINTR if ctx is canceled. Must
+// hold lockMu, which is released while waiting.
+func (m *LockManager) waitLocked(ctx context.Context, w *lockWaiter) syscall.Errno {
+	ch := m.wait()
+	if w != nil {
+		lockWaiters[w] = struct{}{}
+		defer delete(lockWaiters, w)
+	}
+	lockMu.Unlock()
+	defer lockMu.Lock()
+	select {
+	case <-ch:
+		return 0
+	case <-ctx.Done():
+		return syscall.EINTR
+	}
+}
+
+// ReleaseLocks removes all locks of owner.
+func (m *LockManager) ReleaseLocks(ctx context.Context, owner uint64) {
+	lockMu.Lock()
+	defer lockMu.Unlock()
+
+	if _, ok := m.flocks[owner]; ok {
+		m.setFlock(owner, syscall.F_UNLCK)
+	}
+	m.setPosix(&posixLock{owner: owner, start: 0, end: math.MaxUint64, typ: syscall.F_UNLCK})
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/posixtest"
+	"golang.org/x/sys/unix"
+)
+
+type lockingFile struct {
+	MemRegularFile
+	LockManager
+}
+
+func mountLockingFile(t *testing.T) string {
+	root := &Inode{}
+	opts := &Options{
+		OnAdd: func(ctx context.Context) {
+			for _, nm := range []string{"file", "file0", "file1"} {
+				f := &lockingFile{MemRegularFile: MemRegularFile{Attr: fuse.Attr{Mode: 0666}}}
+				root.AddChild(nm, root.NewPersistentInode(ctx, f, StableAttr{}), false)
+			}
+		},
+	}
+	opts.EnableLocks = true
+	mnt, _ := testMount(t, root, opts)
+	return mnt
+}
+
+func openLocking(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func ofdLock(f *os.File, cmd int, typ int16, start, len int64) (unix.Flock_t, error) {
+	lk := unix.Flock_t{Type: typ, Whence: 0, Start: start, Len: len}
+	err := unix.FcntlFlock(f.Fd(), cmd, &lk)
+	return lk, err
+}
+
+func TestLockManagerMount(t *testing.T) {
+	mnt := mountLockingFile(t)
+	name := filepath.Join(mnt, "file")
+	f1 := openLocking(t, name)
+	f2 := openLocking(t, name)
+
+	// OFD locks belong to the open file.
+	if _, err := ofdLock(f1, unix.F_OFD_SETLK, unix.F_WRLCK, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ofdLock(f2, unix.F_OFD_SETLK, unix.F_RDLCK, 5, 10); err != syscall.EAGAIN {
+		t.Errorf("conflict: got %v, want EAGAIN", err)
+	}
+	if lk, err := ofdLock(f2, unix.F_OFD_GETLK, unix.F_RDLCK, 5, 10); err != nil || lk.Type != unix.F_WRLCK || lk.Start != 0 || lk.Len != 10 {
+		t.Errorf("getlk: got %+v, %v", lk, err)
+	}
+	if _, err := ofdLock(f2, unix.F_OFD_SETLK, unix.F_RDLCK, 10, 10); err != nil {
+		t.Errorf("adjacent: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ofdLock(f2, unix.F_OFD_SETLKW, unix.F_WRLCK, 0, 20)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("setlkw did not block: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := ofdLock(f1, unix.F_OFD_SETLK, unix.F_UNLCK, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("setlkw: %v", err)
+	}
+}
+
+func TestLockManagerClose(t *testing.T) {
+	mnt := mountLockingFile(t)
+	name := filepath.Join(mnt, "file")
+	probe := openLocking(t, name)
+
+	// Closing any file releases the POSIX locks of the process.
+	f1 := openLocking(t, name)
+	if _, err := ofdLock(f1, unix.F_SETLK, unix.F_WRLCK, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if lk, err := ofdLock(probe, unix.F_OFD_GETLK, unix.F_RDLCK, 0, 0); err != nil || lk.Type != unix.F_WRLCK {
+		t.Errorf("getlk: got %+v, %v", lk, err)
+	}
+	f2, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2.Close()
+	if lk, err := ofdLock(probe, unix.F_OFD_GETLK, unix.F_RDLCK, 0, 0); err != nil || lk.Type != unix.F_UNLCK {
+		t.Errorf("getlk after close: got %+v, %v", lk, err)
+	}
+
+	// Flock locks are released when the file is closed. This
+	// happens on RELEASE, which is asynchronous.
+	f3, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Flock(int(f3.Fd()), unix.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Flock(int(probe.Fd()), unix.LOCK_SH|unix.LOCK_NB); err != syscall.EWOULDBLOCK {
+		t.Errorf("flock: got %v, want EWOULDBLOCK", err)
+	}
+	f3.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := unix.Flock(int(probe.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("flock after close: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLockManagerPosixtest(t *testing.T) {
+	// FcntlFlockLocksFile expects locks of the same process to
+	// conflict, which is what loopback does with OFD locks.
+	mnt := mountLockingFile(t)
+	posixtest.FcntlFlockSetLk(t, mnt)
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func wrlk(start, end uint64) *fuse.FileLock {
+	return &fuse.FileLock{Start: start, End: end, Typ: syscall.F_WRLCK}
+}
+
+func rdlk(start, end uint64) *fuse.FileLock {
+	return &fuse.FileLock{Start: start, End: end, Typ: syscall.F_RDLCK}
+}
+
+func unlk(start, end uint64) *fuse.FileLock {
+	return &fuse.FileLock{Start: start, End: end, Typ: syscall.F_UNLCK}
+}
+
+// waitBlocked returns a channel for the result of fn, after checking
+// that it blocks.
+func waitBlocked(t *testing.T, fn func() syscall.Errno) <-chan syscall.Errno {
+	t.Helper()
+	done := make(chan syscall.Errno, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case errno := <-done:
+		t.Fatalf("did not block: %v", errno)
+	case <-time.After(20 * time.Millisecond):
+	}
+	return done
+}
+
+func TestLockManagerPosix(t *testing.T) {
+	ctx := context.Background()
+	m := &LockManager{}
+
+	lk := wrlk(0, 99)
+	lk.Pid = 42
+	if errno := m.Setlk(ctx, nil, 1, lk, 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	var out fuse.FileLock
+	if errno := m.Getlk(ctx, nil, 2, rdlk(50, 60), 0, &out); errno != 0 {
+		t.Fatal(errno)
+	}
+	if want := *lk; out != want {
+		t.Errorf("Getlk: got %v, want %v", out, want)
+	}
+	if errno := m.Getlk(ctx, nil, 1, rdlk(50, 60), 0, &out); errno != 0 || out.Typ != syscall.F_UNLCK {
+		t.Errorf("Getlk by owner: got %v, %v", out, errno)
+	}
+
+	if errno := m.Setlk(ctx, nil, 2, rdlk(100, 199), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	if errno := m.Setlk(ctx, nil, 2, wrlk(90, 110), 0); errno != syscall.EAGAIN {
+		t.Errorf("conflict: got %v, want EAGAIN", errno)
+	}
+
+	// Split.
+	if errno := m.Setlk(ctx, nil, 1, unlk(40, 59), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	want := []posixLock{
+		{owner: 1, start: 0, end: 39, typ: syscall.F_WRLCK, pid: 42},
+		{owner: 1, start: 60, end: 99, typ: syscall.F_WRLCK, pid: 42},
+		{owner: 2, start: 100, end: 199, typ: syscall.F_RDLCK},
+	}
+	if !reflect.DeepEqual(m.posix, want) {
+		t.Errorf("split: got %v, want %v", m.posix, want)
+	}
+	if errno := m.Setlk(ctx, nil, 2, rdlk(45, 50), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+
+	// Conversion to a read lock, and merging.
+	if errno := m.Setlk(ctx, nil, 1, rdlk(0, 99), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	if errno := m.Setlk(ctx, nil, 1, rdlk(100, 149), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	want = []posixLock{
+		{owner: 1, start: 0, end: 149, typ: syscall.F_RDLCK},
+		{owner: 2, start: 45, end: 50, typ: syscall.F_RDLCK},
+		{owner: 2, start: 100, end: 199, typ: syscall.F_RDLCK},
+	}
+	if !reflect.DeepEqual(m.posix, want) {
+		t.Errorf("merge: got %v, want %v", m.posix, want)
+	}
+
+	// Upgrade waits for the read locks of others.
+	done := waitBlocked(t, func() syscall.Errno {
+		return m.Setlkw(ctx, nil, 1, wrlk(0, 149), 0)
+	})
+	m.ReleaseLocks(ctx, 2)
+	if errno := <-done; errno != 0 {
+		t.Errorf("Setlkw: %v", errno)
+	}
+
+	if errno := m.Setlk(ctx, nil, 1, &fuse.FileLock{Typ: 99}, 0); errno != syscall.EINVAL {
+		t.Errorf("bad type: got %v, want EINVAL", errno)
+	}
+}
+
+func TestLockManagerInterrupt(t *testing.T) {
+	m := &LockManager{}
+	if errno := m.Setlk(context.Background(), nil, 1, wrlk(0, 10), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := waitBlocked(t, func() syscall.Errno {
+		return m.Setlkw(ctx, nil, 2, rdlk(5, 5), 0)
+	})
+	cancel()
+	if errno := <-done; errno != syscall.EINTR {
+		t.Errorf("got %v, want EINTR", errno)
+	}
+	lockMu.Lock()
+	defer lockMu.Unlock()
+	if len(lockWaiters) != 0 {
+		t.Errorf("waiter not removed: %v", lockWaiters)
+	}
+}
+
+func TestLockManagerDeadlock(t *testing.T) {
+	ctx := context.Background()
+	m1, m2 := &LockManager{}, &LockManager{}
+	if errno := m1.Setlk(ctx, nil, 1, wrlk(0, 10), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	if errno := m2.Setlk(ctx, nil, 2, wrlk(0, 10), 0); errno != 0 {
+		t.Fatal(errno)
+	}
+	done := waitBlocked(t, func() syscall.Errno {
+		return m2.Setlkw(ctx, nil, 1, wrlk(5, 5), 0)
+	})
+	if errno := m1.Setlkw(ctx, nil, 2, rdlk(0, 0), 0); errno != syscall.EDEADLK {
+		t.Errorf("got %v, want EDEADLK", errno)
+	}
+	// Does not overlap, so no deadlock.
+	if errno := m1.Setlkw(ctx, nil, 2, rdlk(20, 30), 0); errno != 0 {
+		t.Errorf("got %v", errno)
+	}
+	m2.ReleaseLocks(ctx, 2)
+	if errno := <-done; errno != 0 {
+		t.Errorf("Setlkw: %v", errno)
+	}
+}
+
+func TestLockManagerFlock(t *testing.T) {
+	ctx := context.Background()
+	m := &LockManager{}
+	flags := uint32(fuse.FUSE_LK_FLOCK)
+	for _, owner := range []uint64{1, 2} {
+		if errno := m.Setlk(ctx, nil, owner, rdlk(0, 0), flags); errno != 0 {
+			t.Fatal(errno)
+		}
+	}
+	if errno := m.Setlk(ctx, nil, 3, wrlk(0, 0), flags); errno != syscall.EAGAIN {
+		t.Errorf("got %v, want EAGAIN", errno)
+	}
+	// Flock and POSIX locks are independent.
+	if errno := m.Setlk(ctx, nil, 3, wrlk(0, 0), 0); errno != 0 {
+		t.Errorf("posix: %v", errno)
+	}
+
+	done := waitBlocked(t, func() syscall.Errno {
+		return m.Setlkw(ctx, nil, 3, wrlk(0, 0), flags)
+	})
+	m.ReleaseLocks(ctx, 1)
+	if errno := m.Setlk(ctx, nil, 2, unlk(0, 0), flags); errno != 0 {
+		t.Fatal(errno)
+	}
+	if errno := <-done; errno != 0 {
+		t.Errorf("Setlkw: %v", errno)
+	}
+	if errno := m.Setlk(ctx, nil, 1, rdlk(0, 0), flags); errno != syscall.EAGAIN {
+		t.Errorf("got %v, want EAGAIN", errno)
+	}
+}
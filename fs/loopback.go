@@ -146,6 +146,22 @@ func (n *LoopbackNode) preserveOwner(ctx context.Context, path string) error {
 	return syscall.Lchown(path, int(caller.Uid), int(caller.Gid))
 }
 
+// setSecurityContext sets the security labels that the kernel
+// computed for a new inode (see fuse.MountOptions.EnableSecurityContext).
+// This happens before the inode is returned to the kernel, so it is
+// never visible through the mount without them. Other users of the
+// underlying file system may see the inode before the labels are
+// set, though. Create avoids this with createTmpfile where possible.
+func (n *LoopbackNode) setSecurityContext(ctx context.Context, path string) error {
+	secctx, _ := fuse.SecurityContextFromContext(ctx)
+	for _, sc := range secctx {
+		if err := unix.Lsetxattr(path, sc.Name, sc.Value, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var _ = (NodeMknoder)((*LoopbackNode)(nil))
 
 func (n *LoopbackNode) Mknod(ctx context.Context, name string, mode, rdev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
@@ -155,6 +171,10 @@ func (n *LoopbackNode) Mknod(ctx context.Context, name string, mode, rdev uint32
 		return nil, ToErrno(err)
 	}
 	n.preserveOwner(ctx, p)
+	if err := n.setSecurityContext(ctx, p); err != nil {
+		syscall.Unlink(p)
+		return nil, ToErrno(err)
+	}
 	st := syscall.Stat_t{}
 	if err := syscall.Lstat(p, &st); err != nil {
 		syscall.Rmdir(p)
@@ -178,6 +198,10 @@ func (n *LoopbackNode) Mkdir(ctx context.Context, name string, mode uint32, out
 		return nil, ToErrno(err)
 	}
 	n.preserveOwner(ctx, p)
+	if err := n.setSecurityContext(ctx, p); err != nil {
+		syscall.Rmdir(p)
+		return nil, ToErrno(err)
+	}
 	st := syscall.Stat_t{}
 	if err := syscall.Lstat(p, &st); err != nil {
 		syscall.Rmdir(p)
@@ -236,11 +260,10 @@ var _ = (NodeCreater)((*LoopbackNode)(nil))
 func (n *LoopbackNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (inode *Inode, fh FileHandle, fuseFlags uint32, errno syscall.Errno) {
 	p := filepath.Join(n.path(), name)
 	flags = flags &^ syscall.O_APPEND
-	fd, err := syscall.Open(p, int(flags)|os.O_CREATE, mode)
+	fd, err := n.createFile(ctx, p, int(flags), mode)
 	if err != nil {
 		return nil, nil, 0, ToErrno(err)
 	}
-	n.preserveOwner(ctx, p)
 	st := syscall.Stat_t{}
 	if err := syscall.Fstat(fd, &st); err != nil {
 		syscall.Close(fd)
@@ -255,6 +278,27 @@ func (n *LoopbackNode) Create(ctx context.Context, name string, flags uint32, mo
 	return ch, lf, 0, 0
 }
 
+// createFile creates and opens the file p for Create.
+func (n *LoopbackNode) createFile(ctx context.Context, p string, flags int, mode uint32) (int, error) {
+	if secctx, _ := fuse.SecurityContextFromContext(ctx); len(secctx) > 0 {
+		fd, err := n.createTmpfile(ctx, p, flags, mode, secctx)
+		if err != syscall.ENOTSUP {
+			return fd, err
+		}
+	}
+	fd, err := syscall.Open(p, flags|os.O_CREATE, mode)
+	if err != nil {
+		return -1, err
+	}
+	n.preserveOwner(ctx, p)
+	if err := n.setSecurityContext(ctx, p); err != nil {
+		syscall.Close(fd)
+		syscall.Unlink(p)
+		return -1, err
+	}
+	return fd, nil
+}
+
 func (n *LoopbackNode) renameExchange(name string, newParent *LoopbackNode, newName string) syscall.Errno {
 	fd1, err := syscall.Open(n.path(), syscall.O_DIRECTORY, 0)
 	if err != nil {
@@ -297,6 +341,10 @@ func (n *LoopbackNode) Symlink(ctx context.Context, target, name string, out *fu
 		return nil, ToErrno(err)
 	}
 	n.preserveOwner(ctx, p)
+	if err := n.setSecurityContext(ctx, p); err != nil {
+		syscall.Unlink(p)
+		return nil, ToErrno(err)
+	}
 	st := syscall.Stat_t{}
 	if err := syscall.Lstat(p, &st); err != nil {
 		syscall.Unlink(p)
@@ -9,6 +9,9 @@ package fs
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"syscall"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -48,3 +51,50 @@ func (n *LoopbackNode) Statx(ctx context.Context, f FileHandle,
 	out.FromStatx(&st)
 	return OK
 }
+
+// createTmpfile creates the file p as an unnamed file with O_TMPFILE,
+// sets its owner and the security labels secctx, and then links it
+// into its directory, so the file is never visible without them. It
+// returns ENOTSUP if the underlying file system does not support
+// O_TMPFILE.
+func (n *LoopbackNode) createTmpfile(ctx context.Context, p string, flags int, mode uint32, secctx []fuse.SecurityContext) (int, error) {
+	tmpFlags := flags &^ (syscall.O_CREAT | syscall.O_EXCL | syscall.O_TRUNC)
+	if tmpFlags&syscall.O_ACCMODE == syscall.O_RDONLY {
+		// O_TMPFILE needs write access.
+		tmpFlags |= syscall.O_RDWR
+	}
+	fd, err := syscall.Open(filepath.Dir(p), tmpFlags|unix.O_TMPFILE, mode)
+	switch err {
+	case nil:
+	case syscall.EOPNOTSUPP, syscall.EISDIR:
+		// Kernels without O_TMPFILE return EISDIR, because it
+		// includes O_DIRECTORY.
+		return -1, syscall.ENOTSUP
+	default:
+		return -1, err
+	}
+
+	if caller, ok := fuse.FromContext(ctx); ok && os.Getuid() == 0 {
+		syscall.Fchown(fd, int(caller.Uid), int(caller.Gid))
+	}
+	for _, sc := range secctx {
+		if err := unix.Fsetxattr(fd, sc.Name, sc.Value, 0); err != nil {
+			syscall.Close(fd)
+			return -1, err
+		}
+	}
+	// Linking with AT_EMPTY_PATH needs CAP_DAC_READ_SEARCH, the
+	// path in /proc does not.
+	err = unix.Linkat(unix.AT_FDCWD, fmt.Sprintf("/proc/self/fd/%d", fd), unix.AT_FDCWD, p, unix.AT_SYMLINK_FOLLOW)
+	if err == syscall.EEXIST && flags&syscall.O_EXCL == 0 {
+		// The file was created concurrently, so it is opened
+		// like open(2) would.
+		syscall.Close(fd)
+		return syscall.Open(p, flags|syscall.O_CREAT, mode)
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
@@ -6,8 +6,10 @@ package fs
 
 import (
 	"bytes"
+	"context"
 	"os"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -273,3 +275,167 @@ func TestDirectMount(t *testing.T) {
 	}
 	newTestCase(t, opts)
 }
+
+func TestLoopbackSecurityContext(t *testing.T) {
+	tc := newTestCase(t, nil)
+	root := tc.loopback.(*LoopbackNode)
+	label := []byte("label")
+	ctx := fuse.WithSecurityContext(context.Background(), []fuse.SecurityContext{
+		{Name: "user.label", Value: label},
+	})
+
+	var out fuse.EntryOut
+	if _, errno := root.Mkdir(ctx, "dir", 0755, &out); errno != 0 {
+		t.Fatalf("Mkdir: %v", errno)
+	}
+	var createOut fuse.EntryOut
+	_, fh, _, errno := root.Create(ctx, "file", syscall.O_RDWR, 0644, &createOut)
+	if errno != 0 {
+		t.Fatalf("Create: %v", errno)
+	}
+	fh.(FileReleaser).Release(ctx)
+
+	// An existing file is opened, unless O_EXCL is given.
+	if _, fh, _, errno := root.Create(ctx, "file", syscall.O_RDONLY, 0644, &createOut); errno != 0 {
+		t.Errorf("Create existing: %v", errno)
+	} else {
+		fh.(FileReleaser).Release(ctx)
+	}
+	if _, _, _, errno := root.Create(ctx, "file", syscall.O_RDWR|syscall.O_EXCL, 0644, &createOut); errno != syscall.EEXIST {
+		t.Errorf("Create existing with O_EXCL: got %v, want EEXIST", errno)
+	}
+
+	for _, name := range []string{"dir", "file"} {
+		buf := make([]byte, 100)
+		sz, err := unix.Lgetxattr(tc.origDir+"/"+name, "user.label", buf)
+		if err != nil || !bytes.Equal(buf[:sz], label) {
+			t.Errorf("%s: got xattr %q, %v, want %q", name, buf[:sz], err, label)
+		}
+	}
+
+	// user.* xattrs are not allowed on symlinks, so the symlink
+	// must be removed again.
+	if _, errno := root.Symlink(ctx, "target", "link", &out); errno != syscall.EPERM {
+		t.Errorf("Symlink: got %v, want EPERM", errno)
+	}
+	if _, err := os.Lstat(tc.origDir + "/link"); !os.IsNotExist(err) {
+		t.Errorf("Lstat: got %v, want ENOENT", err)
+	}
+}
+
+func TestMountSecurityContext(t *testing.T) {
+	orig := t.TempDir()
+	root, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &Options{}
+	opts.EnableSecurityContext = true
+	mnt, _ := testMount(t, root, opts)
+
+	// The kernel appends the security context after the names,
+	// or an empty extension without a security module. Either
+	// way, it must not end up in the names.
+	if err := os.Mkdir(mnt+"/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mnt+"/dir/file", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file", mnt+"/dir/link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mknod(mnt+"/dir/fifo", syscall.S_IFIFO|0644, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dir", "dir/file", "dir/link", "dir/fifo"} {
+		if _, err := os.Lstat(orig + "/" + name); err != nil {
+			t.Errorf("Lstat: %v", err)
+		}
+	}
+	if target, err := os.Readlink(orig + "/dir/link"); err != nil || target != "file" {
+		t.Errorf("Readlink: got %q, %v", target, err)
+	}
+}
+
+// spliceCountingFile hides PassthroughFd, so writes reach the
+// server, and counts SpliceWrite calls.
+type spliceCountingFile struct {
+	*loopbackFile
+	splices *atomic.Int32
+}
+
+func (f *spliceCountingFile) PassthroughFd() (int, bool) {
+	return 0, false
+}
+
+func (f *spliceCountingFile) SpliceWrite(ctx context.Context, data *fuse.WritePipe, off int64) (uint32, syscall.Errno) {
+	f.splices.Add(1)
+	return f.loopbackFile.SpliceWrite(ctx, data, off)
+}
+
+type spliceCountingNode struct {
+	LoopbackNode
+	splices *atomic.Int32
+}
+
+func (n *spliceCountingNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	fh, fuseFlags, errno := n.LoopbackNode.Open(ctx, flags)
+	if errno != 0 {
+		return nil, 0, errno
+	}
+	return &spliceCountingFile{fh.(*loopbackFile), n.splices}, fuseFlags, 0
+}
+
+func TestLoopbackSpliceWrite(t *testing.T) {
+	testLoopbackSpliceWrite(t, func(root InodeEmbedder) InodeEmbedder { return root })
+}
+
+// TestWrapSpliceWrite checks that wrappers pass the pipe on.
+func TestWrapSpliceWrite(t *testing.T) {
+	rec := &auditRecorder{}
+	testLoopbackSpliceWrite(t, func(root InodeEmbedder) InodeEmbedder {
+		return NewAuditRoot(root, rec)
+	})
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var written int64
+	for _, r := range rec.records {
+		if r.Op == "write" && r.Path == "file" {
+			written += r.Length
+		}
+	}
+	if want := int64(16 * 64 * 1024); written != want {
+		t.Errorf("audit: got %d bytes written, want %d", written, want)
+	}
+}
+
+func testLoopbackSpliceWrite(t *testing.T, wrap func(InodeEmbedder) InodeEmbedder) {
+	orig := t.TempDir()
+	var splices atomic.Int32
+	root := &LoopbackRoot{
+		Path: orig,
+		NewNode: func(rootData *LoopbackRoot, parent *Inode, name string, st *syscall.Stat_t) InodeEmbedder {
+			return &spliceCountingNode{LoopbackNode{RootData: rootData}, &splices}
+		},
+	}
+	opts := &Options{}
+	opts.EnableSpliceWrite = true
+	mnt, _ := testMount(t, wrap(&LoopbackNode{RootData: root}), opts)
+
+	if err := os.WriteFile(orig+"/file", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	if err := os.WriteFile(mnt+"/file", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(orig + "/file"); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, data) {
+		t.Errorf("got %d bytes, want %d", len(got), len(data))
+	}
+	if splices.Load() == 0 {
+		t.Errorf("no writes were spliced")
+	}
+}
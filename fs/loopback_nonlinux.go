@@ -0,0 +1,20 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// createTmpfile returns ENOTSUP, because O_TMPFILE is specific to
+// Linux.
+func (n *LoopbackNode) createTmpfile(ctx context.Context, p string, flags int, mode uint32, secctx []fuse.SecurityContext) (int, error) {
+	return -1, syscall.ENOTSUP
+}
@@ -37,6 +37,7 @@ func testMount(t *testing.T, root InodeEmbedder, opts *Options) (string, *fuse.S
 		t.Fatal(err)
 	}
 	t.Cleanup(func() {
+		waitReleased(root.embed().bridge)
 		if err := server.Unmount(); err != nil {
 			t.Fatalf("testMount: Unmount failed: %v", err)
 		}
@@ -44,6 +45,21 @@ func testMount(t *testing.T, root InodeEmbedder, opts *Options) (string, *fuse.S
 	return mntDir, server
 }
 
+// waitReleased waits a little for the files of b to be released. The
+// kernel sends RELEASE in the background, and drops it on unmount, so
+// the files closed just before would stay open, and count against
+// posixtest.FdLeak in later tests.
+func waitReleased(b *rawBridge) {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		b.mu.Lock()
+		open := len(b.files) - 1 - len(b.freeFiles)
+		b.mu.Unlock()
+		if open == 0 {
+			return
+		}
+	}
+}
+
 func TestDefaultOwner(t *testing.T) {
 	want := "hello"
 	root := &Inode{}
@@ -0,0 +1,450 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal"
+)
+
+// PathFS is a file system API expressed in paths, for file systems
+// that are easier to write in terms of names than of inodes, such as
+// those ported from the fuse/pathfs package. Paths are relative to
+// the root of the file system, which is "". NewPathFSRoot turns a
+// PathFS into a tree of Inodes.
+//
+// Getattr is the only required method: it is called for every
+// lookup. If it sets out.Ino, the number must identify the file, and
+// it is used to recognize hard links. If it leaves Ino zero, each
+// path is a different file.
+//
+// The other operations are optional, and are implemented by the
+// PathXxxx interfaces below. Their defaults are the defaults of the
+// corresponding NodeXxxx interfaces. Reading and writing, locking,
+// Lseek, passthrough and the like are implemented by the FileHandle
+// returned from Open or Create.
+//
+// The operations are called concurrently, and a rename may change
+// paths while an operation runs, so path-based file systems must not
+// rely on paths being stable across calls.
+type PathFS interface {
+	Getattr(ctx context.Context, name string, f FileHandle, out *fuse.AttrOut) syscall.Errno
+}
+
+// PathSetattrer is the path version of NodeSetattrer.
+type PathSetattrer interface {
+	Setattr(ctx context.Context, name string, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno
+}
+
+// PathStatxer is the path version of NodeStatxer.
+type PathStatxer interface {
+	Statx(ctx context.Context, name string, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno
+}
+
+// PathAccesser is the path version of NodeAccesser.
+type PathAccesser interface {
+	Access(ctx context.Context, name string, mask uint32) syscall.Errno
+}
+
+// PathStatfser is the path version of NodeStatfser.
+type PathStatfser interface {
+	Statfs(ctx context.Context, name string, out *fuse.StatfsOut) syscall.Errno
+}
+
+// PathReadlinker is the path version of NodeReadlinker.
+type PathReadlinker interface {
+	Readlink(ctx context.Context, name string) ([]byte, syscall.Errno)
+}
+
+// PathOpener is the path version of NodeOpener.
+type PathOpener interface {
+	Open(ctx context.Context, name string, flags uint32) (fh FileHandle, fuseFlags uint32, errno syscall.Errno)
+}
+
+// PathCreater creates and opens a file. Afterwards, Getattr is called
+// for the new file.
+type PathCreater interface {
+	Create(ctx context.Context, name string, flags uint32, mode uint32) (fh FileHandle, fuseFlags uint32, errno syscall.Errno)
+}
+
+// PathReaddirer is the path version of NodeReaddirer. If it is not
+// implemented, directories cannot be listed.
+type PathReaddirer interface {
+	Readdir(ctx context.Context, name string) (DirStream, syscall.Errno)
+}
+
+// PathMkdirer creates a directory. Afterwards, Getattr is called for
+// the new directory.
+type PathMkdirer interface {
+	Mkdir(ctx context.Context, name string, mode uint32) syscall.Errno
+}
+
+// PathMknoder creates a device or other special file. Afterwards,
+// Getattr is called for the new file.
+type PathMknoder interface {
+	Mknod(ctx context.Context, name string, mode uint32, dev uint32) syscall.Errno
+}
+
+// PathSymlinker creates a symlink at name pointing to target.
+// Afterwards, Getattr is called for the new symlink.
+type PathSymlinker interface {
+	Symlink(ctx context.Context, target, name string) syscall.Errno
+}
+
+// PathLinker creates a hard link newName for oldName. Afterwards,
+// Getattr is called for newName. It should return the Ino of oldName,
+// so both names share the Inode.
+type PathLinker interface {
+	Link(ctx context.Context, oldName, newName string) syscall.Errno
+}
+
+// PathUnlinker is the path version of NodeUnlinker.
+type PathUnlinker interface {
+	Unlink(ctx context.Context, name string) syscall.Errno
+}
+
+// PathRmdirer is the path version of NodeRmdirer.
+type PathRmdirer interface {
+	Rmdir(ctx context.Context, name string) syscall.Errno
+}
+
+// PathRenamer is the path version of NodeRenamer. The flags are
+// RENAME_NOREPLACE and RENAME_EXCHANGE, as for renameat2(2).
+type PathRenamer interface {
+	Rename(ctx context.Context, oldName, newName string, flags uint32) syscall.Errno
+}
+
+// PathCopyFileRanger is the path version of NodeCopyFileRanger.
+type PathCopyFileRanger interface {
+	CopyFileRange(ctx context.Context, nameIn string, fhIn FileHandle, offIn uint64,
+		nameOut string, fhOut FileHandle, offOut uint64, len uint64, flags uint64) (uint32, syscall.Errno)
+}
+
+// PathGetxattrer is the path version of NodeGetxattrer.
+type PathGetxattrer interface {
+	Getxattr(ctx context.Context, name string, attr string, dest []byte) (uint32, syscall.Errno)
+}
+
+// PathSetxattrer is the path version of NodeSetxattrer.
+type PathSetxattrer interface {
+	Setxattr(ctx context.Context, name string, attr string, data []byte, flags uint32) syscall.Errno
+}
+
+// PathRemovexattrer is the path version of NodeRemovexattrer.
+type PathRemovexattrer interface {
+	Removexattr(ctx context.Context, name string, attr string) syscall.Errno
+}
+
+// PathListxattrer is the path version of NodeListxattrer.
+type PathListxattrer interface {
+	Listxattr(ctx context.Context, name string, dest []byte) (uint32, syscall.Errno)
+}
+
+// pathFSRoot is shared by the nodes of a PathFS.
+type pathFSRoot struct {
+	fs   PathFS
+	root *Inode
+}
+
+// pathNode is an Inode of a PathFS. Its path is derived from its
+// position in the tree, which the bridge maintains for renames,
+// unlinks and hard links.
+type pathNode struct {
+	Inode
+	root *pathFSRoot
+}
+
+var _ = (NodeLookuper)((*pathNode)(nil))
+var _ = (NodeGetattrer)((*pathNode)(nil))
+var _ = (NodeSetattrer)((*pathNode)(nil))
+var _ = (NodeStatxer)((*pathNode)(nil))
+var _ = (NodeAccesser)((*pathNode)(nil))
+var _ = (NodeStatfser)((*pathNode)(nil))
+var _ = (NodeReadlinker)((*pathNode)(nil))
+var _ = (NodeOpener)((*pathNode)(nil))
+var _ = (NodeCreater)((*pathNode)(nil))
+var _ = (NodeReaddirer)((*pathNode)(nil))
+var _ = (NodeMkdirer)((*pathNode)(nil))
+var _ = (NodeMknoder)((*pathNode)(nil))
+var _ = (NodeSymlinker)((*pathNode)(nil))
+var _ = (NodeLinker)((*pathNode)(nil))
+var _ = (NodeUnlinker)((*pathNode)(nil))
+var _ = (NodeRmdirer)((*pathNode)(nil))
+var _ = (NodeRenamer)((*pathNode)(nil))
+var _ = (NodeCopyFileRanger)((*pathNode)(nil))
+var _ = (NodeGetxattrer)((*pathNode)(nil))
+var _ = (NodeSetxattrer)((*pathNode)(nil))
+var _ = (NodeRemovexattrer)((*pathNode)(nil))
+var _ = (NodeListxattrer)((*pathNode)(nil))
+
+// NewPathFSRoot returns the root of a tree of Inodes serving fsys. The
+// root may also be added to another tree: paths are relative to it.
+func NewPathFSRoot(fsys PathFS) InodeEmbedder {
+	n := &pathNode{root: &pathFSRoot{fs: fsys}}
+	n.root.root = &n.Inode
+	return n
+}
+
+// path returns the path of the node.
+func (n *pathNode) path() string {
+	return n.Path(n.root.root)
+}
+
+// childPath returns the path of the child name.
+func (n *pathNode) childPath(name string) string {
+	p := n.path()
+	if p == "" {
+		return name
+	}
+	return p + "/" + name
+}
+
+// pathOf returns the path of another node. It returns false if the
+// node is not part of the same PathFS.
+func (n *pathNode) pathOf(node InodeEmbedder) (string, bool) {
+	p, ok := node.(*pathNode)
+	if !ok || p.root != n.root {
+		return "", false
+	}
+	return p.path(), true
+}
+
+// newChild returns the Inode for the child name, whose attributes
+// were just read into out.
+func (n *pathNode) newChild(ctx context.Context, name string, out *fuse.EntryOut) *Inode {
+	st := StableAttr{Mode: out.Mode & syscall.S_IFMT, Ino: out.Ino}
+	if st.Ino == 0 {
+		// Without inode numbers, the path identifies the
+		// file, so reuse the Inode we already have.
+		if ch := n.GetChild(name); ch != nil && ch.StableAttr().Mode == st.Mode {
+			return ch
+		}
+	}
+	return n.NewInode(ctx, &pathNode{root: n.root}, st)
+}
+
+// lookupNew reads the attributes of a child that was just created.
+func (n *pathNode) lookupNew(ctx context.Context, name string, f FileHandle, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	var attr fuse.AttrOut
+	if errno := n.root.fs.Getattr(ctx, n.childPath(name), f, &attr); errno != 0 {
+		return nil, errno
+	}
+	out.Attr = attr.Attr
+	return n.newChild(ctx, name, out), 0
+}
+
+func (n *pathNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	return n.lookupNew(ctx, name, nil, out)
+}
+
+func (n *pathNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	return n.root.fs.Getattr(ctx, n.path(), f, out)
+}
+
+func (n *pathNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if s, ok := n.root.fs.(PathSetattrer); ok {
+		return s.Setattr(ctx, n.path(), f, in, out)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *pathNode) Statx(ctx context.Context, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	if s, ok := n.root.fs.(PathStatxer); ok {
+		return s.Statx(ctx, n.path(), f, flags, mask, out)
+	}
+	// The kernel falls back to GETATTR.
+	return syscall.ENOSYS
+}
+
+func (n *pathNode) Access(ctx context.Context, mask uint32) syscall.Errno {
+	if a, ok := n.root.fs.(PathAccesser); ok {
+		return a.Access(ctx, n.path(), mask)
+	}
+
+	// Like the bridge, check the attributes.
+	var out fuse.AttrOut
+	if errno := n.Getattr(ctx, nil, &out); errno != 0 {
+		return errno
+	}
+	caller, ok := fuse.FromContext(ctx)
+	if !ok {
+		return 0
+	}
+	if !internal.HasAccessGroups(caller.Uid, caller.Gid, caller.Groups, out.Uid, out.Gid, out.Mode, mask) {
+		return syscall.EACCES
+	}
+	return 0
+}
+
+func (n *pathNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	if s, ok := n.root.fs.(PathStatfser); ok {
+		return s.Statfs(ctx, n.path(), out)
+	}
+	*out = fuse.StatfsOut{}
+	return 0
+}
+
+func (n *pathNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	if r, ok := n.root.fs.(PathReadlinker); ok {
+		return r.Readlink(ctx, n.path())
+	}
+	return nil, syscall.ENOTSUP
+}
+
+func (n *pathNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if o, ok := n.root.fs.(PathOpener); ok {
+		return o.Open(ctx, n.path(), flags)
+	}
+	return nil, 0, syscall.ENOTSUP
+}
+
+func (n *pathNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	c, ok := n.root.fs.(PathCreater)
+	if !ok {
+		return nil, nil, 0, syscall.EROFS
+	}
+	f, fuseFlags, errno := c.Create(ctx, n.childPath(name), flags, mode)
+	if errno != 0 {
+		return nil, nil, 0, errno
+	}
+	ch, errno := n.lookupNew(ctx, name, f, out)
+	if errno != 0 {
+		if r, ok := f.(FileReleaser); ok {
+			r.Release(ctx)
+		}
+		return nil, nil, 0, errno
+	}
+	return ch, f, fuseFlags, 0
+}
+
+func (n *pathNode) Readdir(ctx context.Context) (DirStream, syscall.Errno) {
+	if r, ok := n.root.fs.(PathReaddirer); ok {
+		return r.Readdir(ctx, n.path())
+	}
+	return nil, syscall.ENOTSUP
+}
+
+func (n *pathNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	m, ok := n.root.fs.(PathMkdirer)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	if errno := m.Mkdir(ctx, n.childPath(name), mode); errno != 0 {
+		return nil, errno
+	}
+	return n.lookupNew(ctx, name, nil, out)
+}
+
+func (n *pathNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	m, ok := n.root.fs.(PathMknoder)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	if errno := m.Mknod(ctx, n.childPath(name), mode, dev); errno != 0 {
+		return nil, errno
+	}
+	return n.lookupNew(ctx, name, nil, out)
+}
+
+func (n *pathNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	s, ok := n.root.fs.(PathSymlinker)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	if errno := s.Symlink(ctx, target, n.childPath(name)); errno != 0 {
+		return nil, errno
+	}
+	return n.lookupNew(ctx, name, nil, out)
+}
+
+func (n *pathNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	l, ok := n.root.fs.(PathLinker)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	targetPath, ok := n.pathOf(target)
+	if !ok {
+		return nil, syscall.EXDEV
+	}
+	if errno := l.Link(ctx, targetPath, n.childPath(name)); errno != 0 {
+		return nil, errno
+	}
+	return n.lookupNew(ctx, name, nil, out)
+}
+
+func (n *pathNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	if u, ok := n.root.fs.(PathUnlinker); ok {
+		return u.Unlink(ctx, n.childPath(name))
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *pathNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if r, ok := n.root.fs.(PathRmdirer); ok {
+		return r.Rmdir(ctx, n.childPath(name))
+	}
+	return syscall.ENOTSUP
+}
+
+// Rename renames in the PathFS. The bridge then moves the Inodes, so
+// the paths of the children follow.
+func (n *pathNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	r, ok := n.root.fs.(PathRenamer)
+	if !ok {
+		return syscall.ENOTSUP
+	}
+	p, ok := n.pathOf(newParent)
+	if !ok {
+		return syscall.EXDEV
+	}
+	newPath := newName
+	if p != "" {
+		newPath = p + "/" + newName
+	}
+	return r.Rename(ctx, n.childPath(name), newPath, flags)
+}
+
+func (n *pathNode) CopyFileRange(ctx context.Context, fhIn FileHandle, offIn uint64, out *Inode, fhOut FileHandle, offOut uint64, len uint64, flags uint64) (uint32, syscall.Errno) {
+	c, ok := n.root.fs.(PathCopyFileRanger)
+	if !ok {
+		return 0, syscall.ENOTSUP
+	}
+	outPath, ok := n.pathOf(out.Operations())
+	if !ok {
+		return 0, syscall.EXDEV
+	}
+	return c.CopyFileRange(ctx, n.path(), fhIn, offIn, outPath, fhOut, offOut, len, flags)
+}
+
+func (n *pathNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	if x, ok := n.root.fs.(PathGetxattrer); ok {
+		return x.Getxattr(ctx, n.path(), attr, dest)
+	}
+	return 0, ENOATTR
+}
+
+func (n *pathNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	if x, ok := n.root.fs.(PathSetxattrer); ok {
+		return x.Setxattr(ctx, n.path(), attr, data, flags)
+	}
+	return ENOATTR
+}
+
+func (n *pathNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	if x, ok := n.root.fs.(PathRemovexattrer); ok {
+		return x.Removexattr(ctx, n.path(), attr)
+	}
+	return ENOATTR
+}
+
+func (n *pathNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	if x, ok := n.root.fs.(PathListxattrer); ok {
+		return x.Listxattr(ctx, n.path(), dest)
+	}
+	return 0, 0
+}
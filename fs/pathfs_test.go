@@ -0,0 +1,254 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/internal/renameat"
+	"github.com/hanwen/go-fuse/v2/posixtest"
+	"golang.org/x/sys/unix"
+)
+
+// pathLoopback is a loopback file system written against PathFS.
+type pathLoopback struct {
+	root string
+}
+
+func (fs *pathLoopback) abs(name string) string {
+	return filepath.Join(fs.root, name)
+}
+
+func (fs *pathLoopback) Getattr(ctx context.Context, name string, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fga, ok := f.(FileGetattrer); ok {
+		return fga.Getattr(ctx, out)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Lstat(fs.abs(name), &st); err != nil {
+		return ToErrno(err)
+	}
+	out.FromStat(&st)
+	return 0
+}
+
+func (fs *pathLoopback) Setattr(ctx context.Context, name string, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if fsa, ok := f.(FileSetattrer); ok {
+		return fsa.Setattr(ctx, in, out)
+	}
+	p := fs.abs(name)
+	if m, ok := in.GetMode(); ok {
+		if err := syscall.Chmod(p, m); err != nil {
+			return ToErrno(err)
+		}
+	}
+	uid, uok := in.GetUID()
+	gid, gok := in.GetGID()
+	if uok || gok {
+		suid, sgid := -1, -1
+		if uok {
+			suid = int(uid)
+		}
+		if gok {
+			sgid = int(gid)
+		}
+		if err := syscall.Lchown(p, suid, sgid); err != nil {
+			return ToErrno(err)
+		}
+	}
+	mtime, mok := in.GetMTime()
+	atime, aok := in.GetATime()
+	if mok || aok {
+		ts := []unix.Timespec{{Nsec: unix.UTIME_OMIT}, {Nsec: unix.UTIME_OMIT}}
+		if aok {
+			ts[0] = unix.NsecToTimespec(atime.UnixNano())
+		}
+		if mok {
+			ts[1] = unix.NsecToTimespec(mtime.UnixNano())
+		}
+		if err := unix.UtimesNanoAt(unix.AT_FDCWD, p, ts, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			return ToErrno(err)
+		}
+	}
+	if sz, ok := in.GetSize(); ok {
+		if err := syscall.Truncate(p, int64(sz)); err != nil {
+			return ToErrno(err)
+		}
+	}
+	return fs.Getattr(ctx, name, nil, out)
+}
+
+func (fs *pathLoopback) Readlink(ctx context.Context, name string) ([]byte, syscall.Errno) {
+	target, err := os.Readlink(fs.abs(name))
+	if err != nil {
+		return nil, ToErrno(err)
+	}
+	return []byte(target), 0
+}
+
+func (fs *pathLoopback) Open(ctx context.Context, name string, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	fd, err := syscall.Open(fs.abs(name), int(flags), 0)
+	if err != nil {
+		return nil, 0, ToErrno(err)
+	}
+	return NewLoopbackFile(fd), 0, 0
+}
+
+func (fs *pathLoopback) Create(ctx context.Context, name string, flags uint32, mode uint32) (FileHandle, uint32, syscall.Errno) {
+	fd, err := syscall.Open(fs.abs(name), int(flags)|os.O_CREATE, mode)
+	if err != nil {
+		return nil, 0, ToErrno(err)
+	}
+	return NewLoopbackFile(fd), 0, 0
+}
+
+func (fs *pathLoopback) Readdir(ctx context.Context, name string) (DirStream, syscall.Errno) {
+	return NewLoopbackDirStream(fs.abs(name))
+}
+
+func (fs *pathLoopback) Mkdir(ctx context.Context, name string, mode uint32) syscall.Errno {
+	return ToErrno(syscall.Mkdir(fs.abs(name), mode))
+}
+
+func (fs *pathLoopback) Mknod(ctx context.Context, name string, mode uint32, dev uint32) syscall.Errno {
+	return ToErrno(syscall.Mknod(fs.abs(name), mode, int(dev)))
+}
+
+func (fs *pathLoopback) Symlink(ctx context.Context, target, name string) syscall.Errno {
+	return ToErrno(syscall.Symlink(target, fs.abs(name)))
+}
+
+func (fs *pathLoopback) Link(ctx context.Context, oldName, newName string) syscall.Errno {
+	return ToErrno(syscall.Link(fs.abs(oldName), fs.abs(newName)))
+}
+
+func (fs *pathLoopback) Unlink(ctx context.Context, name string) syscall.Errno {
+	return ToErrno(syscall.Unlink(fs.abs(name)))
+}
+
+func (fs *pathLoopback) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return ToErrno(syscall.Rmdir(fs.abs(name)))
+}
+
+func (fs *pathLoopback) Rename(ctx context.Context, oldName, newName string, flags uint32) syscall.Errno {
+	return ToErrno(renameat.Renameat(unix.AT_FDCWD, fs.abs(oldName), unix.AT_FDCWD, fs.abs(newName), uint(flags)))
+}
+
+func (fs *pathLoopback) Getxattr(ctx context.Context, name string, attr string, dest []byte) (uint32, syscall.Errno) {
+	sz, err := unix.Lgetxattr(fs.abs(name), attr, dest)
+	return uint32(sz), ToErrno(err)
+}
+
+func (fs *pathLoopback) Setxattr(ctx context.Context, name string, attr string, data []byte, flags uint32) syscall.Errno {
+	return ToErrno(unix.Lsetxattr(fs.abs(name), attr, data, int(flags)))
+}
+
+func (fs *pathLoopback) Removexattr(ctx context.Context, name string, attr string) syscall.Errno {
+	return ToErrno(unix.Lremovexattr(fs.abs(name), attr))
+}
+
+func (fs *pathLoopback) Listxattr(ctx context.Context, name string, dest []byte) (uint32, syscall.Errno) {
+	sz, err := unix.Llistxattr(fs.abs(name), dest)
+	return uint32(sz), ToErrno(err)
+}
+
+func TestPathFSPosix(t *testing.T) {
+	for nm, fn := range posixtest.All {
+		t.Run(nm, func(t *testing.T) {
+			orig := t.TempDir()
+			opts := &Options{}
+			opts.EnableLocks = true
+			mnt, _ := testMount(t, NewPathFSRoot(&pathLoopback{root: orig}), opts)
+			fn(t, mnt)
+		})
+	}
+}
+
+func TestPathFSRenameHardlink(t *testing.T) {
+	orig := t.TempDir()
+	mnt, _ := testMount(t, NewPathFSRoot(&pathLoopback{root: orig}), nil)
+
+	if err := os.MkdirAll(filepath.Join(mnt, "a/b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mnt, "a/b/file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(mnt, "a/b/file"), filepath.Join(mnt, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// After renaming the directory, the children are found under
+	// the new path.
+	if err := os.Rename(filepath.Join(mnt, "a"), filepath.Join(mnt, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(mnt, "c/b/file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(orig, "c/b/file"), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode&0777 != 0600 {
+		t.Errorf("got mode %o, want 0600", st.Mode&0777)
+	}
+
+	// Both names of the hard link are the same Inode, which
+	// stays usable after one name is removed.
+	var st1, st2 syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(mnt, "link"), &st1); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(filepath.Join(mnt, "c/b/file"), &st2); err != nil {
+		t.Fatal(err)
+	}
+	if st1.Ino != st2.Ino || st1.Nlink != 2 {
+		t.Errorf("got ino %d, %d nlink %d, want same ino and nlink 2", st1.Ino, st2.Ino, st1.Nlink)
+	}
+	if err := os.Remove(filepath.Join(mnt, "c/b/file")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(mnt, "link"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(mnt, "link")); err != nil || string(got) != "hello" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+}
+
+func TestPathFSCrossFS(t *testing.T) {
+	origA := t.TempDir()
+	origB := t.TempDir()
+	if err := os.WriteFile(filepath.Join(origA, "file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := &Inode{}
+	mnt, _ := testMount(t, root, &Options{
+		OnAdd: func(ctx context.Context) {
+			for nm, orig := range map[string]string{"a": origA, "b": origB} {
+				ch := root.NewPersistentInode(ctx, NewPathFSRoot(&pathLoopback{root: orig}), StableAttr{Mode: syscall.S_IFDIR})
+				root.AddChild(nm, ch, true)
+			}
+		},
+	})
+
+	// Nodes of another PathFS have no path in this one.
+	if err := os.Link(filepath.Join(mnt, "a/file"), filepath.Join(mnt, "b/link")); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("Link: got %v, want EXDEV", err)
+	}
+	if err := os.Rename(filepath.Join(mnt, "a/file"), filepath.Join(mnt, "b/file")); !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("Rename: got %v, want EXDEV", err)
+	}
+	if _, err := os.Stat(filepath.Join(origB, "file")); !os.IsNotExist(err) {
+		t.Errorf("Stat: got %v, want ENOENT", err)
+	}
+}
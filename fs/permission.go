@@ -0,0 +1,637 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// PermissionChecker decides whether a caller may access a file,
+// following the POSIX rules the kernel applies for mounts with
+// Options.DefaultPermissions, including POSIX ACLs. It is for file
+// systems that cannot use DefaultPermissions, and can be used
+// directly, or through NewPermissionRoot.
+//
+// The permission bits of the file mode are authoritative: they take
+// precedence over the ACL_USER_OBJ, ACL_MASK (or ACL_GROUP_OBJ) and
+// ACL_OTHER entries of an access ACL. The root user (UID 0) may do
+// anything, except executing files that have no execute bit set.
+//
+// The zero value is ready for use.
+type PermissionChecker struct {
+	// Groups returns the supplementary groups of the caller. If
+	// nil, fuse.Caller.Groups is used. If it fails, the caller
+	// is only a member of its primary group.
+	Groups func(caller *fuse.Caller) ([]uint32, error)
+}
+
+// InGroup returns true if gid is the primary or a supplementary
+// group of the caller.
+func (c *PermissionChecker) InGroup(caller *fuse.Caller, gid uint32) bool {
+	if caller.Gid == gid {
+		return true
+	}
+	return slices.Contains(c.groups(caller), gid)
+}
+
+func (c *PermissionChecker) groups(caller *fuse.Caller) []uint32 {
+	groups := caller.Groups
+	if c.Groups != nil {
+		groups = func() ([]uint32, error) { return c.Groups(caller) }
+	}
+	gs, err := groups()
+	if err != nil {
+		return nil
+	}
+	return gs
+}
+
+// Check returns EACCES unless the caller may access a file with the
+// given attributes and access ACL in mode mask, a combination of
+// R_OK, W_OK and X_OK. The ACL may be nil.
+func (c *PermissionChecker) Check(caller *fuse.Caller, attr *fuse.Attr, acl ACL, mask uint32) syscall.Errno {
+	mask &= 7
+	if caller.Uid == 0 {
+		if mask&fuse.X_OK == 0 || attr.Mode&syscall.S_IFMT == syscall.S_IFDIR || attr.Mode&0111 != 0 {
+			return OK
+		}
+		return syscall.EACCES
+	}
+	if c.permits(caller, attr, acl, mask) {
+		return OK
+	}
+	return syscall.EACCES
+}
+
+// permits implements Check for callers other than root.
+func (c *PermissionChecker) permits(caller *fuse.Caller, attr *fuse.Attr, acl ACL, mask uint32) bool {
+	granted := func(perm uint32) bool {
+		return perm&mask == mask
+	}
+	if caller.Uid == attr.Uid {
+		return granted(attr.Mode >> 6 & 7)
+	}
+
+	// The group bits of the mode are the mask for named users, and
+	// for groups.
+	groupMask := attr.Mode >> 3 & 7
+	if !acl.Extended() {
+		acl = nil
+	}
+	for _, e := range acl {
+		if e.Tag == ACL_USER && e.ID == caller.Uid {
+			return granted(uint32(e.Perm) & groupMask)
+		}
+	}
+
+	var groups []uint32
+	loaded := false
+	member := func(gid uint32) bool {
+		if caller.Gid == gid {
+			return true
+		}
+		if !loaded {
+			groups = c.groups(caller)
+			loaded = true
+		}
+		return slices.Contains(groups, gid)
+	}
+
+	// Any matching group entry that grants the access suffices.
+	found := false
+	if member(attr.Gid) {
+		found = true
+		perm := groupMask
+		if e := acl.find(ACL_GROUP_OBJ); e != nil {
+			perm &= uint32(e.Perm)
+		}
+		if granted(perm) {
+			return true
+		}
+	}
+	for _, e := range acl {
+		if e.Tag == ACL_GROUP && member(e.ID) {
+			found = true
+			if granted(uint32(e.Perm) & groupMask) {
+				return true
+			}
+		}
+	}
+	if found {
+		return false
+	}
+	return granted(attr.Mode & 7)
+}
+
+// CheckDelete checks if the caller may remove or rename the entry
+// for child from directory dir, with the given access ACL. Besides
+// write and search permission on the directory, this needs
+// ownership of the directory or the child if the directory has the
+// sticky bit. It returns EACCES or EPERM if it is not allowed.
+func (c *PermissionChecker) CheckDelete(caller *fuse.Caller, dir *fuse.Attr, dirACL ACL, child *fuse.Attr) syscall.Errno {
+	if errno := c.Check(caller, dir, dirACL, fuse.W_OK|fuse.X_OK); errno != 0 {
+		return errno
+	}
+	if dir.Mode&syscall.S_ISVTX != 0 && caller.Uid != 0 && caller.Uid != dir.Uid && caller.Uid != child.Uid {
+		return syscall.EPERM
+	}
+	return OK
+}
+
+// CheckSetattr checks if the caller may change the attributes of a
+// file with the given attributes and access ACL as requested by in:
+//
+//   - changing the mode needs ownership. The set-group-ID bit is
+//     cleared from the new mode in if the caller is not in the group
+//     of the file;
+//   - changing the owner is reserved to root;
+//   - changing the group needs ownership and membership of the new
+//     group;
+//   - truncating needs write permission, unless it is done through
+//     an open file;
+//   - setting the times to the current time needs ownership or write
+//     permission, and setting them to other values ownership.
+//
+// It returns EACCES or EPERM if the change is not allowed.
+func (c *PermissionChecker) CheckSetattr(caller *fuse.Caller, attr *fuse.Attr, acl ACL, in *fuse.SetAttrIn) syscall.Errno {
+	if caller.Uid == 0 {
+		return OK
+	}
+	owner := caller.Uid == attr.Uid
+	if uid, ok := in.GetUID(); ok && uid != attr.Uid {
+		return syscall.EPERM
+	}
+	gid, ok := in.GetGID()
+	if ok && gid != attr.Gid && (!owner || !c.InGroup(caller, gid)) {
+		return syscall.EPERM
+	}
+	if !ok {
+		gid = attr.Gid
+	}
+	if in.Valid&fuse.FATTR_MODE != 0 {
+		if !owner {
+			return syscall.EPERM
+		}
+		if !c.InGroup(caller, gid) {
+			in.Mode &^= syscall.S_ISGID
+		}
+	}
+	if in.Valid&fuse.FATTR_SIZE != 0 && in.Valid&fuse.FATTR_FH == 0 {
+		if errno := c.Check(caller, attr, acl, fuse.W_OK); errno != 0 {
+			return errno
+		}
+	}
+	if !owner {
+		atime := in.Valid&(fuse.FATTR_ATIME|fuse.FATTR_ATIME_NOW) == fuse.FATTR_ATIME
+		mtime := in.Valid&(fuse.FATTR_MTIME|fuse.FATTR_MTIME_NOW) == fuse.FATTR_MTIME
+		if atime || mtime {
+			return syscall.EPERM
+		}
+		if in.Valid&(fuse.FATTR_ATIME|fuse.FATTR_MTIME) != 0 {
+			if errno := c.Check(caller, attr, acl, fuse.W_OK); errno != 0 {
+				return errno
+			}
+		}
+	}
+	return OK
+}
+
+// NewPermissionRoot returns a root for a view of the tree at root
+// that enforces permissions with checker, which may be nil to use a
+// zero PermissionChecker. It is for mounts without
+// Options.DefaultPermissions, and checks
+//
+//   - search permission on directories for Lookup;
+//   - the access mode for Open, Opendir and Access;
+//   - write and search permission on the directory, and the sticky
+//     bit, for creating, removing and renaming entries, and write
+//     permission on directories moved to another parent;
+//   - the rules of CheckSetattr for Setattr;
+//   - ownership for setting ACLs, and write permission for setting
+//     other "user." extended attributes.
+//
+// New files and directories inherit the default ACL of their
+// directory, and setting an access ACL updates the mode of the file.
+// The wrapped tree must support extended attributes to store ACLs.
+// Note that the kernel applies the umask to the mode of new files
+// before the file system sees it, also if there is a default ACL.
+//
+// Operations that do not come from the kernel are not checked.
+// The kernel does not repeat lookups for cached entries, so mount
+// with a zero Options.EntryTimeout if permissions of directories must
+// be enforced immediately after they change.
+//
+// Root must not be mounted or be part of another tree; see "Wrapping
+// trees" in the package documentation.
+func NewPermissionRoot(root InodeEmbedder, checker *PermissionChecker) InodeEmbedder {
+	if checker == nil {
+		checker = &PermissionChecker{}
+	}
+	t := newWrapTree(root, func() wrapper { return &permissionNode{checker: checker} })
+	return t.wrapRoot(root.embed())
+}
+
+type permissionNode struct {
+	wrapNode
+
+	checker *PermissionChecker
+}
+
+// fmodeExec is the flag the kernel adds to the open flags if a file
+// is opened for execution.
+const fmodeExec = 0x20
+
+// requestCaller returns the caller of a request from the kernel, or
+// nil.
+func requestCaller(ctx context.Context) *fuse.Caller {
+	caller, ok := fuse.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return caller
+}
+
+// readACL returns the ACL stored in the extended attribute name of
+// the wrapped node inner, or nil if it has none.
+func readACL(ctx context.Context, inner *Inode, name string) (ACL, syscall.Errno) {
+	gx, ok := inner.ops.(NodeGetxattrer)
+	if !ok {
+		return nil, OK
+	}
+	buf := make([]byte, 256)
+	for {
+		sz, errno := gx.Getxattr(ctx, name, buf)
+		switch errno {
+		case OK:
+			acl, err := ParseACL(buf[:sz])
+			if err != nil {
+				return nil, syscall.EIO
+			}
+			return acl, OK
+		case syscall.ERANGE:
+			if len(buf) < 1<<16 {
+				buf = make([]byte, 16*len(buf))
+				continue
+			}
+		case ENOATTR, syscall.ENOTSUP, syscall.ENOSYS:
+			return nil, OK
+		}
+		return nil, errno
+	}
+}
+
+// attr returns the attributes of the wrapped node inner, and its
+// access ACL if it is needed to check permissions of caller.
+func (n *permissionNode) attr(ctx context.Context, caller *fuse.Caller, inner *Inode) (*fuse.Attr, ACL, syscall.Errno) {
+	var out fuse.AttrOut
+	if errno := n.tree.bridge.getattr(ctx, inner, nil, &out); errno != 0 {
+		return nil, nil, errno
+	}
+	if caller.Uid == 0 || caller.Uid == out.Uid {
+		return &out.Attr, nil, OK
+	}
+	acl, errno := readACL(ctx, inner, XATTR_NAME_POSIX_ACL_ACCESS)
+	if errno != 0 {
+		return nil, nil, errno
+	}
+	return &out.Attr, acl, OK
+}
+
+// check checks that caller may access the wrapped node inner in
+// mode mask.
+func (n *permissionNode) check(ctx context.Context, caller *fuse.Caller, inner *Inode, mask uint32) syscall.Errno {
+	attr, acl, errno := n.attr(ctx, caller, inner)
+	if errno != 0 {
+		return errno
+	}
+	return n.checker.Check(caller, attr, acl, mask)
+}
+
+// checkDelete checks that caller may remove the entry name. If
+// moveDir is set and the entry is a directory, it also checks that
+// caller may write it, as moving it to another directory changes its
+// "..".
+func (n *permissionNode) checkDelete(ctx context.Context, caller *fuse.Caller, name string, moveDir bool) syscall.Errno {
+	dir, dirACL, errno := n.attr(ctx, caller, n.inner)
+	if errno != 0 {
+		return errno
+	}
+	var out fuse.EntryOut
+	child, errno := n.tree.bridge.lookup(ctx, n.inner, name, &out)
+	if errno != 0 {
+		return errno
+	}
+	errno = n.checker.CheckDelete(caller, dir, dirACL, &out.Attr)
+	if errno != 0 || !moveDir || child.Mode()&syscall.S_IFMT != syscall.S_IFDIR {
+		n.tree.bridge.discardChild(child)
+		return errno
+	}
+
+	// The directory is added to the wrapped tree, so it can be
+	// checked itself.
+	child, _ = n.tree.bridge.addNewChild(n.inner, name, child, nil, 0, &out)
+	defer child.removeRef(1, false)
+	return n.check(ctx, caller, child, fuse.W_OK)
+}
+
+// inherit returns the mode of a new entry of n, and the access and
+// default ACLs it gets from the default ACL of n, if any.
+func (n *permissionNode) inherit(ctx context.Context, mode uint32, dir bool) (uint32, ACL, ACL, syscall.Errno) {
+	def, errno := readACL(ctx, n.inner, XATTR_NAME_POSIX_ACL_DEFAULT)
+	if errno != 0 || def == nil {
+		return mode, nil, nil, errno
+	}
+	acl, mode := def.Inherit(mode)
+	if !acl.Extended() {
+		acl = nil
+	}
+	if !dir {
+		def = nil
+	}
+	return mode, acl, def, OK
+}
+
+// setACLs stores the inherited ACLs on the new entry name, whose
+// node is child.
+func (n *permissionNode) setACLs(ctx context.Context, name string, child *Inode, acl, def ACL) {
+	inner, _ := n.unwrap(child.ops)
+	sx, ok := inner.ops.(NodeSetxattrer)
+	if !ok {
+		return
+	}
+	for _, a := range []struct {
+		name string
+		acl  ACL
+	}{{XATTR_NAME_POSIX_ACL_ACCESS, acl}, {XATTR_NAME_POSIX_ACL_DEFAULT, def}} {
+		if a.acl == nil {
+			continue
+		}
+		if errno := sx.Setxattr(ctx, a.name, a.acl.Bytes(), 0); errno != 0 {
+			n.tree.bridge.logf("permissionNode: setting %s of %q: %v", a.name, n.childPath(name), errno)
+		}
+	}
+}
+
+func (n *permissionNode) Access(ctx context.Context, mask uint32) syscall.Errno {
+	caller := requestCaller(ctx)
+	if caller == nil {
+		return n.wrapNode.Access(ctx, mask)
+	}
+	return n.check(ctx, caller, n.inner, mask)
+}
+
+func (n *permissionNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if caller := requestCaller(ctx); caller != nil {
+		if errno := n.check(ctx, caller, n.inner, fuse.X_OK); errno != 0 {
+			return nil, errno
+		}
+	}
+	return n.wrapNode.Lookup(ctx, name, out)
+}
+
+func (n *permissionNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if caller := requestCaller(ctx); caller != nil {
+		var mask uint32
+		switch flags & syscall.O_ACCMODE {
+		case syscall.O_RDONLY:
+			mask = fuse.R_OK
+		case syscall.O_WRONLY:
+			mask = fuse.W_OK
+		default:
+			mask = fuse.R_OK | fuse.W_OK
+		}
+		if flags&fmodeExec != 0 {
+			mask = fuse.X_OK
+		}
+		if flags&syscall.O_TRUNC != 0 {
+			mask |= fuse.W_OK
+		}
+		if errno := n.check(ctx, caller, n.inner, mask); errno != 0 {
+			return nil, 0, errno
+		}
+	}
+	return n.wrapNode.Open(ctx, flags)
+}
+
+func (n *permissionNode) OpendirHandle(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if caller := requestCaller(ctx); caller != nil {
+		if errno := n.check(ctx, caller, n.inner, fuse.R_OK); errno != 0 {
+			return nil, 0, errno
+		}
+	}
+	return n.wrapNode.OpendirHandle(ctx, flags)
+}
+
+func (n *permissionNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	caller := requestCaller(ctx)
+	if caller != nil {
+		attr, acl, errno := n.attr(ctx, caller, n.inner)
+		if errno != 0 {
+			return errno
+		}
+		if errno := n.checker.CheckSetattr(caller, attr, acl, in); errno != 0 {
+			return errno
+		}
+	}
+	if errno := n.wrapNode.Setattr(ctx, f, in, out); errno != 0 {
+		return errno
+	}
+	if mode, ok := in.GetMode(); ok {
+		// Keep the access ACL in sync with the mode, like chmod
+		// does.
+		acl, errno := readACL(ctx, n.inner, XATTR_NAME_POSIX_ACL_ACCESS)
+		if errno == 0 && acl != nil {
+			errno = n.wrapNode.Setxattr(ctx, XATTR_NAME_POSIX_ACL_ACCESS, acl.Chmod(mode).Bytes(), 0)
+		}
+		if errno != 0 {
+			return errno
+		}
+	}
+	return OK
+}
+
+func (n *permissionNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	if errno := n.checkXattr(ctx, attr); errno != 0 {
+		return errno
+	}
+	if attr != XATTR_NAME_POSIX_ACL_ACCESS && attr != XATTR_NAME_POSIX_ACL_DEFAULT {
+		return n.wrapNode.Setxattr(ctx, attr, data, flags)
+	}
+
+	acl, err := ParseACL(data)
+	if err != nil {
+		return ToErrno(err)
+	}
+	var out fuse.AttrOut
+	if errno := n.tree.bridge.getattr(ctx, n.inner, nil, &out); errno != 0 {
+		return errno
+	}
+	if attr == XATTR_NAME_POSIX_ACL_DEFAULT {
+		if out.Mode&syscall.S_IFMT != syscall.S_IFDIR {
+			return syscall.EACCES
+		}
+		return n.wrapNode.Setxattr(ctx, attr, data, flags)
+	}
+
+	// An access ACL sets the mode. If the mode can represent it,
+	// it is not stored.
+	var errno syscall.Errno
+	if acl.Extended() {
+		errno = n.wrapNode.Setxattr(ctx, attr, data, flags)
+	} else if errno = n.wrapNode.Removexattr(ctx, attr); errno == ENOATTR {
+		errno = OK
+	}
+	if errno != 0 {
+		return errno
+	}
+	in := fuse.SetAttrIn{
+		SetAttrInCommon: fuse.SetAttrInCommon{
+			Valid: fuse.FATTR_MODE,
+			Mode:  out.Mode&^0777 | acl.Mode(),
+		},
+	}
+	return n.wrapNode.Setattr(ctx, nil, &in, &out)
+}
+
+func (n *permissionNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	if errno := n.checkXattr(ctx, attr); errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Removexattr(ctx, attr)
+}
+
+// checkXattr checks that the caller may set or remove the extended
+// attribute attr.
+func (n *permissionNode) checkXattr(ctx context.Context, attr string) syscall.Errno {
+	caller := requestCaller(ctx)
+	if caller == nil {
+		return OK
+	}
+	switch {
+	case attr == XATTR_NAME_POSIX_ACL_ACCESS || attr == XATTR_NAME_POSIX_ACL_DEFAULT:
+		var out fuse.AttrOut
+		if errno := n.tree.bridge.getattr(ctx, n.inner, nil, &out); errno != 0 {
+			return errno
+		}
+		if caller.Uid != 0 && caller.Uid != out.Uid {
+			return syscall.EPERM
+		}
+	case strings.HasPrefix(attr, "user."):
+		return n.check(ctx, caller, n.inner, fuse.W_OK)
+	}
+	return OK
+}
+
+// checkCreate checks that the caller may add entries to n.
+func (n *permissionNode) checkCreate(ctx context.Context) syscall.Errno {
+	if caller := requestCaller(ctx); caller != nil {
+		return n.check(ctx, caller, n.inner, fuse.W_OK|fuse.X_OK)
+	}
+	return OK
+}
+
+func (n *permissionNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.checkCreate(ctx); errno != 0 {
+		return nil, errno
+	}
+	mode, acl, def, errno := n.inherit(ctx, mode, true)
+	if errno != 0 {
+		return nil, errno
+	}
+	child, errno := n.wrapNode.Mkdir(ctx, name, mode, out)
+	if errno == 0 {
+		n.setACLs(ctx, name, child, acl, def)
+	}
+	return child, errno
+}
+
+func (n *permissionNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.checkCreate(ctx); errno != 0 {
+		return nil, errno
+	}
+	mode, acl, _, errno := n.inherit(ctx, mode, false)
+	if errno != 0 {
+		return nil, errno
+	}
+	child, errno := n.wrapNode.Mknod(ctx, name, mode, dev, out)
+	if errno == 0 {
+		n.setACLs(ctx, name, child, acl, nil)
+	}
+	return child, errno
+}
+
+func (n *permissionNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.checkCreate(ctx); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Link(ctx, target, name, out)
+}
+
+func (n *permissionNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	if errno := n.checkCreate(ctx); errno != 0 {
+		return nil, errno
+	}
+	return n.wrapNode.Symlink(ctx, target, name, out)
+}
+
+func (n *permissionNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	if errno := n.checkCreate(ctx); errno != 0 {
+		return nil, nil, 0, errno
+	}
+	mode, acl, _, errno := n.inherit(ctx, mode, false)
+	if errno != 0 {
+		return nil, nil, 0, errno
+	}
+	child, fh, fuseFlags, errno := n.wrapNode.Create(ctx, name, flags, mode, out)
+	if errno == 0 {
+		n.setACLs(ctx, name, child, acl, nil)
+	}
+	return child, fh, fuseFlags, errno
+}
+
+func (n *permissionNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	if caller := requestCaller(ctx); caller != nil {
+		if errno := n.checkDelete(ctx, caller, name, false); errno != 0 {
+			return errno
+		}
+	}
+	return n.wrapNode.Unlink(ctx, name)
+}
+
+func (n *permissionNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	if caller := requestCaller(ctx); caller != nil {
+		if errno := n.checkDelete(ctx, caller, name, false); errno != 0 {
+			return errno
+		}
+	}
+	return n.wrapNode.Rmdir(ctx, name)
+}
+
+func (n *permissionNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	caller := requestCaller(ctx)
+	p, ok := newParent.(*permissionNode)
+	if caller == nil || !ok {
+		return n.wrapNode.Rename(ctx, name, newParent, newName, flags)
+	}
+
+	if errno := n.checkDelete(ctx, caller, name, p != n); errno != 0 {
+		return errno
+	}
+	// The target is replaced, or moved to n on exchange.
+	exchange := flags&RENAME_EXCHANGE != 0
+	errno := p.checkDelete(ctx, caller, newName, p != n && exchange)
+	if errno == syscall.ENOENT && !exchange {
+		errno = p.check(ctx, caller, p.inner, fuse.W_OK|fuse.X_OK)
+	}
+	if errno != 0 {
+		return errno
+	}
+	return n.wrapNode.Rename(ctx, name, newParent, newName, flags)
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// asUser runs f on a thread with the given file system UID and GID
+// and supplementary groups. The thread exits afterwards.
+func asUser(t *testing.T, uid, gid int, groups []int, f func()) {
+	t.Helper()
+	errs := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		err := unix.Setgroups(groups)
+		if err == nil {
+			err = unix.Setfsgid(gid)
+		}
+		if err == nil {
+			err = unix.Setfsuid(uid)
+		}
+		if err == nil {
+			f()
+		}
+		errs <- err
+	}()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPermissionRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("must run as root")
+	}
+	const alice, bob, staff = 1000, 1001, 100
+
+	orig := t.TempDir()
+	for _, d := range []struct {
+		name     string
+		dir      bool
+		mode     os.FileMode
+		uid, gid int
+	}{
+		{"", true, 0755, 0, 0},
+		{"private", true, 0700, alice, alice},
+		{"private/secret", false, 0644, alice, alice},
+		{"shared", true, 0777 | os.ModeSticky, 0, 0},
+		{"shared/alice", false, 0644, alice, alice},
+		{"file", false, 0640, alice, staff},
+		{"acl", false, 0600, alice, alice},
+		{"inherit", true, 0777, 0, 0},
+		{"src", true, 0777, bob, bob},
+		{"src/dir", true, 0755, bob, bob},
+		{"src/other", true, 0755, alice, alice},
+		{"dst", true, 0777, 0, 0},
+	} {
+		p := filepath.Join(orig, d.name)
+		var err error
+		if d.dir {
+			err = os.MkdirAll(p, 0755)
+		} else {
+			err = os.WriteFile(p, []byte("hello"), 0644)
+		}
+		if err == nil {
+			err = os.Chown(p, d.uid, d.gid)
+		}
+		if err == nil {
+			err = os.Chmod(p, d.mode)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loop, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := time.Duration(0)
+	opts := &Options{
+		EntryTimeout: &zero,
+		AttrTimeout:  &zero,
+	}
+	opts.AllowOther = true
+	mnt, _ := testMount(t, NewPermissionRoot(loop, nil), opts)
+	// Let other users reach the mount point.
+	if err := os.Chmod(filepath.Dir(mnt), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dirfd, err := unix.Open(mnt, unix.O_DIRECTORY|unix.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(dirfd)
+
+	acl := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 6},
+		{Tag: ACL_USER, Perm: 6, ID: bob},
+		{Tag: ACL_GROUP_OBJ, Perm: 0},
+		{Tag: ACL_MASK, Perm: 6},
+		{Tag: ACL_OTHER, Perm: 0},
+	}
+	if err := unix.Setxattr(filepath.Join(mnt, "acl"), XATTR_NAME_POSIX_ACL_ACCESS, acl.Bytes(), 0); err != nil {
+		t.Fatalf("Setxattr: %v", err)
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(filepath.Join(mnt, "acl"), &st); err != nil {
+		t.Fatal(err)
+	} else if st.Mode&07777 != 0660 {
+		t.Errorf("mode after setting ACL: got %o, want 660", st.Mode&07777)
+	}
+	def := append(ACL(nil), acl...)
+	def[0].Perm = 7
+	if err := unix.Setxattr(filepath.Join(mnt, "inherit"), XATTR_NAME_POSIX_ACL_DEFAULT, def.Bytes(), 0); err != nil {
+		t.Fatalf("Setxattr: %v", err)
+	}
+
+	open := func(name string, flags int) error {
+		fd, err := unix.Openat(dirfd, name, flags|unix.O_CLOEXEC, 0644)
+		if err == nil {
+			unix.Close(fd)
+		}
+		return err
+	}
+	type step struct {
+		name string
+		op   func() error
+		want error
+	}
+	run := func(uid int, groups []int, steps []step) {
+		asUser(t, uid, uid, groups, func() {
+			for _, s := range steps {
+				if got := s.op(); got != s.want {
+					t.Errorf("uid %d: %s: got %v, want %v", uid, s.name, got, s.want)
+				}
+			}
+		})
+	}
+
+	run(bob, []int{staff}, []step{
+		{"lookup in private dir", func() error { return open("private/secret", unix.O_RDONLY) }, syscall.EACCES},
+		{"read file of group", func() error { return open("file", unix.O_RDONLY) }, nil},
+		{"write file of group", func() error { return open("file", unix.O_WRONLY) }, syscall.EACCES},
+		{"truncate file of group", func() error { return unix.Truncate(filepath.Join(mnt, "file"), 0) }, syscall.EACCES},
+		{"chmod file of other user", func() error { return unix.Fchmodat(dirfd, "file", 0666, 0) }, syscall.EPERM},
+		{"create in root", func() error { return open("new", unix.O_CREAT|unix.O_WRONLY) }, syscall.EACCES},
+		{"create in shared", func() error { return open("shared/bob", unix.O_CREAT|unix.O_WRONLY) }, nil},
+		{"unlink in sticky dir", func() error { return unix.Unlinkat(dirfd, "shared/alice", 0) }, syscall.EPERM},
+		{"rename in sticky dir", func() error { return unix.Renameat(dirfd, "shared/alice", dirfd, "shared/moved") }, syscall.EPERM},
+		{"rename own file in sticky dir", func() error { return unix.Renameat(dirfd, "shared/bob", dirfd, "shared/bob2") }, nil},
+		{"open with ACL", func() error { return open("acl", unix.O_RDWR) }, nil},
+		{"move own dir to other dir", func() error { return unix.Renameat(dirfd, "src/dir", dirfd, "dst/dir") }, nil},
+		{"move dir of other user to other dir", func() error { return unix.Renameat(dirfd, "src/other", dirfd, "dst/other") }, syscall.EACCES},
+	})
+	run(alice, nil, []step{
+		{"lookup in private dir", func() error { return open("private/secret", unix.O_RDONLY) }, nil},
+		{"replace in sticky dir", func() error { return unix.Renameat(dirfd, "shared/alice", dirfd, "shared/bob2") }, syscall.EPERM},
+		{"chmod own file", func() error { return unix.Fchmodat(dirfd, "file", 0600, 0) }, nil},
+		{"set ACL", func() error {
+			return unix.Setxattr(filepath.Join(mnt, "acl"), XATTR_NAME_POSIX_ACL_ACCESS, acl.Bytes(), 0)
+		}, nil},
+		{"create with default ACL", func() error { return open("inherit/new", unix.O_CREAT|unix.O_WRONLY) }, nil},
+		{"mkdir with default ACL", func() error { return unix.Mkdirat(dirfd, "inherit/dir", 0777) }, nil},
+	})
+	run(bob, []int{staff}, []step{
+		{"read after chmod", func() error { return open("file", unix.O_RDONLY) }, syscall.EACCES},
+		{"set ACL of other user", func() error {
+			return unix.Setxattr(filepath.Join(mnt, "acl"), XATTR_NAME_POSIX_ACL_ACCESS, acl.Bytes(), 0)
+		}, syscall.EPERM},
+		// The mode of the new file, 0644, limits the mask to r--.
+		{"read inherited ACL", func() error { return open("inherit/new", unix.O_RDONLY) }, nil},
+		{"write inherited ACL", func() error { return open("inherit/new", unix.O_WRONLY) }, syscall.EACCES},
+		{"unlink own file", func() error { return unix.Unlinkat(dirfd, "shared/bob2", 0) }, nil},
+	})
+
+	for name, want := range map[string]bool{"inherit/new": false, "inherit/dir": true} {
+		buf := make([]byte, 1024)
+		sz, err := unix.Getxattr(filepath.Join(mnt, name), XATTR_NAME_POSIX_ACL_ACCESS, buf)
+		if err != nil {
+			t.Errorf("%s: Getxattr: %v", name, err)
+		} else if got, err := ParseACL(buf[:sz]); err != nil || !got.Extended() {
+			t.Errorf("%s: got ACL %v, %v", name, got, err)
+		}
+		_, err = unix.Getxattr(filepath.Join(mnt, name), XATTR_NAME_POSIX_ACL_DEFAULT, buf)
+		if got := err == nil; got != want {
+			t.Errorf("%s: got default ACL %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func testCaller(uid, gid uint32) *fuse.Caller {
+	return &fuse.Caller{Owner: fuse.Owner{Uid: uid, Gid: gid}}
+}
+
+func TestPermissionCheckerCheck(t *testing.T) {
+	pc := &PermissionChecker{
+		Groups: func(caller *fuse.Caller) ([]uint32, error) {
+			if caller.Uid == 1002 {
+				return []uint32{200, 300}, nil
+			}
+			return nil, syscall.ESRCH
+		},
+	}
+	file := func(mode uint32) *fuse.Attr {
+		return &fuse.Attr{Mode: syscall.S_IFREG | mode, Owner: fuse.Owner{Uid: 1000, Gid: 100}}
+	}
+	acl := ACL{
+		{Tag: ACL_USER_OBJ, Perm: 6},
+		{Tag: ACL_USER, Perm: 6, ID: 1001},
+		{Tag: ACL_USER, Perm: 7, ID: 1003},
+		{Tag: ACL_GROUP_OBJ, Perm: 4},
+		{Tag: ACL_GROUP, Perm: 2, ID: 200},
+		{Tag: ACL_GROUP, Perm: 4, ID: 300},
+		{Tag: ACL_MASK, Perm: 6},
+		{Tag: ACL_OTHER, Perm: 0},
+	}
+
+	for i, tc := range []struct {
+		caller *fuse.Caller
+		attr   *fuse.Attr
+		acl    ACL
+		mask   uint32
+		want   syscall.Errno
+	}{
+		// Mode bits.
+		{testCaller(1000, 1000), file(0604), nil, fuse.R_OK | fuse.W_OK, OK},
+		{testCaller(1000, 1000), file(0077), nil, fuse.R_OK, syscall.EACCES},
+		{testCaller(1001, 100), file(0640), nil, fuse.R_OK, OK},
+		{testCaller(1001, 100), file(0640), nil, fuse.W_OK, syscall.EACCES},
+		{testCaller(1001, 100), file(0604), nil, fuse.R_OK, syscall.EACCES},
+		{testCaller(1001, 101), file(0604), nil, fuse.R_OK, OK},
+		{testCaller(1001, 101), file(0644), nil, 0, OK},
+
+		// Supplementary groups.
+		{testCaller(1002, 1002), &fuse.Attr{Mode: 0660, Owner: fuse.Owner{Uid: 1000, Gid: 300}}, nil, fuse.W_OK, OK},
+		{testCaller(1001, 1001), &fuse.Attr{Mode: 0660, Owner: fuse.Owner{Uid: 1000, Gid: 300}}, nil, fuse.W_OK, syscall.EACCES},
+
+		// Root.
+		{testCaller(0, 0), file(0), nil, fuse.R_OK | fuse.W_OK, OK},
+		{testCaller(0, 0), file(0644), nil, fuse.X_OK, syscall.EACCES},
+		{testCaller(0, 0), file(0010), nil, fuse.X_OK, OK},
+		{testCaller(0, 0), &fuse.Attr{Mode: syscall.S_IFDIR}, nil, fuse.X_OK, OK},
+
+		// Named users, limited by the mask.
+		{testCaller(1001, 1001), file(0660), acl, fuse.R_OK | fuse.W_OK, OK},
+		{testCaller(1001, 1001), file(0640), acl, fuse.W_OK, syscall.EACCES},
+		{testCaller(1003, 1003), file(0670), acl, fuse.X_OK, OK},
+		{testCaller(1003, 1003), file(0660), acl, fuse.X_OK, syscall.EACCES},
+
+		// Groups: one matching entry must grant all of mask.
+		{testCaller(1004, 100), file(0660), acl, fuse.R_OK, OK},
+		{testCaller(1004, 100), file(0666), acl, fuse.W_OK, syscall.EACCES},
+		{testCaller(1002, 1002), file(0660), acl, fuse.W_OK, OK},
+		{testCaller(1002, 1002), file(0660), acl, fuse.R_OK, OK},
+		{testCaller(1002, 1002), file(0666), acl, fuse.R_OK | fuse.W_OK, syscall.EACCES},
+
+		// Others.
+		{testCaller(1004, 1004), file(0664), acl, fuse.R_OK, OK},
+		{testCaller(1004, 1004), file(0660), acl, fuse.R_OK, syscall.EACCES},
+
+		// The mode takes precedence over a minimal ACL.
+		{testCaller(1001, 100), file(0640), ACL{{Tag: ACL_USER_OBJ}, {Tag: ACL_GROUP_OBJ}, {Tag: ACL_OTHER}}, fuse.R_OK, OK},
+	} {
+		if got := pc.Check(tc.caller, tc.attr, tc.acl, tc.mask); got != tc.want {
+			t.Errorf("%d: got %v, want %v", i, got, tc.want)
+		}
+	}
+}
+
+func TestPermissionCheckerDelete(t *testing.T) {
+	var pc PermissionChecker
+	dir := &fuse.Attr{Mode: syscall.S_IFDIR | syscall.S_ISVTX | 0777, Owner: fuse.Owner{Uid: 1000, Gid: 1000}}
+	child := &fuse.Attr{Mode: syscall.S_IFREG | 0644, Owner: fuse.Owner{Uid: 1001, Gid: 1001}}
+
+	for uid, want := range map[uint32]syscall.Errno{
+		0:    OK,
+		1000: OK,
+		1001: OK,
+		1002: syscall.EPERM,
+	} {
+		if got := pc.CheckDelete(testCaller(uid, uid), dir, nil, child); got != want {
+			t.Errorf("uid %d: got %v, want %v", uid, got, want)
+		}
+	}
+
+	dir.Mode = syscall.S_IFDIR | 0755
+	if got := pc.CheckDelete(testCaller(1001, 1001), dir, nil, child); got != syscall.EACCES {
+		t.Errorf("got %v, want EACCES", got)
+	}
+}
+
+func TestPermissionCheckerSetattr(t *testing.T) {
+	pc := &PermissionChecker{
+		Groups: func(caller *fuse.Caller) ([]uint32, error) {
+			return []uint32{200}, nil
+		},
+	}
+	attr := &fuse.Attr{Mode: syscall.S_IFREG | 0664, Owner: fuse.Owner{Uid: 1000, Gid: 100}}
+	in := func(valid uint32) *fuse.SetAttrIn {
+		return &fuse.SetAttrIn{SetAttrInCommon: fuse.SetAttrInCommon{Valid: valid}}
+	}
+	chown := func(uid, gid uint32) *fuse.SetAttrIn {
+		in := in(fuse.FATTR_UID | fuse.FATTR_GID)
+		in.Uid = uid
+		in.Gid = gid
+		return in
+	}
+
+	for i, tc := range []struct {
+		caller *fuse.Caller
+		in     *fuse.SetAttrIn
+		want   syscall.Errno
+	}{
+		{testCaller(1000, 1000), in(fuse.FATTR_MODE), OK},
+		{testCaller(1001, 100), in(fuse.FATTR_MODE), syscall.EPERM},
+		{testCaller(0, 0), chown(1001, 1001), OK},
+		{testCaller(1000, 1000), chown(1000, 100), OK},
+		{testCaller(1000, 1000), chown(1001, 100), syscall.EPERM},
+		{testCaller(1000, 1000), chown(1000, 200), OK},
+		{testCaller(1000, 1000), chown(1000, 300), syscall.EPERM},
+		{testCaller(1001, 100), chown(1000, 200), syscall.EPERM},
+		{testCaller(1001, 100), in(fuse.FATTR_SIZE), OK},
+		{testCaller(1001, 1001), in(fuse.FATTR_SIZE), syscall.EACCES},
+		{testCaller(1001, 1001), in(fuse.FATTR_SIZE | fuse.FATTR_FH), OK},
+		{testCaller(1001, 100), in(fuse.FATTR_ATIME | fuse.FATTR_ATIME_NOW | fuse.FATTR_MTIME | fuse.FATTR_MTIME_NOW), OK},
+		{testCaller(1001, 1001), in(fuse.FATTR_ATIME | fuse.FATTR_ATIME_NOW | fuse.FATTR_MTIME | fuse.FATTR_MTIME_NOW), syscall.EACCES},
+		{testCaller(1001, 100), in(fuse.FATTR_ATIME | fuse.FATTR_MTIME | fuse.FATTR_MTIME_NOW), syscall.EPERM},
+		{testCaller(1000, 1000), in(fuse.FATTR_ATIME | fuse.FATTR_MTIME), OK},
+	} {
+		if got := pc.CheckSetattr(tc.caller, attr, nil, tc.in); got != tc.want {
+			t.Errorf("%d: got %v, want %v", i, got, tc.want)
+		}
+	}
+
+	// The set-group-ID bit is cleared for non-members.
+	for gid, want := range map[uint32]uint32{200: syscall.S_ISGID | 0775, 300: 0775} {
+		a := *attr
+		a.Gid = gid
+		set := in(fuse.FATTR_MODE)
+		set.Mode = syscall.S_ISGID | 0775
+		if errno := pc.CheckSetattr(testCaller(1000, 1000), &a, nil, set); errno != 0 {
+			t.Fatal(errno)
+		}
+		if set.Mode != want {
+			t.Errorf("gid %d: got mode %o, want %o", gid, set.Mode, want)
+		}
+	}
+}
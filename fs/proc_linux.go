@@ -0,0 +1,39 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// threadGroup returns the process ID for the thread ID that the
+// kernel reports as the PID of the caller, or 0 if it is not known.
+func threadGroup(tid uint32) uint32 {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", tid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if rest, ok := bytes.CutPrefix(line, []byte("Tgid:")); ok {
+			pid, _ := strconv.ParseUint(string(bytes.TrimSpace(rest)), 10, 32)
+			return uint32(pid)
+		}
+	}
+	return 0
+}
+
+// procCmdline returns the command line of a process, or nil if it
+// cannot be read.
+func procCmdline(pid uint32) []string {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+// threadGroup returns the process ID of the caller.
+func threadGroup(pid uint32) uint32 {
+	return pid
+}
+
+// procCmdline returns nil, because the command line of other
+// processes is not readable as a file.
+func procCmdline(pid uint32) []string {
+	return nil
+}
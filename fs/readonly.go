@@ -0,0 +1,125 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewReadonlyRoot returns a root for a read-only view of the tree at
+// root. All operations that modify the tree fail with EROFS, and
+// the others are forwarded unchanged. In particular, files opened
+// for reading keep the file handles of the wrapped tree, so they can
+// use passthrough.
+//
+// Root must not be mounted or be part of another tree; see "Wrapping
+// trees" in the package documentation.
+func NewReadonlyRoot(root InodeEmbedder) InodeEmbedder {
+	t := newWrapTree(root, func() wrapper { return &readonlyNode{} })
+	return t.wrapRoot(root.embed())
+}
+
+type readonlyNode struct {
+	wrapNode
+}
+
+func (n *readonlyNode) Access(ctx context.Context, mask uint32) syscall.Errno {
+	if mask&fuse.W_OK != 0 {
+		return syscall.EROFS
+	}
+	return n.wrapNode.Access(ctx, mask)
+}
+
+func (n *readonlyNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY || flags&syscall.O_TRUNC != 0 {
+		return nil, 0, syscall.EROFS
+	}
+	return n.wrapNode.Open(ctx, flags)
+}
+
+func (n *readonlyNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	return syscall.EROFS
+}
+
+func (n *readonlyNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	return syscall.EROFS
+}
+
+func (n *readonlyNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	return syscall.EROFS
+}
+
+func (n *readonlyNode) Write(ctx context.Context, f FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	return 0, syscall.EROFS
+}
+
+func (n *readonlyNode) spliceWrite(ctx context.Context, f FileHandle, data *fuse.WritePipe, off int64) (uint32, syscall.Errno, bool) {
+	return 0, syscall.EROFS, true
+}
+
+func (n *readonlyNode) Allocate(ctx context.Context, f FileHandle, off uint64, size uint64, mode uint32) syscall.Errno {
+	return syscall.EROFS
+}
+
+// CopyFileRange fails, because the destination is always in this
+// file system.
+func (n *readonlyNode) CopyFileRange(ctx context.Context, fhIn FileHandle, offIn uint64, out *Inode, fhOut FileHandle, offOut uint64, len uint64, flags uint64) (uint32, syscall.Errno) {
+	return 0, syscall.EROFS
+}
+
+func (n *readonlyNode) Setlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	if isWriteLock(lk, flags) {
+		return syscall.EROFS
+	}
+	return n.wrapNode.Setlk(ctx, f, owner, lk, flags)
+}
+
+func (n *readonlyNode) Setlkw(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	if isWriteLock(lk, flags) {
+		return syscall.EROFS
+	}
+	return n.wrapNode.Setlkw(ctx, f, owner, lk, flags)
+}
+
+// isWriteLock returns true for POSIX write locks. Exclusive flock
+// locks are allowed, like on read-only mounts.
+func isWriteLock(lk *fuse.FileLock, flags uint32) bool {
+	return flags&fuse.FUSE_LK_FLOCK == 0 && lk.Typ == syscall.F_WRLCK
+}
+
+func (n *readonlyNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	return nil, syscall.EROFS
+}
+
+func (n *readonlyNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	return nil, syscall.EROFS
+}
+
+func (n *readonlyNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	return nil, syscall.EROFS
+}
+
+func (n *readonlyNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	return nil, syscall.EROFS
+}
+
+func (n *readonlyNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	return nil, nil, 0, syscall.EROFS
+}
+
+func (n *readonlyNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	return syscall.EROFS
+}
+
+func (n *readonlyNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return syscall.EROFS
+}
+
+func (n *readonlyNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	return syscall.EROFS
+}
@@ -6,9 +6,12 @@ package fs
 
 import (
 	"context"
+	"os"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"testing"
+	"testing/fstest"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
 	"golang.org/x/sys/unix"
@@ -49,3 +52,180 @@ func TestDefaultPermissions(t *testing.T) {
 		}
 	}
 }
+
+func TestReadonlyRoot(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(orig, "dir/sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(orig, "dir/file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(orig, "dir/file"), filepath.Join(orig, "link")); err != nil {
+		t.Fatal(err)
+	}
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mnt, _ := testMount(t, NewReadonlyRoot(loopback), nil)
+
+	if got, err := os.ReadFile(filepath.Join(mnt, "dir/file")); err != nil || string(got) != "hello" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+	entries, err := os.ReadDir(filepath.Join(mnt, "dir"))
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ReadDir: got %v, %v", entries, err)
+	}
+	var st1, st2 syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(mnt, "dir/file"), &st1); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(filepath.Join(mnt, "link"), &st2); err != nil {
+		t.Fatal(err)
+	}
+	if st1.Ino != st2.Ino {
+		t.Errorf("hard link: got inodes %d, %d", st1.Ino, st2.Ino)
+	}
+
+	file := filepath.Join(mnt, "dir/file")
+	for nm, fn := range map[string]func() error{
+		"open O_WRONLY": func() error {
+			_, err := unix.Open(file, unix.O_WRONLY, 0)
+			return err
+		},
+		"open O_RDWR": func() error {
+			_, err := unix.Open(file, unix.O_RDWR, 0)
+			return err
+		},
+		"open O_TRUNC": func() error {
+			_, err := unix.Open(file, unix.O_RDONLY|unix.O_TRUNC, 0)
+			return err
+		},
+		"create":   func() error { _, err := unix.Open(mnt+"/new", unix.O_CREAT|unix.O_WRONLY, 0644); return err },
+		"mkdir":    func() error { return unix.Mkdir(mnt+"/newdir", 0755) },
+		"mknod":    func() error { return unix.Mknod(mnt+"/fifo", unix.S_IFIFO|0644, 0) },
+		"symlink":  func() error { return unix.Symlink("file", mnt+"/symlink") },
+		"link":     func() error { return unix.Link(file, mnt+"/link2") },
+		"unlink":   func() error { return unix.Unlink(file) },
+		"rmdir":    func() error { return unix.Rmdir(mnt + "/dir/sub") },
+		"rename":   func() error { return unix.Rename(file, mnt+"/renamed") },
+		"chmod":    func() error { return unix.Chmod(file, 0600) },
+		"truncate": func() error { return unix.Truncate(file, 0) },
+		"setxattr": func() error { return unix.Setxattr(file, "user.attr", []byte("x"), 0) },
+		"rmxattr":  func() error { return unix.Removexattr(file, "user.attr") },
+		"access":   func() error { return unix.Access(file, unix.W_OK) },
+	} {
+		if err := fn(); err != syscall.EROFS {
+			t.Errorf("%s: got %v, want EROFS", nm, err)
+		}
+	}
+
+	if got, err := os.ReadFile(filepath.Join(orig, "dir/file")); err != nil || string(got) != "hello" {
+		t.Errorf("backing file changed: got %q, %v", got, err)
+	}
+}
+
+func TestReadonlyRootIOFS(t *testing.T) {
+	tree := &iofsTreeRoot{files: map[string]string{
+		"file":      "hello",
+		"dir/sub/x": "x",
+		"dir/link":  "->../file",
+	}}
+	fsys := NewIOFS(NewReadonlyRoot(tree), nil)
+	if err := fstest.TestFS(fsys, "file", "dir/sub/x", "dir/link"); err != nil {
+		t.Fatal(err)
+	}
+
+	// All lookups on the wrapped tree were released.
+	if n := tree.bridge.kernelNodeIds.Size(); n != 1 {
+		t.Errorf("%d node IDs in use, want only the root", n)
+	}
+}
+
+func TestReadonlyRootPassthrough(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("passthrough requires CAP_SYS_ADMIN")
+	}
+
+	var mu sync.Mutex
+	reads := 0
+	orig := t.TempDir()
+	if err := os.WriteFile(filepath.Join(orig, "file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootData := &LoopbackRoot{
+		Path: orig,
+		NewNode: func(rootData *LoopbackRoot, parent *Inode, name string, st *syscall.Stat_t) InodeEmbedder {
+			return &readCountingNode{LoopbackNode: LoopbackNode{RootData: rootData}, mu: &mu, reads: &reads}
+		},
+	}
+	root := &LoopbackNode{RootData: rootData}
+	mnt, server := testMount(t, NewReadonlyRoot(root), &Options{})
+	if server.KernelSettings().Flags64()&fuse.CAP_PASSTHROUGH == 0 {
+		t.Skip("Kernel does not support passthrough")
+	}
+
+	if got, err := os.ReadFile(filepath.Join(mnt, "file")); err != nil || string(got) != "hello" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if reads > 0 {
+		t.Errorf("got %d reads, want 0", reads)
+	}
+}
+
+type readCountingNode struct {
+	LoopbackNode
+
+	mu    *sync.Mutex
+	reads *int
+}
+
+func (n *readCountingNode) Read(ctx context.Context, f FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	n.mu.Lock()
+	*n.reads++
+	n.mu.Unlock()
+	return f.(FileReader).Read(ctx, dest, off)
+}
+
+func TestReadonlyRootGraft(t *testing.T) {
+	root := &Inode{}
+	testMount(t, root, nil)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("grafting a wrapper into the tree it wraps did not panic")
+		}
+	}()
+	ro := NewReadonlyRoot(root)
+	root.AddChild("ro", root.NewPersistentInode(context.Background(), ro, StableAttr{Mode: syscall.S_IFDIR}), false)
+}
+
+func TestReadonlyRootAutomaticIno(t *testing.T) {
+	ctx := context.Background()
+	inner := &Inode{}
+	ro := NewReadonlyRoot(inner)
+	inner.AddChild("inner", inner.NewPersistentInode(ctx, &MemRegularFile{Data: []byte("inner")}, StableAttr{}), false)
+
+	// Both files get automatic inode numbers, in different
+	// bridges.
+	root := &Inode{}
+	mnt, _ := testMount(t, root, &Options{
+		OnAdd: func(ctx context.Context) {
+			root.AddChild("outer", root.NewPersistentInode(ctx, &MemRegularFile{Data: []byte("outer")}, StableAttr{}), false)
+			root.AddChild("ro", root.NewPersistentInode(ctx, ro, StableAttr{Mode: syscall.S_IFDIR}), false)
+		},
+	})
+
+	for name, want := range map[string]string{"outer": "outer", "ro/inner": "inner"} {
+		got, err := os.ReadFile(filepath.Join(mnt, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
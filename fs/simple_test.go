@@ -48,6 +48,7 @@ func (tc *testCase) writeOrig(path, content string, mode os.FileMode) {
 }
 
 func (tc *testCase) clean() {
+	waitReleased(tc.rawFS.(*rawBridge))
 	if err := tc.server.Unmount(); err != nil {
 		tc.Fatal(err)
 	}
@@ -0,0 +1,63 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// NewSubtreeRoot returns a root for the directory at the
+// slash-separated path dir in the tree at root, like
+// pathfs.NewPrefixFileSystem. The directory is looked up once, and
+// stays the root when it is renamed. Inode numbers are those of the
+// original tree.
+//
+// Root must not be mounted or be part of another tree; see "Wrapping
+// trees" in the package documentation.
+func NewSubtreeRoot(root InodeEmbedder, dir string) (InodeEmbedder, error) {
+	t := newWrapTree(root, func() wrapper { return &wrapNode{} })
+	b := t.bridge
+
+	ctx := context.Background()
+	n := root.embed()
+	// We keep the lookups on the directories on the way down, so
+	// they stay in the tree while they are ancestors of the
+	// subtree.
+	var lookups []*Inode
+	fail := func(errno syscall.Errno) (InodeEmbedder, error) {
+		for i := len(lookups) - 1; i >= 0; i-- {
+			lookups[i].removeRef(1, false)
+		}
+		return nil, errno
+	}
+	for _, name := range strings.Split(path.Clean(dir), "/") {
+		if name == "." || name == "" {
+			continue
+		}
+		if name == ".." {
+			return fail(syscall.EINVAL)
+		}
+		if !n.IsDir() {
+			return fail(syscall.ENOTDIR)
+		}
+
+		var out fuse.EntryOut
+		child, errno := b.lookup(ctx, n, name, &out)
+		if errno != 0 {
+			return fail(errno)
+		}
+		n, _ = b.addNewChild(n, name, child, nil, 0, &out)
+		lookups = append(lookups, n)
+	}
+	if !n.IsDir() {
+		return fail(syscall.ENOTDIR)
+	}
+	return t.wrapRoot(n), nil
+}
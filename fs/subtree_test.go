@@ -0,0 +1,114 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/posixtest"
+)
+
+func TestSubtreeRoot(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(orig, "a/b/c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(orig, "a/b/file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(orig, "top"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(orig, "a/b/file"), filepath.Join(orig, "a/b/c/link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"top", "missing", "../a", "a/b/file/x"} {
+		loopback, err := NewLoopbackRoot(orig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewSubtreeRoot(loopback, dir); err == nil {
+			t.Errorf("NewSubtreeRoot(%q) succeeded", dir)
+		}
+	}
+
+	loopback, err := NewLoopbackRoot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewSubtreeRoot(loopback, "/a/b/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ancestors of the subtree keep a lookup.
+	a := loopback.EmbeddedInode().GetChild("a")
+	if a == nil {
+		t.Fatal("a is not in the tree")
+	}
+	a.mu.Lock()
+	lookups := a.lookupCount
+	a.mu.Unlock()
+	if lookups == 0 {
+		t.Errorf("a has no lookups")
+	}
+	mnt, _ := testMount(t, root, nil)
+
+	if got, err := os.ReadFile(filepath.Join(mnt, "file")); err != nil || string(got) != "hello" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+	entries, err := os.ReadDir(mnt)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ReadDir: got %v, %v", entries, err)
+	}
+
+	// Loopback uses the inode numbers of the underlying files.
+	var st1, st2, want syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(mnt, "file"), &st1); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(filepath.Join(mnt, "c/link"), &st2); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(filepath.Join(orig, "a/b/file"), &want); err != nil {
+		t.Fatal(err)
+	}
+	if st1.Ino != want.Ino || st2.Ino != want.Ino {
+		t.Errorf("got inodes %d, %d, want %d", st1.Ino, st2.Ino, want.Ino)
+	}
+
+	if err := os.WriteFile(filepath.Join(mnt, "c/new"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(orig, "a/b/c/new")); err != nil || string(got) != "new" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+}
+
+func TestSubtreeRootPosix(t *testing.T) {
+	for nm, fn := range posixtest.All {
+		t.Run(nm, func(t *testing.T) {
+			orig := t.TempDir()
+			if err := os.Mkdir(filepath.Join(orig, "sub"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			loopback, err := NewLoopbackRoot(orig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := NewSubtreeRoot(loopback, "sub")
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &Options{}
+			opts.EnableLocks = true
+			mnt, _ := testMount(t, root, opts)
+			fn(t, mnt)
+		})
+	}
+}
@@ -0,0 +1,607 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// wrapTree is the state shared by the nodes of a wrapper around
+// another tree. The wrapped tree is served by its own rawBridge,
+// which does the bookkeeping (lookup counts, children) as if the
+// kernel talked to it directly.
+type wrapTree struct {
+	// bridge serves the wrapped tree.
+	bridge *rawBridge
+
+	// root is the root of the wrapper.
+	root wrapper
+
+	// newNode returns an uninitialized node of the wrapper.
+	newNode func() wrapper
+}
+
+// wrapper is implemented by the nodes of wrappers, by embedding
+// wrapNode.
+type wrapper interface {
+	InodeEmbedder
+	wrap() *wrapNode
+}
+
+// wrapNode forwards all operations to a node of another tree. The
+// wrappers in this package embed it, and override the operations
+// they change.
+//
+// A wrapNode uses the StableAttr of the node it wraps, so inode
+// numbers and hard links are preserved. Each wrapNode holds a lookup
+// on the wrapped node, which is released when it is forgotten.
+type wrapNode struct {
+	Inode
+
+	tree  *wrapTree
+	inner *Inode
+}
+
+var _ = (NodeStatfser)((*wrapNode)(nil))
+var _ = (NodeAccesser)((*wrapNode)(nil))
+var _ = (NodeGetattrer)((*wrapNode)(nil))
+var _ = (NodeSetattrer)((*wrapNode)(nil))
+var _ = (NodeStatxer)((*wrapNode)(nil))
+var _ = (NodeGetxattrer)((*wrapNode)(nil))
+var _ = (NodeSetxattrer)((*wrapNode)(nil))
+var _ = (NodeRemovexattrer)((*wrapNode)(nil))
+var _ = (NodeListxattrer)((*wrapNode)(nil))
+var _ = (NodeReadlinker)((*wrapNode)(nil))
+var _ = (NodeOpener)((*wrapNode)(nil))
+var _ = (NodeReader)((*wrapNode)(nil))
+var _ = (NodeWriter)((*wrapNode)(nil))
+var _ = (NodeFsyncer)((*wrapNode)(nil))
+var _ = (NodeFlusher)((*wrapNode)(nil))
+var _ = (NodeReleaser)((*wrapNode)(nil))
+var _ = (NodeAllocater)((*wrapNode)(nil))
+var _ = (NodeCopyFileRanger)((*wrapNode)(nil))
+var _ = (NodeLseeker)((*wrapNode)(nil))
+var _ = (NodeGetlker)((*wrapNode)(nil))
+var _ = (NodeSetlker)((*wrapNode)(nil))
+var _ = (NodeSetlkwer)((*wrapNode)(nil))
+var _ = (NodeLockReleaser)((*wrapNode)(nil))
+var _ = (NodeOnForgetter)((*wrapNode)(nil))
+var _ = (NodeDiscarder)((*wrapNode)(nil))
+var _ = (NodeLookuper)((*wrapNode)(nil))
+var _ = (NodeOpendirHandler)((*wrapNode)(nil))
+var _ = (NodeMkdirer)((*wrapNode)(nil))
+var _ = (NodeMknoder)((*wrapNode)(nil))
+var _ = (NodeLinker)((*wrapNode)(nil))
+var _ = (NodeSymlinker)((*wrapNode)(nil))
+var _ = (NodeCreater)((*wrapNode)(nil))
+var _ = (NodeUnlinker)((*wrapNode)(nil))
+var _ = (NodeRmdirer)((*wrapNode)(nil))
+var _ = (NodeRenamer)((*wrapNode)(nil))
+
+// newWrapTree returns a wrapper around the tree at root, which is
+// served by a new rawBridge, whose notifications are forwarded to
+// the wrapper. The caller must set the root of the wrapper with
+// wrapRoot.
+//
+// It panics if root is already part of a tree: the nodes of the
+// wrapper use the StableAttrs of the wrapped nodes, so if the wrapper
+// were grafted into the tree it wraps, the bridge would return the
+// wrapped nodes instead of the wrapper nodes.
+func newWrapTree(root InodeEmbedder, newNode func() wrapper) *wrapTree {
+	if root.embed().bridge != nil {
+		log.Panicf("cannot wrap %T: it is already part of a tree", root)
+	}
+	t := &wrapTree{newNode: newNode}
+	t.bridge = NewNodeFS(root, &Options{
+		ServerCallbacks:   t,
+		FirstAutomaticIno: 1<<62 + wrapTrees.Add(1)<<32,
+	}).(*rawBridge)
+	return t
+}
+
+// wrapTrees counts the wrapped trees, so each can hand out automatic
+// inode numbers from a range of its own, below the default range of
+// 1<<63. Otherwise, the inode numbers of the wrapped nodes, which
+// the wrapper nodes use, could clash with those of the tree the
+// wrapper is added to, or of another wrapper in it.
+var wrapTrees atomic.Uint64
+
+// wrapRoot returns the root of the wrapper, which wraps inner. The
+// caller must keep inner alive, for example by holding a lookup.
+func (t *wrapTree) wrapRoot(inner *Inode) wrapper {
+	t.root = t.node(inner)
+	return t.root
+}
+
+// node returns a wrapper node for the wrapped node inner.
+func (t *wrapTree) node(inner *Inode) wrapper {
+	ops := t.newNode()
+	n := ops.wrap()
+	n.tree = t
+	n.inner = inner
+	return ops
+}
+
+func (n *wrapNode) wrap() *wrapNode {
+	return n
+}
+
+// innerOps returns the operations of the wrapped node.
+func (n *wrapNode) innerOps() InodeEmbedder {
+	return n.inner.ops
+}
+
+// unwrap returns the wrapped node for a node of the same wrapper.
+func (n *wrapNode) unwrap(ops InodeEmbedder) (*Inode, bool) {
+	w, ok := ops.(wrapper)
+	if !ok || w.wrap().tree != n.tree {
+		return nil, false
+	}
+	return w.wrap().inner, true
+}
+
+// newChild adds child under name to the wrapped tree, and returns a
+// wrapper node for it.
+func (n *wrapNode) newChild(ctx context.Context, name string, child *Inode, flags uint32, out *fuse.EntryOut) *Inode {
+	b := n.tree.bridge
+	child, _ = b.addNewChild(n.inner, name, child, nil, flags, out)
+	child.setEntryOut(out)
+	b.setEntryOutTimeout(out)
+	return n.NewInode(ctx, n.tree.node(child), child.stableAttr)
+}
+
+// relPath returns the slash-separated path of n relative to the
+// root of the wrapper.
+func (n *wrapNode) relPath() string {
+	return n.Path(n.tree.root.embed())
+}
+
+// childPath returns the path of the child name of n, relative to the
+// root of the wrapper.
+func (n *wrapNode) childPath(name string) string {
+	if p := n.relPath(); p != "" {
+		return p + "/" + name
+	}
+	return name
+}
+
+// hiddenFile hides a file handle of the wrapped tree from the
+// bridge, so it is not used for passthrough. Wrappers that must see
+// all reads and writes return it from Open and Create; wrapNode
+// unwraps it.
+type hiddenFile struct {
+	inner FileHandle
+}
+
+// hideFile returns fh as a hiddenFile.
+func hideFile(fh FileHandle) FileHandle {
+	if fh == nil {
+		return nil
+	}
+	return &hiddenFile{inner: fh}
+}
+
+// innerFile returns the file handle of the wrapped tree.
+func innerFile(f FileHandle) FileHandle {
+	if hf, ok := f.(*hiddenFile); ok {
+		return hf.inner
+	}
+	return f
+}
+
+// release drops the lookup on the wrapped node.
+func (n *wrapNode) release() {
+	if n == n.tree.root.wrap() {
+		return
+	}
+	n.inner.removeRef(1, false)
+}
+
+func (n *wrapNode) OnForget() {
+	n.release()
+}
+
+func (n *wrapNode) Discard() {
+	n.release()
+}
+
+func (n *wrapNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	if sf, ok := n.innerOps().(NodeStatfser); ok {
+		return sf.Statfs(ctx, out)
+	}
+	return OK
+}
+
+func (n *wrapNode) Access(ctx context.Context, mask uint32) syscall.Errno {
+	caller, ok := fuse.FromContext(ctx)
+	if !ok {
+		caller = &fuse.Caller{}
+	}
+	return n.tree.bridge.access(ctx, n.inner, caller, mask)
+}
+
+func (n *wrapNode) Getattr(ctx context.Context, f FileHandle, out *fuse.AttrOut) syscall.Errno {
+	f = innerFile(f)
+	return n.tree.bridge.getattr(ctx, n.inner, f, out)
+}
+
+func (n *wrapNode) Setattr(ctx context.Context, f FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	f = innerFile(f)
+	if sa, ok := n.innerOps().(NodeSetattrer); ok {
+		return sa.Setattr(ctx, f, in, out)
+	}
+	if sa, ok := f.(FileSetattrer); ok {
+		return sa.Setattr(ctx, in, out)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *wrapNode) Statx(ctx context.Context, f FileHandle, flags uint32, mask uint32, out *fuse.StatxOut) syscall.Errno {
+	f = innerFile(f)
+	if sx, ok := n.innerOps().(NodeStatxer); ok {
+		return sx.Statx(ctx, f, flags, mask, out)
+	}
+	if sx, ok := f.(FileStatxer); ok {
+		return sx.Statx(ctx, flags, mask, out)
+	}
+	return syscall.ENOSYS
+}
+
+func (n *wrapNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	if xa, ok := n.innerOps().(NodeGetxattrer); ok {
+		return xa.Getxattr(ctx, attr, dest)
+	}
+	return 0, ENOATTR
+}
+
+func (n *wrapNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	if xa, ok := n.innerOps().(NodeSetxattrer); ok {
+		return xa.Setxattr(ctx, attr, data, flags)
+	}
+	return ENOATTR
+}
+
+func (n *wrapNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	if xa, ok := n.innerOps().(NodeRemovexattrer); ok {
+		return xa.Removexattr(ctx, attr)
+	}
+	return ENOATTR
+}
+
+func (n *wrapNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	if xa, ok := n.innerOps().(NodeListxattrer); ok {
+		return xa.Listxattr(ctx, dest)
+	}
+	return 0, OK
+}
+
+func (n *wrapNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	if rl, ok := n.innerOps().(NodeReadlinker); ok {
+		return rl.Readlink(ctx)
+	}
+	return nil, syscall.ENOTSUP
+}
+
+func (n *wrapNode) Open(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	if op, ok := n.innerOps().(NodeOpener); ok {
+		return op.Open(ctx, flags)
+	}
+	return nil, 0, syscall.ENOTSUP
+}
+
+func (n *wrapNode) Read(ctx context.Context, f FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	f = innerFile(f)
+	if r, ok := n.innerOps().(NodeReader); ok {
+		return r.Read(ctx, f, dest, off)
+	}
+	if r, ok := f.(FileReader); ok {
+		return r.Read(ctx, dest, off)
+	}
+	return nil, syscall.ENOTSUP
+}
+
+func (n *wrapNode) Write(ctx context.Context, f FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	f = innerFile(f)
+	if w, ok := n.innerOps().(NodeWriter); ok {
+		return w.Write(ctx, f, data, off)
+	}
+	if w, ok := f.(FileWriter); ok {
+		return w.Write(ctx, data, off)
+	}
+	return 0, syscall.ENOTSUP
+}
+
+func (n *wrapNode) spliceWrite(ctx context.Context, f FileHandle, data *fuse.WritePipe, off int64) (uint32, syscall.Errno, bool) {
+	return spliceWrite(ctx, n.innerOps(), innerFile(f), data, off)
+}
+
+func (n *wrapNode) Fsync(ctx context.Context, f FileHandle, flags uint32) syscall.Errno {
+	f = innerFile(f)
+	if fs, ok := n.innerOps().(NodeFsyncer); ok {
+		return fs.Fsync(ctx, f, flags)
+	}
+	if fs, ok := f.(FileFsyncer); ok {
+		return fs.Fsync(ctx, flags)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *wrapNode) Flush(ctx context.Context, f FileHandle) syscall.Errno {
+	f = innerFile(f)
+	if fl, ok := n.innerOps().(NodeFlusher); ok {
+		return fl.Flush(ctx, f)
+	}
+	if fl, ok := f.(FileFlusher); ok {
+		return fl.Flush(ctx)
+	}
+	return OK
+}
+
+func (n *wrapNode) Release(ctx context.Context, f FileHandle) syscall.Errno {
+	f = innerFile(f)
+	if r, ok := n.innerOps().(NodeReleaser); ok {
+		return r.Release(ctx, f)
+	}
+	if r, ok := f.(FileReleaser); ok {
+		return r.Release(ctx)
+	}
+	return OK
+}
+
+func (n *wrapNode) Allocate(ctx context.Context, f FileHandle, off uint64, size uint64, mode uint32) syscall.Errno {
+	f = innerFile(f)
+	if a, ok := n.innerOps().(NodeAllocater); ok {
+		return a.Allocate(ctx, f, off, size, mode)
+	}
+	if a, ok := f.(FileAllocater); ok {
+		return a.Allocate(ctx, off, size, mode)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *wrapNode) CopyFileRange(ctx context.Context, fhIn FileHandle, offIn uint64, out *Inode, fhOut FileHandle, offOut uint64, len uint64, flags uint64) (uint32, syscall.Errno) {
+	fhIn, fhOut = innerFile(fhIn), innerFile(fhOut)
+	cfr, ok := n.innerOps().(NodeCopyFileRanger)
+	if !ok {
+		return 0, syscall.ENOTSUP
+	}
+	innerOut, ok := n.unwrap(out.ops)
+	if !ok {
+		return 0, syscall.EXDEV
+	}
+	return cfr.CopyFileRange(ctx, fhIn, offIn, innerOut, fhOut, offOut, len, flags)
+}
+
+func (n *wrapNode) Lseek(ctx context.Context, f FileHandle, off uint64, whence uint32) (uint64, syscall.Errno) {
+	f = innerFile(f)
+	return n.tree.bridge.lseek(ctx, n.inner, f, off, whence)
+}
+
+func (n *wrapNode) Getlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) syscall.Errno {
+	f = innerFile(f)
+	if l, ok := n.innerOps().(NodeGetlker); ok {
+		return l.Getlk(ctx, f, owner, lk, flags, out)
+	}
+	if l, ok := f.(FileGetlker); ok {
+		return l.Getlk(ctx, owner, lk, flags, out)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *wrapNode) Setlk(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	f = innerFile(f)
+	if l, ok := n.innerOps().(NodeSetlker); ok {
+		return l.Setlk(ctx, f, owner, lk, flags)
+	}
+	if l, ok := f.(FileSetlker); ok {
+		return l.Setlk(ctx, owner, lk, flags)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *wrapNode) Setlkw(ctx context.Context, f FileHandle, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	f = innerFile(f)
+	if l, ok := n.innerOps().(NodeSetlkwer); ok {
+		return l.Setlkw(ctx, f, owner, lk, flags)
+	}
+	if l, ok := f.(FileSetlkwer); ok {
+		return l.Setlkw(ctx, owner, lk, flags)
+	}
+	return syscall.ENOTSUP
+}
+
+func (n *wrapNode) ReleaseLocks(ctx context.Context, owner uint64) {
+	if lr, ok := n.innerOps().(NodeLockReleaser); ok {
+		lr.ReleaseLocks(ctx, owner)
+	}
+}
+
+func (n *wrapNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	child, errno := n.tree.bridge.lookup(ctx, n.inner, name, out)
+	if errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, name, child, 0, out), 0
+}
+
+func (n *wrapNode) OpendirHandle(ctx context.Context, flags uint32) (FileHandle, uint32, syscall.Errno) {
+	return n.tree.bridge.opendir(ctx, n.inner, flags)
+}
+
+func (n *wrapNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	mk, ok := n.innerOps().(NodeMkdirer)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	child, errno := mk.Mkdir(ctx, name, mode, out)
+	if errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, name, child, syscall.O_EXCL, out), 0
+}
+
+func (n *wrapNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	mk, ok := n.innerOps().(NodeMknoder)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	child, errno := mk.Mknod(ctx, name, mode, dev, out)
+	if errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, name, child, syscall.O_EXCL, out), 0
+}
+
+func (n *wrapNode) Link(ctx context.Context, target InodeEmbedder, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	l, ok := n.innerOps().(NodeLinker)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	innerTarget, ok := n.unwrap(target)
+	if !ok {
+		return nil, syscall.EXDEV
+	}
+	child, errno := l.Link(ctx, innerTarget.ops, name, out)
+	if errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, name, child, 0, out), 0
+}
+
+func (n *wrapNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*Inode, syscall.Errno) {
+	s, ok := n.innerOps().(NodeSymlinker)
+	if !ok {
+		return nil, syscall.ENOTSUP
+	}
+	child, errno := s.Symlink(ctx, target, name, out)
+	if errno != 0 {
+		return nil, errno
+	}
+	return n.newChild(ctx, name, child, syscall.O_EXCL, out), 0
+}
+
+func (n *wrapNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*Inode, FileHandle, uint32, syscall.Errno) {
+	c, ok := n.innerOps().(NodeCreater)
+	if !ok {
+		return nil, nil, 0, syscall.EROFS
+	}
+	child, f, fuseFlags, errno := c.Create(ctx, name, flags, mode, out)
+	if errno != 0 {
+		return nil, nil, 0, errno
+	}
+	return n.newChild(ctx, name, child, flags|syscall.O_CREAT|syscall.O_EXCL, out), f, fuseFlags, 0
+}
+
+func (n *wrapNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	var errno syscall.Errno
+	if u, ok := n.innerOps().(NodeUnlinker); ok {
+		errno = u.Unlink(ctx, name)
+	}
+	if errno == 0 {
+		n.inner.RmChild(name)
+	}
+	return errno
+}
+
+func (n *wrapNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	var errno syscall.Errno
+	if r, ok := n.innerOps().(NodeRmdirer); ok {
+		errno = r.Rmdir(ctx, name)
+	}
+	if errno == 0 {
+		n.inner.RmChild(name)
+	}
+	return errno
+}
+
+func (n *wrapNode) Rename(ctx context.Context, name string, newParent InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	r, ok := n.innerOps().(NodeRenamer)
+	if !ok {
+		return syscall.ENOTSUP
+	}
+	innerParent, ok := n.unwrap(newParent)
+	if !ok {
+		return syscall.EXDEV
+	}
+	errno := r.Rename(ctx, name, innerParent.ops, newName, flags)
+	if errno == 0 {
+		if flags&RENAME_EXCHANGE != 0 {
+			n.inner.ExchangeChild(name, innerParent, newName)
+		} else {
+			n.inner.MvChild(name, innerParent, newName, true)
+		}
+	}
+	return errno
+}
+
+// outerNode returns the wrapper node for a node ID of the wrapped
+// tree, or nil if the wrapper has no node for it.
+func (t *wrapTree) outerNode(id uint64) *Inode {
+	inner, _ := t.bridge.kernelNodeIds.Load(id)
+	b := t.root.embed().bridge
+	if inner == nil || b == nil {
+		return nil
+	}
+	if inner == t.root.wrap().inner {
+		return t.root.embed()
+	}
+	outer, _ := b.stableAttrs.Load(inner.stableAttr)
+	if outer == nil {
+		return nil
+	}
+	if w, ok := outer.ops.(wrapper); !ok || w.wrap().inner != inner {
+		return nil
+	}
+	return outer
+}
+
+// The ServerCallbacks methods forward the notifications of the
+// wrapped tree to the wrapper nodes.
+
+func (t *wrapTree) DeleteNotify(parent uint64, child uint64, name string) fuse.Status {
+	p, ch := t.outerNode(parent), t.outerNode(child)
+	if p == nil || ch == nil {
+		return fuse.ENOENT
+	}
+	return fuse.Status(p.NotifyDelete(name, ch))
+}
+
+func (t *wrapTree) EntryNotify(parent uint64, name string) fuse.Status {
+	p := t.outerNode(parent)
+	if p == nil {
+		return fuse.ENOENT
+	}
+	return fuse.Status(p.NotifyEntry(name))
+}
+
+func (t *wrapTree) InodeNotify(node uint64, off int64, length int64) fuse.Status {
+	n := t.outerNode(node)
+	if n == nil {
+		return fuse.ENOENT
+	}
+	return fuse.Status(n.NotifyContent(off, length))
+}
+
+func (t *wrapTree) InodeRetrieveCache(node uint64, offset int64, dest []byte) (int, fuse.Status) {
+	n := t.outerNode(node)
+	if n == nil {
+		return 0, fuse.ENOENT
+	}
+	sz, errno := n.ReadCache(offset, dest)
+	return sz, fuse.Status(errno)
+}
+
+func (t *wrapTree) InodeNotifyStoreCache(node uint64, offset int64, data []byte) fuse.Status {
+	n := t.outerNode(node)
+	if n == nil {
+		return fuse.ENOENT
+	}
+	return fuse.Status(n.WriteCache(offset, data))
+}
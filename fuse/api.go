@@ -93,8 +93,14 @@
 // setuid-root helper to call `mount(2)` for us. This is the default.
 // Does not need root permissions but needs `fusermount` installed.
 //
-// 2) If `MountOptions.DirectMount` is set, go-fuse calls `mount(2)` itself.
-// Needs root permissions, but works without `fusermount`.
+// 2) If `MountOptions.DirectMount` is set, go-fuse mounts the file system
+// itself. Needs root permissions, but works without `fusermount`. If the
+// kernel supports it, this uses the new mount API (`fsopen(2)`,
+// `fsconfig(2)`, `fsmount(2)` and `move_mount(2)`), which reports why a
+// mount fails in a MountError, can create detached mounts
+// (`MountOptions.DetachedMount`), and can mount into another mount
+// namespace (`MountOptions.MountNamespaceFd`). Otherwise, go-fuse calls
+// `mount(2)`.
 //
 // 3) If `mountPoint` has the magic `/dev/fd/N` syntax, it means that that a
 // privileged parent process:
@@ -121,6 +127,12 @@
 //
 // [2] https://sylabs.io/guides/3.7/user-guide/bind_paths_and_mounts.html#fuse-mounts
 //
+// Finally, NewTransportServer does not mount anything, but serves the
+// FUSE protocol over a Transport, for example a unix socket wrapped
+// with NewStreamTransport. The other end plays the role of the
+// kernel. The fuse/client package implements that role, so file
+// systems can be tested without mounting.
+//
 // # Aborting a file system
 //
 // A caller that has an open file in a buggy or crashed FUSE
@@ -132,7 +144,11 @@
 // the Dev field in the Stat_t result for a file in the mount.
 package fuse
 
-import "log"
+import (
+	"log"
+	"syscall"
+	"time"
+)
 
 // Types for users to implement.
 
@@ -228,7 +244,8 @@ type MountOptions struct {
 	// Xattr operations at all.
 	DisableXAttrs bool
 
-	// If set, print debugging information.
+	// If set, print debugging information. For structured
+	// output, use NewSlogTracer instead.
 	Debug bool
 
 	// If set, sink for debug statements.
@@ -299,8 +316,9 @@ type MountOptions struct {
 	// for more details.
 	SyncRead bool
 
-	// If set, fuse will first attempt to use syscall.Mount instead of
-	// fusermount to mount the filesystem. This will not update /etc/mtab
+	// If set, fuse will first attempt to mount the filesystem itself,
+	// with the new mount API or syscall.Mount, instead of using
+	// fusermount. This will not update /etc/mtab
 	// but might be needed if fusermount is not available.
 	// Also, Server.Unmount will attempt syscall.Unmount before calling
 	// fusermount.
@@ -311,13 +329,29 @@ type MountOptions struct {
 	// DirectMountStrict wins.
 	DirectMountStrict bool
 
-	// DirectMountFlags are the mountflags passed to syscall.Mount. If zero, the
+	// DirectMountFlags are the mountflags passed to syscall.Mount, or
+	// their equivalents for the new mount API. If zero, the
 	// default value used by fusermount are used: syscall.MS_NOSUID|syscall.MS_NODEV.
 	//
 	// If you actually *want* zero flags, pass syscall.MS_MGC_VAL, which is ignored
 	// by the kernel. See `man 2 mount` for details about MS_MGC_VAL.
 	DirectMountFlags uintptr
 
+	// DetachedMount creates the mount with fsmount(2) without
+	// attaching it anywhere. NewServer must then be called with an
+	// empty mount point. Attach the file system with
+	// Server.AttachMount, or pass Server.DetachedMountFd to
+	// another process to attach it with move_mount(2). Needs
+	// root permissions and Linux 5.2 or later.
+	DetachedMount bool
+
+	// MountNamespaceFd, if non-zero, is a file descriptor for the
+	// mount namespace (eg. /proc/PID/ns/mnt) to mount the file
+	// system in. The mount point is resolved in that namespace,
+	// and fusermount is not used. Needs CAP_SYS_ADMIN in the
+	// namespace. Only supported on Linux.
+	MountNamespaceFd int
+
 	// EnableAcls enables kernel ACL support.
 	//
 	// See the comments to FUSE_CAP_POSIX_ACL
@@ -325,6 +359,13 @@ type MountOptions struct {
 	// for details.
 	EnableAcl bool
 
+	// EnableSecurityContext asks the kernel to send the security
+	// labels (eg. for SELinux) of new inodes with CREATE, MKDIR,
+	// MKNOD, SYMLINK and TMPFILE requests. Retrieve them with
+	// SecurityContextFromContext. Needs protocol version 7.38
+	// (Linux 6.2).
+	EnableSecurityContext bool
+
 	// Disable ReadDirPlus capability so ReadDir is used instead. Simple
 	// directory queries (i.e. 'ls' without '-l') can be faster with
 	// ReadDir, as no per-file stat calls are needed
@@ -333,9 +374,81 @@ type MountOptions struct {
 	// Disable splicing from files to the FUSE device.
 	DisableSplice bool
 
+	// EnableSpliceWrite reads requests from the FUSE device
+	// through a pipe, and leaves the data of large WRITE requests
+	// in the pipe, if the file system implements
+	// RawSpliceWriter. This saves a copy if the data ends up in
+	// a file descriptor. It has no effect if splicing is
+	// disabled or unavailable, or if a Recorder is set.
+	EnableSpliceWrite bool
+
 	// Maximum stacking depth for passthrough files. Defaults to 1.
 	MaxStackDepth int
 
+	// CloneQueues is the number of FUSE device channels to read
+	// requests from. If larger than 1, the mount fd is cloned
+	// (FUSE_DEV_IOC_CLONE) so the kernel distributes requests
+	// over multiple queues, each with its own reader goroutines
+	// and buffers. This reduces contention on many-core
+	// machines. If the kernel does not support cloning, a single
+	// channel is used. Only supported on Linux.
+	CloneQueues int
+
+	// EnableIOUring asks the kernel to deliver requests over
+	// io_uring (FUSE_OVER_IO_URING, Linux 6.14 and later), which
+	// saves a read/write syscall pair per request. The server
+	// registers a queue for each CPU. If the kernel or the fuse
+	// module (parameter enable_uring) does not support it,
+	// requests are read from /dev/fuse as usual.
+	//
+	// The kernel cannot deliver cache retrieve replies over
+	// io_uring, so InodeRetrieveCache returns ENOSYS if io_uring
+	// is in use.
+	EnableIOUring bool
+
+	// IOUringQueueDepth is the number of requests each io_uring
+	// queue can have outstanding. Defaults to 8.
+	IOUringQueueDepth int
+
+	// Tracer, if set, is called at the start and end of every
+	// request. See NewSlogTracer for structured logging.
+	Tracer Tracer
+
+	// Recorder, if set, records all requests, replies and
+	// notifications, for debugging with Replay.
+	Recorder *Recorder
+
+	// RequestTimeout bounds how long the file system may take to
+	// answer a request. When it passes, the request is canceled,
+	// so Done() of its Context fires, and the kernel is answered
+	// with TimeoutErrno, even if the handler has not returned.
+	// The reply of the handler is then dropped. If it hands out
+	// an inode or a file handle, eg. for LOOKUP, CREATE or OPEN,
+	// the server forgets or releases it again, so the file system
+	// sees a FORGET or RELEASE call as if the kernel had sent
+	// one.
+	//
+	// INIT, DESTROY, FORGET, INTERRUPT and SETLKW have no
+	// timeout, unless listed in OpcodeTimeouts. READDIRPLUS has
+	// no timeout. Timeouts cannot be combined with
+	// EnableIOUring.
+	RequestTimeout time.Duration
+
+	// OpcodeTimeouts overrides RequestTimeout for opcodes, keyed
+	// by their name in the debug output, eg. "READ". A zero entry
+	// disables the timeout for that opcode.
+	OpcodeTimeouts map[string]time.Duration
+
+	// TimeoutErrno answers requests that exceed their timeout.
+	// The default is EIO; ETIMEDOUT is more specific, but
+	// surprising for callers of eg. read(2).
+	TimeoutErrno syscall.Errno
+
+	// SlowRequestThreshold, if set, starts a watchdog that logs
+	// the stack trace of the handler of each request that takes
+	// longer than this.
+	SlowRequestThreshold time.Duration
+
 	// Enable ID-mapped mount if the Kernel supports it.
 	// ID-mapped mount allows the device to be mounted on the system
 	// with the IDs remapped (via mount_setattr, move_mount syscalls) to
@@ -91,7 +91,7 @@ func TestBufferPoolRequestHandler(t *testing.T) {
 	// read, so thread scheduling may cause it to happen after we
 	// check.  Unmount to be sure we have finished all the work.
 	srv.Unmount()
-	ctr := srv.buffers.counters()
+	ctr := srv.channels[0].buffers.counters()
 	for i, c := range ctr {
 		if c != 0 {
 			t.Errorf("page count %d: %d buffers outstanding", i, c)
@@ -0,0 +1,373 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package client implements the kernel side of the FUSE protocol,
+// so file systems can be exercised in-process, without mounting and
+// without privileges.
+//
+// The Client encodes requests the way the Linux kernel does, and
+// tracks lookups in a dentry cache, so the server sees the same
+// reference counts as it would on a real mount. Inodes are
+// forgotten when their last directory entry is dropped, which
+// happens when an expired entry is no longer in use, on unlink,
+// rename, cache invalidation, revalidation returning a different
+// node, or a call to DropCaches. Like the kernel on unmount, Close
+// does not send FORGET for the remaining nodes.
+//
+// A typical test looks like
+//
+//	c, _, err := client.Start(fs.NewNodeFS(root, &fs.Options{}), nil, nil)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer c.Close()
+//	data, err := c.ReadFile("dir/file")
+//
+// The client implements an API modeled after the os package, and
+// c.FS() exposes the file system as an io/fs.FS.
+package client
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Options configures a Client.
+type Options struct {
+	// Caller is the identity sent along with each request. If
+	// unset, the credentials of the current process are used.
+	Caller *fuse.Caller
+
+	// Umask is sent with requests that create files, and
+	// applied to their mode.
+	Umask uint32
+
+	// DisableReadDirPlus makes the client use READDIR even if
+	// the server supports READDIRPLUS.
+	DisableReadDirPlus bool
+}
+
+// Client sends FUSE requests to a server, taking the role of the
+// kernel.
+type Client struct {
+	conn   io.ReadWriteCloser
+	srv    *fuse.Server
+	opts   Options
+	caller fuse.Caller
+
+	initOut fuse.InitOut
+	flags   uint64
+
+	writeMu sync.Mutex
+
+	// pendingMu protects the fields below.
+	pendingMu sync.Mutex
+	unique    uint64
+	pending   map[uint64]chan reply
+	err       syscall.Errno
+
+	readerDone chan struct{}
+
+	// mu protects the dentry cache.
+	mu    sync.Mutex
+	root  *node
+	nodes map[uint64]*node
+
+	// FORGETs to send once mu is released.
+	forgets []forgetOne
+}
+
+type reply struct {
+	errno syscall.Errno
+	data  []byte
+}
+
+// New connects to a FUSE server at the other end of conn, and
+// negotiates the protocol with it. The server must be ready to
+// receive INIT, so it is usually started concurrently, eg. with
+// fuse.NewTransportServer. Closing the client closes conn.
+func New(conn io.ReadWriteCloser, opts *Options) (*Client, error) {
+	c := &Client{
+		conn:       conn,
+		pending:    map[uint64]chan reply{},
+		readerDone: make(chan struct{}),
+		nodes:      map[uint64]*node{},
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Caller != nil {
+		c.caller = *c.opts.Caller
+	} else {
+		c.caller = fuse.Caller{
+			Owner: fuse.Owner{
+				Uid: uint32(os.Getuid()),
+				Gid: uint32(os.Getgid()),
+			},
+			Pid: uint32(os.Getpid()),
+		}
+	}
+	c.root = &node{id: rootID, mode: syscall.S_IFDIR, refs: 1, children: map[string]*dentry{}}
+	c.nodes[rootID] = c.root
+
+	go c.readLoop()
+
+	if err := c.init(); err != nil {
+		conn.Close()
+		<-c.readerDone
+		return nil, err
+	}
+	return c, nil
+}
+
+// Start runs a server for fs over an in-memory connection, and
+// returns a client for it. The server is stopped when the client is
+// closed.
+func Start(fs fuse.RawFileSystem, mountOpts *fuse.MountOptions, opts *Options) (*Client, *fuse.Server, error) {
+	kernelSide, serverSide := net.Pipe()
+
+	type result struct {
+		c   *Client
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		c, err := New(kernelSide, opts)
+		ch <- result{c, err}
+	}()
+
+	srv, err := fuse.NewTransportServer(fs, fuse.NewStreamTransport(serverSide), mountOpts)
+	if err != nil {
+		kernelSide.Close()
+		<-ch
+		return nil, nil, err
+	}
+	go srv.Serve()
+
+	r := <-ch
+	if r.err != nil {
+		serverSide.Close()
+		srv.Wait()
+		return nil, nil, r.err
+	}
+	r.c.srv = srv
+	return r.c, srv, nil
+}
+
+func (c *Client) init() error {
+	in := fuse.InitIn{
+		Major:        kernelVersion,
+		Minor:        kernelMinor,
+		MaxReadAhead: 128 * 1024,
+	}
+	flags := uint64(fuse.CAP_ASYNC_READ | fuse.CAP_BIG_WRITES | fuse.CAP_FILE_OPS |
+		fuse.CAP_PARALLEL_DIROPS | fuse.CAP_MAX_PAGES | fuse.CAP_NO_OPEN_SUPPORT |
+		initExt)
+	if !c.opts.DisableReadDirPlus {
+		flags |= fuse.CAP_READDIRPLUS
+	}
+	in.Flags = uint32(flags)
+	in.Flags2 = uint32(flags >> 32)
+
+	data, errno := c.call(opInit, 0, body(&in))
+	if errno != 0 {
+		return &os.SyscallError{Syscall: "init", Err: errno}
+	}
+	out, errno := decode[fuse.InitOut](data)
+	if errno != 0 {
+		return &os.SyscallError{Syscall: "init", Err: errno}
+	}
+	if out.Major != kernelVersion {
+		return &os.SyscallError{Syscall: "init", Err: syscall.EPROTO}
+	}
+	c.initOut = *out
+	c.flags = uint64(out.Flags)
+	if out.Flags&initExt != 0 {
+		c.flags |= uint64(out.Flags2) << 32
+	}
+	return nil
+}
+
+// InitOut returns the server's reply to INIT.
+func (c *Client) InitOut() fuse.InitOut {
+	return c.initOut
+}
+
+// maxWrite returns the maximum payload size for READ and WRITE.
+func (c *Client) maxWrite() int {
+	if c.initOut.MaxWrite < 4096 {
+		return 4096
+	}
+	return int(c.initOut.MaxWrite)
+}
+
+// Close shuts down the connection. If the server was created with
+// Start, Close waits for it to exit.
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	<-c.readerDone
+	if c.srv != nil {
+		c.srv.Wait()
+	}
+	return err
+}
+
+// send writes a request. It returns a channel for the reply, or nil
+// if noReply is set.
+func (c *Client) send(opcode uint32, nodeid uint64, noReply bool, data ...[]byte) (<-chan reply, syscall.Errno) {
+	var hdr fuse.InHeader
+	hdr.Opcode = opcode
+	hdr.NodeId = nodeid
+	hdr.Caller = c.caller
+	hdr.Length = uint32(inHeaderSize)
+	for _, d := range data {
+		hdr.Length += uint32(len(d))
+	}
+
+	var ch chan reply
+	c.pendingMu.Lock()
+	if c.err != 0 {
+		c.pendingMu.Unlock()
+		return nil, c.err
+	}
+	c.unique++
+	hdr.Unique = c.unique
+	if !noReply {
+		ch = make(chan reply, 1)
+		c.pending[hdr.Unique] = ch
+	}
+	c.pendingMu.Unlock()
+
+	bufs := net.Buffers(append([][]byte{asBytes(&hdr)}, data...))
+	c.writeMu.Lock()
+	_, err := bufs.WriteTo(c.conn)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.pendingMu.Lock()
+		delete(c.pending, hdr.Unique)
+		c.pendingMu.Unlock()
+		return nil, syscall.ENOTCONN
+	}
+	return ch, 0
+}
+
+// call sends a request and waits for its reply.
+func (c *Client) call(opcode uint32, nodeid uint64, data ...[]byte) ([]byte, syscall.Errno) {
+	ch, errno := c.send(opcode, nodeid, false, data...)
+	if errno != 0 {
+		return nil, errno
+	}
+	r := <-ch
+	return r.data, r.errno
+}
+
+func (c *Client) readLoop() {
+	defer close(c.readerDone)
+	var errno syscall.Errno
+	for {
+		hdrBuf := make([]byte, outHeaderSize)
+		if _, err := io.ReadFull(c.conn, hdrBuf); err != nil {
+			errno = syscall.ENOTCONN
+			break
+		}
+		hdr := (*fuse.OutHeader)(unsafe.Pointer(&hdrBuf[0]))
+		if int(hdr.Length) < outHeaderSize {
+			errno = syscall.EPROTO
+			break
+		}
+		data := make([]byte, int(hdr.Length)-outHeaderSize)
+		if _, err := io.ReadFull(c.conn, data); err != nil {
+			errno = syscall.ENOTCONN
+			break
+		}
+
+		if hdr.Unique == 0 {
+			// The error field holds the notification code,
+			// which is positive on the wire.
+			c.handleNotify(-hdr.Status, data)
+			continue
+		}
+
+		c.pendingMu.Lock()
+		ch := c.pending[hdr.Unique]
+		delete(c.pending, hdr.Unique)
+		c.pendingMu.Unlock()
+		if ch == nil {
+			errno = syscall.EPROTO
+			break
+		}
+		ch <- reply{errno: syscall.Errno(-hdr.Status), data: data}
+	}
+
+	// Like an aborted connection in the kernel, fail all
+	// outstanding and future requests.
+	c.conn.Close()
+	c.pendingMu.Lock()
+	c.err = errno
+	for k, ch := range c.pending {
+		ch <- reply{errno: errno}
+		delete(c.pending, k)
+	}
+	c.pendingMu.Unlock()
+}
+
+// handleNotify processes a notification from the server. It runs on
+// the reader goroutine, so anything that has to be sent in response
+// goes out on another goroutine.
+func (c *Client) handleNotify(code int32, data []byte) {
+	switch code {
+	case fuse.NOTIFY_INVAL_ENTRY:
+		in, errno := decode[fuse.NotifyInvalEntryOut](data)
+		if errno != 0 {
+			return
+		}
+		name := notifyName(data[unsafe.Sizeof(*in):], in.NameLen)
+		c.invalidateEntry(in.Parent, 0, name)
+	case fuse.NOTIFY_DELETE:
+		in, errno := decode[fuse.NotifyInvalDeleteOut](data)
+		if errno != 0 {
+			return
+		}
+		name := notifyName(data[unsafe.Sizeof(*in):], in.NameLen)
+		c.invalidateEntry(in.Parent, in.Child, name)
+	case fuse.NOTIFY_RETRIEVE_CACHE:
+		in, errno := decode[fuse.NotifyRetrieveOut](data)
+		if errno != 0 {
+			return
+		}
+		// There is no page cache, so we return no data.
+		ret := fuse.NotifyRetrieveIn{
+			Offset: in.Offset,
+		}
+		go c.sendNotifyReply(in.NotifyUnique, in.Nodeid, &ret)
+	case fuse.NOTIFY_INVAL_INODE, fuse.NOTIFY_STORE_CACHE:
+		// We do not cache attributes or data.
+	}
+}
+
+func notifyName(data []byte, l uint32) string {
+	if int(l) > len(data) {
+		l = uint32(len(data))
+	}
+	return string(data[:l])
+}
+
+// sendNotifyReply answers a NOTIFY_RETRIEVE_CACHE. The reply is a
+// request whose unique ID is the one from the notification.
+func (c *Client) sendNotifyReply(unique, nodeid uint64, in *fuse.NotifyRetrieveIn) {
+	in.Opcode = opNotifyReply
+	in.Unique = unique
+	in.NodeId = nodeid
+	in.Caller = c.caller
+	in.Length = uint32(unsafe.Sizeof(*in))
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.Write(asBytes(in))
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/client"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func startClient(t *testing.T, root fs.InodeEmbedder, opts *fs.Options) *client.Client {
+	t.Helper()
+	if opts == nil {
+		opts = &fs.Options{}
+	}
+	opts.Debug = testutil.VerboseTest()
+	c, _, err := client.Start(fs.NewNodeFS(root, opts), &opts.MountOptions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientLoopback(t *testing.T) {
+	dir := t.TempDir()
+	root, err := fs.NewLoopbackRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := startClient(t, root, nil)
+
+	want := []byte("hello")
+	if err := c.WriteFile("file", want, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "file")); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("got %q, %v, want %q", got, err, want)
+	}
+	if err := c.Mkdir("dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := c.Mkdir("dir", 0755); !errors.Is(err, iofs.ErrExist) {
+		t.Fatalf("Mkdir: got %v, want EEXIST", err)
+	}
+	if err := c.Rename("file", "dir/file"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if _, err := c.Stat("file"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("Stat after rename: got %v, want ENOENT", err)
+	}
+	if err := c.Symlink("dir/file", "link"); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+	if target, err := c.Readlink("link"); err != nil || target != "dir/file" {
+		t.Fatalf("Readlink: got %q, %v", target, err)
+	}
+	if fi, err := c.Lstat("link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Lstat: got %v, %v", fi, err)
+	}
+	if got, err := c.ReadFile("link"); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("ReadFile through symlink: got %q, %v", got, err)
+	}
+	if err := c.Link("dir/file", "hardlink"); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if fi, err := c.Stat("hardlink"); err != nil {
+		t.Fatalf("Stat: %v", err)
+	} else if attr := fi.Sys().(*fuse.Attr); attr.Nlink != 2 {
+		t.Errorf("got nlink %d, want 2", attr.Nlink)
+	}
+	if err := c.Chmod("hardlink", 0600); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	if fi, err := c.Stat("dir/file"); err != nil || fi.Mode() != 0600 {
+		t.Fatalf("Stat after chmod: got %v, %v", fi.Mode(), err)
+	}
+
+	f, err := c.OpenFile("dir/file", os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	if _, err := f.Write([]byte(" world")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	buf := make([]byte, 5)
+	if n, err := f.ReadAt(buf, 6); err != nil || string(buf[:n]) != "world" {
+		t.Fatalf("ReadAt: got %q, %v", buf[:n], err)
+	}
+	if err := f.Truncate(5); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries, err := c.ReadDir("")
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got, want := names, []string{"dir", "hardlink", "link"}; !slicesEqual(got, want) {
+		t.Errorf("ReadDir: got %v, want %v", got, want)
+	}
+
+	if err := c.Remove("hardlink"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := c.Remove("link"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := c.Remove("dir"); !errors.Is(err, syscall.ENOTEMPTY) {
+		t.Fatalf("Remove non-empty directory: got %v", err)
+	}
+
+	if err := fstest.TestFS(c.FS(), "dir/file"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func slicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// forgetRoot creates a fresh node for every lookup of "file", and
+// counts how often nodes are forgotten.
+type forgetRoot struct {
+	fs.Inode
+
+	forgets atomic.Int64
+}
+
+var _ = (fs.NodeLookuper)((*forgetRoot)(nil))
+var _ = (fs.NodeReaddirer)((*forgetRoot)(nil))
+
+func (r *forgetRoot) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if name != "file" {
+		return nil, syscall.ENOENT
+	}
+	return r.NewInode(ctx, &forgetNode{root: r}, fs.StableAttr{Mode: syscall.S_IFREG, Ino: 2}), 0
+}
+
+func (r *forgetRoot) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream([]fuse.DirEntry{{Name: "file", Mode: syscall.S_IFREG, Ino: 2}}), 0
+}
+
+type forgetNode struct {
+	fs.Inode
+	root *forgetRoot
+}
+
+var _ = (fs.NodeOpener)((*forgetNode)(nil))
+var _ = (fs.NodeOnForgetter)((*forgetNode)(nil))
+
+func (n *forgetNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return nil, fuse.FOPEN_KEEP_CACHE, 0
+}
+
+func (n *forgetNode) OnForget() {
+	n.root.forgets.Add(1)
+}
+
+// waitForgets waits for the server to process FORGET requests, which
+// have no reply.
+func waitForgets(t *testing.T, root *forgetRoot, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for root.forgets.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := root.forgets.Load(); got != want {
+		t.Fatalf("got %d forgets, want %d", got, want)
+	}
+}
+
+func TestClientForget(t *testing.T) {
+	root := &forgetRoot{}
+	var zero time.Duration
+	c := startClient(t, root, &fs.Options{EntryTimeout: &zero})
+
+	// Expired entries are dropped as soon as they are unused, so
+	// every stat is followed by a FORGET.
+	for i := 0; i < 3; i++ {
+		if _, err := c.Stat("file"); err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+	}
+	waitForgets(t, root, 3)
+
+	// An open file keeps the node alive.
+	f, err := c.Open("file")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	waitForgets(t, root, 3)
+	f.Close()
+	waitForgets(t, root, 4)
+}
+
+func TestClientForgetCached(t *testing.T) {
+	root := &forgetRoot{}
+	timeout := time.Hour
+	c := startClient(t, root, &fs.Options{EntryTimeout: &timeout})
+
+	// Cached entries keep their nodes until they are dropped.
+	for i := 0; i < 3; i++ {
+		if _, err := c.Stat("file"); err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	waitForgets(t, root, 0)
+	c.DropCaches()
+	waitForgets(t, root, 1)
+
+	// READDIRPLUS adds entries to the cache.
+	if _, err := c.ReadDir(""); err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	c.DropCaches()
+	waitForgets(t, root, 2)
+
+	// Entry invalidation from the server drops the entry.
+	if _, err := c.Stat("file"); err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if errno := root.NotifyEntry("file"); errno != 0 {
+		t.Fatalf("NotifyEntry: %v", errno)
+	}
+	waitForgets(t, root, 3)
+}
@@ -0,0 +1,401 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"io"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// File is an open file or directory. It mirrors the methods of
+// os.File, and implements io/fs.File and io/fs.ReadDirFile.
+type File struct {
+	c         *Client
+	name      string
+	n         *node
+	fh        uint64
+	flag      int
+	dir       bool
+	noRelease bool
+
+	mu     sync.Mutex
+	offset int64
+	closed bool
+
+	// Directory reading state.
+	dirOffset  uint64
+	dirEOF     bool
+	dirEntries []os.DirEntry
+}
+
+// Name returns the name the file was opened with.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Fh returns the file handle the server returned on open.
+func (f *File) Fh() uint64 {
+	return f.fh
+}
+
+func (f *File) pathError(op string, errno syscall.Errno) error {
+	return pathError(op, f.name, errno)
+}
+
+// read issues a single READ.
+func (f *File) read(p []byte, off int64) (int, syscall.Errno) {
+	if f.dir {
+		return 0, syscall.EISDIR
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, syscall.EBADF
+	}
+	if len(p) > f.c.maxWrite() {
+		p = p[:f.c.maxWrite()]
+	}
+	in := fuse.ReadIn{
+		Fh:     f.fh,
+		Offset: uint64(off),
+		Size:   uint32(len(p)),
+		Flags:  uint32(f.flag),
+	}
+	data, errno := f.c.call(opRead, f.n.id, body(&in))
+	if errno != 0 {
+		return 0, errno
+	}
+	return copy(p, data), 0
+}
+
+// Read reads from the current offset, issuing at most one READ.
+func (f *File) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, errno := f.read(p, f.offset)
+	if errno != 0 {
+		return 0, f.pathError("read", errno)
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	f.offset += int64(n)
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes at off. It returns io.EOF if the file is
+// shorter.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, f.pathError("readat", syscall.EINVAL)
+	}
+	total := 0
+	for total < len(p) {
+		n, errno := f.read(p[total:], off+int64(total))
+		if errno != 0 {
+			return total, f.pathError("read", errno)
+		}
+		if n == 0 {
+			return total, io.EOF
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// writeAt issues WRITE requests until p is written.
+func (f *File) writeAt(p []byte, off int64) (int, syscall.Errno) {
+	if f.dir {
+		return 0, syscall.EISDIR
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, syscall.EBADF
+	}
+	total := 0
+	for total < len(p) {
+		chunk := p[total:]
+		if len(chunk) > f.c.maxWrite() {
+			chunk = chunk[:f.c.maxWrite()]
+		}
+		in := fuse.WriteIn{
+			Fh:     f.fh,
+			Offset: uint64(off) + uint64(total),
+			Size:   uint32(len(chunk)),
+			Flags:  uint32(f.flag),
+		}
+		data, errno := f.c.call(opWrite, f.n.id, body(&in), chunk)
+		if errno != 0 {
+			return total, errno
+		}
+		out, errno := decode[fuse.WriteOut](data)
+		if errno != 0 {
+			return total, errno
+		}
+		total += int(out.Size)
+		if int(out.Size) < len(chunk) {
+			break
+		}
+	}
+	return total, 0
+}
+
+// Write writes at the current offset, or at the end of the file if
+// it was opened with os.O_APPEND.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.flag&os.O_APPEND != 0 {
+		attr, errno := f.c.getattr(f.n, f.fh, true)
+		if errno != 0 {
+			return 0, f.pathError("write", errno)
+		}
+		f.offset = int64(attr.Size)
+	}
+	n, errno := f.writeAt(p, f.offset)
+	f.offset += int64(n)
+	if errno != 0 {
+		return n, f.pathError("write", errno)
+	}
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// WriteAt writes p at offset off.
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	if f.flag&os.O_APPEND != 0 {
+		return 0, f.pathError("writeat", syscall.EINVAL)
+	}
+	n, errno := f.writeAt(p, off)
+	if errno != 0 {
+		return n, f.pathError("write", errno)
+	}
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Seek sets the offset for the next Read or Write. For directories,
+// only seeking to the start is supported.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.dir {
+		if offset != 0 || whence != io.SeekStart {
+			return 0, f.pathError("seek", syscall.EINVAL)
+		}
+		f.dirOffset = 0
+		f.dirEOF = false
+		f.dirEntries = nil
+		return 0, nil
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		attr, errno := f.c.getattr(f.n, f.fh, true)
+		if errno != 0 {
+			return 0, f.pathError("seek", errno)
+		}
+		offset += int64(attr.Size)
+	default:
+		return 0, f.pathError("seek", syscall.EINVAL)
+	}
+	if offset < 0 {
+		return 0, f.pathError("seek", syscall.EINVAL)
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Stat returns the attributes of the open file.
+func (f *File) Stat() (os.FileInfo, error) {
+	attr, errno := f.c.getattr(f.n, f.fh, !f.dir)
+	if errno != 0 {
+		return nil, f.pathError("stat", errno)
+	}
+	return &fileInfo{name: baseName(f.name), attr: *attr}, nil
+}
+
+func (f *File) truncate(size int64) syscall.Errno {
+	in := fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_SIZE | fuse.FATTR_FH
+	in.Size = uint64(size)
+	in.Fh = f.fh
+	_, errno := f.c.setattr(f.n, &in)
+	return errno
+}
+
+// Truncate changes the size of the file.
+func (f *File) Truncate(size int64) error {
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return f.pathError("truncate", syscall.EINVAL)
+	}
+	return f.pathError("truncate", f.truncate(size))
+}
+
+// Sync sends FSYNC for the file.
+func (f *File) Sync() error {
+	in := fuse.FsyncIn{Fh: f.fh}
+	_, errno := f.c.call(opFsync, f.n.id, body(&in))
+	return f.pathError("sync", errno)
+}
+
+// fillDir reads the next batch of directory entries. With
+// READDIRPLUS, the entries are added to the dentry cache, like the
+// kernel does.
+func (f *File) fillDir() syscall.Errno {
+	op := opReaddir
+	plus := f.c.flags&fuse.CAP_READDIRPLUS != 0 && !f.c.opts.DisableReadDirPlus
+	if plus {
+		op = opReaddirplus
+	}
+	in := fuse.ReadIn{
+		Fh:     f.fh,
+		Offset: f.dirOffset,
+		Size:   4096,
+	}
+	data, errno := f.c.call(op, f.n.id, body(&in))
+	if errno != 0 {
+		return errno
+	}
+	entries, errno := parseDirents(data, plus)
+	if errno != 0 {
+		return errno
+	}
+	if len(entries) == 0 {
+		f.dirEOF = true
+		return 0
+	}
+
+	f.c.mu.Lock()
+	for _, e := range entries {
+		f.dirOffset = e.off
+		if e.name == "." || e.name == ".." {
+			continue
+		}
+		de := &dirEntry{
+			dir:  f,
+			name: e.name,
+			mode: e.typ << 12,
+		}
+		if e.entry != nil && e.entry.NodeId != 0 {
+			if ch := f.c.addEntry(f.n, e.name, e.entry); ch != nil {
+				f.c.dropExpired(ch)
+			}
+			de.attr = &e.entry.Attr
+		}
+		f.dirEntries = append(f.dirEntries, de)
+	}
+	f.c.unlock()
+	return 0
+}
+
+// ReadDir reads directory entries, skipping "." and "..". If n > 0,
+// it returns at most n entries, and io.EOF at the end of the
+// directory. If n <= 0, it returns all remaining entries.
+func (f *File) ReadDir(n int) ([]os.DirEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	if !f.dir {
+		return nil, f.pathError("readdirent", syscall.ENOTDIR)
+	}
+	for !f.dirEOF && (n <= 0 || len(f.dirEntries) < n) {
+		if errno := f.fillDir(); errno != 0 {
+			return nil, f.pathError("readdirent", errno)
+		}
+	}
+
+	result := f.dirEntries
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	f.dirEntries = f.dirEntries[len(result):]
+	if n > 0 && len(result) == 0 {
+		return nil, io.EOF
+	}
+	if result == nil {
+		result = []os.DirEntry{}
+	}
+	return result, nil
+}
+
+// release sends the requests for closing the file.
+func (f *File) release() syscall.Errno {
+	var errno syscall.Errno
+	if f.noRelease {
+		return 0
+	}
+	if f.dir {
+		in := fuse.ReleaseIn{Fh: f.fh, Flags: openFlags(f.flag)}
+		_, errno = f.c.call(opReleasedir, f.n.id, body(&in))
+		return errno
+	}
+
+	flush := fuse.FlushIn{Fh: f.fh}
+	_, errno = f.c.call(opFlush, f.n.id, body(&flush))
+	if errno == syscall.ENOSYS {
+		errno = 0
+	}
+	in := fuse.ReleaseIn{Fh: f.fh, Flags: openFlags(f.flag)}
+	f.c.call(opRelease, f.n.id, body(&in))
+	return errno
+}
+
+// Close releases the file. It returns the result of FLUSH, like
+// close(2) does.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	errno := f.release()
+	f.c.put(f.n)
+	return f.pathError("close", errno)
+}
+
+// dirEntry implements os.DirEntry.
+type dirEntry struct {
+	dir  *File
+	name string
+	mode uint32
+
+	// attr is set if the entry came from READDIRPLUS.
+	attr *fuse.Attr
+}
+
+func (e *dirEntry) Name() string      { return e.name }
+func (e *dirEntry) IsDir() bool       { return e.Type().IsDir() }
+func (e *dirEntry) Type() os.FileMode { return fileMode(e.mode).Type() }
+func (e *dirEntry) String() string    { return e.name }
+func (e *dirEntry) Info() (os.FileInfo, error) {
+	if e.attr != nil {
+		return &fileInfo{name: e.name, attr: *e.attr}, nil
+	}
+	return e.dir.c.Lstat(path.Join(e.dir.name, e.name))
+}
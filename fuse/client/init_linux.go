@@ -0,0 +1,10 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import "github.com/hanwen/go-fuse/v2/fuse"
+
+// initExt marks the second word of INIT flags.
+const initExt = fuse.CAP_INIT_EXT
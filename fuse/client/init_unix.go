@@ -0,0 +1,11 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+// initExt is 0, because only Linux has a second word of INIT flags.
+// Its bit has a different meaning elsewhere.
+const initExt = 0
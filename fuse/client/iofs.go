@@ -0,0 +1,57 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"io/fs"
+)
+
+// ioFS exposes the client as an io/fs.FS.
+type ioFS struct {
+	c *Client
+}
+
+var _ = (fs.StatFS)((*ioFS)(nil))
+var _ = (fs.ReadDirFS)((*ioFS)(nil))
+var _ = (fs.ReadFileFS)((*ioFS)(nil))
+var _ = (fs.ReadDirFile)((*File)(nil))
+
+// FS returns the file system as an io/fs.FS. The returned files
+// are of type *File.
+func (c *Client) FS() fs.FS {
+	return &ioFS{c}
+}
+
+func (f *ioFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	file, err := f.c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *ioFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.c.Stat(name)
+}
+
+func (f *ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.c.ReadDir(name)
+}
+
+func (f *ioFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	return f.c.ReadFile(name)
+}
@@ -0,0 +1,410 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// node mirrors a kernel inode. The server hands out a lookup count
+// for every entry reply, and expects to get all of them back in a
+// FORGET once the inode is evicted.
+type node struct {
+	id      uint64
+	nlookup uint64
+
+	// The file type, as S_IFMT bits.
+	mode uint32
+
+	// refs counts references other than directory entries: open
+	// files and operations in progress.
+	refs int
+
+	// dentries counts directory entries naming the node. Only
+	// files can have more than one.
+	dentries int
+
+	// dentry is the last directory entry that named the node.
+	// This is used to resolve "..".
+	dentry *dentry
+
+	// cached children, for directories.
+	children map[string]*dentry
+}
+
+func (n *node) isDir() bool {
+	return n.mode&syscall.S_IFMT == syscall.S_IFDIR
+}
+
+func (n *node) isSymlink() bool {
+	return n.mode&syscall.S_IFMT == syscall.S_IFLNK
+}
+
+// dentry is a cached directory entry.
+type dentry struct {
+	parent  *node
+	name    string
+	node    *node
+	expires time.Time
+}
+
+// addEntry records the entry returned by LOOKUP, CREATE, MKDIR,
+// etc. for name in parent, and returns the node, which gains a lookup
+// count. It returns nil for negative entries. c.mu must be held.
+func (c *Client) addEntry(parent *node, name string, out *fuse.EntryOut) *node {
+	if out.NodeId == 0 {
+		if d := parent.children[name]; d != nil {
+			c.dropDentry(d)
+		}
+		return nil
+	}
+	n := c.nodes[out.NodeId]
+	if n == nil {
+		n = &node{id: out.NodeId}
+		c.nodes[out.NodeId] = n
+	}
+	n.nlookup++
+	n.mode = out.Mode & syscall.S_IFMT
+	if n.isDir() && n.children == nil {
+		n.children = map[string]*dentry{}
+	}
+
+	expires := time.Now().Add(out.EntryTimeout())
+	if d := parent.children[name]; d != nil {
+		if d.node == n {
+			d.expires = expires
+			return n
+		}
+		// Pin n, in case it was the only thing keeping the
+		// old entry alive.
+		n.refs++
+		c.dropDentry(d)
+		n.refs--
+	}
+	d := &dentry{
+		parent:  parent,
+		name:    name,
+		node:    n,
+		expires: expires,
+	}
+	parent.children[name] = d
+	n.dentries++
+	n.dentry = d
+	return n
+}
+
+// dropDentry removes a directory entry, along with the cached
+// entries below it, and evicts the nodes that become unused. c.mu
+// must be held.
+func (c *Client) dropDentry(d *dentry) {
+	if d.parent.children[d.name] == d {
+		delete(d.parent.children, d.name)
+	}
+	n := d.node
+	for _, ch := range n.children {
+		c.dropDentry(ch)
+	}
+	n.dentries--
+	if n.dentry == d {
+		n.dentry = nil
+	}
+	c.maybeEvict(n)
+}
+
+// maybeEvict evicts a node that has no references left, queuing a
+// FORGET for its lookups. c.mu must be held.
+func (c *Client) maybeEvict(n *node) {
+	if n.id == rootID || n.dentries > 0 || n.refs > 0 {
+		return
+	}
+	if c.nodes[n.id] == n {
+		delete(c.nodes, n.id)
+	}
+	if n.nlookup > 0 {
+		c.forgets = append(c.forgets, forgetOne{NodeId: n.id, Nlookup: n.nlookup})
+		n.nlookup = 0
+	}
+}
+
+// unlock releases c.mu, and sends the FORGETs that were queued while
+// it was held.
+func (c *Client) unlock() {
+	forgets := c.forgets
+	c.forgets = nil
+	c.mu.Unlock()
+	c.sendForgets(forgets)
+}
+
+// put releases a reference obtained from walk or lookup.
+func (c *Client) put(n *node) {
+	c.mu.Lock()
+	n.refs--
+	c.dropExpired(n)
+	c.maybeEvict(n)
+	c.unlock()
+}
+
+// dropExpired drops the entry for n if it is unused and has expired,
+// and then does the same for its parent. The kernel deletes such
+// entries on their last dput rather than caching them, so with a
+// zero entry timeout, every lookup is followed by a FORGET. c.mu
+// must be held.
+func (c *Client) dropExpired(n *node) {
+	now := time.Now()
+	for n.refs == 0 && len(n.children) == 0 {
+		d := n.dentry
+		if d == nil || now.Before(d.expires) {
+			return
+		}
+		c.dropDentry(d)
+		n = d.parent
+	}
+}
+
+func (c *Client) pinRoot() *node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.root.refs++
+	return c.root
+}
+
+func (c *Client) sendForgets(forgets []forgetOne) {
+	switch len(forgets) {
+	case 0:
+	case 1:
+		in := fuse.ForgetIn{Nlookup: forgets[0].Nlookup}
+		c.send(opForget, forgets[0].NodeId, true, body(&in))
+	default:
+		in := batchForgetIn{Count: uint32(len(forgets))}
+		data := [][]byte{body(&in)}
+		for i := range forgets {
+			data = append(data, asBytes(&forgets[i]))
+		}
+		c.send(opBatchForget, 0, true, data...)
+	}
+}
+
+// invalidateEntry handles NOTIFY_INVAL_ENTRY and NOTIFY_DELETE. If
+// child is non-zero, the entry is only dropped if it points to that
+// node.
+func (c *Client) invalidateEntry(parent, child uint64, name string) {
+	c.mu.Lock()
+	if p := c.nodes[parent]; p != nil {
+		if d := p.children[name]; d != nil && (child == 0 || d.node.id == child) {
+			c.dropDentry(d)
+		}
+	}
+	forgets := c.forgets
+	c.forgets = nil
+	c.mu.Unlock()
+
+	// We are on the reader goroutine, and the server may need
+	// us to read replies before it can read our FORGET.
+	if len(forgets) > 0 {
+		go c.sendForgets(forgets)
+	}
+}
+
+// DropCaches drops all directory entries that are not in use, and
+// forgets the nodes that are no longer referenced, like writing 2
+// to /proc/sys/vm/drop_caches. The root and nodes with open files
+// are kept.
+func (c *Client) DropCaches() {
+	c.mu.Lock()
+	c.prune(c.root)
+	c.unlock()
+}
+
+func (c *Client) prune(n *node) {
+	for _, d := range n.children {
+		c.prune(d.node)
+		if len(d.node.children) == 0 && d.node.refs == 0 {
+			c.dropDentry(d)
+		}
+	}
+}
+
+// lookup returns the child called name of parent, which must be
+// pinned. A cached entry is used until it expires; after that, the
+// entry is revalidated with LOOKUP, like the kernel does. The
+// returned node is pinned.
+func (c *Client) lookup(parent *node, name string) (*node, syscall.Errno) {
+	if len(name) > 255 {
+		return nil, syscall.ENAMETOOLONG
+	}
+	if !parent.isDir() {
+		return nil, syscall.ENOTDIR
+	}
+	c.mu.Lock()
+	if d := parent.children[name]; d != nil && time.Now().Before(d.expires) {
+		d.node.refs++
+		c.mu.Unlock()
+		return d.node, 0
+	}
+	c.mu.Unlock()
+
+	data, errno := c.call(opLookup, parent.id, cstr(name))
+	var out *fuse.EntryOut
+	if errno == 0 {
+		out, errno = decode[fuse.EntryOut](data)
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	if errno == syscall.ENOENT {
+		if d := parent.children[name]; d != nil {
+			c.dropDentry(d)
+		}
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	n := c.addEntry(parent, name, out)
+	if n == nil {
+		return nil, syscall.ENOENT
+	}
+	n.refs++
+	return n, 0
+}
+
+// entryReply processes a reply holding an EntryOut for a new name
+// in parent, and returns the pinned node.
+func (c *Client) entryReply(parent *node, name string, data []byte) (*node, syscall.Errno) {
+	out, errno := decode[fuse.EntryOut](data)
+	if errno != 0 {
+		return nil, errno
+	}
+	c.mu.Lock()
+	defer c.unlock()
+	n := c.addEntry(parent, name, out)
+	if n == nil {
+		return nil, syscall.EIO
+	}
+	n.refs++
+	return n, 0
+}
+
+// removeEntry drops the entry for name in parent if it points to
+// child.
+func (c *Client) removeEntry(parent *node, name string, child *node) {
+	c.mu.Lock()
+	defer c.unlock()
+	if d := parent.children[name]; d != nil && d.node == child {
+		c.dropDentry(d)
+	}
+}
+
+// splitPath splits a slash-separated path into components, dropping
+// empty ones and ".".
+func splitPath(name string) []string {
+	var comps []string
+	for _, comp := range strings.Split(name, "/") {
+		if comp == "" || comp == "." {
+			continue
+		}
+		comps = append(comps, comp)
+	}
+	return comps
+}
+
+// maxSymlinks is the limit on symlinks followed in a path, as in
+// Linux.
+const maxSymlinks = 40
+
+// walk resolves a path relative to the root, following symlinks in
+// the directory part. If follow is set, a symlink in the last
+// component is followed as well. Absolute symlinks are resolved
+// relative to the root. The returned node is pinned.
+func (c *Client) walk(name string, follow bool) (*node, syscall.Errno) {
+	comps := splitPath(name)
+	cur := c.pinRoot()
+	links := 0
+	for len(comps) > 0 {
+		comp := comps[0]
+		comps = comps[1:]
+
+		if comp == ".." {
+			c.mu.Lock()
+			next := cur
+			if cur != c.root {
+				if cur.dentry == nil {
+					c.unlock()
+					c.put(cur)
+					return nil, syscall.ENOENT
+				}
+				next = cur.dentry.parent
+			}
+			next.refs++
+			c.unlock()
+			c.put(cur)
+			cur = next
+			continue
+		}
+
+		child, errno := c.lookup(cur, comp)
+		if errno != 0 {
+			c.put(cur)
+			return nil, errno
+		}
+		if child.isSymlink() && (len(comps) > 0 || follow) {
+			links++
+			if links > maxSymlinks {
+				c.put(child)
+				c.put(cur)
+				return nil, syscall.ELOOP
+			}
+			target, errno := c.readlink(child)
+			c.put(child)
+			if errno != 0 {
+				c.put(cur)
+				return nil, errno
+			}
+			if strings.HasPrefix(target, "/") {
+				c.put(cur)
+				cur = c.pinRoot()
+			}
+			comps = append(splitPath(target), comps...)
+			continue
+		}
+		c.put(cur)
+		cur = child
+	}
+	return cur, 0
+}
+
+// walkParent resolves the directory containing name, and returns it
+// pinned, along with the last path component.
+func (c *Client) walkParent(name string) (*node, string, syscall.Errno) {
+	name = strings.TrimRight(name, "/")
+	dir, base := "", name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		dir, base = name[:i], name[i+1:]
+	}
+	switch base {
+	case "", ".", "..":
+		return nil, "", syscall.EINVAL
+	}
+	parent, errno := c.walk(dir, true)
+	if errno != 0 {
+		return nil, "", errno
+	}
+	if !parent.isDir() {
+		c.put(parent)
+		return nil, "", syscall.ENOTDIR
+	}
+	return parent, base, 0
+}
+
+func (c *Client) readlink(n *node) (string, syscall.Errno) {
+	data, errno := c.call(opReadlink, n.id)
+	if errno != 0 {
+		return "", errno
+	}
+	return string(data), 0
+}
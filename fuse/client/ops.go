@@ -0,0 +1,657 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"io"
+	"os"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// The methods below resolve paths relative to the root of the file
+// system, and return errors as *os.PathError wrapping a
+// syscall.Errno, like the os package.
+
+func pathError(op, name string, errno syscall.Errno) error {
+	if errno == 0 {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: name, Err: errno}
+}
+
+// unixMode converts the permission bits of an os.FileMode.
+func unixMode(m os.FileMode) uint32 {
+	mode := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		mode |= syscall.S_ISUID
+	}
+	if m&os.ModeSetgid != 0 {
+		mode |= syscall.S_ISGID
+	}
+	if m&os.ModeSticky != 0 {
+		mode |= syscall.S_ISVTX
+	}
+	return mode
+}
+
+func (c *Client) getattr(n *node, fh uint64, useFh bool) (*fuse.Attr, syscall.Errno) {
+	in := fuse.GetAttrIn{}
+	if useFh {
+		in.Flags_ = fuse.FUSE_GETATTR_FH
+		in.Fh_ = fh
+	}
+	data, errno := c.call(opGetattr, n.id, body(&in))
+	if errno != 0 {
+		return nil, errno
+	}
+	out, errno := decode[fuse.AttrOut](data)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &out.Attr, 0
+}
+
+func (c *Client) setattr(n *node, in *fuse.SetAttrIn) (*fuse.Attr, syscall.Errno) {
+	data, errno := c.call(opSetattr, n.id, body(in))
+	if errno != 0 {
+		return nil, errno
+	}
+	out, errno := decode[fuse.AttrOut](data)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &out.Attr, 0
+}
+
+func baseName(name string) string {
+	if comps := splitPath(name); len(comps) > 0 {
+		return comps[len(comps)-1]
+	}
+	return "."
+}
+
+func (c *Client) stat(op, name string, follow bool) (os.FileInfo, error) {
+	n, errno := c.walk(name, follow)
+	if errno != 0 {
+		return nil, pathError(op, name, errno)
+	}
+	defer c.put(n)
+	attr, errno := c.getattr(n, 0, false)
+	if errno != 0 {
+		return nil, pathError(op, name, errno)
+	}
+	return &fileInfo{name: baseName(name), attr: *attr}, nil
+}
+
+// Stat returns the attributes of the named file, following
+// symlinks. The Sys method of the result returns a *fuse.Attr.
+func (c *Client) Stat(name string) (os.FileInfo, error) {
+	return c.stat("stat", name, true)
+}
+
+// Lstat is like Stat, but does not follow a symlink in the last
+// component.
+func (c *Client) Lstat(name string) (os.FileInfo, error) {
+	return c.stat("lstat", name, false)
+}
+
+// Mkdir creates a directory.
+func (c *Client) Mkdir(name string, perm os.FileMode) error {
+	parent, base, errno := c.walkParent(name)
+	if errno != 0 {
+		return pathError("mkdir", name, errno)
+	}
+	defer c.put(parent)
+
+	if ch, errno := c.lookup(parent, base); errno == 0 {
+		c.put(ch)
+		return pathError("mkdir", name, syscall.EEXIST)
+	} else if errno != syscall.ENOENT {
+		return pathError("mkdir", name, errno)
+	}
+
+	in := fuse.MkdirIn{
+		Mode:  unixMode(perm) &^ c.opts.Umask,
+		Umask: c.opts.Umask,
+	}
+	data, errno := c.call(opMkdir, parent.id, body(&in), cstr(base))
+	if errno == 0 {
+		var ch *node
+		ch, errno = c.entryReply(parent, base, data)
+		if errno == 0 {
+			c.put(ch)
+		}
+	}
+	return pathError("mkdir", name, errno)
+}
+
+// Remove removes a file or an empty directory.
+func (c *Client) Remove(name string) error {
+	parent, base, errno := c.walkParent(name)
+	if errno != 0 {
+		return pathError("remove", name, errno)
+	}
+	defer c.put(parent)
+
+	ch, errno := c.lookup(parent, base)
+	if errno != 0 {
+		return pathError("remove", name, errno)
+	}
+	defer c.put(ch)
+
+	op := opUnlink
+	if ch.isDir() {
+		op = opRmdir
+	}
+	if _, errno := c.call(op, parent.id, cstr(base)); errno != 0 {
+		return pathError("remove", name, errno)
+	}
+	c.removeEntry(parent, base, ch)
+	return nil
+}
+
+// Rename renames oldpath to newpath, replacing newpath if it exists.
+func (c *Client) Rename(oldpath, newpath string) error {
+	oldParent, oldBase, errno := c.walkParent(oldpath)
+	if errno != 0 {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errno}
+	}
+	defer c.put(oldParent)
+	newParent, newBase, errno := c.walkParent(newpath)
+	if errno != 0 {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errno}
+	}
+	defer c.put(newParent)
+
+	// Like the kernel, look up both names first.
+	src, errno := c.lookup(oldParent, oldBase)
+	if errno != 0 {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errno}
+	}
+	defer c.put(src)
+	if dst, errno := c.lookup(newParent, newBase); errno == 0 {
+		c.put(dst)
+	} else if errno != syscall.ENOENT {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errno}
+	}
+
+	in := fuse.Rename1In{Newdir: newParent.id}
+	if _, errno := c.call(opRename, oldParent.id, body(&in), cstr(oldBase), cstr(newBase)); errno != 0 {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errno}
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	if d := newParent.children[newBase]; d != nil && d.node != src {
+		c.dropDentry(d)
+	}
+	if d := oldParent.children[oldBase]; d != nil && d.node == src {
+		delete(oldParent.children, oldBase)
+		d.parent = newParent
+		d.name = newBase
+		newParent.children[newBase] = d
+	}
+	return nil
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (c *Client) Symlink(oldname, newname string) error {
+	parent, base, errno := c.walkParent(newname)
+	if errno != 0 {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: errno}
+	}
+	defer c.put(parent)
+	if ch, errno := c.lookup(parent, base); errno == 0 {
+		c.put(ch)
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: syscall.EEXIST}
+	} else if errno != syscall.ENOENT {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: errno}
+	}
+
+	data, errno := c.call(opSymlink, parent.id, cstr(base), cstr(oldname))
+	if errno == 0 {
+		var ch *node
+		ch, errno = c.entryReply(parent, base, data)
+		if errno == 0 {
+			c.put(ch)
+		}
+	}
+	if errno != 0 {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: errno}
+	}
+	return nil
+}
+
+// Readlink returns the target of a symbolic link.
+func (c *Client) Readlink(name string) (string, error) {
+	n, errno := c.walk(name, false)
+	if errno != 0 {
+		return "", pathError("readlink", name, errno)
+	}
+	defer c.put(n)
+	if !n.isSymlink() {
+		return "", pathError("readlink", name, syscall.EINVAL)
+	}
+	target, errno := c.readlink(n)
+	if errno != 0 {
+		return "", pathError("readlink", name, errno)
+	}
+	return target, nil
+}
+
+// Link creates newname as a hard link to oldname.
+func (c *Client) Link(oldname, newname string) error {
+	old, errno := c.walk(oldname, false)
+	if errno != 0 {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errno}
+	}
+	defer c.put(old)
+	if old.isDir() {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EPERM}
+	}
+	parent, base, errno := c.walkParent(newname)
+	if errno != 0 {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errno}
+	}
+	defer c.put(parent)
+	if ch, errno := c.lookup(parent, base); errno == 0 {
+		c.put(ch)
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: syscall.EEXIST}
+	} else if errno != syscall.ENOENT {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errno}
+	}
+
+	in := fuse.LinkIn{Oldnodeid: old.id}
+	data, errno := c.call(opLink, parent.id, body(&in), cstr(base))
+	if errno == 0 {
+		var ch *node
+		ch, errno = c.entryReply(parent, base, data)
+		if errno == 0 {
+			c.put(ch)
+		}
+	}
+	if errno != 0 {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errno}
+	}
+	return nil
+}
+
+// changeAttr issues a SETATTR for the named file.
+func (c *Client) changeAttr(op, name string, in *fuse.SetAttrIn) error {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return pathError(op, name, errno)
+	}
+	defer c.put(n)
+	if in.Valid&fuse.FATTR_MODE != 0 {
+		in.Mode |= n.mode
+	}
+	_, errno = c.setattr(n, in)
+	return pathError(op, name, errno)
+}
+
+// Chmod changes the permission bits of a file.
+func (c *Client) Chmod(name string, mode os.FileMode) error {
+	in := fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_MODE
+	in.Mode = unixMode(mode)
+	return c.changeAttr("chmod", name, &in)
+}
+
+// Chown changes the owner of a file. An ID of -1 leaves it
+// unchanged.
+func (c *Client) Chown(name string, uid, gid int) error {
+	in := fuse.SetAttrIn{}
+	if uid >= 0 {
+		in.Valid |= fuse.FATTR_UID
+		in.Uid = uint32(uid)
+	}
+	if gid >= 0 {
+		in.Valid |= fuse.FATTR_GID
+		in.Gid = uint32(gid)
+	}
+	return c.changeAttr("chown", name, &in)
+}
+
+// Chtimes changes the access and modification times of a file. A
+// zero time leaves the corresponding field unchanged.
+func (c *Client) Chtimes(name string, atime, mtime time.Time) error {
+	in := fuse.SetAttrIn{}
+	if !atime.IsZero() {
+		in.Valid |= fuse.FATTR_ATIME
+		in.Atime = uint64(atime.Unix())
+		in.Atimensec = uint32(atime.Nanosecond())
+	}
+	if !mtime.IsZero() {
+		in.Valid |= fuse.FATTR_MTIME
+		in.Mtime = uint64(mtime.Unix())
+		in.Mtimensec = uint32(mtime.Nanosecond())
+	}
+	return c.changeAttr("chtimes", name, &in)
+}
+
+// Truncate changes the size of a file.
+func (c *Client) Truncate(name string, size int64) error {
+	in := fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_SIZE
+	in.Size = uint64(size)
+	return c.changeAttr("truncate", name, &in)
+}
+
+// Access checks whether the caller may access a file. mode is a
+// combination of fuse.R_OK, fuse.W_OK and fuse.X_OK.
+func (c *Client) Access(name string, mode uint32) error {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return pathError("access", name, errno)
+	}
+	defer c.put(n)
+	in := fuse.AccessIn{Mask: mode}
+	_, errno = c.call(opAccess, n.id, body(&in))
+	return pathError("access", name, errno)
+}
+
+// Statfs returns file system statistics.
+func (c *Client) Statfs(name string) (*fuse.StatfsOut, error) {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return nil, pathError("statfs", name, errno)
+	}
+	defer c.put(n)
+	data, errno := c.call(opStatfs, n.id)
+	if errno != 0 {
+		return nil, pathError("statfs", name, errno)
+	}
+	out, errno := decode[fuse.StatfsOut](data)
+	if errno != 0 {
+		return nil, pathError("statfs", name, errno)
+	}
+	return out, nil
+}
+
+// Getxattr reads an extended attribute into dest, and returns its
+// size. If dest is empty, it only returns the size.
+func (c *Client) Getxattr(name, attr string, dest []byte) (int, error) {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return 0, pathError("getxattr", name, errno)
+	}
+	defer c.put(n)
+	in := fuse.GetXAttrIn{Size: uint32(len(dest))}
+	sz, errno := c.xattrCall(opGetxattr, n, body(&in), dest, cstr(attr))
+	return sz, pathError("getxattr", name, errno)
+}
+
+// Listxattr reads the NUL-separated list of extended attribute names
+// into dest, and returns its size. If dest is empty, it only returns
+// the size.
+func (c *Client) Listxattr(name string, dest []byte) (int, error) {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return 0, pathError("listxattr", name, errno)
+	}
+	defer c.put(n)
+	in := fuse.GetXAttrIn{Size: uint32(len(dest))}
+	sz, errno := c.xattrCall(opListxattr, n, body(&in), dest)
+	return sz, pathError("listxattr", name, errno)
+}
+
+// xattrCall issues GETXATTR or LISTXATTR, which return either a size
+// or the data.
+func (c *Client) xattrCall(op uint32, n *node, in []byte, dest []byte, rest ...[]byte) (int, syscall.Errno) {
+	data, errno := c.call(op, n.id, append([][]byte{in}, rest...)...)
+	if errno != 0 {
+		return 0, errno
+	}
+	if len(dest) == 0 {
+		out, errno := decode[fuse.GetXAttrOut](data)
+		if errno != 0 {
+			return 0, errno
+		}
+		return int(out.Size), 0
+	}
+	if len(data) > len(dest) {
+		return 0, syscall.ERANGE
+	}
+	return copy(dest, data), 0
+}
+
+// Setxattr sets an extended attribute.
+func (c *Client) Setxattr(name, attr string, data []byte, flags int) error {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return pathError("setxattr", name, errno)
+	}
+	defer c.put(n)
+	in := fuse.SetXAttrIn{Size: uint32(len(data)), Flags: uint32(flags)}
+	_, errno = c.call(opSetxattr, n.id, body(&in), cstr(attr), data)
+	return pathError("setxattr", name, errno)
+}
+
+// Removexattr removes an extended attribute.
+func (c *Client) Removexattr(name, attr string) error {
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return pathError("removexattr", name, errno)
+	}
+	defer c.put(n)
+	_, errno = c.call(opRemovexattr, n.id, cstr(attr))
+	return pathError("removexattr", name, errno)
+}
+
+// ReadFile returns the contents of a file.
+func (c *Client) ReadFile(name string) ([]byte, error) {
+	f, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile writes data to a file, creating it with perm if needed,
+// and truncating it otherwise.
+func (c *Client) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := c.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// ReadDir returns the entries of a directory, sorted by name.
+func (c *Client) ReadDir(name string) ([]os.DirEntry, error) {
+	f, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries, err := f.ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, err
+}
+
+// Open opens a file or directory for reading.
+func (c *Client) Open(name string) (*File, error) {
+	return c.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates a file, and opens it for reading and
+// writing.
+func (c *Client) Create(name string) (*File, error) {
+	return c.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// OpenFile opens a file with the given os.O_* flags. If the file is
+// created, it gets mode perm, minus the umask.
+func (c *Client) OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	if flag&os.O_CREATE != 0 {
+		f, errno := c.create(name, flag, perm)
+		if f != nil || errno != 0 {
+			return f, pathError("open", name, errno)
+		}
+		// The file exists.
+		if flag&os.O_EXCL != 0 {
+			return nil, pathError("open", name, syscall.EEXIST)
+		}
+	}
+	n, errno := c.walk(name, true)
+	if errno != 0 {
+		return nil, pathError("open", name, errno)
+	}
+	f, errno := c.open(n, name, flag)
+	if errno != 0 {
+		c.put(n)
+		return nil, pathError("open", name, errno)
+	}
+	return f, nil
+}
+
+// create creates the named file if it does not exist. It returns
+// nil and no error if it does.
+func (c *Client) create(name string, flag int, perm os.FileMode) (*File, syscall.Errno) {
+	parent, base, errno := c.walkParent(name)
+	if errno != 0 {
+		return nil, errno
+	}
+	defer c.put(parent)
+	if ch, errno := c.lookup(parent, base); errno == 0 {
+		c.put(ch)
+		return nil, 0
+	} else if errno != syscall.ENOENT {
+		return nil, errno
+	}
+
+	mode := syscall.S_IFREG | unixMode(perm)&^c.opts.Umask
+	in := fuse.CreateIn{
+		Flags: openFlags(flag),
+		Mode:  mode,
+		Umask: c.opts.Umask,
+	}
+	data, errno := c.call(opCreate, parent.id, body(&in), cstr(base))
+	if errno == syscall.ENOSYS {
+		// Like the kernel, fall back to MKNOD and OPEN.
+		mk := fuse.MknodIn{Mode: mode, Umask: c.opts.Umask}
+		data, errno = c.call(opMknod, parent.id, body(&mk), cstr(base))
+		if errno != 0 {
+			return nil, errno
+		}
+		n, errno := c.entryReply(parent, base, data)
+		if errno != 0 {
+			return nil, errno
+		}
+		f, errno := c.open(n, name, flag&^os.O_TRUNC)
+		if errno != 0 {
+			c.put(n)
+		}
+		return f, errno
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	out, errno := decode[fuse.CreateOut](data)
+	if errno != 0 {
+		return nil, errno
+	}
+	n, errno := c.entryReply(parent, base, data)
+	if errno != 0 {
+		return nil, errno
+	}
+	return &File{c: c, name: name, n: n, fh: out.Fh, flag: flag}, 0
+}
+
+// openFlags returns the flags the kernel passes in OPEN and CREATE.
+func openFlags(flag int) uint32 {
+	return uint32(flag &^ (os.O_CREATE | os.O_EXCL | syscall.O_NOCTTY | os.O_TRUNC))
+}
+
+// open sends OPEN or OPENDIR for n, which must be pinned. The
+// reference is transferred to the returned File.
+func (c *Client) open(n *node, name string, flag int) (*File, syscall.Errno) {
+	if n.isSymlink() {
+		return nil, syscall.ELOOP
+	}
+	if n.isDir() && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, syscall.EISDIR
+	}
+	op := opOpen
+	if n.isDir() {
+		op = opOpendir
+	}
+	f := &File{c: c, name: name, n: n, flag: flag, dir: n.isDir()}
+	in := fuse.OpenIn{Flags: openFlags(flag)}
+	data, errno := c.call(op, n.id, body(&in))
+	if errno == syscall.ENOSYS && c.flags&fuse.CAP_NO_OPEN_SUPPORT != 0 && !f.dir {
+		f.noRelease = true
+	} else if errno != 0 {
+		return nil, errno
+	} else {
+		out, errno := decode[fuse.OpenOut](data)
+		if errno != 0 {
+			return nil, errno
+		}
+		f.fh = out.Fh
+	}
+
+	if flag&os.O_TRUNC != 0 && !f.dir {
+		if errno := f.truncate(0); errno != 0 {
+			f.release()
+			return nil, errno
+		}
+	}
+	return f, 0
+}
+
+// fileInfo implements os.FileInfo for a fuse.Attr.
+type fileInfo struct {
+	name string
+	attr fuse.Attr
+}
+
+func (fi *fileInfo) Name() string      { return fi.name }
+func (fi *fileInfo) Size() int64       { return int64(fi.attr.Size) }
+func (fi *fileInfo) Mode() os.FileMode { return fileMode(fi.attr.Mode) }
+func (fi *fileInfo) ModTime() time.Time {
+	return time.Unix(int64(fi.attr.Mtime), int64(fi.attr.Mtimensec))
+}
+func (fi *fileInfo) IsDir() bool { return fi.Mode().IsDir() }
+func (fi *fileInfo) Sys() any    { return &fi.attr }
+
+// fileMode converts a unix mode to an os.FileMode.
+func fileMode(mode uint32) os.FileMode {
+	m := os.FileMode(mode & 0777)
+	switch mode & syscall.S_IFMT {
+	case syscall.S_IFDIR:
+		m |= os.ModeDir
+	case syscall.S_IFLNK:
+		m |= os.ModeSymlink
+	case syscall.S_IFIFO:
+		m |= os.ModeNamedPipe
+	case syscall.S_IFSOCK:
+		m |= os.ModeSocket
+	case syscall.S_IFCHR:
+		m |= os.ModeDevice | os.ModeCharDevice
+	case syscall.S_IFBLK:
+		m |= os.ModeDevice
+	}
+	if mode&syscall.S_ISUID != 0 {
+		m |= os.ModeSetuid
+	}
+	if mode&syscall.S_ISGID != 0 {
+		m |= os.ModeSetgid
+	}
+	if mode&syscall.S_ISVTX != 0 {
+		m |= os.ModeSticky
+	}
+	return m
+}
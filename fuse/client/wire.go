@@ -0,0 +1,159 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Opcodes from the kernel's include/uapi/linux/fuse.h. The fuse
+// package keeps its own copy unexported.
+const (
+	opLookup      = uint32(1)
+	opForget      = uint32(2)
+	opGetattr     = uint32(3)
+	opSetattr     = uint32(4)
+	opReadlink    = uint32(5)
+	opSymlink     = uint32(6)
+	opMknod       = uint32(8)
+	opMkdir       = uint32(9)
+	opUnlink      = uint32(10)
+	opRmdir       = uint32(11)
+	opRename      = uint32(12)
+	opLink        = uint32(13)
+	opOpen        = uint32(14)
+	opRead        = uint32(15)
+	opWrite       = uint32(16)
+	opStatfs      = uint32(17)
+	opRelease     = uint32(18)
+	opFsync       = uint32(20)
+	opSetxattr    = uint32(21)
+	opGetxattr    = uint32(22)
+	opListxattr   = uint32(23)
+	opRemovexattr = uint32(24)
+	opFlush       = uint32(25)
+	opInit        = uint32(26)
+	opOpendir     = uint32(27)
+	opReaddir     = uint32(28)
+	opReleasedir  = uint32(29)
+	opAccess      = uint32(34)
+	opCreate      = uint32(35)
+	opNotifyReply = uint32(41)
+	opBatchForget = uint32(42)
+	opReaddirplus = uint32(44)
+)
+
+const (
+	kernelVersion = 7
+	kernelMinor   = 31
+
+	// The root always has this node ID.
+	rootID = 1
+)
+
+var (
+	inHeaderSize  = int(unsafe.Sizeof(fuse.InHeader{}))
+	outHeaderSize = int(unsafe.Sizeof(fuse.OutHeader{}))
+)
+
+// forgetOne is an entry in a BATCH_FORGET request.
+type forgetOne struct {
+	NodeId  uint64
+	Nlookup uint64
+}
+
+type batchForgetIn struct {
+	fuse.InHeader
+	Count uint32
+	Dummy uint32
+}
+
+// dirent is the fixed part of a directory entry in the READDIR
+// reply.
+type dirent struct {
+	Ino     uint64
+	Off     uint64
+	NameLen uint32
+	Typ     uint32
+}
+
+// asBytes returns the memory of v as a byte slice.
+func asBytes[T any](v *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(v)), unsafe.Sizeof(*v))
+}
+
+// body returns the part of an input struct that follows its
+// InHeader. All request structs start with an InHeader.
+func body[T any](in *T) []byte {
+	return asBytes(in)[inHeaderSize:]
+}
+
+// decode copies the start of a reply into a struct.
+func decode[T any](data []byte) (*T, syscall.Errno) {
+	var out T
+	sz := int(unsafe.Sizeof(out))
+	if len(data) < sz {
+		return nil, syscall.EIO
+	}
+	copy(asBytes(&out), data[:sz])
+	return &out, 0
+}
+
+// cstr returns name as a NUL-terminated string.
+func cstr(name string) []byte {
+	b := make([]byte, len(name)+1)
+	copy(b, name)
+	return b
+}
+
+// parseDirents decodes the reply to READDIR or READDIRPLUS.
+func parseDirents(data []byte, plus bool) ([]direntry, syscall.Errno) {
+	var result []direntry
+	entrySize := int(unsafe.Sizeof(fuse.EntryOut{}))
+	for len(data) > 0 {
+		var e direntry
+		if plus {
+			out, errno := decode[fuse.EntryOut](data)
+			if errno != 0 {
+				return nil, errno
+			}
+			e.entry = out
+			data = data[entrySize:]
+		}
+		d, errno := decode[dirent](data)
+		if errno != 0 {
+			return nil, errno
+		}
+		sz := int(unsafe.Sizeof(*d)) + int(d.NameLen)
+		if len(data) < sz {
+			return nil, syscall.EIO
+		}
+		e.name = string(data[unsafe.Sizeof(*d):sz])
+		e.ino = d.Ino
+		e.off = d.Off
+		e.typ = d.Typ
+		result = append(result, e)
+
+		// Entries are padded to 8 bytes.
+		sz = (sz + 7) &^ 7
+		if sz > len(data) {
+			sz = len(data)
+		}
+		data = data[sz:]
+	}
+	return result, 0
+}
+
+// direntry is a decoded directory entry.
+type direntry struct {
+	name  string
+	ino   uint64
+	off   uint64
+	typ   uint32
+	entry *fuse.EntryOut
+}
@@ -0,0 +1,30 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const _DEV_IOC_CLONE = 0x8004e500
+
+// cloneDevFd opens a new FUSE device file descriptor, and attaches
+// it to the connection of fd using FUSE_DEV_IOC_CLONE. Requests for
+// the connection are then distributed across both file descriptors.
+func cloneDevFd(fd int) (int, error) {
+	newFd, err := syscall.Open("/dev/fuse", os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, err
+	}
+	src := uint32(fd)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(newFd), uintptr(_DEV_IOC_CLONE), uintptr(unsafe.Pointer(&src)))
+	if errno != 0 {
+		syscall.Close(newFd)
+		return -1, os.NewSyscallError("ioctl FUSE_DEV_IOC_CLONE", errno)
+	}
+	return newFd, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestCloneQueues(t *testing.T) {
+	mnt := t.TempDir()
+	opts := &MountOptions{
+		Debug:       testutil.VerboseTest(),
+		CloneQueues: 4,
+	}
+
+	rfs := readFS{}
+	srv, err := NewServer(&rfs, mnt, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Unmount() })
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(srv.channels); got != opts.CloneQueues {
+		t.Fatalf("got %d channels, want %d", got, opts.CloneQueues)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := os.ReadFile(mnt + "/file"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	srv.Unmount()
+	for i, ch := range srv.channels {
+		for sz, c := range ch.buffers.counters() {
+			if c != 0 {
+				t.Errorf("channel %d, page count %d: %d buffers outstanding", i, sz, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import "syscall"
+
+// cloneDevFd is only supported on Linux.
+func cloneDevFd(fd int) (int, error) {
+	return -1, syscall.ENOSYS
+}
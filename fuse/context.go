@@ -19,9 +19,31 @@ import (
 type Context struct {
 	Caller
 	Cancel <-chan struct{}
+
+	// ctx is the context of the request, if it was returned by
+	// Server.Context.
+	ctx context.Context
+}
+
+// Context returns the Context for a request that a RawFileSystem
+// method received with the given cancel channel and header. Unlike a
+// Context constructed directly, it carries the security contexts of
+// the request, the values added by MountOptions.Tracer, and the
+// deadline of MountOptions.RequestTimeout.
+func (ms *Server) Context(cancel <-chan struct{}, header *InHeader) *Context {
+	return &Context{
+		Caller: header.Caller,
+		Cancel: cancel,
+		ctx:    ms.requestContext(header.Unique, cancel),
+	}
 }
 
+// Deadline returns the deadline of the request, if it has a timeout
+// (see MountOptions.RequestTimeout).
 func (c *Context) Deadline() (time.Time, bool) {
+	if c.ctx != nil {
+		return c.ctx.Deadline()
+	}
 	return time.Time{}, false
 }
 
@@ -32,6 +54,9 @@ func (c *Context) Done() <-chan struct{} {
 func (c *Context) Err() error {
 	select {
 	case <-c.Cancel:
+		if d, ok := c.Deadline(); ok && !time.Now().Before(d) {
+			return context.DeadlineExceeded
+		}
 		return context.Canceled
 	default:
 		return nil
@@ -51,10 +76,18 @@ func NewContext(ctx context.Context, caller *Caller) context.Context {
 	return context.WithValue(ctx, callerKey, caller)
 }
 
+// Value returns the Caller for the key used by FromContext. For a
+// Context returned by Server.Context, other keys are looked up in the
+// context of the request, which holds its security contexts (see
+// SecurityContextFromContext), and the values added by
+// MountOptions.Tracer.
 func (c *Context) Value(key interface{}) interface{} {
 	if key == callerKey {
 		return &c.Caller
 	}
+	if c.ctx != nil {
+		return c.ctx.Value(key)
+	}
 	return nil
 }
 
@@ -0,0 +1,294 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// requestTimeouts returns the timeout for each opcode from
+// MountOptions.RequestTimeout and OpcodeTimeouts, or nil if no
+// request has a timeout.
+func requestTimeouts(o *MountOptions) ([]time.Duration, error) {
+	if o.RequestTimeout <= 0 && len(o.OpcodeTimeouts) == 0 {
+		return nil, nil
+	}
+	if o.EnableIOUring {
+		// The reply to a request from io_uring goes into its
+		// ring entry, which the handler owns until it returns.
+		return nil, errors.New("RequestTimeout and OpcodeTimeouts cannot be used with EnableIOUring")
+	}
+	timeouts := make([]time.Duration, _OPCODE_COUNT)
+	for op := range timeouts {
+		switch uint32(op) {
+		case _OP_INIT, _OP_DESTROY, _OP_FORGET, _OP_BATCH_FORGET,
+			_OP_INTERRUPT, _OP_NOTIFY_REPLY, _OP_SETLKW:
+			// These are not answered, or may
+			// legitimately block for a long time.
+		case _OP_READDIRPLUS:
+			// A late reply would leave the lookups of
+			// all its entries behind, see
+			// releaseLateReply.
+		default:
+			timeouts[op] = o.RequestTimeout
+		}
+	}
+	for name, d := range o.OpcodeTimeouts {
+		op := -1
+		for i, h := range operationHandlers {
+			if h != nil && h.Name == name {
+				op = i
+			}
+		}
+		if op < 0 {
+			return nil, fmt.Errorf("OpcodeTimeouts: unknown opcode %q", name)
+		}
+		if uint32(op) == _OP_READDIRPLUS && d > 0 {
+			return nil, fmt.Errorf("OpcodeTimeouts: %s cannot have a timeout", name)
+		}
+		timeouts[op] = d
+	}
+	return timeouts, nil
+}
+
+// requestDeadline tracks the timeout of a request. The fields are
+// protected by protocolServer.interruptMu.
+type requestDeadline struct {
+	timer *time.Timer
+
+	// expired is set if the timeout reply was sent, and
+	// finished once the handler has returned.
+	expired  bool
+	finished bool
+}
+
+// deadlineContext adds the deadline of a request to its context, for
+// Context.Deadline.
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+// startDeadline arms the timeout for req, if its opcode has one.
+func (ms *Server) startDeadline(req *request) {
+	op := req.inHeader().Opcode
+	if ms.timeouts == nil || op >= _OPCODE_COUNT || ms.timeouts[op] <= 0 {
+		return
+	}
+	d := ms.timeouts[op]
+	rd := &requestDeadline{}
+	req.deadline = time.Now().Add(d)
+	req.timeout = rd
+	rd.timer = time.AfterFunc(d, func() { ms.expireRequest(req, rd) })
+}
+
+// expireRequest cancels a request whose deadline has passed, and
+// answers it with MountOptions.TimeoutErrno.
+func (ms *Server) expireRequest(req *request, rd *requestDeadline) {
+	ms.interruptMu.Lock()
+	if rd.finished {
+		// req may have been reused already.
+		ms.interruptMu.Unlock()
+		return
+	}
+	if !req.interrupted {
+		close(req.cancel)
+		req.interrupted = true
+	}
+	rd.expired = true
+	hdr := req.inHeader()
+	unique, op := hdr.Unique, hdr.Opcode
+	t := ms.replyTransport(req)
+	ms.interruptMu.Unlock()
+
+	errno := ms.opts.TimeoutErrno
+	if errno == 0 {
+		errno = syscall.EIO
+	}
+	out := OutHeader{
+		Length: uint32(sizeOfOutHeader),
+		Status: -int32(errno),
+		Unique: unique,
+	}
+	err := t.WriteReply([][]byte{unsafe.Slice((*byte)(unsafe.Pointer(&out)), sizeOfOutHeader)})
+	if ms.opts.Debug {
+		ms.opts.Logger.Printf("tx %d:     %v, deadline exceeded for %s", unique, Status(errno), operationName(op))
+	}
+	if err != nil {
+		ms.opts.Logger.Printf("writing timeout for %s: %v", operationName(op), err)
+	}
+}
+
+// stopDeadline disarms the timeout of req, once its handler has
+// returned. It returns true if the timeout reply was sent, so the
+// reply of the handler must be dropped.
+func (ms *Server) stopDeadline(req *request) bool {
+	rd := req.timeout
+	if rd == nil {
+		return false
+	}
+	rd.timer.Stop()
+	ms.interruptMu.Lock()
+	defer ms.interruptMu.Unlock()
+	rd.finished = true
+	return rd.expired
+}
+
+// releaseLateReply undoes a successful reply that was dropped,
+// because the kernel got the timeout reply already. The kernel never
+// learns about the inodes and file handles the reply hands out, so
+// they are forgotten and released here, as the kernel would.
+func (ms *Server) releaseLateReply(req *request) {
+	if req.status != OK {
+		return
+	}
+	hdr := req.inHeader()
+	switch hdr.Opcode {
+	case _OP_LOOKUP, _OP_MKNOD, _OP_MKDIR, _OP_SYMLINK, _OP_LINK:
+		ms.forgetLateEntry((*EntryOut)(req.outData()))
+	case _OP_CREATE:
+		in := (*CreateIn)(req.inData())
+		out := (*CreateOut)(req.outData())
+		ms.fileSystem.Release(nil, &ReleaseIn{
+			InHeader: InHeader{NodeId: out.NodeId, Caller: hdr.Caller},
+			Fh:       out.Fh,
+			Flags:    in.Flags,
+		})
+		ms.forgetLateEntry(&out.EntryOut)
+	case _OP_OPEN:
+		in := (*OpenIn)(req.inData())
+		out := (*OpenOut)(req.outData())
+		ms.fileSystem.Release(nil, &ReleaseIn{
+			InHeader: InHeader{NodeId: hdr.NodeId, Caller: hdr.Caller},
+			Fh:       out.Fh,
+			Flags:    in.Flags,
+		})
+	case _OP_OPENDIR:
+		in := (*OpenIn)(req.inData())
+		out := (*OpenOut)(req.outData())
+		ms.fileSystem.ReleaseDir(&ReleaseIn{
+			InHeader: InHeader{NodeId: hdr.NodeId, Caller: hdr.Caller},
+			Fh:       out.Fh,
+			Flags:    in.Flags,
+		})
+	}
+}
+
+// forgetLateEntry forgets the lookup that a dropped reply added.
+func (ms *Server) forgetLateEntry(out *EntryOut) {
+	if out.NodeId != 0 {
+		ms.fileSystem.Forget(out.NodeId, 1)
+	}
+}
+
+// watchdog logs requests that take longer than
+// MountOptions.SlowRequestThreshold, until stop is closed.
+func (ms *Server) watchdog(stop <-chan struct{}) {
+	threshold := ms.opts.SlowRequestThreshold
+	ticker := time.NewTicker(max(threshold/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ms.logSlowRequests(threshold)
+		}
+	}
+}
+
+type slowRequest struct {
+	unique    uint64
+	op        uint32
+	nodeId    uint64
+	age       time.Duration
+	goroutine uint64
+}
+
+// logSlowRequests logs the stack of the goroutines handling requests
+// that are older than threshold. Each request is logged once.
+func (ms *Server) logSlowRequests(threshold time.Duration) {
+	now := time.Now()
+	var slow []slowRequest
+	ms.interruptMu.Lock()
+	for _, req := range ms.reqInflight {
+		age := now.Sub(req.arrival)
+		if age < threshold || req.slowLogged {
+			continue
+		}
+		req.slowLogged = true
+		hdr := req.inHeader()
+		slow = append(slow, slowRequest{hdr.Unique, hdr.Opcode, hdr.NodeId, age, req.goroutine})
+	}
+	ms.interruptMu.Unlock()
+	if len(slow) == 0 {
+		return
+	}
+
+	stacks := allStacks()
+	for _, s := range slow {
+		ms.opts.Logger.Printf("slow request %d: %s n%d running for %v\n%s",
+			s.unique, operationName(s.op), s.nodeId, s.age, goroutineStack(stacks, s.goroutine))
+	}
+}
+
+// allStacks returns the stack traces of all goroutines.
+func allStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineStack returns the trace for goroutine id from the output
+// of allStacks.
+func goroutineStack(stacks []byte, id uint64) []byte {
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, s := range bytes.Split(stacks, []byte("\n\n")) {
+		if bytes.HasPrefix(s, prefix) {
+			return s
+		}
+	}
+	return []byte("(goroutine not found)")
+}
+
+// handlerGoroutine returns the ID of the calling goroutine, which
+// runs request handlers, if the watchdog of
+// MountOptions.SlowRequestThreshold needs it. runtime.Stack is slow,
+// so a loop that runs the handlers itself calls it once, rather than
+// for every request.
+func (ms *Server) handlerGoroutine() uint64 {
+	if ms.opts.SlowRequestThreshold <= 0 {
+		return 0
+	}
+	return goroutineID()
+}
+
+// goroutineID returns the ID of the calling goroutine.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(b[:i]), 10, 64)
+		return id
+	}
+	return 0
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// stuckGetAttrFS blocks GETATTR until release is closed, and reports
+// what its context looked like when it was canceled.
+type stuckGetAttrFS struct {
+	contextFS
+	release chan struct{}
+	result  chan error
+}
+
+func (fs *stuckGetAttrFS) GetAttr(cancel <-chan struct{}, input *GetAttrIn, out *AttrOut) Status {
+	ctx := fs.srv.Context(cancel, &input.InHeader)
+	if _, ok := ctx.Deadline(); !ok {
+		fs.result <- nil
+	}
+	<-ctx.Done()
+	fs.result <- ctx.Err()
+	<-fs.release
+	return OK
+}
+
+func streamGetAttr(t *testing.T, unique uint64) []byte {
+	in := GetAttrIn{
+		InHeader: InHeader{
+			Opcode: _OP_GETATTR,
+			Unique: unique,
+			NodeId: FUSE_ROOT_ID,
+		},
+	}
+	in.Length = uint32(unsafe.Sizeof(in))
+	return structBytes(&in)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	fs := &stuckGetAttrFS{
+		release: make(chan struct{}),
+		result:  make(chan error, 2),
+	}
+	client, srv := startStreamServer(t, fs, &MountOptions{
+		OpcodeTimeouts: map[string]time.Duration{"GETATTR": 50 * time.Millisecond},
+		TimeoutErrno:   syscall.ETIMEDOUT,
+	})
+	go srv.Serve()
+
+	start := time.Now()
+	out, _ := streamRoundTrip(t, client, streamGetAttr(t, 2))
+	if got := Status(-out.Status); got != Status(syscall.ETIMEDOUT) {
+		t.Errorf("got status %v, want ETIMEDOUT", got)
+	}
+	if dt := time.Since(start); dt < 50*time.Millisecond {
+		t.Errorf("timed out after %v", dt)
+	}
+	if err := <-fs.result; err != context.DeadlineExceeded {
+		t.Errorf("got Err %v, want DeadlineExceeded", err)
+	}
+
+	// The late reply of the handler is dropped, so the next
+	// reply is for the next request.
+	close(fs.release)
+	out, _ = streamLookup(t, client, 3, "file")
+	if out.Status != 0 {
+		t.Errorf("LOOKUP: status %d", out.Status)
+	}
+
+	client.Close()
+	srv.Wait()
+}
+
+// forgetFS records FORGET calls.
+type forgetFS struct {
+	blockingLookupFS
+	forgets chan uint64
+}
+
+func (fs *forgetFS) Forget(nodeid, nlookup uint64) {
+	fs.forgets <- nodeid
+}
+
+func TestRequestTimeoutForget(t *testing.T) {
+	fs := &forgetFS{
+		blockingLookupFS: blockingLookupFS{
+			entered: make(chan struct{}),
+			release: make(chan struct{}),
+		},
+		forgets: make(chan uint64, 1),
+	}
+	client, srv := startStreamServer(t, fs, &MountOptions{
+		OpcodeTimeouts: map[string]time.Duration{"LOOKUP": 20 * time.Millisecond},
+		TimeoutErrno:   syscall.ETIMEDOUT,
+	})
+	go srv.Serve()
+
+	out, _ := streamLookup(t, client, 2, "file")
+	if got := Status(-out.Status); got != Status(syscall.ETIMEDOUT) {
+		t.Errorf("got status %v, want ETIMEDOUT", got)
+	}
+
+	// The kernel never saw the entry of the late reply, so it
+	// must be forgotten.
+	close(fs.release)
+	select {
+	case id := <-fs.forgets:
+		if id != 2 {
+			t.Errorf("got FORGET for n%d, want n2", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("late LOOKUP reply was not forgotten")
+	}
+
+	client.Close()
+	srv.Wait()
+}
+
+func TestRequestTimeoutOptions(t *testing.T) {
+	_, err := NewTransportServer(&readFS{}, nil, &MountOptions{
+		OpcodeTimeouts: map[string]time.Duration{"NOSUCHOP": time.Second},
+	})
+	if err == nil || !strings.Contains(err.Error(), "NOSUCHOP") {
+		t.Errorf("got %v, want error for unknown opcode", err)
+	}
+
+	for _, opts := range []*MountOptions{
+		{OpcodeTimeouts: map[string]time.Duration{"READDIRPLUS": time.Second}},
+		{RequestTimeout: time.Second, EnableIOUring: true},
+	} {
+		if _, err := requestTimeouts(opts); err == nil {
+			t.Errorf("%+v: no error", opts)
+		}
+	}
+
+	timeouts, err := requestTimeouts(&MountOptions{
+		RequestTimeout: time.Second,
+		OpcodeTimeouts: map[string]time.Duration{"READ": 0, "SETLKW": time.Minute},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for op, want := range map[uint32]time.Duration{
+		_OP_LOOKUP:      time.Second,
+		_OP_READ:        0,
+		_OP_READDIRPLUS: 0,
+		_OP_FORGET:      0,
+		_OP_SETLKW:      time.Minute,
+	} {
+		if got := timeouts[op]; got != want {
+			t.Errorf("%s: got %v, want %v", operationName(op), got, want)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer that can be written by a Logger while
+// the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSlowRequestWatchdog(t *testing.T) {
+	fs := &blockingLookupFS{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	var logs syncBuffer
+	client, srv := startStreamServer(t, fs, &MountOptions{
+		SlowRequestThreshold: 10 * time.Millisecond,
+		Logger:               log.New(&logs, "", 0),
+	})
+	go srv.Serve()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, _, err := streamExchange(client, lookupRequest(2, "file")); err != nil {
+			t.Errorf("LOOKUP: %v", err)
+		}
+	}()
+	<-fs.entered
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if strings.Contains(logs.String(), "slow request 2: LOOKUP") {
+			break
+		}
+	}
+	got := logs.String()
+	if !strings.Contains(got, "slow request 2: LOOKUP") {
+		t.Fatalf("no slow request logged: %q", got)
+	}
+	if !strings.Contains(got, "blockingLookupFS).Lookup") {
+		t.Errorf("log does not have the stack of the handler: %s", got)
+	}
+	if n := strings.Count(got, "slow request"); n != 1 {
+		t.Errorf("request logged %d times", n)
+	}
+
+	close(fs.release)
+	<-done
+	client.Close()
+	srv.Wait()
+}
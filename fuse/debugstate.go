@@ -0,0 +1,120 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ServerState is a snapshot of the internal state of a Server, for
+// debugging. It is meant to be marshaled to JSON.
+type ServerState struct {
+	// Protocol version negotiated with the kernel.
+	Major uint32
+	Minor uint32
+
+	// Capabilities offered by the kernel, and the ones we
+	// enabled in our reply to INIT.
+	KernelCapabilities []string
+	Capabilities       []string
+
+	MaxReadAhead  uint32
+	MaxWrite      uint32
+	MaxPages      uint16
+	MaxBackground uint16
+
+	// Readers is the number of goroutines reading requests from
+	// the channels.
+	Readers  int
+	Channels int
+	IOUring  bool
+
+	InFlight []InFlightRequest
+
+	// Buffers holds the number of buffers handed out by the
+	// buffer pools, by buffer size in bytes.
+	Buffers map[int]int
+
+	// SplicePairs is the number of pipe pairs in the splice
+	// pool, of which SplicePairsUsed are in use.
+	SplicePairs     int
+	SplicePairsUsed int
+}
+
+// InFlightRequest describes a request that has not been answered
+// yet.
+type InFlightRequest struct {
+	Unique      uint64
+	Op          string
+	NodeId      uint64
+	Caller      Caller
+	Age         time.Duration
+	Interrupted bool
+}
+
+// capabilityNames returns the names of the CAP_ flags in flags.
+func capabilityNames(flags uint64) []string {
+	s := flagString(initFlagNames, int64(flags), "")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// DebugState returns a snapshot of the state of the server: the
+// settings negotiated with the kernel, the requests in flight and
+// buffer usage. Unlike DebugData, it is meant for programs that
+// inspect the server, eg. to hunt for leaks in production.
+func (ms *Server) DebugState() *ServerState {
+	kernel := ms.kernelSettings
+	out := ms.initOut
+	st := &ServerState{
+		Major:              out.Major,
+		Minor:              out.Minor,
+		KernelCapabilities: capabilityNames(kernel.Flags64()),
+		Capabilities:       capabilityNames(out.Flags64()),
+		MaxReadAhead:       out.MaxReadAhead,
+		MaxWrite:           out.MaxWrite,
+		MaxPages:           out.MaxPages,
+		MaxBackground:      out.MaxBackground,
+		Channels:           len(ms.channels),
+		IOUring:            ms.usesIOUring(),
+		Buffers:            map[int]int{},
+	}
+
+	for _, ch := range ms.channels {
+		ch.reqMu.Lock()
+		st.Readers += ch.reqReaders
+		ch.reqMu.Unlock()
+		for pages, n := range ch.buffers.counters() {
+			if n != 0 {
+				st.Buffers[pages*pageSize] += n
+			}
+		}
+	}
+
+	now := time.Now()
+	ms.interruptMu.Lock()
+	for _, req := range ms.reqInflight {
+		hdr := req.inHeader()
+		st.InFlight = append(st.InFlight, InFlightRequest{
+			Unique:      hdr.Unique,
+			Op:          operationName(hdr.Opcode),
+			NodeId:      hdr.NodeId,
+			Caller:      hdr.Caller,
+			Age:         now.Sub(req.arrival),
+			Interrupted: req.interrupted,
+		})
+	}
+	ms.interruptMu.Unlock()
+	sort.Slice(st.InFlight, func(i, j int) bool {
+		return st.InFlight[i].Age > st.InFlight[j].Age
+	})
+
+	st.SplicePairs, st.SplicePairsUsed = splicePairs()
+	return st
+}
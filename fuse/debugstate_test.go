@@ -0,0 +1,62 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"testing"
+)
+
+// blockingLookupFS blocks LOOKUP until release is closed.
+type blockingLookupFS struct {
+	readFS
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (fs *blockingLookupFS) Lookup(cancel <-chan struct{}, header *InHeader, name string, out *EntryOut) Status {
+	close(fs.entered)
+	<-fs.release
+	return fs.readFS.Lookup(cancel, header, name, out)
+}
+
+func TestDebugState(t *testing.T) {
+	fs := &blockingLookupFS{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	client, srv := startStreamServer(t, fs, nil)
+	go srv.Serve()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		out, _, err := streamExchange(client, lookupRequest(2, "file"))
+		if err != nil {
+			t.Errorf("LOOKUP: %v", err)
+		} else if out.Status != 0 {
+			t.Errorf("LOOKUP: status %d", out.Status)
+		}
+	}()
+	<-fs.entered
+
+	st := srv.DebugState()
+	if st.Major != _FUSE_KERNEL_VERSION || st.Minor != _OUR_MINOR_VERSION {
+		t.Errorf("got version %d.%d", st.Major, st.Minor)
+	}
+	if len(st.KernelCapabilities) != 0 {
+		t.Errorf("got kernel capabilities %v, want none", st.KernelCapabilities)
+	}
+	if len(st.InFlight) != 1 {
+		t.Fatalf("got in-flight requests %+v, want 1", st.InFlight)
+	}
+	if req := st.InFlight[0]; req.Unique != 2 || req.Op != "LOOKUP" || req.NodeId != FUSE_ROOT_ID || req.Age <= 0 {
+		t.Errorf("got %+v", req)
+	}
+
+	close(fs.release)
+	<-done
+	client.Close()
+	srv.Wait()
+}
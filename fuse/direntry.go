@@ -72,6 +72,9 @@ type DirEntryList struct {
 
 	// pointer to the last serialized _Dirent. Used by FixMode().
 	lastDirent *_Dirent
+
+	// plus is set if the entries are prefixed with an EntryOut.
+	plus bool
 }
 
 // NewDirEntryList creates a DirEntryList with the given data buffer
@@ -159,6 +162,7 @@ func (l *DirEntryList) AddDirLookupEntry(e DirEntry) *EntryOut {
 	if !ok {
 		return nil
 	}
+	l.plus = true
 	l.lastDirent = (*_Dirent)(unsafe.Pointer(&l.buf[oldLen+entryOutSize]))
 	entryOut := (*EntryOut)(unsafe.Pointer(&l.buf[oldLen]))
 	*entryOut = EntryOut{}
@@ -179,6 +183,29 @@ func (l *DirEntryList) FixMode(mode uint32) {
 	l.lastDirent.Typ = modeToType(mode)
 }
 
+// Entries decodes the entries that were added to the list. The Mode
+// of the result only has the file type bits. It is meant for code that
+// calls a RawFileSystem directly, like the kernel would.
+func (l *DirEntryList) Entries() []DirEntry {
+	const entryOutSize = int(unsafe.Sizeof(EntryOut{}))
+	var result []DirEntry
+	for data := l.buf; len(data) > 0; {
+		if l.plus {
+			data = data[entryOutSize:]
+		}
+		d := (*_Dirent)(unsafe.Pointer(&data[0]))
+		sz := direntSize + int(d.NameLen)
+		result = append(result, DirEntry{
+			Name: string(data[direntSize:sz]),
+			Ino:  d.Ino,
+			Off:  d.Off,
+			Mode: d.Typ << 12,
+		})
+		data = data[min((sz+7)&^7, len(data)):]
+	}
+	return result
+}
+
 func (l *DirEntryList) bytes() []byte {
 	return l.buf
 }
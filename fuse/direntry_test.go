@@ -0,0 +1,31 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDirEntryListEntries(t *testing.T) {
+	want := []DirEntry{
+		{Name: "a", Ino: 10, Off: 6, Mode: S_IFREG},
+		{Name: "directory", Ino: 11, Off: 7, Mode: S_IFDIR},
+		{Name: "12345678", Ino: 12, Off: 8, Mode: S_IFLNK},
+	}
+	for _, plus := range []bool{false, true} {
+		l := NewDirEntryList(make([]byte, 4096), 5)
+		for _, e := range want {
+			if plus {
+				l.AddDirLookupEntry(e)
+			} else {
+				l.AddDirEntry(e)
+			}
+		}
+		if got := l.Entries(); !reflect.DeepEqual(got, want) {
+			t.Errorf("plus=%v: got %v, want %v", plus, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"unsafe"
+)
+
+// Request extensions are appended to CREATE, MKDIR, MKNOD, SYMLINK
+// and TMPFILE requests, after the file names. Each starts with an
+// extHeader. Types up to _FUSE_MAX_NR_SECCTX are a secctxHeader,
+// whose NrSecctx field doubles as the type.
+const (
+	_FUSE_MAX_NR_SECCTX = 31
+	_FUSE_EXT_GROUPS    = 32
+)
+
+type extHeader struct {
+	Size uint32
+	Type uint32
+}
+
+type secctxHeader struct {
+	Size     uint32
+	NrSecctx uint32
+}
+
+// secctx is followed by the NUL-terminated xattr name, and Size
+// bytes of context.
+type secctx struct {
+	Size    uint32
+	Padding uint32
+}
+
+// SecurityContext is the security label that a Linux security module
+// (eg. SELinux or Smack) computed for a new inode. It is sent with
+// requests that create inodes if MountOptions.EnableSecurityContext
+// is set.
+type SecurityContext struct {
+	// Name is the name of the xattr that holds the label,
+	// eg. "security.selinux".
+	Name  string
+	Value []byte
+}
+
+type securityContextKeyType struct{}
+
+var securityContextKey securityContextKeyType
+
+// SecurityContextFromContext returns the security contexts for the
+// inode created by the request that ctx belongs to. File systems
+// that store xattrs should set them on the new inode before
+// returning it to the kernel, so it is never visible without its
+// label.
+func SecurityContextFromContext(ctx context.Context) ([]SecurityContext, bool) {
+	v, ok := ctx.Value(securityContextKey).([]SecurityContext)
+	return v, ok
+}
+
+// WithSecurityContext returns a context carrying secctx, for
+// SecurityContextFromContext. It is useful for testing file systems.
+func WithSecurityContext(ctx context.Context, secctx []SecurityContext) context.Context {
+	return context.WithValue(ctx, securityContextKey, secctx)
+}
+
+type createGroupsKeyType struct{}
+
+var createGroupsKey createGroupsKeyType
+
+// CreateGroupsFromContext returns the supplementary groups that the
+// kernel sent with a request that creates an inode. The kernel only
+// sends the group of the parent directory, if the caller is a member
+// of it through its supplementary groups. A file system can use it
+// to decide the group of the new inode, without looking up the
+// groups of the caller (see Caller.Groups).
+func CreateGroupsFromContext(ctx context.Context) ([]uint32, bool) {
+	v, ok := ctx.Value(createGroupsKey).([]uint32)
+	return v, ok
+}
+
+// parseExtensions splits the extensions off r.inPayload, and parses
+// the ones we know.
+func (r *request) parseExtensions() Status {
+	n := int(r.inHeader().TotalExtlen) * 8
+	if n == 0 {
+		return OK
+	}
+	if n > len(r.inPayload) {
+		return EIO
+	}
+	ext := r.inPayload[len(r.inPayload)-n:]
+	r.inPayload = r.inPayload[:len(r.inPayload)-n]
+	for len(ext) > 0 {
+		if len(ext) < int(unsafe.Sizeof(extHeader{})) {
+			return EIO
+		}
+		hdr := (*extHeader)(unsafe.Pointer(&ext[0]))
+		size := int(hdr.Size)
+		if size < int(unsafe.Sizeof(extHeader{})) || size > len(ext) {
+			return EIO
+		}
+		switch {
+		case hdr.Type <= _FUSE_MAX_NR_SECCTX:
+			sc, ok := parseSecurityContexts(ext[:size])
+			if !ok {
+				return EIO
+			}
+			r.secctx = sc
+		case hdr.Type == _FUSE_EXT_GROUPS:
+			groups, ok := parseGroups(ext[unsafe.Sizeof(extHeader{}):size])
+			if !ok {
+				return EIO
+			}
+			r.createGroups = groups
+		}
+		// Unknown extensions are skipped.
+		ext = ext[size:]
+	}
+	return OK
+}
+
+// parseSecurityContexts parses a secctxHeader and the contexts
+// following it.
+func parseSecurityContexts(data []byte) ([]SecurityContext, bool) {
+	hdr := (*secctxHeader)(unsafe.Pointer(&data[0]))
+	data = data[unsafe.Sizeof(secctxHeader{}):]
+
+	var result []SecurityContext
+	for i := 0; i < int(hdr.NrSecctx); i++ {
+		if len(data) < int(unsafe.Sizeof(secctx{})) {
+			return nil, false
+		}
+		ctxSize := int((*secctx)(unsafe.Pointer(&data[0])).Size)
+		rec := data[unsafe.Sizeof(secctx{}):]
+		nul := bytes.IndexByte(rec, 0)
+		if nul < 0 || nul+1+ctxSize > len(rec) {
+			return nil, false
+		}
+		result = append(result, SecurityContext{
+			Name:  string(rec[:nul]),
+			Value: bytes.Clone(rec[nul+1 : nul+1+ctxSize]),
+		})
+
+		// Each record is padded to 8 bytes.
+		recSize := int(unsafe.Sizeof(secctx{})) + nul + 1 + ctxSize
+		recSize = (recSize + 7) &^ 7
+		if recSize > len(data) {
+			recSize = len(data)
+		}
+		data = data[recSize:]
+	}
+	return result, true
+}
+
+// parseGroups parses a fuse_supp_groups struct: a count, followed by
+// the group IDs.
+func parseGroups(data []byte) ([]uint32, bool) {
+	if len(data) < 4 {
+		return nil, false
+	}
+	n := int(*(*uint32)(unsafe.Pointer(&data[0])))
+	data = data[4:]
+	if n > len(data)/4 {
+		return nil, false
+	}
+	groups := make([]uint32, n)
+	copy(groups, unsafe.Slice((*uint32)(unsafe.Pointer(&data[0])), n))
+	return groups, true
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+// secctxFS records the name and security contexts of MKDIR.
+type secctxFS struct {
+	contextFS
+	name   string
+	secctx []SecurityContext
+	groups []uint32
+}
+
+func (fs *secctxFS) Mkdir(cancel <-chan struct{}, input *MkdirIn, name string, out *EntryOut) Status {
+	fs.name = name
+	ctx := fs.srv.Context(cancel, &input.InHeader)
+	fs.secctx, _ = SecurityContextFromContext(ctx)
+	fs.groups, _ = CreateGroupsFromContext(ctx)
+	out.NodeId = 3
+	out.Mode = S_IFDIR | 0755
+	return OK
+}
+
+// secctxExtension encodes a security context extension.
+func secctxExtension(name string, value []byte) []byte {
+	rec := structBytes(&secctx{Size: uint32(len(value))})
+	rec = append(rec, name...)
+	rec = append(rec, 0)
+	rec = append(rec, value...)
+	for len(rec)%8 != 0 {
+		rec = append(rec, 0)
+	}
+	hdr := secctxHeader{
+		Size:     uint32(unsafe.Sizeof(secctxHeader{})) + uint32(len(rec)),
+		NrSecctx: 1,
+	}
+	return append(structBytes(&hdr), rec...)
+}
+
+func TestSecurityContext(t *testing.T) {
+	fs := &secctxFS{}
+	client, srv := startStreamServer(t, fs, nil)
+	go srv.Serve()
+
+	label := []byte("system_u:object_r:tmp_t:s0\x00")
+	ext := secctxExtension("security.selinux", label)
+	// An unknown extension, which should be skipped.
+	ext = append(ext, structBytes(&extHeader{Size: 16, Type: 99})...)
+	ext = append(ext, make([]byte, 8)...)
+	// One group: the count, and the group ID.
+	groups := [2]uint32{1, 42}
+	ext = append(ext, structBytes(&extHeader{Size: 16, Type: _FUSE_EXT_GROUPS})...)
+	ext = append(ext, structBytes(&groups)...)
+
+	in := MkdirIn{
+		InHeader: InHeader{
+			Opcode:      _OP_MKDIR,
+			Unique:      2,
+			NodeId:      FUSE_ROOT_ID,
+			TotalExtlen: uint16(len(ext) / 8),
+		},
+		Mode: 0755,
+	}
+	req := append(structBytes(&in), "dir\x00"...)
+	req = append(req, ext...)
+	(*InHeader)(unsafe.Pointer(&req[0])).Length = uint32(len(req))
+
+	if out, _ := streamRoundTrip(t, client, req); out.Status != 0 {
+		t.Fatalf("MKDIR: status %d", out.Status)
+	}
+	if fs.name != "dir" {
+		t.Errorf("got name %q, want %q", fs.name, "dir")
+	}
+	if len(fs.secctx) != 1 || fs.secctx[0].Name != "security.selinux" || !bytes.Equal(fs.secctx[0].Value, label) {
+		t.Errorf("got security contexts %v", fs.secctx)
+	}
+	if len(fs.groups) != 1 || fs.groups[0] != 42 {
+		t.Errorf("got groups %v, want [42]", fs.groups)
+	}
+
+	// A truncated extension is an error.
+	in.Unique = 3
+	in.TotalExtlen = 100
+	req = append(structBytes(&in), "dir\x00"...)
+	(*InHeader)(unsafe.Pointer(&req[0])).Length = uint32(len(req))
+	if out, _ := streamRoundTrip(t, client, req); out.Status != -int32(EIO) {
+		t.Errorf("MKDIR with bad extension: status %d, want EIO", out.Status)
+	}
+
+	client.Close()
+	srv.Wait()
+	if n := srv.contexts.Load(); n != 0 {
+		t.Errorf("%d request contexts leaked", n)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// MountError is returned if mounting with the new mount API
+// (fsopen, fsconfig, fsmount and move_mount) fails. The kernel
+// explains why fsconfig and fsmount fail in a log attached to the
+// file system context; these messages are in Messages.
+type MountError struct {
+	// Op is the failing system call.
+	Op string
+
+	// Param is the fsconfig parameter that was rejected, if any.
+	Param string
+
+	// Err is the error number.
+	Err error
+
+	// Messages holds the kernel log, eg. "e fuse: Invalid rootmode".
+	// The first letter is the severity: e(rror), w(arning) or
+	// i(nfo).
+	Messages []string
+}
+
+func (e *MountError) Error() string {
+	s := e.Op
+	if e.Param != "" {
+		s += " " + e.Param
+	}
+	s += ": " + e.Err.Error()
+	if len(e.Messages) > 0 {
+		s += " (" + strings.Join(e.Messages, "; ") + ")"
+	}
+	return s
+}
+
+func (e *MountError) Unwrap() error {
+	return e.Err
+}
+
+// errNoMountAPI is returned by fsMount if the new mount API cannot be
+// used, so mount(2) should be tried instead.
+var errNoMountAPI = errors.New("new mount API not available")
+
+// fsContextError reads the log of a file system context.
+func fsContextError(fsfd int, op, param string, err error) *MountError {
+	me := &MountError{Op: op, Param: param, Err: err}
+	buf := make([]byte, 4096)
+	for {
+		n, err := syscall.Read(fsfd, buf)
+		if err != nil || n <= 0 {
+			break
+		}
+		me.Messages = append(me.Messages, strings.TrimRight(string(buf[:n]), "\n"))
+	}
+	return me
+}
+
+// Translation of mount(2) flags to the new mount API. MS_RDONLY
+// applies to both the superblock and the mount.
+var (
+	sbFlagParams = map[uintptr]string{
+		syscall.MS_RDONLY:      "ro",
+		syscall.MS_SYNCHRONOUS: "sync",
+		syscall.MS_DIRSYNC:     "dirsync",
+		unix.MS_LAZYTIME:       "lazytime",
+	}
+	mountAttrFlags = map[uintptr]int{
+		syscall.MS_RDONLY:      unix.MOUNT_ATTR_RDONLY,
+		syscall.MS_NOSUID:      unix.MOUNT_ATTR_NOSUID,
+		syscall.MS_NODEV:       unix.MOUNT_ATTR_NODEV,
+		syscall.MS_NOEXEC:      unix.MOUNT_ATTR_NOEXEC,
+		syscall.MS_NOATIME:     unix.MOUNT_ATTR_NOATIME,
+		syscall.MS_NODIRATIME:  unix.MOUNT_ATTR_NODIRATIME,
+		syscall.MS_STRICTATIME: unix.MOUNT_ATTR_STRICTATIME,
+		syscall.MS_RELATIME:    unix.MOUNT_ATTR_RELATIME,
+	}
+	// Flags that are meaningless here.
+	ignoredMountFlags = uintptr(syscall.MS_MGC_MSK | syscall.MS_SILENT)
+)
+
+// fsMount creates a detached mount for the FUSE connection with the
+// new mount API (Linux 5.2 and later), and returns a file descriptor
+// for it. It returns errNoMountAPI if the kernel does not provide
+// the API, or if the flags cannot be expressed with it.
+func fsMount(source string, flags uintptr, options []string, opts *MountOptions) (int, error) {
+	var sbParams []string
+	attrs := 0
+	for f := uintptr(1); f != 0; f <<= 1 {
+		if flags&f == 0 || ignoredMountFlags&f != 0 {
+			continue
+		}
+		p, isSB := sbFlagParams[f]
+		a, isAttr := mountAttrFlags[f]
+		if !isSB && !isAttr {
+			return -1, errNoMountAPI
+		}
+		if isSB {
+			sbParams = append(sbParams, p)
+		}
+		attrs |= a
+	}
+
+	fsfd, err := unix.Fsopen("fuse", unix.FSOPEN_CLOEXEC)
+	if err == syscall.ENOSYS || err == syscall.EPERM {
+		// EPERM is what seccomp filters commonly return for
+		// unknown syscalls.
+		if opts.Debug {
+			opts.Logger.Printf("fsMount: fsopen: %v, falling back to mount(2)", err)
+		}
+		return -1, errNoMountAPI
+	} else if err != nil {
+		return -1, &MountError{Op: "fsopen", Err: err}
+	}
+	defer syscall.Close(fsfd)
+
+	set := func(param string) error {
+		key, val, hasVal := strings.Cut(param, "=")
+		var err error
+		if hasVal {
+			err = unix.FsconfigSetString(fsfd, key, val)
+		} else {
+			err = unix.FsconfigSetFlag(fsfd, key)
+		}
+		if err != nil {
+			return fsContextError(fsfd, "fsconfig", key, err)
+		}
+		return nil
+	}
+
+	params := []string{"source=" + source}
+	if opts.Name != "" {
+		params = append(params, "subtype="+opts.Name)
+	}
+	params = append(params, options...)
+	params = append(params, sbParams...)
+	if opts.Debug {
+		opts.Logger.Printf("fsMount: fsconfig %q, mount attributes %#x", params, attrs)
+	}
+	for _, p := range params {
+		if err := set(p); err != nil {
+			return -1, err
+		}
+	}
+	if err := unix.FsconfigCreate(fsfd); err != nil {
+		return -1, fsContextError(fsfd, "fsconfig", "create", err)
+	}
+	mfd, err := unix.Fsmount(fsfd, unix.FSMOUNT_CLOEXEC, attrs)
+	if err != nil {
+		return -1, fsContextError(fsfd, "fsmount", "", err)
+	}
+	return mfd, nil
+}
+
+// moveMount attaches the detached mount mfd to mountPoint.
+func moveMount(mfd int, mountPoint string) error {
+	if err := unix.MoveMount(mfd, "", unix.AT_FDCWD, mountPoint, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return &MountError{Op: "move_mount", Err: err}
+	}
+	return nil
+}
+
+// inMountNamespace runs fn in the mount namespace nsFd, or in the
+// current one if nsFd is 0. Setting the namespace needs a thread that
+// does not share its filesystem attributes, so fn runs on a locked
+// thread that is discarded afterwards.
+func inMountNamespace(nsFd int, fn func() error) error {
+	if nsFd == 0 {
+		return fn()
+	}
+	errc := make(chan error, 1)
+	go func() {
+		// We never unlock the thread, so the runtime
+		// terminates it when the goroutine exits.
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_FS); err != nil {
+			errc <- os.NewSyscallError("unshare", err)
+			return
+		}
+		if err := unix.Setns(nsFd, unix.CLONE_NEWNS); err != nil {
+			errc <- os.NewSyscallError("setns", err)
+			return
+		}
+		errc <- fn()
+	}()
+	return <-errc
+}
+
+// mountDetached creates a mount that is not attached anywhere yet, and
+// returns the FUSE connection and the mount file descriptor.
+func mountDetached(opts *MountOptions, ready chan<- error) (fd int, mfd int, err error) {
+	fd, err = syscall.Open("/dev/fuse", os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, -1, err
+	}
+	source, flags := directMountSource(opts)
+	mfd, err = fsMount(source, flags, directMountOptions(fd, syscall.S_IFDIR, opts), opts)
+	if err == errNoMountAPI {
+		err = fmt.Errorf("DetachedMount: %w", syscall.ENOSYS)
+	}
+	if err != nil {
+		syscall.Close(fd)
+		return -1, -1, err
+	}
+	close(ready)
+	return fd, mfd, nil
+}
+
+// AttachMount attaches a mount created with MountOptions.DetachedMount
+// to mountPoint. If MountOptions.MountNamespaceFd is set, mountPoint is
+// resolved in that mount namespace. Call WaitMount afterwards if this
+// process will access the mount itself.
+func (ms *Server) AttachMount(mountPoint string) error {
+	if ms.detachedFd < 0 {
+		return syscall.EINVAL
+	}
+	if !filepath.IsAbs(mountPoint) && ms.opts.MountNamespaceFd == 0 {
+		abs, err := filepath.Abs(mountPoint)
+		if err != nil {
+			return err
+		}
+		mountPoint = abs
+	}
+	err := inMountNamespace(ms.opts.MountNamespaceFd, func() error {
+		return moveMount(ms.detachedFd, mountPoint)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The mount is busy while we hold the descriptor, which would
+	// make unmounting fail.
+	syscall.Close(ms.detachedFd)
+	ms.detachedFd = -1
+	ms.mountPoint = mountPoint
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// groupCacheTTL is how long the groups of a process are cached.
+// setgroups(2) does not change anything we can check cheaply, so
+// changes take this long to be noticed.
+const groupCacheTTL = 5 * time.Second
+
+// maxGroupCacheEntries bounds the size of the cache.
+const maxGroupCacheEntries = 1024
+
+type groupCacheEntry struct {
+	// startTime of the process, to detect PID reuse.
+	startTime uint64
+	owner     Owner
+	groups    []uint32
+	expires   time.Time
+}
+
+var groupCache struct {
+	mu      sync.Mutex
+	entries map[uint32]*groupCacheEntry
+}
+
+// Groups returns the supplementary groups of the calling process,
+// read from /proc/PID/status. The result is cached; the cache entry
+// is dropped if the PID is reused by another process, or if the
+// process changed its UID or GID. The returned slice must not be
+// modified.
+//
+// This fails if the request was not issued by a process (Pid is 0),
+// the process has exited, or the file system runs in a different
+// PID namespace than the mount.
+func (c *Caller) Groups() ([]uint32, error) {
+	if c.Pid == 0 {
+		return nil, syscall.ESRCH
+	}
+	start, err := procStartTime(c.Pid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	groupCache.mu.Lock()
+	e := groupCache.entries[c.Pid]
+	groupCache.mu.Unlock()
+	if e != nil && e.startTime == start && e.owner == c.Owner && now.Before(e.expires) {
+		return e.groups, nil
+	}
+
+	groups, err := procGroups(c.Pid)
+	if err != nil {
+		return nil, err
+	}
+	// The process may have exited, and its PID reused, while we
+	// read its status.
+	if again, err := procStartTime(c.Pid); err != nil {
+		return nil, err
+	} else if again != start {
+		return nil, syscall.ESRCH
+	}
+
+	e = &groupCacheEntry{
+		startTime: start,
+		owner:     c.Owner,
+		groups:    groups,
+		expires:   now.Add(groupCacheTTL),
+	}
+	groupCache.mu.Lock()
+	defer groupCache.mu.Unlock()
+	if len(groupCache.entries) >= maxGroupCacheEntries {
+		for pid, old := range groupCache.entries {
+			if now.After(old.expires) {
+				delete(groupCache.entries, pid)
+			}
+		}
+	}
+	if groupCache.entries == nil || len(groupCache.entries) >= maxGroupCacheEntries {
+		groupCache.entries = map[uint32]*groupCacheEntry{}
+	}
+	groupCache.entries[c.Pid] = e
+	return groups, nil
+}
+
+// procStartTime returns the start time of the process, in clock
+// ticks after boot.
+func procStartTime(pid uint32) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces and parentheses, so
+	// skip to after its last ')'. The start time is field 22;
+	// the fields after the name start at 3.
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("/proc/%d/stat: malformed", pid)
+	}
+	fields := bytes.Fields(data[i+1:])
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("/proc/%d/stat: malformed", pid)
+	}
+	return strconv.ParseUint(string(fields[19]), 10, 64)
+}
+
+// procGroups returns the supplementary groups from
+// /proc/PID/status.
+func procGroups(pid uint32) ([]uint32, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		rest, ok := bytes.CutPrefix(line, []byte("Groups:"))
+		if !ok {
+			continue
+		}
+		groups := []uint32{}
+		for _, f := range bytes.Fields(rest) {
+			g, err := strconv.ParseUint(string(f), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("/proc/%d/status: %v", pid, err)
+			}
+			groups = append(groups, uint32(g))
+		}
+		return groups, nil
+	}
+	return nil, fmt.Errorf("/proc/%d/status: no Groups", pid)
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestCallerGroups(t *testing.T) {
+	want, err := os.Getgroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Caller{
+		Owner: Owner{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())},
+		Pid:   uint32(os.Getpid()),
+	}
+	check := func() {
+		t.Helper()
+		got, err := c.Groups()
+		if err != nil {
+			t.Fatalf("Groups: %v", err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for _, g := range want {
+			if !slices.Contains(got, uint32(g)) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		}
+	}
+	check()
+
+	// Simulate a process that used to have our PID.
+	groupCache.mu.Lock()
+	groupCache.entries[c.Pid].startTime--
+	groupCache.entries[c.Pid].groups = []uint32{12345}
+	groupCache.mu.Unlock()
+	check()
+
+	if _, err := (&Caller{}).Groups(); err == nil {
+		t.Error("Groups succeeded for pid 0")
+	}
+}
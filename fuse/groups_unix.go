@@ -0,0 +1,15 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import "syscall"
+
+// Groups returns the supplementary groups of the calling process.
+// It is only implemented on Linux.
+func (c *Caller) Groups() ([]uint32, error) {
+	return nil, syscall.ENOSYS
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"sync"
+)
+
+// InvalidatorOptions configures an Invalidator.
+type InvalidatorOptions struct {
+	// MaxPending bounds the number of distinct invalidations
+	// waiting to be sent. When it is reached, queueing blocks
+	// until the kernel has caught up. The default is 4096.
+	MaxPending int
+}
+
+// InvalidatorStats counts the invalidations of an Invalidator.
+type InvalidatorStats struct {
+	// Queued is the number of invalidations queued; Coalesced
+	// of those were merged with one that was already pending.
+	Queued    uint64
+	Coalesced uint64
+
+	// Sent is the number of notifications written to the
+	// kernel. Errors counts failures, except ENOENT, which means
+	// the kernel has forgotten the node or entry.
+	Sent   uint64
+	Errors uint64
+}
+
+type invalKey struct {
+	// entries are keyed by parent and name, inodes by node.
+	entry bool
+	node  uint64
+	name  string
+}
+
+type invalOp struct {
+	// For entries: expire rather than invalidate, or send a
+	// delete notification for child.
+	expireOnly bool
+	delete     bool
+	child      uint64
+
+	// For inodes: the range of data to invalidate. off < 0
+	// means only the attributes, end < 0 means up to EOF.
+	off, end int64
+}
+
+// merge combines a pending invalidation o with a newer one.
+func (o *invalOp) merge(n *invalOp) {
+	o.expireOnly = o.expireOnly && n.expireOnly
+	if n.delete {
+		o.delete = true
+		o.child = n.child
+	}
+
+	if n.off < 0 {
+		return
+	}
+	if o.off < 0 {
+		o.off, o.end = n.off, n.end
+		return
+	}
+	o.off = min(o.off, n.off)
+	if o.end < 0 || n.end < 0 {
+		o.end = -1
+	} else {
+		o.end = max(o.end, n.end)
+	}
+}
+
+// Invalidator sends cache invalidations from a background goroutine.
+// Invalidations for the same entry or inode that have not been sent
+// yet are merged, so a burst of changes in the backing store, eg.
+// seen by a file system watcher, results in one notification per
+// entry or inode. If the kernel is slow to accept the notifications,
+// queueing blocks once InvalidatorOptions.MaxPending is reached.
+//
+// Queueing may block, so do not use an Invalidator from FUSE request
+// handlers, for the same reasons as Server.EntryNotify.
+type Invalidator struct {
+	server     *Server
+	maxPending int
+
+	mu   sync.Mutex
+	cond sync.Cond
+
+	pending map[invalKey]*invalOp
+	order   []invalKey
+
+	// sending is set while the sender is writing a batch.
+	sending bool
+	closed  bool
+	stats   InvalidatorStats
+	done    chan struct{}
+}
+
+// NewInvalidator starts an Invalidator for the server. Call Close
+// to stop it.
+func (ms *Server) NewInvalidator(opts *InvalidatorOptions) *Invalidator {
+	iv := &Invalidator{
+		server:     ms,
+		maxPending: 4096,
+		pending:    map[invalKey]*invalOp{},
+		done:       make(chan struct{}),
+	}
+	if opts != nil && opts.MaxPending > 0 {
+		iv.maxPending = opts.MaxPending
+	}
+	iv.cond.L = &iv.mu
+	go iv.loop()
+	return iv
+}
+
+// Entry queues an invalidation of an entry, like
+// Server.EntryNotify.
+func (iv *Invalidator) Entry(parent uint64, name string) {
+	iv.queue(invalKey{true, parent, name}, &invalOp{off: -1})
+}
+
+// ExpireEntry queues marking an entry as expired, like
+// Server.EntryNotifyExpire. If the kernel does not support this, the
+// entry is invalidated instead.
+func (iv *Invalidator) ExpireEntry(parent uint64, name string) {
+	iv.queue(invalKey{true, parent, name}, &invalOp{expireOnly: true, off: -1})
+}
+
+// Delete queues a delete notification, like Server.DeleteNotify.
+func (iv *Invalidator) Delete(parent uint64, child uint64, name string) {
+	iv.queue(invalKey{true, parent, name}, &invalOp{delete: true, child: child, off: -1})
+}
+
+// Inode queues an invalidation of the attributes and the data cache
+// of an inode, like Server.InodeNotify. Pending invalidations for
+// the same inode are merged into one covering all of their ranges.
+func (iv *Invalidator) Inode(node uint64, off int64, length int64) {
+	end := int64(-1)
+	if off >= 0 && length > 0 {
+		end = off + length
+	}
+	iv.queue(invalKey{false, node, ""}, &invalOp{off: off, end: end})
+}
+
+// ExpireInode queues marking the attributes of an inode as expired,
+// like Server.InodeNotifyExpire.
+func (iv *Invalidator) ExpireInode(node uint64) {
+	iv.queue(invalKey{false, node, ""}, &invalOp{off: -1})
+}
+
+func (iv *Invalidator) queue(key invalKey, op *invalOp) {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	if iv.closed {
+		return
+	}
+	iv.stats.Queued++
+	if old := iv.pending[key]; old != nil {
+		old.merge(op)
+		iv.stats.Coalesced++
+		return
+	}
+	for len(iv.pending) >= iv.maxPending && !iv.closed {
+		iv.cond.Wait()
+	}
+	if iv.closed {
+		return
+	}
+	iv.pending[key] = op
+	iv.order = append(iv.order, key)
+	iv.cond.Broadcast()
+}
+
+// Flush waits until all invalidations queued so far are sent.
+func (iv *Invalidator) Flush() {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	for (len(iv.order) > 0 || iv.sending) && !iv.closed {
+		iv.cond.Wait()
+	}
+}
+
+// Close sends the pending invalidations, and stops the Invalidator.
+// Invalidations queued after Close are dropped.
+func (iv *Invalidator) Close() {
+	iv.Flush()
+	iv.mu.Lock()
+	iv.closed = true
+	iv.cond.Broadcast()
+	iv.mu.Unlock()
+	<-iv.done
+}
+
+// Stats returns counters for the invalidations so far.
+func (iv *Invalidator) Stats() InvalidatorStats {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	return iv.stats
+}
+
+func (iv *Invalidator) loop() {
+	defer close(iv.done)
+	var keys []invalKey
+	var ops []*invalOp
+	for {
+		iv.mu.Lock()
+		for len(iv.order) == 0 && !iv.closed {
+			iv.cond.Wait()
+		}
+		if iv.closed {
+			iv.mu.Unlock()
+			return
+		}
+		keys, iv.order = iv.order, keys[:0]
+		ops = ops[:0]
+		for _, k := range keys {
+			ops = append(ops, iv.pending[k])
+			delete(iv.pending, k)
+		}
+		iv.sending = true
+		// There is room in the queue again.
+		iv.cond.Broadcast()
+		iv.mu.Unlock()
+
+		var sent, errors uint64
+		for i, k := range keys {
+			if st := iv.send(k, ops[i]); st != OK && st != ENOENT {
+				errors++
+			}
+			sent++
+		}
+
+		iv.mu.Lock()
+		iv.stats.Sent += sent
+		iv.stats.Errors += errors
+		iv.sending = false
+		iv.cond.Broadcast()
+		iv.mu.Unlock()
+	}
+}
+
+func (iv *Invalidator) send(k invalKey, op *invalOp) Status {
+	ms := iv.server
+	switch {
+	case k.entry && op.delete:
+		return ms.DeleteNotify(k.node, op.child, k.name)
+	case k.entry && op.expireOnly:
+		if st := ms.EntryNotifyExpire(k.node, k.name); st != ENOSYS {
+			return st
+		}
+		return ms.EntryNotify(k.node, k.name)
+	case k.entry:
+		return ms.EntryNotify(k.node, k.name)
+	case op.off < 0:
+		return ms.InodeNotifyExpire(k.node)
+	case op.end < 0:
+		return ms.InodeNotify(k.node, op.off, 0)
+	default:
+		return ms.InodeNotify(k.node, op.off, op.end-op.off)
+	}
+}
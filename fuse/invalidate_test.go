@@ -0,0 +1,118 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// readNotify reads a notification from the kernel side of a stream
+// transport.
+func readNotify(t *testing.T, conn net.Conn) (*OutHeader, []byte) {
+	t.Helper()
+	var out OutHeader
+	if _, err := io.ReadFull(conn, structBytes(&out)); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	data := make([]byte, int(out.Length)-int(unsafe.Sizeof(out)))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	return &out, data
+}
+
+func readEntryNotify(t *testing.T, conn net.Conn) (*NotifyInvalEntryOut, string) {
+	t.Helper()
+	out, data := readNotify(t, conn)
+	if Status(-out.Status) != NOTIFY_INVAL_ENTRY {
+		t.Fatalf("got notify %d, want INVAL_ENTRY", out.Status)
+	}
+	entry := (*NotifyInvalEntryOut)(unsafe.Pointer(&data[0]))
+	name := data[unsafe.Sizeof(*entry):]
+	return entry, string(name[:entry.NameLen])
+}
+
+func TestInvalidator(t *testing.T) {
+	client, srv := startStreamServer(t, &readFS{}, nil)
+	srv.kernelSettings.Flags2 |= uint32(CAP_HAS_EXPIRE_ONLY >> 32)
+	go srv.Serve()
+
+	iv := srv.NewInvalidator(&InvalidatorOptions{MaxPending: 3})
+	// The first notification blocks the sender, until we read
+	// it.
+	iv.Entry(1, "first")
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		iv.ExpireEntry(1, "expire")
+		iv.Inode(2, int64(i)*10, 10)
+	}
+	iv.Entry(1, "entry")
+	iv.ExpireEntry(1, "entry")
+
+	blocked := make(chan struct{})
+	go func() {
+		iv.Entry(1, "blocked")
+		close(blocked)
+	}()
+	select {
+	case <-blocked:
+		t.Fatal("queueing did not block with a full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if _, name := readEntryNotify(t, client); name != "first" {
+		t.Errorf("got %q, want first", name)
+	}
+	<-blocked
+
+	if entry, name := readEntryNotify(t, client); name != "expire" || entry.Flags != FUSE_EXPIRE_ONLY {
+		t.Errorf("got %q %+v, want expire-only entry", name, entry)
+	}
+	out, data := readNotify(t, client)
+	inode := (*NotifyInvalInodeOut)(unsafe.Pointer(&data[0]))
+	if Status(-out.Status) != NOTIFY_INVAL_INODE || inode.Ino != 2 || inode.Off != 0 || inode.Length != 10000 {
+		t.Errorf("got %d %+v, want merged range [0, 10000)", out.Status, inode)
+	}
+	if entry, name := readEntryNotify(t, client); name != "entry" || entry.Flags != 0 {
+		t.Errorf("got %q %+v, want invalidated entry", name, entry)
+	}
+	if _, name := readEntryNotify(t, client); name != "blocked" {
+		t.Errorf("got %q, want blocked", name)
+	}
+
+	iv.Close()
+	st := iv.Stats()
+	if st.Sent != 5 || st.Queued != 2004 || st.Coalesced != 1999 || st.Errors != 0 {
+		t.Errorf("got stats %+v", st)
+	}
+	client.Close()
+	srv.Wait()
+}
+
+func TestInvalOpMerge(t *testing.T) {
+	for _, tc := range []struct {
+		ops  []invalOp
+		want invalOp
+	}{
+		{[]invalOp{{off: -1}, {off: -1}}, invalOp{off: -1}},
+		{[]invalOp{{off: -1}, {off: 10, end: 20}}, invalOp{off: 10, end: 20}},
+		{[]invalOp{{off: 10, end: 20}, {off: 30, end: 40}}, invalOp{off: 10, end: 40}},
+		{[]invalOp{{off: 10, end: 20}, {off: 0, end: -1}}, invalOp{off: 0, end: -1}},
+		{[]invalOp{{expireOnly: true, off: -1}, {off: -1}}, invalOp{off: -1}},
+		{[]invalOp{{off: -1}, {delete: true, child: 5, off: -1}}, invalOp{delete: true, child: 5, off: -1}},
+	} {
+		got := tc.ops[0]
+		for i := range tc.ops[1:] {
+			got.merge(&tc.ops[i+1])
+		}
+		if got != tc.want {
+			t.Errorf("merge %v: got %+v, want %+v", tc.ops, got, tc.want)
+		}
+	}
+}
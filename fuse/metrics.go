@@ -0,0 +1,338 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Latency histograms have buckets with upper bounds of 1µs, 2µs,
+// 4µs, ... up to 2^(latencyBuckets-2) µs (about 67s), plus one for
+// everything slower.
+const latencyBuckets = 28
+
+func bucketBound(i int) time.Duration {
+	return time.Microsecond << i
+}
+
+func bucketIndex(dt time.Duration) int {
+	for i := 0; i < latencyBuckets-1; i++ {
+		if dt <= bucketBound(i) {
+			return i
+		}
+	}
+	return latencyBuckets - 1
+}
+
+type opMetrics struct {
+	count    atomic.Uint64
+	inFlight atomic.Int64
+	total    atomic.Int64
+	max      atomic.Int64
+	buckets  [latencyBuckets]atomic.Uint64
+
+	errMu  sync.Mutex
+	errors map[Status]uint64
+}
+
+func (m *opMetrics) add(dt time.Duration, status Status) {
+	m.count.Add(1)
+	m.total.Add(int64(dt))
+	m.buckets[bucketIndex(dt)].Add(1)
+	for {
+		old := m.max.Load()
+		if int64(dt) <= old || m.max.CompareAndSwap(old, int64(dt)) {
+			break
+		}
+	}
+	if status > OK {
+		m.errMu.Lock()
+		if m.errors == nil {
+			m.errors = map[Status]uint64{}
+		}
+		m.errors[status]++
+		m.errMu.Unlock()
+	}
+}
+
+// Metrics collects statistics about the requests a Server handles:
+// latency histograms, error counts and in-flight requests for each
+// opcode, and how much data was read and written. Pass it to
+// Server.RecordMetrics. Metrics implements http.Handler, serving
+// the statistics in the Prometheus text exposition format.
+type Metrics struct {
+	ops [_OPCODE_COUNT]opMetrics
+
+	bytesRead     atomic.Uint64
+	bytesWritten  atomic.Uint64
+	spliceReplies atomic.Uint64
+	copyReplies   atomic.Uint64
+	spliceWrites  atomic.Uint64
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// RecordMetrics switches on collection of statistics into m. Passing
+// nil switches it off. This should be called before Serve.
+func (ms *Server) RecordMetrics(m *Metrics) {
+	ms.metrics = m
+}
+
+// start is called when a request has been read.
+func (m *Metrics) start(op uint32) {
+	if op < _OPCODE_COUNT {
+		m.ops[op].inFlight.Add(1)
+	}
+}
+
+// done is called when a request is finished.
+func (m *Metrics) done(req *request, dt time.Duration) {
+	op := req.inHeader().Opcode
+	if op >= _OPCODE_COUNT {
+		return
+	}
+	m.ops[op].inFlight.Add(-1)
+	m.ops[op].add(dt, req.status)
+
+	if req.status == OK && req.outputBuf != nil {
+		switch op {
+		case _OP_READ:
+			if n := int(req.outHeader().Length) - int(sizeOfOutHeader); n > 0 {
+				m.bytesRead.Add(uint64(n))
+			}
+		case _OP_WRITE:
+			m.bytesWritten.Add(uint64((*WriteOut)(req.outData()).Size))
+		}
+	}
+	if req.spliced {
+		m.spliceReplies.Add(1)
+	} else if len(req.outPayload) > 0 {
+		m.copyReplies.Add(1)
+	}
+	if req.writePipe != nil {
+		m.spliceWrites.Add(1)
+	}
+}
+
+// OpMetrics holds the statistics for a single opcode.
+type OpMetrics struct {
+	// Count is the number of finished requests.
+	Count uint64
+
+	// InFlight is the number of requests being processed.
+	InFlight int64
+
+	// Total is the sum of the latencies of all finished requests.
+	Total time.Duration
+
+	// P50 and P99 are estimated from the histogram.
+	P50, P99 time.Duration
+	Max      time.Duration
+
+	// Buckets is the latency histogram. Buckets[i] counts the
+	// requests that took longer than Buckets[i-1].UpperBound, and
+	// at most UpperBound.
+	Buckets []LatencyBucket
+
+	// Errors counts the requests that failed, by status.
+	Errors map[Status]uint64
+}
+
+// LatencyBucket is a bucket of a latency histogram. The last bucket
+// has an UpperBound of math.MaxInt64.
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// MetricsSnapshot is a copy of the statistics in a Metrics.
+type MetricsSnapshot struct {
+	// Ops is keyed by opcode name, eg. "LOOKUP". It only
+	// contains opcodes that have been seen.
+	Ops map[string]*OpMetrics
+
+	// BytesRead and BytesWritten count the data returned by
+	// READ, and accepted by WRITE.
+	BytesRead    uint64
+	BytesWritten uint64
+
+	// SpliceReplies and CopyReplies count replies with data
+	// that were spliced from a file descriptor, or copied.
+	SpliceReplies uint64
+	CopyReplies   uint64
+
+	// SpliceWrites counts WRITE requests whose data was passed
+	// in a pipe, see MountOptions.EnableSpliceWrite.
+	SpliceWrites uint64
+}
+
+// quantile estimates the q-th quantile by interpolating linearly
+// inside the bucket that holds it.
+func (o *OpMetrics) quantile(q float64) time.Duration {
+	if o.Count == 0 {
+		return 0
+	}
+	rank := q * float64(o.Count)
+	var cum uint64
+	var lower time.Duration
+	for _, b := range o.Buckets {
+		if b.Count > 0 && float64(cum+b.Count) >= rank {
+			upper := b.UpperBound
+			if upper > o.Max {
+				upper = o.Max
+			}
+			frac := (rank - float64(cum)) / float64(b.Count)
+			return lower + time.Duration(frac*float64(upper-lower))
+		}
+		cum += b.Count
+		lower = b.UpperBound
+	}
+	return o.Max
+}
+
+// Snapshot returns the current statistics. Counters are read one
+// by one, so they may be slightly inconsistent if requests are being
+// served.
+func (m *Metrics) Snapshot() *MetricsSnapshot {
+	s := &MetricsSnapshot{
+		Ops:           map[string]*OpMetrics{},
+		BytesRead:     m.bytesRead.Load(),
+		BytesWritten:  m.bytesWritten.Load(),
+		SpliceReplies: m.spliceReplies.Load(),
+		CopyReplies:   m.copyReplies.Load(),
+		SpliceWrites:  m.spliceWrites.Load(),
+	}
+	for op := range m.ops {
+		om := &m.ops[op]
+		o := &OpMetrics{
+			Count:    om.count.Load(),
+			InFlight: om.inFlight.Load(),
+			Total:    time.Duration(om.total.Load()),
+			Max:      time.Duration(om.max.Load()),
+		}
+		if o.Count == 0 && o.InFlight == 0 {
+			continue
+		}
+		o.Buckets = make([]LatencyBucket, latencyBuckets)
+		for i := range o.Buckets {
+			o.Buckets[i] = LatencyBucket{
+				UpperBound: bucketBound(i),
+				Count:      om.buckets[i].Load(),
+			}
+		}
+		o.Buckets[latencyBuckets-1].UpperBound = math.MaxInt64
+
+		om.errMu.Lock()
+		o.Errors = make(map[Status]uint64, len(om.errors))
+		for k, v := range om.errors {
+			o.Errors[k] = v
+		}
+		om.errMu.Unlock()
+
+		o.P50 = o.quantile(0.5)
+		o.P99 = o.quantile(0.99)
+		s.Ops[operationName(uint32(op))] = o
+	}
+	return s
+}
+
+func errnoName(code Status) string {
+	if name := unix.ErrnoName(syscall.Errno(code)); name != "" {
+		return name
+	}
+	return strconv.Itoa(int(code))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+// WritePrometheus writes the snapshot in the Prometheus text
+// exposition format. All metric names start with "fuse_".
+func (s *MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var names []string
+	for name := range s.Ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("fuse_request_duration_seconds", "histogram", "Latency of FUSE requests by opcode.")
+	for _, name := range names {
+		o := s.Ops[name]
+		var cum uint64
+		for _, b := range o.Buckets {
+			cum += b.Count
+			le := "+Inf"
+			if b.UpperBound != math.MaxInt64 {
+				le = seconds(b.UpperBound)
+			}
+			fmt.Fprintf(bw, "fuse_request_duration_seconds_bucket{op=%q,le=%q} %d\n", name, le, cum)
+		}
+		fmt.Fprintf(bw, "fuse_request_duration_seconds_sum{op=%q} %s\n", name, seconds(o.Total))
+		fmt.Fprintf(bw, "fuse_request_duration_seconds_count{op=%q} %d\n", name, o.Count)
+	}
+
+	header("fuse_request_duration_max_seconds", "gauge", "Slowest FUSE request by opcode.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "fuse_request_duration_max_seconds{op=%q} %s\n", name, seconds(s.Ops[name].Max))
+	}
+
+	header("fuse_request_errors_total", "counter", "Failed FUSE requests by opcode and errno.")
+	for _, name := range names {
+		o := s.Ops[name]
+		var codes []Status
+		for code := range o.Errors {
+			codes = append(codes, code)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		for _, code := range codes {
+			fmt.Fprintf(bw, "fuse_request_errors_total{op=%q,errno=%q} %d\n", name, errnoName(code), o.Errors[code])
+		}
+	}
+
+	header("fuse_requests_in_flight", "gauge", "FUSE requests being processed by opcode.")
+	for _, name := range names {
+		fmt.Fprintf(bw, "fuse_requests_in_flight{op=%q} %d\n", name, s.Ops[name].InFlight)
+	}
+
+	header("fuse_read_bytes_total", "counter", "Bytes returned by READ.")
+	fmt.Fprintf(bw, "fuse_read_bytes_total %d\n", s.BytesRead)
+	header("fuse_written_bytes_total", "counter", "Bytes accepted by WRITE.")
+	fmt.Fprintf(bw, "fuse_written_bytes_total %d\n", s.BytesWritten)
+
+	header("fuse_data_replies_total", "counter", "Replies carrying data, by whether the data was spliced or copied.")
+	fmt.Fprintf(bw, "fuse_data_replies_total{mode=\"splice\"} %d\n", s.SpliceReplies)
+	fmt.Fprintf(bw, "fuse_data_replies_total{mode=\"copy\"} %d\n", s.CopyReplies)
+	header("fuse_splice_writes_total", "counter", "WRITE requests whose data was spliced from the FUSE device.")
+	fmt.Fprintf(bw, "fuse_splice_writes_total %d\n", s.SpliceWrites)
+	return bw.Flush()
+}
+
+// ServeHTTP serves a snapshot in the Prometheus text exposition
+// format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.Snapshot().WritePrometheus(w)
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestMetricsQuantile(t *testing.T) {
+	m := NewMetrics()
+	for i := 0; i < 99; i++ {
+		m.ops[_OP_LOOKUP].add(3*time.Microsecond, OK)
+	}
+	m.ops[_OP_LOOKUP].add(time.Second, EIO)
+
+	s := m.Snapshot()
+	o := s.Ops["LOOKUP"]
+	if o == nil {
+		t.Fatalf("no LOOKUP in %v", s.Ops)
+	}
+	if o.Count != 100 || o.Max != time.Second {
+		t.Errorf("got count %d, max %v", o.Count, o.Max)
+	}
+	// 3µs falls in the (2µs, 4µs] bucket.
+	if o.P50 <= 2*time.Microsecond || o.P50 > 4*time.Microsecond {
+		t.Errorf("got p50 %v", o.P50)
+	}
+	if o.P99 > 4*time.Microsecond {
+		t.Errorf("got p99 %v", o.P99)
+	}
+	if o.Errors[EIO] != 1 || len(o.Errors) != 1 {
+		t.Errorf("got errors %v", o.Errors)
+	}
+}
+
+func TestMetricsServer(t *testing.T) {
+	client, srv := startStreamServer(t, &readFS{}, nil)
+	m := NewMetrics()
+	srv.RecordMetrics(m)
+	go srv.Serve()
+
+	if out, _ := streamLookup(t, client, 2, "file"); out.Status != 0 {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	if out, _ := streamLookup(t, client, 3, "missing"); out.Status != -int32(ENOENT) {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	read := ReadIn{
+		InHeader: InHeader{
+			Opcode: _OP_READ,
+			Unique: 10,
+			NodeId: 2,
+		},
+		Size: 1000,
+	}
+	read.Length = uint32(unsafe.Sizeof(read))
+	if out, _ := streamRoundTrip(t, client, structBytes(&read)); out.Status != 0 {
+		t.Fatalf("READ: status %d", out.Status)
+	}
+
+	// Statistics are recorded after the reply is sent.
+	client.Close()
+	srv.Wait()
+
+	s := m.Snapshot()
+	if o := s.Ops["LOOKUP"]; o == nil || o.Count != 2 || o.Errors[ENOENT] != 1 || o.InFlight != 0 {
+		t.Errorf("LOOKUP: got %+v", o)
+	}
+	if o := s.Ops["READ"]; o == nil || o.Count != 1 {
+		t.Errorf("READ: got %+v", o)
+	}
+	if s.BytesRead != 1000 || s.CopyReplies != 1 || s.SpliceReplies != 0 {
+		t.Errorf("got %+v", s)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`fuse_request_duration_seconds_count{op="LOOKUP"} 2`,
+		`fuse_request_duration_seconds_bucket{op="READ",le="+Inf"} 1`,
+		`fuse_request_errors_total{op="LOOKUP",errno="ENOENT"} 1`,
+		`fuse_requests_in_flight{op="READ"} 0`,
+		`fuse_read_bytes_total 1000`,
+		`fuse_data_replies_total{mode="copy"} 1`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
@@ -96,3 +96,14 @@ func fusermountBinary() (string, error) {
 
 	return "", fmt.Errorf("no FUSE mount utility found")
 }
+
+func mountDetached(opts *MountOptions, ready chan<- error) (fd int, mfd int, err error) {
+	return -1, -1, syscall.ENOSYS
+}
+
+// AttachMount attaches a mount created with
+// MountOptions.DetachedMount. Detached mounts are only supported on
+// Linux.
+func (ms *Server) AttachMount(mountPoint string) error {
+	return syscall.ENOSYS
+}
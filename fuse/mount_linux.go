@@ -25,36 +25,30 @@ func unixgramSocketpair() (l, r *os.File, err error) {
 	return
 }
 
-// Create a FUSE FS on the specified mount point without using
-// fusermount.
-func mountDirect(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, err error) {
-	fd, err = syscall.Open("/dev/fuse", os.O_RDWR, 0) // use syscall.Open since we want an int fd
-	if err != nil {
-		return
-	}
-
-	// managed to open dev/fuse, attempt to mount
-	source := opts.FsName
+// directMountSource returns the source and the mount flags for a
+// direct mount.
+func directMountSource(opts *MountOptions) (source string, flags uintptr) {
+	source = opts.FsName
 	if source == "" {
 		source = opts.Name
 	}
 
-	var flags uintptr = syscall.MS_NOSUID | syscall.MS_NODEV
+	flags = syscall.MS_NOSUID | syscall.MS_NODEV
 	if opts.DirectMountFlags != 0 {
 		flags = opts.DirectMountFlags
 	}
+	return source, flags
+}
 
-	var st syscall.Stat_t
-	err = syscall.Stat(mountPoint, &st)
-	if err != nil {
-		return
-	}
-
+// directMountOptions returns the file system options for a direct
+// mount of the FUSE connection fd on a mount point with the given
+// mode.
+func directMountOptions(fd int, rootMode uint32, opts *MountOptions) []string {
 	// some values we need to pass to mount - we do as fusermount does.
 	// override possible since opts.Options comes after.
 	var r = []string{
 		fmt.Sprintf("fd=%d", fd),
-		fmt.Sprintf("rootmode=%o", st.Mode&syscall.S_IFMT),
+		fmt.Sprintf("rootmode=%o", rootMode&syscall.S_IFMT),
 		fmt.Sprintf("user_id=%d", os.Geteuid()),
 		fmt.Sprintf("group_id=%d", os.Getegid()),
 		// match what we do with fusermount
@@ -68,12 +62,41 @@ func mountDirect(mountPoint string, opts *MountOptions, ready chan<- error) (fd
 	if opts.IDMappedMount && !opts.containsOption("default_permissions") {
 		r = append(r, "default_permissions")
 	}
+	return r
+}
 
-	if opts.Debug {
-		opts.Logger.Printf("mountDirect: calling syscall.Mount(%q, %q, %q, %#x, %q)",
-			source, mountPoint, "fuse."+opts.Name, flags, strings.Join(r, ","))
+// Create a FUSE FS on the specified mount point without using
+// fusermount. This uses the new mount API if available, and mount(2)
+// otherwise.
+func mountDirect(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, err error) {
+	fd, err = syscall.Open("/dev/fuse", os.O_RDWR, 0) // use syscall.Open since we want an int fd
+	if err != nil {
+		return
 	}
-	err = syscall.Mount(source, mountPoint, "fuse."+opts.Name, flags, strings.Join(r, ","))
+
+	// managed to open dev/fuse, attempt to mount
+	source, flags := directMountSource(opts)
+	err = inMountNamespace(opts.MountNamespaceFd, func() error {
+		var st syscall.Stat_t
+		if err := syscall.Stat(mountPoint, &st); err != nil {
+			return err
+		}
+		r := directMountOptions(fd, st.Mode, opts)
+
+		mfd, err := fsMount(source, flags, r, opts)
+		if err == nil {
+			defer syscall.Close(mfd)
+			return moveMount(mfd, mountPoint)
+		} else if err != errNoMountAPI {
+			return err
+		}
+
+		if opts.Debug {
+			opts.Logger.Printf("mountDirect: calling syscall.Mount(%q, %q, %q, %#x, %q)",
+				source, mountPoint, "fuse."+opts.Name, flags, strings.Join(r, ","))
+		}
+		return syscall.Mount(source, mountPoint, "fuse."+opts.Name, flags, strings.Join(r, ","))
+	})
 	if err != nil {
 		syscall.Close(fd)
 		return
@@ -141,14 +164,16 @@ func callFusermount(mountPoint string, opts *MountOptions) (fd int, err error) {
 // Create a FUSE FS on the specified mount point.  The returned
 // mount point is always absolute.
 func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, err error) {
-	if opts.DirectMount || opts.DirectMountStrict {
+	// fusermount cannot mount into another namespace.
+	strict := opts.DirectMountStrict || opts.MountNamespaceFd != 0
+	if opts.DirectMount || strict {
 		fd, err := mountDirect(mountPoint, opts, ready)
 		if err == nil {
 			return fd, nil
 		} else if opts.Debug {
 			opts.Logger.Printf("mount: failed to do direct mount: %s", err)
 		}
-		if opts.DirectMountStrict {
+		if strict {
 			return -1, err
 		}
 	}
@@ -176,6 +201,11 @@ func mount(mountPoint string, opts *MountOptions, ready chan<- error) (fd int, e
 }
 
 func unmount(mountPoint string, opts *MountOptions) (err error) {
+	if opts.MountNamespaceFd != 0 {
+		return inMountNamespace(opts.MountNamespaceFd, func() error {
+			return syscall.Unmount(mountPoint, 0)
+		})
+	}
 	if opts.DirectMount || opts.DirectMountStrict {
 		// Attempt to directly unmount, if fails fallback to fusermount method
 		err := syscall.Unmount(mountPoint, 0)
@@ -1,13 +1,18 @@
 package fuse
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/hanwen/go-fuse/v2/internal/testutil"
 	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
 )
 
 // TestMountDevFd tests the special `/dev/fd/N` mountpoint syntax, where a
@@ -208,3 +213,145 @@ func TestEscapedMountOption(t *testing.T) {
 		t.Errorf("mountinfo(%q): got %q want %q", mnt, m.Source, fsname)
 	}
 }
+
+func skipNoMountAPI(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("must be root")
+	}
+	fd, err := unix.Fsopen("fuse", unix.FSOPEN_CLOEXEC)
+	if err != nil {
+		t.Skipf("fsopen: %v", err)
+	}
+	syscall.Close(fd)
+}
+
+func TestDetachedMount(t *testing.T) {
+	skipNoMountAPI(t)
+	opts := &MountOptions{
+		DetachedMount: true,
+		Debug:         testutil.VerboseTest(),
+	}
+	srv, err := NewServer(NewDefaultRawFileSystem(), "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if srv.DetachedMountFd() < 0 {
+		t.Fatal("no detached mount fd")
+	}
+
+	// The detached mount can be accessed through its fd.
+	var st unix.Stat_t
+	if err := unix.Fstatat(srv.DetachedMountFd(), "", &st, unix.AT_EMPTY_PATH); err != syscall.ENOSYS {
+		t.Errorf("fstatat on detached mount: got %v, want ENOSYS", err)
+	}
+
+	mnt := t.TempDir()
+	if err := srv.AttachMount(mnt); err != nil {
+		t.Fatalf("AttachMount: %v", err)
+	}
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(mnt, &syscall.Stat_t{}); err != syscall.ENOSYS {
+		t.Errorf("stat on mount point: got %v, want ENOSYS", err)
+	}
+	if err := srv.Unmount(); err != nil {
+		t.Fatalf("Unmount: %v", err)
+	}
+}
+
+func TestDetachedMountUnattached(t *testing.T) {
+	skipNoMountAPI(t)
+	srv, err := NewServer(NewDefaultRawFileSystem(), "", &MountOptions{DetachedMount: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.Unmount(); err != nil {
+		t.Fatalf("Unmount: %v", err)
+	}
+}
+
+func TestMountErrorMessages(t *testing.T) {
+	skipNoMountAPI(t)
+	opts := &MountOptions{
+		DirectMountStrict: true,
+		Options:           []string{"bogus_option=1"},
+	}
+	_, err := NewServer(NewDefaultRawFileSystem(), t.TempDir(), opts)
+	var me *MountError
+	if !errors.As(err, &me) {
+		t.Fatalf("got %v, want MountError", err)
+	}
+	if me.Param != "bogus_option" || len(me.Messages) == 0 {
+		t.Errorf("got %#v", me)
+	}
+	if !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("got %v, want EINVAL", err)
+	}
+}
+
+func TestMountNamespace(t *testing.T) {
+	skipNoMountAPI(t)
+	if _, err := exec.LookPath("unshare"); err != nil {
+		t.Skip("unshare not found")
+	}
+	mnt := t.TempDir()
+	cmd := exec.Command("unshare", "--mount", "--propagation", "private", "sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	// Wait for unshare to switch namespaces.
+	self, err := os.Readlink("/proc/self/ns/mnt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsPath := fmt.Sprintf("/proc/%d/ns/mnt", cmd.Process.Pid)
+	for i := 0; ; i++ {
+		if ns, err := os.Readlink(nsPath); err == nil && ns != self {
+			break
+		}
+		if i == 1000 {
+			t.Fatal("unshare did not create a namespace")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ns, err := os.Open(nsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Close()
+
+	opts := &MountOptions{
+		MountNamespaceFd: int(ns.Fd()),
+		Debug:            testutil.VerboseTest(),
+	}
+	srv, err := NewServer(NewDefaultRawFileSystem(), mnt, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The mount is invisible here.
+	if mounts, err := mountinfo.GetMounts(mountinfo.SingleEntryFilter(mnt)); err != nil || len(mounts) != 0 {
+		t.Errorf("got mounts %v, %v in our namespace", mounts, err)
+	}
+	// But visible in the other namespace.
+	info, err := os.ReadFile(fmt.Sprintf("/proc/%d/mountinfo", cmd.Process.Pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(info), " "+mnt+" ") {
+		t.Errorf("mount point %q not in mountinfo of namespace:\n%s", mnt, info)
+	}
+	if err := srv.Unmount(); err != nil {
+		t.Fatalf("Unmount: %v", err)
+	}
+}
@@ -0,0 +1,459 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"context"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// fsBridge serves a FileSystemConnector to a tree of fs.Inodes, by
+// calling its RawFileSystem like the kernel would.
+type fsBridge struct {
+	conn *FileSystemConnector
+	raw  fuse.RawFileSystem
+
+	mu sync.Mutex
+	// nodes has the fs nodes for each node ID of conn.
+	nodes map[uint64][]*fsNode
+}
+
+// fsNode is an fs.Inode for a node of the legacy tree. Each fsNode
+// holds one lookup of its node ID, which is forgotten with the
+// fsNode, or when the fs tree discards it in favor of the fsNode it
+// already has for the node ID.
+type fsNode struct {
+	fs.Inode
+	b      *fsBridge
+	nodeID uint64
+}
+
+// fsFile is an open file of the legacy tree.
+type fsFile struct {
+	n     *fsNode
+	fh    uint64
+	flags uint32
+}
+
+var _ = (fs.NodeLookuper)((*fsNode)(nil))
+var _ = (fs.NodeOnForgetter)((*fsNode)(nil))
+var _ = (fs.NodeDiscarder)((*fsNode)(nil))
+var _ = (fs.NodeGetattrer)((*fsNode)(nil))
+var _ = (fs.NodeSetattrer)((*fsNode)(nil))
+var _ = (fs.NodeAccesser)((*fsNode)(nil))
+var _ = (fs.NodeStatfser)((*fsNode)(nil))
+var _ = (fs.NodeReadlinker)((*fsNode)(nil))
+var _ = (fs.NodeOpener)((*fsNode)(nil))
+var _ = (fs.NodeCreater)((*fsNode)(nil))
+var _ = (fs.NodeReaddirer)((*fsNode)(nil))
+var _ = (fs.NodeMkdirer)((*fsNode)(nil))
+var _ = (fs.NodeMknoder)((*fsNode)(nil))
+var _ = (fs.NodeSymlinker)((*fsNode)(nil))
+var _ = (fs.NodeLinker)((*fsNode)(nil))
+var _ = (fs.NodeUnlinker)((*fsNode)(nil))
+var _ = (fs.NodeRmdirer)((*fsNode)(nil))
+var _ = (fs.NodeRenamer)((*fsNode)(nil))
+var _ = (fs.NodeGetxattrer)((*fsNode)(nil))
+var _ = (fs.NodeSetxattrer)((*fsNode)(nil))
+var _ = (fs.NodeRemovexattrer)((*fsNode)(nil))
+var _ = (fs.NodeListxattrer)((*fsNode)(nil))
+
+var _ = (fs.FileReader)((*fsFile)(nil))
+var _ = (fs.FileWriter)((*fsFile)(nil))
+var _ = (fs.FileFlusher)((*fsFile)(nil))
+var _ = (fs.FileFsyncer)((*fsFile)(nil))
+var _ = (fs.FileReleaser)((*fsFile)(nil))
+var _ = (fs.FileGetattrer)((*fsFile)(nil))
+var _ = (fs.FileSetattrer)((*fsFile)(nil))
+var _ = (fs.FileAllocater)((*fsFile)(nil))
+var _ = (fs.FileLseeker)((*fsFile)(nil))
+var _ = (fs.FileGetlker)((*fsFile)(nil))
+var _ = (fs.FileSetlker)((*fsFile)(nil))
+var _ = (fs.FileSetlkwer)((*fsFile)(nil))
+
+// NewInodeEmbedder returns an fs.InodeEmbedder serving the tree rooted
+// at root, so it can be mounted with fs.Mount or added to an fs tree,
+// eg. to migrate a file system to the fs package incrementally. The
+// tree is served by a FileSystemConnector with the given options,
+// which is not mounted itself: its Server method returns nil, and
+// its notification methods are forwarded to the fs tree.
+func NewInodeEmbedder(root Node, opts *Options) fs.InodeEmbedder {
+	conn := NewFileSystemConnector(root, opts)
+	b := &fsBridge{
+		conn:  conn,
+		raw:   conn.RawFS(),
+		nodes: map[uint64][]*fsNode{},
+	}
+	n := &fsNode{b: b, nodeID: fuse.FUSE_ROOT_ID}
+	b.nodes[n.nodeID] = []*fsNode{n}
+
+	conn.notify = (*fsNotifier)(b)
+	root.OnMount(conn)
+	return n
+}
+
+// header returns the InHeader for a request on the node.
+func (n *fsNode) header(ctx context.Context) fuse.InHeader {
+	h := fuse.InHeader{NodeId: n.nodeID}
+	if caller, ok := fuse.FromContext(ctx); ok {
+		h.Caller = *caller
+	}
+	return h
+}
+
+// newChild returns an fsNode for an entry returned by the legacy tree.
+func (n *fsNode) newChild(ctx context.Context, out *fuse.EntryOut) *fs.Inode {
+	ch := &fsNode{b: n.b, nodeID: out.NodeId}
+	n.b.mu.Lock()
+	defer n.b.mu.Unlock()
+
+	// The inode numbers of the legacy tree may clash with those
+	// of the rest of the fs tree, so we let the fs tree pick
+	// them, and reuse that number while the node ID is live. The
+	// fs tree then returns the existing inode for the node ID, eg.
+	// for hard links, and discards ch.
+	stable := fs.StableAttr{Mode: out.Mode & syscall.S_IFMT}
+	if nodes := n.b.nodes[ch.nodeID]; len(nodes) > 0 {
+		stable.Ino = nodes[0].StableAttr().Ino
+	}
+	child := n.NewInode(ctx, ch, stable)
+	n.b.nodes[ch.nodeID] = append(n.b.nodes[ch.nodeID], ch)
+	return child
+}
+
+func (n *fsNode) OnForget() {
+	n.release()
+}
+
+func (n *fsNode) Discard() {
+	n.release()
+}
+
+// release forgets the lookup held by n.
+func (n *fsNode) release() {
+	n.b.mu.Lock()
+	nodes := n.b.nodes[n.nodeID]
+	for i, o := range nodes {
+		if o == n {
+			nodes = append(nodes[:i], nodes[i+1:]...)
+			break
+		}
+	}
+	if len(nodes) == 0 {
+		delete(n.b.nodes, n.nodeID)
+	} else {
+		n.b.nodes[n.nodeID] = nodes
+	}
+	n.b.mu.Unlock()
+
+	n.b.raw.Forget(n.nodeID, 1)
+}
+
+func (n *fsNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	h := n.header(ctx)
+	if st := n.b.raw.Lookup(ctx.Done(), &h, name, out); !st.Ok() {
+		return nil, syscall.Errno(st)
+	}
+	if out.NodeId == 0 {
+		return nil, syscall.ENOENT
+	}
+	return n.newChild(ctx, out), 0
+}
+
+func (n *fsNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	in := fuse.GetAttrIn{InHeader: n.header(ctx)}
+	if lf, ok := f.(*fsFile); ok {
+		in.Flags_ = fuse.FUSE_GETATTR_FH
+		in.Fh_ = lf.fh
+	}
+	return syscall.Errno(n.b.raw.GetAttr(ctx.Done(), &in, out))
+}
+
+func (n *fsNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	req := *in
+	req.InHeader = n.header(ctx)
+	req.Valid &^= fuse.FATTR_FH
+	if lf, ok := f.(*fsFile); ok {
+		req.Valid |= fuse.FATTR_FH
+		req.Fh = lf.fh
+	}
+	return syscall.Errno(n.b.raw.SetAttr(ctx.Done(), &req, out))
+}
+
+func (n *fsNode) Access(ctx context.Context, mask uint32) syscall.Errno {
+	in := fuse.AccessIn{InHeader: n.header(ctx), Mask: mask}
+	return syscall.Errno(n.b.raw.Access(ctx.Done(), &in))
+}
+
+func (n *fsNode) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	h := n.header(ctx)
+	return syscall.Errno(n.b.raw.StatFs(ctx.Done(), &h, out))
+}
+
+func (n *fsNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	h := n.header(ctx)
+	target, st := n.b.raw.Readlink(ctx.Done(), &h)
+	return target, syscall.Errno(st)
+}
+
+func (n *fsNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	in := fuse.OpenIn{InHeader: n.header(ctx), Flags: flags}
+	var out fuse.OpenOut
+	if st := n.b.raw.Open(ctx.Done(), &in, &out); !st.Ok() {
+		return nil, 0, syscall.Errno(st)
+	}
+	return &fsFile{n: n, fh: out.Fh, flags: flags}, out.OpenFlags, 0
+}
+
+func (n *fsNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	in := fuse.CreateIn{InHeader: n.header(ctx), Flags: flags, Mode: mode}
+	var cout fuse.CreateOut
+	if st := n.b.raw.Create(ctx.Done(), &in, name, &cout); !st.Ok() {
+		return nil, nil, 0, syscall.Errno(st)
+	}
+	*out = cout.EntryOut
+	ch := n.newChild(ctx, out)
+	f := &fsFile{n: ch.Operations().(*fsNode), fh: cout.Fh, flags: flags}
+	return ch, f, cout.OpenFlags, 0
+}
+
+func (n *fsNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	in := fuse.OpenIn{InHeader: n.header(ctx), Flags: syscall.O_RDONLY | syscall.O_DIRECTORY}
+	var out fuse.OpenOut
+	if st := n.b.raw.OpenDir(ctx.Done(), &in, &out); !st.Ok() {
+		return nil, syscall.Errno(st)
+	}
+	rel := fuse.ReleaseIn{InHeader: in.InHeader, Fh: out.Fh}
+	defer n.b.raw.ReleaseDir(&rel)
+
+	var result []fuse.DirEntry
+	for off := uint64(0); ; {
+		rin := fuse.ReadIn{InHeader: in.InHeader, Fh: out.Fh, Offset: off, Size: 64 * 1024}
+		list := fuse.NewDirEntryList(make([]byte, rin.Size), off)
+		if st := n.b.raw.ReadDir(ctx.Done(), &rin, list); !st.Ok() {
+			return nil, syscall.Errno(st)
+		}
+		entries := list.Entries()
+		if len(entries) == 0 {
+			break
+		}
+		for _, e := range entries {
+			if e.Name != "." && e.Name != ".." {
+				result = append(result, e)
+			}
+		}
+		off = entries[len(entries)-1].Off
+	}
+	return fs.NewListDirStream(result), 0
+}
+
+func (n *fsNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	in := fuse.MkdirIn{InHeader: n.header(ctx), Mode: mode}
+	if st := n.b.raw.Mkdir(ctx.Done(), &in, name, out); !st.Ok() {
+		return nil, syscall.Errno(st)
+	}
+	return n.newChild(ctx, out), 0
+}
+
+func (n *fsNode) Mknod(ctx context.Context, name string, mode uint32, dev uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	in := fuse.MknodIn{InHeader: n.header(ctx), Mode: mode, Rdev: dev}
+	if st := n.b.raw.Mknod(ctx.Done(), &in, name, out); !st.Ok() {
+		return nil, syscall.Errno(st)
+	}
+	return n.newChild(ctx, out), 0
+}
+
+func (n *fsNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	h := n.header(ctx)
+	if st := n.b.raw.Symlink(ctx.Done(), &h, target, name, out); !st.Ok() {
+		return nil, syscall.Errno(st)
+	}
+	return n.newChild(ctx, out), 0
+}
+
+func (n *fsNode) Link(ctx context.Context, target fs.InodeEmbedder, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	t, ok := target.(*fsNode)
+	if !ok || t.b != n.b {
+		return nil, syscall.EXDEV
+	}
+	in := fuse.LinkIn{InHeader: n.header(ctx), Oldnodeid: t.nodeID}
+	if st := n.b.raw.Link(ctx.Done(), &in, name, out); !st.Ok() {
+		return nil, syscall.Errno(st)
+	}
+	return n.newChild(ctx, out), 0
+}
+
+func (n *fsNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	h := n.header(ctx)
+	return syscall.Errno(n.b.raw.Unlink(ctx.Done(), &h, name))
+}
+
+func (n *fsNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	h := n.header(ctx)
+	return syscall.Errno(n.b.raw.Rmdir(ctx.Done(), &h, name))
+}
+
+func (n *fsNode) Rename(ctx context.Context, name string, newParent fs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	p, ok := newParent.(*fsNode)
+	if !ok || p.b != n.b {
+		return syscall.EXDEV
+	}
+	in := fuse.RenameIn{InHeader: n.header(ctx), Newdir: p.nodeID, Flags: flags}
+	return syscall.Errno(n.b.raw.Rename(ctx.Done(), &in, name, newName))
+}
+
+func (n *fsNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	h := n.header(ctx)
+	sz, st := n.b.raw.GetXAttr(ctx.Done(), &h, attr, dest)
+	return sz, syscall.Errno(st)
+}
+
+func (n *fsNode) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) syscall.Errno {
+	in := fuse.SetXAttrIn{InHeader: n.header(ctx), Size: uint32(len(data)), Flags: flags}
+	return syscall.Errno(n.b.raw.SetXAttr(ctx.Done(), &in, attr, data))
+}
+
+func (n *fsNode) Removexattr(ctx context.Context, attr string) syscall.Errno {
+	h := n.header(ctx)
+	return syscall.Errno(n.b.raw.RemoveXAttr(ctx.Done(), &h, attr))
+}
+
+func (n *fsNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	h := n.header(ctx)
+	sz, st := n.b.raw.ListXAttr(ctx.Done(), &h, dest)
+	return sz, syscall.Errno(st)
+}
+
+func (f *fsFile) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	in := fuse.ReadIn{InHeader: f.n.header(ctx), Fh: f.fh, Offset: uint64(off), Size: uint32(len(dest)), Flags: f.flags}
+	res, st := f.n.b.raw.Read(ctx.Done(), &in, dest)
+	return res, syscall.Errno(st)
+}
+
+func (f *fsFile) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	in := fuse.WriteIn{InHeader: f.n.header(ctx), Fh: f.fh, Offset: uint64(off), Size: uint32(len(data)), Flags: f.flags}
+	n, st := f.n.b.raw.Write(ctx.Done(), &in, data)
+	return n, syscall.Errno(st)
+}
+
+func (f *fsFile) Flush(ctx context.Context) syscall.Errno {
+	in := fuse.FlushIn{InHeader: f.n.header(ctx), Fh: f.fh}
+	return syscall.Errno(f.n.b.raw.Flush(ctx.Done(), &in))
+}
+
+func (f *fsFile) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	in := fuse.FsyncIn{InHeader: f.n.header(ctx), Fh: f.fh, FsyncFlags: flags}
+	return syscall.Errno(f.n.b.raw.Fsync(ctx.Done(), &in))
+}
+
+func (f *fsFile) Release(ctx context.Context) syscall.Errno {
+	in := fuse.ReleaseIn{InHeader: f.n.header(ctx), Fh: f.fh, Flags: f.flags}
+	f.n.b.raw.Release(ctx.Done(), &in)
+	return 0
+}
+
+func (f *fsFile) Getattr(ctx context.Context, out *fuse.AttrOut) syscall.Errno {
+	return f.n.Getattr(ctx, f, out)
+}
+
+func (f *fsFile) Setattr(ctx context.Context, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	return f.n.Setattr(ctx, f, in, out)
+}
+
+func (f *fsFile) Allocate(ctx context.Context, off uint64, size uint64, mode uint32) syscall.Errno {
+	in := fuse.FallocateIn{InHeader: f.n.header(ctx), Fh: f.fh, Offset: off, Length: size, Mode: mode}
+	return syscall.Errno(f.n.b.raw.Fallocate(ctx.Done(), &in))
+}
+
+func (f *fsFile) Lseek(ctx context.Context, off uint64, whence uint32) (uint64, syscall.Errno) {
+	in := fuse.LseekIn{InHeader: f.n.header(ctx), Fh: f.fh, Offset: off, Whence: whence}
+	var out fuse.LseekOut
+	st := f.n.b.raw.Lseek(ctx.Done(), &in, &out)
+	return out.Offset, syscall.Errno(st)
+}
+
+func (f *fsFile) lkIn(ctx context.Context, owner uint64, lk *fuse.FileLock, flags uint32) *fuse.LkIn {
+	return &fuse.LkIn{InHeader: f.n.header(ctx), Fh: f.fh, Owner: owner, Lk: *lk, LkFlags: flags}
+}
+
+func (f *fsFile) Getlk(ctx context.Context, owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) syscall.Errno {
+	var lout fuse.LkOut
+	st := f.n.b.raw.GetLk(ctx.Done(), f.lkIn(ctx, owner, lk, flags), &lout)
+	*out = lout.Lk
+	return syscall.Errno(st)
+}
+
+func (f *fsFile) Setlk(ctx context.Context, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	return syscall.Errno(f.n.b.raw.SetLk(ctx.Done(), f.lkIn(ctx, owner, lk, flags)))
+}
+
+func (f *fsFile) Setlkw(ctx context.Context, owner uint64, lk *fuse.FileLock, flags uint32) syscall.Errno {
+	return syscall.Errno(f.n.b.raw.SetLkw(ctx.Done(), f.lkIn(ctx, owner, lk, flags)))
+}
+
+// fsNotifier forwards the notifications of the legacy tree to the
+// fs nodes.
+type fsNotifier fsBridge
+
+func (b *fsNotifier) inodes(nodeID uint64) []*fsNode {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*fsNode(nil), b.nodes[nodeID]...)
+}
+
+func (b *fsNotifier) InodeNotify(node uint64, off int64, length int64) fuse.Status {
+	errno := syscall.Errno(0)
+	for _, n := range b.inodes(node) {
+		if e := n.NotifyContent(off, length); e != 0 {
+			errno = e
+		}
+	}
+	return fuse.Status(errno)
+}
+
+func (b *fsNotifier) InodeNotifyStoreCache(node uint64, offset int64, data []byte) fuse.Status {
+	nodes := b.inodes(node)
+	if len(nodes) == 0 {
+		return fuse.ENOENT
+	}
+	return fuse.Status(nodes[len(nodes)-1].WriteCache(offset, data))
+}
+
+func (b *fsNotifier) InodeRetrieveCache(node uint64, offset int64, dest []byte) (int, fuse.Status) {
+	nodes := b.inodes(node)
+	if len(nodes) == 0 {
+		return 0, fuse.OK
+	}
+	n, errno := nodes[len(nodes)-1].ReadCache(offset, dest)
+	return n, fuse.Status(errno)
+}
+
+func (b *fsNotifier) EntryNotify(parent uint64, name string) fuse.Status {
+	errno := syscall.Errno(0)
+	for _, n := range b.inodes(parent) {
+		if e := n.NotifyEntry(name); e != 0 {
+			errno = e
+		}
+	}
+	return fuse.Status(errno)
+}
+
+func (b *fsNotifier) DeleteNotify(parent uint64, child uint64, name string) fuse.Status {
+	errno := syscall.Errno(0)
+	for _, n := range b.inodes(parent) {
+		ch := n.GetChild(name)
+		if ch == nil {
+			continue
+		}
+		if e := n.NotifyDelete(name, ch); e != 0 {
+			errno = e
+		}
+	}
+	return fuse.Status(errno)
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nodefs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestInodeEmbedder(t *testing.T) {
+	tmp := t.TempDir()
+	back := filepath.Join(tmp, "backing")
+	os.Mkdir(back, 0700)
+	mnt := filepath.Join(tmp, "mnt")
+	os.Mkdir(mnt, 0700)
+
+	root := NewInodeEmbedder(NewMemNodeFSRoot(back), nil)
+	opts := &fs.Options{}
+	opts.Debug = testutil.VerboseTest()
+	server, err := fs.Mount(mnt, root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Unmount() })
+
+	if err := os.Mkdir(filepath.Join(mnt, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, nm := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(mnt, "dir", nm), []byte(nm), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Rename(filepath.Join(mnt, "dir/b"), filepath.Join(mnt, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(mnt, "c")); err != nil || string(got) != "b" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+
+	f, err := os.Open(mnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if want := []string{"c", "dir"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	if err := os.Remove(filepath.Join(mnt, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(mnt, "c")); !os.IsNotExist(err) {
+		t.Errorf("Stat after Remove: got %v", err)
+	}
+}
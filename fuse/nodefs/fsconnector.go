@@ -31,6 +31,10 @@ type FileSystemConnector struct {
 	// Callbacks for talking back to the kernel.
 	server *fuse.Server
 
+	// notify receives cache notifications. It is the server, or
+	// an fs tree if the connector is served by NewInodeEmbedder.
+	notify notifier
+
 	// Translate between uint64 handles and *Inode.
 	inodeMap handleMap
 
@@ -48,6 +52,15 @@ type FileSystemConnector struct {
 	lookupLock sync.RWMutex
 }
 
+// notifier is the part of fuse.Server used for cache notifications.
+type notifier interface {
+	InodeNotify(node uint64, off int64, length int64) fuse.Status
+	InodeNotifyStoreCache(node uint64, offset int64, data []byte) fuse.Status
+	InodeRetrieveCache(node uint64, offset int64, dest []byte) (n int, st fuse.Status)
+	EntryNotify(parent uint64, name string) fuse.Status
+	DeleteNotify(parent uint64, child uint64, name string) fuse.Status
+}
+
 // NewOptions generates FUSE options that correspond to libfuse's
 // defaults.
 func NewOptions() *Options {
@@ -343,7 +356,7 @@ func (c *FileSystemConnector) Unmount(node *Inode) fuse.Status {
 	// racy.
 	mount.treeLock.Unlock()
 	parentNode.mount.treeLock.Unlock()
-	code := c.server.DeleteNotify(parentId, nodeID, name)
+	code := c.notify.DeleteNotify(parentId, nodeID, name)
 
 	if code.Ok() {
 		delay := 100 * time.Microsecond
@@ -392,7 +405,7 @@ func (c *FileSystemConnector) FileNotify(node *Inode, off int64, length int64) f
 	if nID == 0 {
 		return fuse.OK
 	}
-	return c.server.InodeNotify(nID, off, length)
+	return c.notify.InodeNotify(nID, off, length)
 }
 
 // FileNotifyStoreCache notifies the kernel about changed data of the inode.
@@ -419,7 +432,7 @@ func (c *FileSystemConnector) FileNotifyStoreCache(node *Inode, off int64, data
 		// the kernel does not currently know about this inode.
 		return fuse.ENOENT
 	}
-	return c.server.InodeNotifyStoreCache(nID, off, data)
+	return c.notify.InodeNotifyStoreCache(nID, off, data)
 }
 
 // FileRetrieveCache retrieves data from kernel's inode cache.
@@ -445,7 +458,7 @@ func (c *FileSystemConnector) FileRetrieveCache(node *Inode, off int64, dest []b
 		// -> we can pretend that its cache for the inode is empty.
 		return 0, fuse.OK
 	}
-	return c.server.InodeRetrieveCache(nID, off, dest)
+	return c.notify.InodeRetrieveCache(nID, off, dest)
 }
 
 // EntryNotify makes the kernel forget the entry data from the given
@@ -463,7 +476,7 @@ func (c *FileSystemConnector) EntryNotify(node *Inode, name string) fuse.Status
 	if nID == 0 {
 		return fuse.OK
 	}
-	return c.server.EntryNotify(nID, name)
+	return c.notify.EntryNotify(nID, name)
 }
 
 // DeleteNotify signals to the kernel that the named entry in dir for
@@ -484,5 +497,5 @@ func (c *FileSystemConnector) DeleteNotify(dir *Inode, child *Inode, name string
 
 	chId := c.inodeMap.Handle(&child.handled)
 
-	return c.server.DeleteNotify(nID, chId, name)
+	return c.notify.DeleteNotify(nID, chId, name)
 }
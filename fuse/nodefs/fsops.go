@@ -58,6 +58,7 @@ func (c *rawBridge) String() string {
 
 func (c *rawBridge) Init(s *fuse.Server) {
 	c.server = s
+	c.notify = s
 	c.rootNode.Node().OnMount((*FileSystemConnector)(c))
 }
 
@@ -72,6 +73,14 @@ func (c *FileSystemConnector) lookupMountUpdate(out *fuse.Attr, mount *fileSyste
 	return mount.mountInode, fuse.OK
 }
 
+// newContext returns the context for a request from the kernel.
+func (c *FileSystemConnector) newContext(cancel <-chan struct{}, header *fuse.InHeader) *fuse.Context {
+	if c.server == nil {
+		return &fuse.Context{Caller: header.Caller, Cancel: cancel}
+	}
+	return c.server.Context(cancel, header)
+}
+
 // internalLookup executes a lookup without affecting NodeId reference counts.
 func (c *FileSystemConnector) internalLookup(cancel <-chan struct{}, out *fuse.Attr, parent *Inode, name string, header *fuse.InHeader) (node *Inode, code fuse.Status) {
 
@@ -85,9 +94,9 @@ func (c *FileSystemConnector) internalLookup(cancel <-chan struct{}, out *fuse.A
 	}
 
 	if child != nil && !parent.mount.options.LookupKnownChildren {
-		code = child.fsInode.GetAttr(out, nil, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+		code = child.fsInode.GetAttr(out, nil, c.newContext(cancel, header))
 	} else {
-		child, code = parent.fsInode.Lookup(out, name, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+		child, code = parent.fsInode.Lookup(out, name, c.newContext(cancel, header))
 	}
 
 	return child, code
@@ -144,7 +153,7 @@ func (c *rawBridge) GetAttr(cancel <-chan struct{}, input *fuse.GetAttrIn, out *
 	}
 
 	dest := &out.Attr
-	code = node.fsInode.GetAttr(dest, f, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	code = node.fsInode.GetAttr(dest, f, c.fsConn().newContext(cancel, &input.InHeader))
 	if !code.Ok() {
 		return code
 	}
@@ -186,7 +195,7 @@ func (c *rawBridge) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, out
 
 func (c *rawBridge) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) (status fuse.Status) {
 	node := c.toInode(input.NodeId)
-	f, code := node.fsInode.Open(input.Flags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	f, code := node.fsInode.Open(input.Flags, c.fsConn().newContext(cancel, &input.InHeader))
 	if !code.Ok() {
 		return code
 	}
@@ -207,17 +216,17 @@ func (c *rawBridge) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *
 	}
 
 	if permissions, ok := input.GetMode(); ok {
-		code = node.fsInode.Chmod(f, permissions, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = node.fsInode.Chmod(f, permissions, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 
 	uid, uok := input.GetUID()
 	gid, gok := input.GetGID()
 
 	if code.Ok() && (uok || gok) {
-		code = node.fsInode.Chown(f, uid, gid, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = node.fsInode.Chown(f, uid, gid, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 	if sz, ok := input.GetSize(); code.Ok() && ok {
-		code = node.fsInode.Truncate(f, sz, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = node.fsInode.Truncate(f, sz, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 
 	atime, aok := input.GetATime()
@@ -232,7 +241,7 @@ func (c *rawBridge) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *
 			m = &mtime
 		}
 
-		code = node.fsInode.Utimens(f, a, m, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		code = node.fsInode.Utimens(f, a, m, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 
 	if !code.Ok() {
@@ -242,7 +251,7 @@ func (c *rawBridge) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *
 	// Must call GetAttr(); the filesystem may override some of
 	// the changes we effect here.
 	attr := &out.Attr
-	code = node.fsInode.GetAttr(attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	code = node.fsInode.GetAttr(attr, nil, c.fsConn().newContext(cancel, &input.InHeader))
 	if code.Ok() {
 		node.mount.fillAttr(out, input.NodeId)
 	}
@@ -253,21 +262,21 @@ func (c *rawBridge) Fallocate(cancel <-chan struct{}, input *fuse.FallocateIn) (
 	n := c.toInode(input.NodeId)
 	opened := n.mount.getOpenedFile(input.Fh)
 
-	return n.fsInode.Fallocate(opened, input.Offset, input.Length, input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.Fallocate(opened, input.Offset, input.Length, input.Mode, c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) Readlink(cancel <-chan struct{}, header *fuse.InHeader) (out []byte, code fuse.Status) {
 	n := c.toInode(header.NodeId)
-	return n.fsInode.Readlink(&fuse.Context{Caller: header.Caller, Cancel: cancel})
+	return n.fsInode.Readlink(c.fsConn().newContext(cancel, header))
 }
 
 func (c *rawBridge) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name string, out *fuse.EntryOut) (code fuse.Status) {
 	parent := c.toInode(input.NodeId)
 
-	child, code := parent.fsInode.Mknod(name, input.Mode, uint32(input.Rdev), &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	child, code := parent.fsInode.Mknod(name, input.Mode, uint32(input.Rdev), c.fsConn().newContext(cancel, &input.InHeader))
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		c.childLookup(out, child, c.fsConn().newContext(cancel, &input.InHeader))
+		code = child.fsInode.GetAttr(&out.Attr, nil, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 	return code
 }
@@ -275,31 +284,31 @@ func (c *rawBridge) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name stri
 func (c *rawBridge) Mkdir(cancel <-chan struct{}, input *fuse.MkdirIn, name string, out *fuse.EntryOut) (code fuse.Status) {
 	parent := c.toInode(input.NodeId)
 
-	child, code := parent.fsInode.Mkdir(name, input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	child, code := parent.fsInode.Mkdir(name, input.Mode, c.fsConn().newContext(cancel, &input.InHeader))
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		c.childLookup(out, child, c.fsConn().newContext(cancel, &input.InHeader))
+		code = child.fsInode.GetAttr(&out.Attr, nil, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 	return code
 }
 
 func (c *rawBridge) Unlink(cancel <-chan struct{}, header *fuse.InHeader, name string) (code fuse.Status) {
 	parent := c.toInode(header.NodeId)
-	return parent.fsInode.Unlink(name, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	return parent.fsInode.Unlink(name, c.fsConn().newContext(cancel, header))
 }
 
 func (c *rawBridge) Rmdir(cancel <-chan struct{}, header *fuse.InHeader, name string) (code fuse.Status) {
 	parent := c.toInode(header.NodeId)
-	return parent.fsInode.Rmdir(name, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	return parent.fsInode.Rmdir(name, c.fsConn().newContext(cancel, header))
 }
 
 func (c *rawBridge) Symlink(cancel <-chan struct{}, header *fuse.InHeader, pointedTo string, linkName string, out *fuse.EntryOut) (code fuse.Status) {
 	parent := c.toInode(header.NodeId)
 
-	child, code := parent.fsInode.Symlink(linkName, pointedTo, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	child, code := parent.fsInode.Symlink(linkName, pointedTo, c.fsConn().newContext(cancel, header))
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: header.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+		c.childLookup(out, child, c.fsConn().newContext(cancel, header))
+		code = child.fsInode.GetAttr(&out.Attr, nil, c.fsConn().newContext(cancel, header))
 	}
 	return code
 }
@@ -323,7 +332,7 @@ func (c *rawBridge) Rename(cancel <-chan struct{}, input *fuse.RenameIn, oldName
 		return fuse.EXDEV
 	}
 
-	return oldParent.fsInode.Rename(oldName, newParent.fsInode, newName, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return oldParent.fsInode.Rename(oldName, newParent.fsInode, newName, c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) Link(cancel <-chan struct{}, input *fuse.LinkIn, name string, out *fuse.EntryOut) (code fuse.Status) {
@@ -334,10 +343,10 @@ func (c *rawBridge) Link(cancel <-chan struct{}, input *fuse.LinkIn, name string
 		return fuse.EXDEV
 	}
 
-	child, code := parent.fsInode.Link(name, existing.fsInode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	child, code := parent.fsInode.Link(name, existing.fsInode, c.fsConn().newContext(cancel, &input.InHeader))
 	if code.Ok() {
-		c.childLookup(out, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
-		code = child.fsInode.GetAttr(&out.Attr, nil, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+		c.childLookup(out, child, c.fsConn().newContext(cancel, &input.InHeader))
+		code = child.fsInode.GetAttr(&out.Attr, nil, c.fsConn().newContext(cancel, &input.InHeader))
 	}
 
 	return code
@@ -345,17 +354,17 @@ func (c *rawBridge) Link(cancel <-chan struct{}, input *fuse.LinkIn, name string
 
 func (c *rawBridge) Access(cancel <-chan struct{}, input *fuse.AccessIn) (code fuse.Status) {
 	n := c.toInode(input.NodeId)
-	return n.fsInode.Access(input.Mask, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.Access(input.Mask, c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) Create(cancel <-chan struct{}, input *fuse.CreateIn, name string, out *fuse.CreateOut) (code fuse.Status) {
 	parent := c.toInode(input.NodeId)
-	f, child, code := parent.fsInode.Create(name, uint32(input.Flags), input.Mode, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	f, child, code := parent.fsInode.Create(name, uint32(input.Flags), input.Mode, c.fsConn().newContext(cancel, &input.InHeader))
 	if !code.Ok() {
 		return code
 	}
 
-	c.childLookup(&out.EntryOut, child, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	c.childLookup(&out.EntryOut, child, c.fsConn().newContext(cancel, &input.InHeader))
 	handle, opened := parent.mount.registerFileHandle(child, nil, f, input.Flags)
 
 	out.OpenOut.OpenFlags = opened.FuseFlags
@@ -379,7 +388,7 @@ func (c *rawBridge) ReleaseDir(input *fuse.ReleaseIn) {
 }
 func (c *rawBridge) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attribute string, dest []byte) (sz uint32, code fuse.Status) {
 	node := c.toInode(header.NodeId)
-	data, errno := node.fsInode.GetXAttr(attribute, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	data, errno := node.fsInode.GetXAttr(attribute, c.fsConn().newContext(cancel, header))
 
 	if len(data) > len(dest) {
 		return uint32(len(data)), fuse.ERANGE
@@ -390,22 +399,22 @@ func (c *rawBridge) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr
 
 func (c *rawBridge) GetXAttrData(cancel <-chan struct{}, header *fuse.InHeader, attribute string) (data []byte, code fuse.Status) {
 	node := c.toInode(header.NodeId)
-	return node.fsInode.GetXAttr(attribute, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	return node.fsInode.GetXAttr(attribute, c.fsConn().newContext(cancel, header))
 }
 
 func (c *rawBridge) RemoveXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr string) fuse.Status {
 	node := c.toInode(header.NodeId)
-	return node.fsInode.RemoveXAttr(attr, &fuse.Context{Caller: header.Caller, Cancel: cancel})
+	return node.fsInode.RemoveXAttr(attr, c.fsConn().newContext(cancel, header))
 }
 
 func (c *rawBridge) SetXAttr(cancel <-chan struct{}, input *fuse.SetXAttrIn, attr string, data []byte) fuse.Status {
 	node := c.toInode(input.NodeId)
-	return node.fsInode.SetXAttr(attr, data, int(input.Flags), &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return node.fsInode.SetXAttr(attr, data, int(input.Flags), c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) ListXAttr(cancel <-chan struct{}, header *fuse.InHeader, dest []byte) (uint32, fuse.Status) {
 	node := c.toInode(header.NodeId)
-	attrs, code := node.fsInode.ListXAttr(&fuse.Context{Caller: header.Caller, Cancel: cancel})
+	attrs, code := node.fsInode.ListXAttr(c.fsConn().newContext(cancel, header))
 	if code != fuse.OK {
 		return 0, code
 	}
@@ -440,7 +449,7 @@ func (c *rawBridge) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []by
 		f = opened.WithFlags.File
 	}
 
-	return node.Node().Write(f, data, int64(input.Offset), &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return node.Node().Write(f, data, int64(input.Offset), c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) Read(cancel <-chan struct{}, input *fuse.ReadIn, buf []byte) (fuse.ReadResult, fuse.Status) {
@@ -452,28 +461,28 @@ func (c *rawBridge) Read(cancel <-chan struct{}, input *fuse.ReadIn, buf []byte)
 		f = opened.WithFlags.File
 	}
 
-	return node.Node().Read(f, buf, int64(input.Offset), &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return node.Node().Read(f, buf, int64(input.Offset), c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) GetLk(cancel <-chan struct{}, input *fuse.LkIn, out *fuse.LkOut) (code fuse.Status) {
 	n := c.toInode(input.NodeId)
 	opened := n.mount.getOpenedFile(input.Fh)
 
-	return n.fsInode.GetLk(opened, input.Owner, &input.Lk, input.LkFlags, &out.Lk, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.GetLk(opened, input.Owner, &input.Lk, input.LkFlags, &out.Lk, c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) SetLk(cancel <-chan struct{}, input *fuse.LkIn) (code fuse.Status) {
 	n := c.toInode(input.NodeId)
 	opened := n.mount.getOpenedFile(input.Fh)
 
-	return n.fsInode.SetLk(opened, input.Owner, &input.Lk, input.LkFlags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.SetLk(opened, input.Owner, &input.Lk, input.LkFlags, c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) SetLkw(cancel <-chan struct{}, input *fuse.LkIn) (code fuse.Status) {
 	n := c.toInode(input.NodeId)
 	opened := n.mount.getOpenedFile(input.Fh)
 
-	return n.fsInode.SetLkw(opened, input.Owner, &input.Lk, input.LkFlags, &fuse.Context{Caller: input.Caller, Cancel: cancel})
+	return n.fsInode.SetLkw(opened, input.Owner, &input.Lk, input.LkFlags, c.fsConn().newContext(cancel, &input.InHeader))
 }
 
 func (c *rawBridge) StatFs(cancel <-chan struct{}, header *fuse.InHeader, out *fuse.StatfsOut) fuse.Status {
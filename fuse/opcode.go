@@ -111,6 +111,18 @@ func doInit(server *protocolServer, req *request) {
 	if server.opts.EnableAcl {
 		kernelFlags |= CAP_POSIX_ACL
 	}
+	if server.opts.EnableSpliceWrite {
+		kernelFlags |= input.Flags64() & CAP_SPLICE_READ
+	}
+	if server.opts.EnableSecurityContext && input.Minor >= 38 {
+		// Older kernels send the security context without
+		// marking it with InHeader.TotalExtlen.
+		kernelFlags |= input.Flags64() & CAP_SECURITY_CTX
+	}
+	if input.Minor >= 38 {
+		// See CreateGroupsFromContext.
+		kernelFlags |= input.Flags64() & CAP_CREATE_SUPP_GROUP
+	}
 	if server.opts.SyncRead {
 		// Clear CAP_ASYNC_READ
 		kernelFlags &= ^uint64(CAP_ASYNC_READ)
@@ -119,6 +131,9 @@ func doInit(server *protocolServer, req *request) {
 		// Clear CAP_READDIRPLUS
 		kernelFlags &= ^uint64(CAP_READDIRPLUS)
 	}
+	if server.ioUring {
+		kernelFlags |= input.Flags64() & CAP_OVER_IO_URING
+	}
 	if !server.opts.IDMappedMount {
 		// Clear CAP_ALLOW_IDMAP
 		kernelFlags &= ^uint64(CAP_ALLOW_IDMAP)
@@ -154,6 +169,7 @@ func doInit(server *protocolServer, req *request) {
 	if out.Minor > input.Minor {
 		out.Minor = input.Minor
 	}
+	server.initOut = *out
 
 	req.status = OK
 }
@@ -202,7 +218,13 @@ func doSetattr(server *protocolServer, req *request) {
 }
 
 func doWrite(server *protocolServer, req *request) {
-	n, status := server.fileSystem.Write(req.cancel, (*WriteIn)(req.inData()), req.inPayload)
+	var n uint32
+	var status Status
+	if req.writePipe != nil {
+		n, status = server.fileSystem.(RawSpliceWriter).SpliceWrite(req.cancel, (*WriteIn)(req.inData()), req.writePipe)
+	} else {
+		n, status = server.fileSystem.Write(req.cancel, (*WriteIn)(req.inData()), req.inPayload)
+	}
 	o := (*WriteOut)(req.outData())
 	o.Size = n
 	req.status = status
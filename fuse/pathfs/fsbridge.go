@@ -0,0 +1,18 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+)
+
+// NewInodeEmbedder returns an fs.InodeEmbedder serving the given
+// FileSystem, so it can be mounted with fs.Mount or added to an fs
+// tree. The nodefs options apply to the FileSystemConnector serving
+// it; see nodefs.NewInodeEmbedder for details.
+func NewInodeEmbedder(fsys FileSystem, opts *PathNodeFsOptions, nodeOpts *nodefs.Options) fs.InodeEmbedder {
+	return nodefs.NewInodeEmbedder(NewPathNodeFs(fsys, opts).Root(), nodeOpts)
+}
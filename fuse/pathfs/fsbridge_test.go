@@ -0,0 +1,81 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pathfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+	"github.com/hanwen/go-fuse/v2/posixtest"
+)
+
+// graftRoot is an fs tree with a legacy file system in "sub".
+type graftRoot struct {
+	fs.Inode
+	sub fs.InodeEmbedder
+}
+
+func (r *graftRoot) OnAdd(ctx context.Context) {
+	ch := r.NewPersistentInode(ctx, r.sub, fs.StableAttr{Mode: syscall.S_IFDIR})
+	r.AddChild("sub", ch, true)
+}
+
+func mountGraft(t *testing.T, orig string) string {
+	mnt := t.TempDir()
+	root := &graftRoot{sub: NewInodeEmbedder(NewLoopbackFileSystem(orig), &PathNodeFsOptions{ClientInodes: true}, nil)}
+	opts := &fs.Options{}
+	opts.Debug = testutil.VerboseTest()
+	server, err := fs.Mount(mnt, root, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Unmount() })
+	return filepath.Join(mnt, "sub")
+}
+
+func TestInodeEmbedderPosix(t *testing.T) {
+	skip := map[string]string{
+		"FcntlFlockLocksFile": "PathNodeFs does not implement locks",
+		"FstatDeleted":        "nodefs reports Nlink 0 as 1",
+		"NlinkZero":           "nodefs reports Nlink 0 as 1",
+		"RenameOpenDir":       "os.Rename does not replace directories",
+	}
+	for nm, fn := range posixtest.All {
+		t.Run(nm, func(t *testing.T) {
+			if why := skip[nm]; why != "" {
+				t.Skip(why)
+			}
+			fn(t, mountGraft(t, t.TempDir()))
+		})
+	}
+}
+
+func TestInodeEmbedderLoopback(t *testing.T) {
+	orig := t.TempDir()
+	if err := os.WriteFile(filepath.Join(orig, "file"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sub := mountGraft(t, orig)
+
+	if got, err := os.ReadFile(filepath.Join(sub, "file")); err != nil || string(got) != "hello" {
+		t.Errorf("ReadFile: got %q, %v", got, err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "new"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(orig, "new")); err != nil || string(got) != "new" {
+		t.Errorf("backing ReadFile: got %q, %v", got, err)
+	}
+
+	// Renames out of the legacy tree are refused.
+	if err := os.Rename(filepath.Join(sub, "new"), filepath.Join(sub, "../new")); err == nil {
+		t.Errorf("Rename out of the legacy tree succeeded")
+	}
+}
@@ -185,7 +185,7 @@ func (fs *loopbackFileSystem) Access(name string, mode uint32, context *fuse.Con
 		return status
 
 	}
-	if !internal.HasAccess(context.Uid, context.Gid, attr.Uid, attr.Gid, attr.Mode, mode) {
+	if !internal.HasAccessGroups(context.Uid, context.Gid, context.Groups, attr.Uid, attr.Gid, attr.Mode, mode) {
 		return fuse.EACCES
 	}
 
@@ -54,6 +54,7 @@ var (
 		{CAP_NO_EXPORT_SUPPORT, "NO_EXPORT_SUPPORT"},
 		{CAP_HAS_RESEND, "HAS_RESEND"},
 		{CAP_ALLOW_IDMAP, "ALLOW_IDMAP"},
+		{CAP_OVER_IO_URING, "OVER_IO_URING"},
 	})
 	releaseFlagNames = newFlagNames([]flagNameEntry{
 		{RELEASE_FLUSH, "FLUSH"},
@@ -291,6 +292,9 @@ func (o *StatfsOut) string() string {
 }
 
 func (o *NotifyInvalEntryOut) string() string {
+	if o.Flags&FUSE_EXPIRE_ONLY != 0 {
+		return fmt.Sprintf("{parent i%d sz %d EXPIRE_ONLY}", o.Parent, o.NameLen)
+	}
 	return fmt.Sprintf("{parent i%d sz %d}", o.Parent, o.NameLen)
 }
 
@@ -6,6 +6,8 @@ package fuse
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // protocolServer bridges from the FUSE datatypes to a RawFileSystem
@@ -16,10 +18,27 @@ type protocolServer struct {
 	reqInflight    []*request
 	connectionDead bool
 
+	// contexts counts the in-flight requests that have a context,
+	// so Server.Context can skip the lookup if there are none.
+	contexts atomic.Int64
+
+	// contextMu protects contextReqs, the in-flight requests that
+	// have a context, by unique ID.
+	contextMu   sync.Mutex
+	contextReqs map[uint64]*request
+
 	latencies LatencyMap
+	metrics   *Metrics
 
 	kernelSettings InitIn
 
+	// initOut is our reply to INIT, ie. the negotiated settings.
+	initOut InitOut
+
+	// set if io_uring queues are available, so we can offer
+	// CAP_OVER_IO_URING.
+	ioUring bool
+
 	opts *MountOptions
 
 	// in-flight notify-retrieve queries
@@ -35,6 +54,12 @@ func (ms *protocolServer) handleRequest(h *operationHandler, req *request) {
 	if req.status.Ok() && ms.opts.Debug {
 		ms.opts.Logger.Println(req.InputDebug())
 	}
+	if req.secctx != nil || req.createGroups != nil || !req.deadline.IsZero() {
+		ms.setContext(req, newRequestContext(req))
+	}
+	if ms.opts.Tracer != nil {
+		ms.startTrace(h, req)
+	}
 
 	if req.inHeader().NodeId == pollHackInode ||
 		req.inHeader().NodeId == FUSE_ROOT_ID && h.FileNames > 0 && req.filename() == pollHackName {
@@ -72,7 +97,7 @@ func (ms *protocolServer) handleRequest(h *operationHandler, req *request) {
 		// This includes osxfuse (a.k.a. macfuse).
 		req.outHeader().Length = uint32(sizeOfOutHeader) + 24
 	}
-	if req.fdData != nil && ms.opts.DisableSplice {
+	if req.fdData != nil && (ms.opts.DisableSplice || ms.opts.Recorder != nil) {
 		req.outPayload, req.status = req.fdData.Bytes(req.outPayload)
 		req.fdData = nil
 	}
@@ -87,11 +112,13 @@ func (ms *protocolServer) handleRequest(h *operationHandler, req *request) {
 func (ms *protocolServer) addInflight(req *request) {
 	ms.interruptMu.Lock()
 	defer ms.interruptMu.Unlock()
+	req.arrival = time.Now()
 	req.inflightIndex = len(ms.reqInflight)
 	ms.reqInflight = append(ms.reqInflight, req)
 }
 
 func (ms *protocolServer) dropInflight(req *request) {
+	ms.dropContext(req)
 	ms.interruptMu.Lock()
 	defer ms.interruptMu.Unlock()
 	this := req.inflightIndex
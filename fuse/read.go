@@ -30,6 +30,36 @@ func ReadResultData(b []byte) ReadResult {
 	return &readResultData{b}
 }
 
+// ReadResultVmsplice returns data like ReadResultData, but on Linux
+// the server maps the pages of large replies into a pipe with
+// vmsplice(2), and splices them to the FUSE device, which saves
+// copying them through a write(2). This helps file systems that
+// serve large blobs from memory. The kernel refers to data until the
+// reply is consumed, so data must not be modified until done is
+// called. done may be nil.
+func ReadResultVmsplice(data []byte, done func()) ReadResult {
+	return &readResultVmsplice{data, done}
+}
+
+type readResultVmsplice struct {
+	data []byte
+	done func()
+}
+
+func (r *readResultVmsplice) Size() int {
+	return len(r.data)
+}
+
+func (r *readResultVmsplice) Done() {
+	if r.done != nil {
+		r.done()
+	}
+}
+
+func (r *readResultVmsplice) Bytes(buf []byte) ([]byte, Status) {
+	return r.data, OK
+}
+
 func ReadResultFd(fd uintptr, off int64, sz int) ReadResult {
 	return &readResultFd{fd, off, sz}
 }
@@ -0,0 +1,85 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// vmspliceFS serves a file from blob with ReadResultVmsplice.
+type vmspliceFS struct {
+	readFS
+	blob []byte
+
+	// busy counts replies that refer to blob.
+	busy atomic.Int64
+	done atomic.Int64
+}
+
+func (fs *vmspliceFS) Read(cancel <-chan struct{}, input *ReadIn, buf []byte) (ReadResult, Status) {
+	if input.NodeId != 2 {
+		return nil, ENOENT
+	}
+	off := min(int(input.Offset), len(fs.blob))
+	end := min(off+int(input.Size), len(fs.blob))
+	fs.busy.Add(1)
+	return ReadResultVmsplice(fs.blob[off:end], func() {
+		fs.busy.Add(-1)
+		fs.done.Add(1)
+	}), OK
+}
+
+func TestReadResultVmsplice(t *testing.T) {
+	blob := make([]byte, 1<<20)
+	for i := range blob {
+		blob[i] = byte(i * 7)
+	}
+	fs := &vmspliceFS{blob: blob}
+
+	mnt := t.TempDir()
+	srv, err := NewServer(fs, mnt, &MountOptions{
+		Debug: testutil.VerboseTest(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMetrics()
+	srv.RecordMetrics(m)
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+
+	got, err := os.ReadFile(filepath.Join(mnt, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, blob) {
+		t.Errorf("got %d bytes, want %d", len(got), len(blob))
+	}
+	// The reader may see the data before the server calls Done.
+	for i := 0; fs.busy.Load() != 0 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := fs.busy.Load(); n != 0 {
+		t.Errorf("%d replies were not marked done", n)
+	}
+	if fs.done.Load() == 0 {
+		t.Errorf("done was not called")
+	}
+	if srv.canSplice {
+		if n := m.Snapshot().SpliceReplies; n == 0 {
+			t.Errorf("no replies were spliced")
+		}
+	}
+}
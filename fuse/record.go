@@ -0,0 +1,455 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// A trace file starts with traceMagic, followed by frames. A frame
+// is the kind (1 byte), the opcode, the time since the start of the
+// recording in nanoseconds and the data length (all uvarints),
+// followed by the data as it was sent over the wire.
+const traceMagic = "go-fuse trace 1\n"
+
+// TraceKind is the type of a frame in a trace.
+type TraceKind byte
+
+const (
+	// TraceRequest is a request from the kernel.
+	TraceRequest TraceKind = 1
+	// TraceReply is the reply to a request.
+	TraceReply TraceKind = 2
+	// TraceNotify is a notification sent to the kernel.
+	TraceNotify TraceKind = 3
+)
+
+func (k TraceKind) String() string {
+	switch k {
+	case TraceRequest:
+		return "request"
+	case TraceReply:
+		return "reply"
+	case TraceNotify:
+		return "notify"
+	}
+	return fmt.Sprintf("TraceKind(%d)", byte(k))
+}
+
+// Recorder writes the FUSE traffic of a server to a trace, which can
+// be printed with TraceReader, or fed to a file system with Replay.
+// Set it in MountOptions.Recorder. Splicing is disabled while
+// recording, so reply data can be captured.
+type Recorder struct {
+	mu    sync.Mutex
+	w     *bufio.Writer
+	start time.Time
+	err   error
+	hdr   [3 * binary.MaxVarintLen64]byte
+}
+
+// NewRecorder starts a trace on w.
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	r := &Recorder{
+		w:     bufio.NewWriter(w),
+		start: time.Now(),
+	}
+	if _, err := r.w.WriteString(traceMagic); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// record appends a frame. The data is the concatenation of bufs.
+func (r *Recorder) record(kind TraceKind, opcode uint32, bufs ...[]byte) {
+	size := 0
+	for _, b := range bufs {
+		size += len(b)
+	}
+	dt := time.Since(r.start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	n := binary.PutUvarint(r.hdr[:], uint64(opcode))
+	n += binary.PutUvarint(r.hdr[n:], uint64(dt))
+	n += binary.PutUvarint(r.hdr[n:], uint64(size))
+	r.err = r.w.WriteByte(byte(kind))
+	if r.err == nil {
+		_, r.err = r.w.Write(r.hdr[:n])
+	}
+	for _, b := range bufs {
+		if r.err == nil {
+			_, r.err = r.w.Write(b)
+		}
+	}
+}
+
+// Flush writes buffered frames to the underlying writer, and returns
+// the first error encountered while recording. The server flushes
+// the recorder when Serve returns.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+// recordReply records the reply to req, as it goes on the wire.
+func (r *Recorder) recordReply(kind TraceKind, req *request) {
+	n := int(req.outHeader().Length)
+	out, payload := req.outputBuf, req.outPayload
+	if n < len(out) {
+		out, payload = out[:n], nil
+	} else if n-len(out) < len(payload) {
+		payload = payload[:n-len(out)]
+	}
+	r.record(kind, req.inHeader().Opcode, out, payload)
+}
+
+// TraceFrame is a frame read from a trace.
+type TraceFrame struct {
+	Kind   TraceKind
+	Opcode uint32
+
+	// Time is the time since the start of the recording.
+	Time time.Duration
+
+	// Data holds the frame as it was sent over the wire, starting
+	// with the InHeader or OutHeader.
+	Data []byte
+
+	// For replies, the request it answers.
+	req *TraceFrame
+
+	// The INIT request of the trace.
+	init *InitIn
+}
+
+// Unique returns the unique ID of the request or reply. It is 0 for
+// notifications.
+func (f *TraceFrame) Unique() uint64 {
+	if f.Kind == TraceRequest {
+		if len(f.Data) < int(unsafe.Sizeof(InHeader{})) {
+			return 0
+		}
+		return (*InHeader)(unsafe.Pointer(&f.Data[0])).Unique
+	}
+	if len(f.Data) < int(sizeOfOutHeader) {
+		return 0
+	}
+	return (*OutHeader)(unsafe.Pointer(&f.Data[0])).Unique
+}
+
+// Request returns the request frame a reply answers, or nil.
+func (f *TraceFrame) Request() *TraceFrame {
+	return f.req
+}
+
+// parse reconstructs a request from a request frame.
+func (f *TraceFrame) parse() *request {
+	if getHandler(f.Opcode) == nil {
+		return nil
+	}
+	_, inSize, _, _, code := parseRequest(f.Data, f.init)
+	if !code.Ok() {
+		return nil
+	}
+	r := &request{
+		inputBuf:  f.Data[:inSize],
+		inPayload: f.Data[inSize:],
+	}
+	r.parseExtensions()
+	return r
+}
+
+// parseReply reconstructs the request for a reply or notification,
+// with the output filled in.
+func (f *TraceFrame) parseReply() *request {
+	h := getHandler(f.Opcode)
+	if h == nil || len(f.Data) < int(sizeOfOutHeader) {
+		return nil
+	}
+	var r *request
+	if f.Kind == TraceNotify {
+		in := InHeader{Opcode: f.Opcode}
+		r = &request{inputBuf: unsafe.Slice((*byte)(unsafe.Pointer(&in)), unsafe.Sizeof(in))}
+	} else if f.req != nil {
+		r = f.req.parse()
+	}
+	if r == nil {
+		return nil
+	}
+
+	hdr := (*OutHeader)(unsafe.Pointer(&f.Data[0]))
+	r.status = Status(-hdr.Status)
+	structLen := int(h.OutputSize)
+	if r.status > OK {
+		structLen = 0
+	}
+	if (f.Opcode == _OP_GETXATTR || f.Opcode == _OP_LISTXATTR) && (*GetXAttrIn)(r.inData()).Size != 0 {
+		structLen = 0
+	}
+	data := f.Data[sizeOfOutHeader:]
+	if structLen > len(data) {
+		// Old protocol versions have shorter structs; pad
+		// them so they can be printed.
+		structLen = len(data)
+	}
+	r.outputBuf = make([]byte, int(sizeOfOutHeader)+int(h.OutputSize))
+	copy(r.outputBuf, f.Data[:int(sizeOfOutHeader)+structLen])
+	if structLen == 0 {
+		r.outputBuf = r.outputBuf[:sizeOfOutHeader]
+	}
+	r.outPayload = data[structLen:]
+	return r
+}
+
+// String prints the frame like the debug output of the server does.
+func (f *TraceFrame) String() string {
+	switch f.Kind {
+	case TraceRequest:
+		if r := f.parse(); r != nil {
+			return r.InputDebug()
+		}
+	case TraceReply, TraceNotify:
+		if r := f.parseReply(); r != nil {
+			return r.OutputDebug()
+		}
+	}
+	return fmt.Sprintf("%v %s: %d bytes", f.Kind, operationName(f.Opcode), len(f.Data))
+}
+
+// TraceReader reads frames from a trace written by a Recorder.
+type TraceReader struct {
+	r       *bufio.Reader
+	init    *InitIn
+	pending map[uint64]*TraceFrame
+}
+
+// NewTraceReader checks the header of a trace, and returns a reader
+// for its frames.
+func NewTraceReader(r io.Reader) (*TraceReader, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(traceMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != traceMagic {
+		return nil, fmt.Errorf("not a go-fuse trace: header %q", magic)
+	}
+	return &TraceReader{
+		r:       br,
+		pending: map[uint64]*TraceFrame{},
+	}, nil
+}
+
+// Next returns the next frame, or io.EOF at the end of the trace.
+func (tr *TraceReader) Next() (*TraceFrame, error) {
+	kind, err := tr.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var vals [3]uint64
+	for i := range vals {
+		vals[i], err = binary.ReadUvarint(tr.r)
+		if err != nil {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+	f := &TraceFrame{
+		Kind:   TraceKind(kind),
+		Opcode: uint32(vals[0]),
+		Time:   time.Duration(vals[1]),
+		Data:   make([]byte, vals[2]),
+		init:   tr.init,
+	}
+	if _, err := io.ReadFull(tr.r, f.Data); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch f.Kind {
+	case TraceRequest:
+		if f.Opcode == _OP_INIT && tr.init == nil && len(f.Data) >= int(unsafe.Sizeof(InHeader{}))+8 {
+			in := InitIn{}
+			copy(unsafe.Slice((*byte)(unsafe.Pointer(&in)), unsafe.Sizeof(in)), f.Data)
+			tr.init = &in
+			f.init = &in
+		}
+		switch f.Opcode {
+		case _OP_FORGET, _OP_BATCH_FORGET, _OP_NOTIFY_REPLY:
+			// No reply.
+		default:
+			tr.pending[f.Unique()] = f
+		}
+	case TraceReply:
+		u := f.Unique()
+		f.req = tr.pending[u]
+		delete(tr.pending, u)
+	}
+	return f, nil
+}
+
+// ReplayDiff describes a reply that differs from the recorded one.
+type ReplayDiff struct {
+	// Request is the recorded request.
+	Request *TraceFrame
+
+	// Want is the recorded reply, and Got the reply of the
+	// file system, both printed like the debug output. Got is
+	// empty if the file system did not reply.
+	Want, Got string
+}
+
+func (d *ReplayDiff) String() string {
+	return fmt.Sprintf("%v\n  want %s\n  got  %s", d.Request, d.Want, d.Got)
+}
+
+// Replay feeds the requests of a trace to fs, one at a time, and
+// compares the replies with the recorded ones. The trace must start
+// with the INIT request. Recorded timing is not reproduced, and
+// requests that ran concurrently are replayed in the order they
+// arrived. Replies that depend on the kernel, such as backing IDs
+// for passthrough, cannot be reproduced. It returns the replies that
+// differ.
+func Replay(trace io.Reader, fs RawFileSystem, opts *MountOptions) ([]ReplayDiff, error) {
+	tr, err := NewTraceReader(trace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pair requests with replies, so we know which requests to
+	// wait for.
+	var requests []*TraceFrame
+	replies := map[*TraceFrame]*TraceFrame{}
+	for {
+		f, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch f.Kind {
+		case TraceRequest:
+			requests = append(requests, f)
+		case TraceReply:
+			if f.req != nil {
+				replies[f.req] = f
+			}
+		}
+	}
+	if len(requests) == 0 || requests[0].Opcode != _OP_INIT {
+		return nil, errors.New("trace does not start with INIT")
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	type result struct {
+		srv *Server
+		err error
+	}
+	started := make(chan result, 1)
+	go func() {
+		srv, err := NewTransportServer(fs, NewStreamTransport(server), opts)
+		started <- result{srv, err}
+	}()
+
+	var diffs []ReplayDiff
+	replay := func(req *TraceFrame) error {
+		if _, err := client.Write(req.Data); err != nil {
+			return err
+		}
+		want := replies[req]
+		if want == nil && !hasReply(req.Opcode) {
+			return nil
+		}
+		got, err := readReply(client, req.Unique())
+		if err != nil {
+			return err
+		}
+		if want == nil {
+			// The recording stopped before the reply was
+			// written. Discard ours, so it is not taken
+			// for the reply to the next request.
+			return nil
+		}
+		if !bytes.Equal(got, want.Data) {
+			gotFrame := &TraceFrame{Kind: TraceReply, Opcode: req.Opcode, Data: got, req: req}
+			diffs = append(diffs, ReplayDiff{
+				Request: req,
+				Want:    want.String(),
+				Got:     gotFrame.String(),
+			})
+		}
+		return nil
+	}
+
+	if err := replay(requests[0]); err != nil {
+		return nil, err
+	}
+	res := <-started
+	if res.err != nil {
+		return nil, res.err
+	}
+	go res.srv.Serve()
+	for _, req := range requests[1:] {
+		if err := replay(req); err != nil {
+			return diffs, err
+		}
+	}
+	client.Close()
+	res.srv.Wait()
+	return diffs, nil
+}
+
+// hasReply returns whether the file system replies to requests with
+// the given opcode.
+func hasReply(opcode uint32) bool {
+	switch opcode {
+	case _OP_FORGET, _OP_BATCH_FORGET, _OP_NOTIFY_REPLY:
+		return false
+	}
+	return true
+}
+
+// readReply reads frames from a stream until it finds the reply for
+// unique, skipping notifications.
+func readReply(r io.Reader, unique uint64) ([]byte, error) {
+	for {
+		var hdr OutHeader
+		hdrBytes := unsafe.Slice((*byte)(unsafe.Pointer(&hdr)), unsafe.Sizeof(hdr))
+		if _, err := io.ReadFull(r, hdrBytes); err != nil {
+			return nil, err
+		}
+		if int(hdr.Length) < len(hdrBytes) {
+			return nil, fmt.Errorf("reply too short: %d bytes", hdr.Length)
+		}
+		data := make([]byte, hdr.Length)
+		copy(data, hdrBytes)
+		if _, err := io.ReadFull(r, data[len(hdrBytes):]); err != nil {
+			return nil, err
+		}
+		if hdr.Unique == unique {
+			return data, nil
+		}
+		if hdr.Unique != 0 {
+			return nil, fmt.Errorf("got reply for %d, want %d", hdr.Unique, unique)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// shortReadFS returns less data than readFS.
+type shortReadFS struct {
+	readFS
+}
+
+func (fs *shortReadFS) Read(cancel <-chan struct{}, input *ReadIn, buf []byte) (ReadResult, Status) {
+	res, code := fs.readFS.Read(cancel, input, buf)
+	if code != OK {
+		return nil, code
+	}
+	data, _ := res.Bytes(buf)
+	return ReadResultData(data[:len(data)/2]), OK
+}
+
+func TestRecordReplay(t *testing.T) {
+	var trace bytes.Buffer
+	rec, err := NewRecorder(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, srv := startStreamServer(t, &readFS{}, &MountOptions{Recorder: rec})
+	go srv.Serve()
+
+	if out, _ := streamLookup(t, client, 2, "file"); out.Status != 0 {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	if out, _ := streamLookup(t, client, 3, "missing"); out.Status != -int32(ENOENT) {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	read := ReadIn{
+		InHeader: InHeader{
+			Opcode: _OP_READ,
+			Unique: 4,
+			NodeId: 2,
+		},
+		Size: 100,
+	}
+	read.Length = uint32(unsafe.Sizeof(read))
+	if out, _ := streamRoundTrip(t, client, structBytes(&read)); out.Status != 0 {
+		t.Fatalf("READ: status %d", out.Status)
+	}
+	client.Close()
+	srv.Wait()
+
+	// Serve flushes the recorder too, but Wait may return
+	// before it does.
+	if err := rec.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	tr, err := NewTraceReader(bytes.NewReader(trace.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for {
+		f, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if f.Kind == TraceReply && f.Request() == nil {
+			t.Errorf("reply %v has no request", f)
+		}
+		lines = append(lines, f.String())
+	}
+	want := []string{
+		"rx 1: INIT",
+		"tx 1:     OK, {7.",
+		`rx 2: LOOKUP n1  "file"`,
+		"tx 2:     OK, {n2 g0",
+		`rx 3: LOOKUP n1  "missing"`,
+		"tx 3:     2=no such file or directory",
+		"rx 4: READ n2 {Fh 0 [0 +100)",
+		`tx 4:     OK,  100b data "xxxxxxxx"...`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got frames\n%s", strings.Join(lines, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(lines[i], want[i]) {
+			t.Errorf("frame %d: got %q, want prefix %q", i, lines[i], want[i])
+		}
+	}
+
+	diffs, err := Replay(bytes.NewReader(trace.Bytes()), &readFS{}, &MountOptions{})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("replay against the same file system: got diffs %v", diffs)
+	}
+
+	diffs, err = Replay(bytes.NewReader(trace.Bytes()), &shortReadFS{}, &MountOptions{})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].Request.Opcode != _OP_READ || !strings.Contains(diffs[0].Got, " 50b data") {
+		t.Errorf("got diffs %v, want the READ to differ", diffs)
+	}
+}
+
+func TestReplayMissingReply(t *testing.T) {
+	var trace bytes.Buffer
+	rec, err := NewRecorder(&trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, srv := startStreamServer(t, &readFS{}, &MountOptions{Recorder: rec})
+	go srv.Serve()
+	streamLookup(t, client, 2, "file")
+	streamLookup(t, client, 3, "missing")
+	client.Close()
+	srv.Wait()
+	if err := rec.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// Copy the trace without the reply to the first LOOKUP, as
+	// if the recording had stopped before it was written.
+	var cut bytes.Buffer
+	cutRec, err := NewRecorder(&cut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTraceReader(bytes.NewReader(trace.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		f, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if f.Kind == TraceReply && f.Unique() == 2 {
+			continue
+		}
+		cutRec.record(f.Kind, f.Opcode, f.Data)
+	}
+	if err := cutRec.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	diffs, err := Replay(bytes.NewReader(cut.Bytes()), &readFS{}, &MountOptions{})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("got diffs %v", diffs)
+	}
+}
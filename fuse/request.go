@@ -6,6 +6,7 @@ package fuse
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"reflect"
@@ -27,6 +28,10 @@ type request struct {
 	// written under Server.interruptMu
 	interrupted bool
 
+	// arrival is when the request was added to the in-flight
+	// list.
+	arrival time.Time
+
 	// inHeader + opcode specific data
 	inputBuf []byte
 
@@ -49,6 +54,44 @@ type request struct {
 
 	// Start timestamp for timing info.
 	startTime time.Time
+
+	// Set if the reply data was spliced.
+	spliced bool
+
+	// deadline is set if the request has a timeout, see
+	// MountOptions.RequestTimeout.
+	deadline time.Time
+	timeout  *requestDeadline
+
+	// goroutine runs the handler, for the watchdog of
+	// MountOptions.SlowRequestThreshold. It is set before the
+	// request is in flight; slowLogged is protected by
+	// Server.interruptMu.
+	goroutine  uint64
+	slowLogged bool
+
+	// For WRITE requests read with MountOptions.EnableSpliceWrite,
+	// the data, which is not in inPayload.
+	writePipe *WritePipe
+
+	// Set if MountOptions.Tracer is set.
+	trace *RequestInfo
+
+	// Security contexts and groups from the request extensions.
+	secctx       []SecurityContext
+	createGroups []uint32
+
+	// ctx is the context for Context.Value, if the request has
+	// one. See Server.Context.
+	ctx context.Context
+
+	// The channel this request was read from, or nil for
+	// notifications.
+	channel *devChannel
+
+	// If set, the request arrived through io_uring, and the
+	// reply goes into this entry.
+	ringEntry *uringEntry
 }
 
 // requestAlloc holds the request, plus I/O buffers, which are
@@ -89,7 +132,18 @@ func (r *request) clear() {
 	r.outPayload = nil
 	r.fdData = nil
 	r.startTime = time.Time{}
+	r.spliced = false
+	r.deadline = time.Time{}
+	r.timeout = nil
+	r.goroutine = 0
+	r.slowLogged = false
+	r.writePipe = nil
+	r.trace = nil
+	r.secctx = nil
+	r.createGroups = nil
+	r.ctx = nil
 	r.readResult = nil
+	r.ringEntry = nil
 }
 
 func (r *requestAlloc) clear() {
@@ -133,6 +187,13 @@ func (r *request) InputDebug() string {
 		names = fmt.Sprintf("%q%s %db", r.inPayload[:l], dots, len(r.inPayload))
 	}
 
+	for _, sc := range r.secctx {
+		names += fmt.Sprintf(" %s=%q", sc.Name, sc.Value)
+	}
+	if r.createGroups != nil {
+		names += fmt.Sprintf(" groups=%v", r.createGroups)
+	}
+
 	return fmt.Sprintf("rx %d: %s n%d %s%s p%d",
 		hdr.Unique, operationName(hdr.Opcode), hdr.NodeId,
 		val, names, hdr.Caller.Pid)
@@ -15,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
@@ -43,31 +44,46 @@ type Server struct {
 	// Empty if unmounted.
 	mountPoint string
 
+	// detachedFd is the mount created with
+	// MountOptions.DetachedMount until it is attached, or -1.
+	detachedFd int
+
 	// writeMu serializes close and notify writes
 	writeMu sync.Mutex
 
-	// I/O with kernel and daemon.
+	// I/O with kernel and daemon. This is -1 if the server runs
+	// on a different transport.
 	mountFd int
 
 	opts *MountOptions
 
-	// maxReaders is the maximum number of goroutines reading requests
+	// maxReaders is the maximum number of goroutines reading
+	// requests from a single channel.
 	maxReaders int
 
-	// Pools for []byte
-	buffers bufferPool
+	// channels holds the transports we read requests from. The
+	// first entry is the mount fd or the transport passed to
+	// NewTransportServer; further entries are clones of the
+	// mount fd, see MountOptions.CloneQueues.
+	channels []*devChannel
+
+	// rings holds the io_uring queues, see
+	// MountOptions.EnableIOUring.
+	rings []*uringQueue
 
 	// Pool for request structs.
 	reqPool sync.Pool
 
-	// Pool for raw requests data
-	readPool sync.Pool
-
-	reqMu      sync.Mutex
-	reqReaders int
+	// timeouts holds the timeout for each opcode, or is nil if
+	// requests have no timeouts.
+	timeouts []time.Duration
 
 	singleReader bool
 	canSplice    bool
+
+	// spliceWrites is set if requests are read through a pipe,
+	// see MountOptions.EnableSpliceWrite.
+	spliceWrites atomic.Bool
 	loops        sync.WaitGroup
 	serving      bool // for preventing duplicate Serve() calls
 
@@ -78,6 +94,24 @@ type Server struct {
 	requestProcessingMu sync.Mutex
 }
 
+// devChannel is a transport, typically a file descriptor for the
+// FUSE device. Each channel has its own queue of requests in the
+// kernel, and its own reader goroutines and buffers in the
+// server. Replies must be written to the channel the request was
+// read from.
+type devChannel struct {
+	transport Transport
+
+	// Pools for []byte
+	buffers bufferPool
+
+	// Pool for raw requests data
+	readPool sync.Pool
+
+	reqMu      sync.Mutex
+	reqReaders int
+}
+
 // SetDebug is deprecated. Use MountOptions.Debug instead.
 func (ms *Server) SetDebug(dbg bool) {
 	// This will typically trigger the race detector.
@@ -118,6 +152,14 @@ func (ms *Server) RecordLatencies(l LatencyMap) {
 //
 // in this case.
 func (ms *Server) Unmount() (err error) {
+	if ms.detachedFd >= 0 {
+		// Dropping the last reference to a detached mount
+		// unmounts it.
+		syscall.Close(ms.detachedFd)
+		ms.detachedFd = -1
+		ms.loops.Wait()
+		return nil
+	}
 	if ms.mountPoint == "" {
 		return nil
 	}
@@ -146,6 +188,14 @@ func (ms *Server) Unmount() (err error) {
 	return err
 }
 
+// DetachedMountFd returns the file descriptor of a mount created with
+// MountOptions.DetachedMount, or -1 if there is none. The descriptor
+// can be passed to another process, which can attach the mount with
+// move_mount(2). It is closed by AttachMount and Unmount.
+func (ms *Server) DetachedMountFd() int {
+	return ms.detachedFd
+}
+
 // alignSlice ensures that the byte at alignedByte is aligned with the
 // given logical block size.  The input slice should be at least (size
 // + blockSize)
@@ -160,7 +210,95 @@ func alignSlice(buf []byte, alignedByte, blockSize, size uintptr) []byte {
 //
 // See the "Mount styles" section in the package documentation if you want to
 // know about the inner workings of the mount process. Usually you do not.
+//
+// If MountOptions.DetachedMount is set, `mountPoint` must be empty, and
+// the file system is attached later with AttachMount.
 func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server, error) {
+	ms, err := newServer(fs, opts)
+	if err != nil {
+		return nil, err
+	}
+	o := ms.opts
+	var fd int
+	if o.DetachedMount {
+		if mountPoint != "" {
+			return nil, fmt.Errorf("DetachedMount: mountPoint %q must be empty", mountPoint)
+		}
+		fd, ms.detachedFd, err = mountDetached(o, ms.ready)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		mountPoint = filepath.Clean(mountPoint)
+		if !filepath.IsAbs(mountPoint) && o.MountNamespaceFd == 0 {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return nil, err
+			}
+			mountPoint = filepath.Clean(filepath.Join(cwd, mountPoint))
+		}
+		fd, err = mount(mountPoint, o, ms.ready)
+		if err != nil {
+			return nil, err
+		}
+		ms.mountPoint = mountPoint
+	}
+
+	ms.mountFd = fd
+	ms.channels = []*devChannel{ms.newChannel(NewDevTransport(fd))}
+	if o.EnableIOUring {
+		if err := ms.newRingQueues(); err != nil {
+			o.Logger.Printf("io_uring unavailable, falling back to /dev/fuse: %v", err)
+		}
+	}
+
+	if code := ms.handleInit(); !code.Ok() {
+		ms.closeRings()
+		syscall.Close(fd)
+		if ms.detachedFd >= 0 {
+			syscall.Close(ms.detachedFd)
+		}
+		// TODO - unmount as well?
+		return nil, fmt.Errorf("init: %s", code)
+	}
+	if !ms.usesIOUring() {
+		ms.closeRings()
+	}
+	ms.cloneChannels()
+
+	// This prepares for Serve being called somewhere, either
+	// synchronously or asynchronously.
+	ms.loops.Add(1)
+	return ms, nil
+}
+
+// NewTransportServer creates a server that speaks the FUSE protocol
+// over the given transport, rather than a mounted /dev/fuse. It
+// waits for the INIT request from the client, and answers it before
+// returning. Options that only apply to mounting are ignored. The
+// server stops once the transport reports ENODEV, typically because
+// the client closed the connection.
+func NewTransportServer(fs RawFileSystem, t Transport, opts *MountOptions) (*Server, error) {
+	ms, err := newServer(fs, opts)
+	if err != nil {
+		return nil, err
+	}
+	ms.mountFd = -1
+	ms.channels = []*devChannel{ms.newChannel(t)}
+	close(ms.ready)
+
+	if code := ms.handleInit(); !code.Ok() {
+		t.Close()
+		return nil, fmt.Errorf("init: %s", code)
+	}
+
+	ms.loops.Add(1)
+	return ms, nil
+}
+
+// newServer creates a Server with options filled in, without a
+// channel to read requests from.
+func newServer(fs RawFileSystem, opts *MountOptions) (*Server, error) {
 	if opts == nil {
 		opts = &MountOptions{
 			MaxBackground: _DEFAULT_BACKGROUND_TASKS,
@@ -190,6 +328,10 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 		}
 		o.Name = strings.Replace(name[:l], ",", ";", -1)
 	}
+	timeouts, err := requestTimeouts(&o)
+	if err != nil {
+		return nil, err
+	}
 
 	maxReaders := runtime.GOMAXPROCS(0)
 	if maxReaders < minMaxReaders {
@@ -208,6 +350,8 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 		maxReaders:   maxReaders,
 		singleReader: useSingleReader,
 		ready:        make(chan error, 1),
+		detachedFd:   -1,
+		timeouts:     timeouts,
 	}
 	ms.reqPool.New = func() interface{} {
 		return &requestAlloc{
@@ -216,8 +360,14 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 			},
 		}
 	}
-	ms.readPool.New = func() interface{} {
-		targetSize := o.MaxWrite + int(maxInputSize)
+	return ms, nil
+}
+
+// newChannel sets up the reader state for a transport.
+func (ms *Server) newChannel(t Transport) *devChannel {
+	ch := &devChannel{transport: t}
+	ch.readPool.New = func() interface{} {
+		targetSize := ms.opts.MaxWrite + int(maxInputSize)
 		if targetSize < _FUSE_MIN_READ_BUFFER {
 			targetSize = _FUSE_MIN_READ_BUFFER
 		}
@@ -229,32 +379,33 @@ func NewServer(fs RawFileSystem, mountPoint string, opts *MountOptions) (*Server
 		buf = alignSlice(buf, unsafe.Sizeof(WriteIn{}), logicalBlockSize, uintptr(targetSize))
 		return buf
 	}
-	mountPoint = filepath.Clean(mountPoint)
-	if !filepath.IsAbs(mountPoint) {
-		cwd, err := os.Getwd()
+	return ch
+}
+
+// cloneChannels opens the extra channels requested through
+// MountOptions.CloneQueues. If the kernel does not support cloning
+// the device, we continue with the mount fd only.
+func (ms *Server) cloneChannels() {
+	if ms.singleReader {
+		return
+	}
+	for len(ms.channels) < ms.opts.CloneQueues {
+		fd, err := cloneDevFd(ms.mountFd)
 		if err != nil {
-			return nil, err
+			ms.opts.Logger.Printf("cloning FUSE device: %v; continuing with %d channel(s)", err, len(ms.channels))
+			break
 		}
-		mountPoint = filepath.Clean(filepath.Join(cwd, mountPoint))
+		ms.channels = append(ms.channels, ms.newChannel(NewDevTransport(fd)))
 	}
-	fd, err := mount(mountPoint, &o, ms.ready)
-	if err != nil {
-		return nil, err
-	}
-
-	ms.mountPoint = mountPoint
-	ms.mountFd = fd
 
-	if code := ms.handleInit(); !code.Ok() {
-		syscall.Close(fd)
-		// TODO - unmount as well?
-		return nil, fmt.Errorf("init: %s", code)
+	// Spread the readers over the channels, so the total number
+	// of readers stays the same.
+	if n := len(ms.channels); n > 1 {
+		ms.maxReaders = ms.maxReaders / n
+		if ms.maxReaders < minMaxReaders {
+			ms.maxReaders = minMaxReaders
+		}
 	}
-
-	// This prepares for Serve being called somewhere, either
-	// synchronously or asynchronously.
-	ms.loops.Add(1)
-	return ms, nil
 }
 
 func escape(optionValue string) string {
@@ -310,10 +461,15 @@ func (o *MountOptions) containsOption(opt string) bool {
 // purposes.
 func (ms *Server) DebugData() string {
 	var r int
-	ms.reqMu.Lock()
-	r = ms.reqReaders
-	ms.reqMu.Unlock()
+	for _, ch := range ms.channels {
+		ch.reqMu.Lock()
+		r += ch.reqReaders
+		ch.reqMu.Unlock()
+	}
 
+	if len(ms.channels) > 1 {
+		return fmt.Sprintf("readers: %d, channels: %d", r, len(ms.channels))
+	}
 	return fmt.Sprintf("readers: %d", r)
 }
 
@@ -338,46 +494,48 @@ func handleEINTR(fn func() error) (err error) {
 
 // Returns a new request, or error. In case exitIdle is given, returns
 // nil, OK if we have too many readers already.
-func (ms *Server) readRequest(exitIdle bool) (req *requestAlloc, code Status) {
-	ms.reqMu.Lock()
-	if ms.reqReaders > ms.maxReaders {
-		ms.reqMu.Unlock()
+func (ms *Server) readRequest(ch *devChannel, exitIdle bool) (req *requestAlloc, code Status) {
+	ch.reqMu.Lock()
+	if ch.reqReaders > ms.maxReaders {
+		ch.reqMu.Unlock()
 		return nil, OK
 	}
-	ms.reqReaders++
-	ms.reqMu.Unlock()
+	ch.reqReaders++
+	ch.reqMu.Unlock()
 
 	reqIface := ms.reqPool.Get()
 	req = reqIface.(*requestAlloc)
-	destIface := ms.readPool.Get()
+	destIface := ch.readPool.Get()
 	dest := destIface.([]byte)
 
 	var n int
-	err := handleEINTR(func() error {
-		var err error
-		n, err = syscall.Read(ms.mountFd, dest)
-		return err
-	})
+	var pipe *WritePipe
+	var err error
+	if ms.spliceWrites.Load() && ch.transport.SpliceFd() >= 0 {
+		n, pipe, err = ms.readSplice(ch.transport, dest)
+	} else {
+		n, err = ch.transport.ReadRequest(dest)
+	}
 	if err != nil {
 		code = ToStatus(err)
 		ms.reqPool.Put(reqIface)
-		ms.reqMu.Lock()
-		ms.reqReaders--
-		ms.reqMu.Unlock()
+		ch.reqMu.Lock()
+		ch.reqReaders--
+		ch.reqMu.Unlock()
 		return nil, code
 	}
 
-	if ms.latencies != nil {
-		req.startTime = time.Now()
-	}
-	ms.reqMu.Lock()
-	defer ms.reqMu.Unlock()
+	req.channel = ch
+	ch.reqMu.Lock()
+	defer ch.reqMu.Unlock()
 	gobbled := req.setInput(dest[:n])
+	req.writePipe = pipe
 	if len(req.inputBuf) < int(unsafe.Sizeof(InHeader{})) {
 		log.Printf("Short read for input header: %v", req.inputBuf)
 		return nil, EINVAL
 	}
 	opCode := ((*InHeader)(unsafe.Pointer(&req.inputBuf[0]))).Opcode
+	ms.startStats(&req.request, opCode)
 	/* These messages don't expect reply, so they cost nothing for
 	   the kernel to send. Make sure we're not overwhelmed by not
 	   spawning a new reader.
@@ -385,12 +543,12 @@ func (ms *Server) readRequest(exitIdle bool) (req *requestAlloc, code Status) {
 	needsBackPressure := (opCode == _OP_FORGET || opCode == _OP_BATCH_FORGET)
 
 	if !gobbled {
-		ms.readPool.Put(destIface)
+		ch.readPool.Put(destIface)
 	}
-	ms.reqReaders--
-	if !ms.singleReader && ms.reqReaders <= 0 && !needsBackPressure {
+	ch.reqReaders--
+	if !ms.singleReader && ch.reqReaders <= 0 && !needsBackPressure {
 		ms.loops.Add(1)
-		go ms.loop(true)
+		go ms.loop(ch, true)
 	}
 
 	return req, OK
@@ -398,10 +556,20 @@ func (ms *Server) readRequest(exitIdle bool) (req *requestAlloc, code Status) {
 
 // returnRequest returns a request to the pool of unused requests.
 func (ms *Server) returnRequest(req *requestAlloc) {
+	if e := req.ringEntry; e != nil {
+		// The request needs no reply, or failed to parse. The
+		// entry must be committed to be reused.
+		e.replyStatus(req.inHeader().Unique, req.status)
+	}
 	ms.recordStats(&req.request)
+	ms.endTrace(&req.request)
+	if req.writePipe != nil {
+		req.writePipe.release()
+	}
 
+	ch := req.channel
 	if req.bufferPoolOutputBuf != nil {
-		ms.buffers.FreeBuffer(req.bufferPoolOutputBuf)
+		ch.buffers.FreeBuffer(req.bufferPoolOutputBuf)
 		req.bufferPoolOutputBuf = nil
 	}
 	if req.interrupted {
@@ -412,17 +580,34 @@ func (ms *Server) returnRequest(req *requestAlloc) {
 
 	if p := req.bufferPoolInputBuf; p != nil {
 		req.bufferPoolInputBuf = nil
-		ms.readPool.Put(p)
+		ch.readPool.Put(p)
 	}
 	ms.reqPool.Put(req)
 }
 
+// startStats starts timing a request, if statistics are collected.
+func (ms *Server) startStats(req *request, opCode uint32) {
+	if ms.latencies == nil && ms.metrics == nil {
+		return
+	}
+	req.startTime = time.Now()
+	if ms.metrics != nil {
+		ms.metrics.start(opCode)
+	}
+}
+
 func (ms *Server) recordStats(req *request) {
+	if req.startTime.IsZero() {
+		return
+	}
+	dt := time.Now().Sub(req.startTime)
 	if ms.latencies != nil {
-		dt := time.Now().Sub(req.startTime)
 		opname := operationName(req.inHeader().Opcode)
 		ms.latencies.Add(opname, dt)
 	}
+	if ms.metrics != nil {
+		ms.metrics.done(req, dt)
+	}
 }
 
 // Serve initiates the FUSE loop. Normally, callers should run Serve()
@@ -439,12 +624,31 @@ func (ms *Server) Serve() {
 	}
 	ms.serving = true
 
-	ms.loop(false)
+	for _, ch := range ms.channels[1:] {
+		ms.loops.Add(1)
+		go ms.loop(ch, false)
+	}
+	if ms.usesIOUring() {
+		ms.startRings()
+	}
+	if ms.opts.SlowRequestThreshold > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go ms.watchdog(stop)
+	}
+	ms.loop(ms.channels[0], false)
 	ms.loops.Wait()
 
 	ms.writeMu.Lock()
-	syscall.Close(ms.mountFd)
+	for _, ch := range ms.channels {
+		ch.transport.Close()
+	}
 	ms.writeMu.Unlock()
+	if rec := ms.opts.Recorder; rec != nil {
+		if err := rec.Flush(); err != nil {
+			ms.opts.Logger.Printf("Recorder: %v", err)
+		}
+	}
 
 	// shutdown in-flight cache retrieves.
 	//
@@ -475,7 +679,7 @@ func (ms *Server) handleInit() Status {
 	// and don't spawn new readers.
 	orig := ms.singleReader
 	ms.singleReader = true
-	req, errNo := ms.readRequest(false)
+	req, errNo := ms.readRequest(ms.channels[0], false)
 	ms.singleReader = orig
 
 	if errNo != OK || req == nil {
@@ -522,11 +726,12 @@ func (ms *Server) handleInit() Status {
 // BenchmarkGoFuseStat-2          	    9310	    121332 ns/op
 // BenchmarkGoFuseReaddir         	    4074	    361568 ns/op
 // BenchmarkGoFuseReaddir-2       	    3511	    319765 ns/op
-func (ms *Server) loop(exitIdle bool) {
+func (ms *Server) loop(ch *devChannel, exitIdle bool) {
 	defer ms.loops.Done()
+	goroutine := ms.handlerGoroutine()
 exit:
 	for {
-		req, errNo := ms.readRequest(exitIdle)
+		req, errNo := ms.readRequest(ch, exitIdle)
 		switch errNo {
 		case OK:
 			if req == nil {
@@ -549,8 +754,12 @@ exit:
 		}
 
 		if ms.singleReader {
-			go ms.handleRequest(req)
+			go func() {
+				req.goroutine = ms.handlerGoroutine()
+				ms.handleRequest(req)
+			}()
 		} else {
+			req.goroutine = goroutine
 			ms.handleRequest(req)
 		}
 	}
@@ -563,24 +772,44 @@ func (ms *Server) handleRequest(req *requestAlloc) Status {
 		defer ms.requestProcessingMu.Unlock()
 	}
 
+	if rec := ms.opts.Recorder; rec != nil {
+		rec.record(TraceRequest, req.inHeader().Opcode, req.inputBuf)
+	}
 	h, inSize, outSize, outPayloadSize, code := parseRequest(req.inputBuf, &ms.kernelSettings)
 	if !code.Ok() {
 		ms.opts.Logger.Printf("parseRequest: %v", code)
+		req.status = code
 		return code
 	}
 
 	req.inPayload = req.inputBuf[inSize:]
 	req.inputBuf = req.inputBuf[:inSize]
+	if code := req.parseExtensions(); !code.Ok() {
+		ms.opts.Logger.Printf("parseExtensions: %v", code)
+		req.status = code
+	}
 	req.outputBuf = req.outBuf[:outSize+int(sizeOfOutHeader)]
 	copy(req.outputBuf, zeroOutBuf[:])
 	if outPayloadSize > 0 {
-		req.bufferPoolOutputBuf = ms.buffers.AllocBuffer(uint32(outPayloadSize))
+		req.bufferPoolOutputBuf = req.channel.buffers.AllocBuffer(uint32(outPayloadSize))
 		req.outPayload = req.bufferPoolOutputBuf
 	}
+	ms.startDeadline(&req.request)
 	ms.protocolServer.handleRequest(h, &req.request)
+	if ms.stopDeadline(&req.request) {
+		// The kernel got the timeout reply already.
+		ms.releaseLateReply(&req.request)
+		if req.readResult != nil {
+			req.readResult.Done()
+		}
+		return OK
+	}
 	if req.suppressReply {
 		return OK
 	}
+	if rec := ms.opts.Recorder; rec != nil {
+		rec.recordReply(TraceReply, &req.request)
+	}
 	errno := ms.write(&req.request)
 	if errno != 0 {
 		// Ignore ENOENT for INTERRUPT responses which
@@ -604,8 +833,21 @@ func (ms *Server) handleRequest(req *requestAlloc) Status {
 	return errno
 }
 
+// replyTransport returns the transport to write the reply for req
+// to. The kernel only accepts a reply on the channel that delivered
+// the request.
+func (ms *Server) replyTransport(req *request) Transport {
+	if req.channel != nil {
+		return req.channel.transport
+	}
+	return ms.channels[0].transport
+}
+
 func (ms *Server) notifyWrite(req *request) Status {
 	req.serializeHeader(req.outPayloadSize())
+	if rec := ms.opts.Recorder; rec != nil {
+		rec.recordReply(TraceNotify, req)
+	}
 
 	if ms.opts.Debug {
 		ms.opts.Logger.Println(req.OutputDebug())
@@ -656,6 +898,14 @@ func (ms *Server) InodeNotify(node uint64, off int64, length int64) Status {
 	return ms.notifyWrite(req)
 }
 
+// InodeNotifyExpire marks the attributes of the inode as expired, so
+// the next stat issues a GETATTR. Unlike InodeNotify, the data cache
+// is kept.
+func (ms *Server) InodeNotifyExpire(node uint64) Status {
+	// The kernel only invalidates pages for offsets >= 0.
+	return ms.InodeNotify(node, -1, 0)
+}
+
 // InodeNotifyStoreCache tells kernel to store data into inode's cache.
 //
 // This call is similar to InodeNotify, but instead of only invalidating a data
@@ -744,6 +994,11 @@ func (ms *Server) inodeRetrieveCache1(node uint64, offset int64, dest []byte) (n
 	if !ms.kernelSettings.SupportsNotify(NOTIFY_RETRIEVE_CACHE) {
 		return 0, ENOSYS
 	}
+	if ms.usesIOUring() {
+		// The kernel fails to deliver the NOTIFY_REPLY over
+		// io_uring, so we would wait forever.
+		return 0, ENOSYS
+	}
 
 	req := newNotifyRequest(_OP_NOTIFY_RETRIEVE_CACHE)
 
@@ -846,6 +1101,22 @@ func (ms *Server) DeleteNotify(parent uint64, child uint64, name string) Status
 // within a directory changes. You should not hold any FUSE filesystem
 // locks, as that can lead to deadlock.
 func (ms *Server) EntryNotify(parent uint64, name string) Status {
+	return ms.entryNotify(parent, name, 0)
+}
+
+// EntryNotifyExpire marks an entry within a directory as expired,
+// so the next access revalidates it with a LOOKUP. Unlike
+// EntryNotify, the entry is not dropped from the kernel cache, so
+// eg. mounts on top of it stay. This needs CAP_HAS_EXPIRE_ONLY
+// (Linux 6.2); older kernels return ENOSYS.
+func (ms *Server) EntryNotifyExpire(parent uint64, name string) Status {
+	if ms.kernelSettings.Flags64()&CAP_HAS_EXPIRE_ONLY == 0 {
+		return ENOSYS
+	}
+	return ms.entryNotify(parent, name, FUSE_EXPIRE_ONLY)
+}
+
+func (ms *Server) entryNotify(parent uint64, name string, flags uint32) Status {
 	if !ms.kernelSettings.SupportsNotify(NOTIFY_INVAL_ENTRY) {
 		return ENOSYS
 	}
@@ -853,6 +1124,7 @@ func (ms *Server) EntryNotify(parent uint64, name string) Status {
 	entry := (*NotifyInvalEntryOut)(req.outData())
 	entry.Parent = parent
 	entry.NameLen = uint32(len(name))
+	entry.Flags = flags
 
 	// Many versions of FUSE generate stacktraces if the
 	// terminating null byte is missing.
@@ -864,6 +1136,11 @@ func (ms *Server) EntryNotify(parent uint64, name string) Status {
 	return ms.notifyWrite(req)
 }
 
+// usesIOUring returns whether the kernel accepted FUSE over io_uring.
+func (ms *Server) usesIOUring() bool {
+	return ms.ioUring && ms.kernelSettings.Flags64()&CAP_OVER_IO_URING != 0
+}
+
 // SupportsVersion returns true if the kernel supports the given
 // protocol version or newer.
 func (in *InitIn) SupportsVersion(maj, min uint32) bool {
@@ -900,11 +1177,19 @@ func (ms *Server) WaitMount() error {
 	if err != nil {
 		return err
 	}
+	if ms.mountPoint == "" {
+		// Served over a transport; there is nothing to poll.
+		return nil
+	}
 	if parseFuseFd(ms.mountPoint) >= 0 {
 		// Magic `/dev/fd/N` mountpoint. We don't know the real mountpoint, so
 		// we cannot run the poll hack.
 		return nil
 	}
+	if ms.opts.MountNamespaceFd != 0 {
+		// The mount point is in another namespace.
+		return nil
+	}
 	return pollHack(ms.mountPoint)
 }
 
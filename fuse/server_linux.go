@@ -4,24 +4,21 @@
 
 package fuse
 
-import (
-	"syscall"
-)
-
 const useSingleReader = false
 
 func (ms *Server) write(req *request) Status {
+	if req.ringEntry != nil {
+		return req.ringEntry.reply(req)
+	}
+	t := ms.replyTransport(req)
 	if req.outPayloadSize() == 0 {
-		err := handleEINTR(func() error {
-			_, err := syscall.Write(ms.mountFd, req.outputBuf)
-			return err
-		})
-		return ToStatus(err)
+		return ToStatus(t.WriteReply([][]byte{req.outputBuf}))
 	}
 	if req.fdData != nil {
-		if ms.canSplice {
+		if ms.canSplice && t.SpliceFd() >= 0 {
 			err := ms.trySplice(req, req.fdData)
 			if err == nil {
+				req.spliced = true
 				req.readResult.Done()
 				return OK
 			}
@@ -30,9 +27,19 @@ func (ms *Server) write(req *request) Status {
 
 		req.outPayload, req.status = req.fdData.Bytes(req.outPayload)
 		req.serializeHeader(len(req.outPayload))
+	} else if _, ok := req.readResult.(*readResultVmsplice); ok && len(req.outPayload) >= minVmsplice {
+		if ms.canSplice && t.SpliceFd() >= 0 {
+			err := ms.tryVmsplice(req)
+			if err == nil {
+				req.spliced = true
+				req.readResult.Done()
+				return OK
+			}
+			ms.opts.Logger.Println("tryVmsplice:", err)
+		}
 	}
 
-	_, err := writev(ms.mountFd, [][]byte{req.outputBuf, req.outPayload})
+	err := t.WriteReply([][]byte{req.outputBuf, req.outPayload})
 	if req.readResult != nil {
 		req.readResult.Done()
 	}
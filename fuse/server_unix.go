@@ -2,22 +2,15 @@
 
 package fuse
 
-import (
-	"golang.org/x/sys/unix"
-)
-
 // OSX and FreeBSD has races when multiple routines read
 // from the FUSE device: on unmount, sometime some reads
 // do not error-out, meaning that unmount will hang.
 const useSingleReader = true
 
 func (ms *Server) write(req *request) Status {
+	t := ms.replyTransport(req)
 	if req.outPayloadSize() == 0 {
-		err := handleEINTR(func() error {
-			_, err := unix.Write(ms.mountFd, req.outputBuf)
-			return err
-		})
-		return ToStatus(err)
+		return ToStatus(t.WriteReply([][]byte{req.outputBuf}))
 	}
 
 	if req.fdData != nil {
@@ -25,7 +18,7 @@ func (ms *Server) write(req *request) Status {
 		req.serializeHeader(len(req.outPayload))
 	}
 
-	_, err := writev(int(ms.mountFd), [][]byte{req.outputBuf, req.outPayload})
+	err := t.WriteReply([][]byte{req.outputBuf, req.outPayload})
 	if req.readResult != nil {
 		req.readResult.Done()
 	}
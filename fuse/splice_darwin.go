@@ -15,3 +15,11 @@ func (ms *Server) setSplice() {
 func (ms *Server) trySplice(header []byte, req *request, fdData *readResultFd) error {
 	return fmt.Errorf("unimplemented")
 }
+
+func (ms *Server) readSplice(t Transport, dest []byte) (int, *WritePipe, error) {
+	return 0, nil, fmt.Errorf("unimplemented")
+}
+
+func splicePairs() (total, used int) {
+	return 0, 0
+}
@@ -7,12 +7,107 @@ package fuse
 import (
 	"fmt"
 	"os"
+	"syscall"
+	"unsafe"
 
 	"github.com/hanwen/go-fuse/v2/splice"
 )
 
+// minSpliceWrite is the smallest WRITE whose data is left in the
+// pipe. For smaller writes, setting up the splice costs more than
+// copying the data.
+const minSpliceWrite = 16 * 1024
+
 func (ms *Server) setSplice() {
 	ms.canSplice = splice.Resizable() && !ms.opts.DisableSplice
+
+	_, ok := ms.fileSystem.(RawSpliceWriter)
+	if ok && ms.canSplice && ms.opts.EnableSpliceWrite && ms.opts.Recorder == nil {
+		ms.spliceWrites.Store(true)
+	}
+}
+
+// readSplice reads a request from the FUSE device through a pipe.
+// For a large WRITE request, only the header and WriteIn are read
+// into dest, and the data is returned in a WritePipe. Other requests
+// are read into dest completely. If no pipe can be set up, the
+// request is read directly. If the device does not support
+// splicing, it also switches off spliceWrites.
+func (ms *Server) readSplice(t Transport, dest []byte) (int, *WritePipe, error) {
+	pair, err := splice.Get()
+	if err != nil {
+		return ms.readNoSplice(t, dest)
+	}
+	// Without the extra page the kernel will block once the
+	// pipe is almost full.
+	if err := pair.Grow(len(dest) + os.Getpagesize()); err != nil {
+		splice.Done(pair)
+		return ms.readNoSplice(t, dest)
+	}
+
+	var n int64
+	err = handleEINTR(func() error {
+		var err error
+		n, err = syscall.Splice(t.SpliceFd(), nil, int(pair.WriteFd()), nil, len(dest), 0)
+		return err
+	})
+	if err == syscall.EINVAL || err == syscall.ENOSYS {
+		splice.Done(pair)
+		if ms.spliceWrites.CompareAndSwap(true, false) {
+			ms.opts.Logger.Printf("splicing requests: %v; switching to read", err)
+		}
+		return ms.readNoSplice(t, dest)
+	}
+	if err != nil {
+		splice.Done(pair)
+		return 0, nil, err
+	}
+
+	hdrSize := int(unsafe.Sizeof(WriteIn{}))
+	m, err := readPipe(pair, dest[:min(int(n), hdrSize)])
+	if err != nil {
+		splice.Done(pair)
+		return 0, nil, err
+	}
+	if m == hdrSize && int(n)-m >= minSpliceWrite {
+		if in := (*WriteIn)(unsafe.Pointer(&dest[0])); in.Opcode == _OP_WRITE && int(in.Size) == int(n)-m {
+			return m, &WritePipe{
+				fd:   int(pair.ReadFd()),
+				size: int(n) - m,
+				done: func() { splice.Done(pair) },
+			}, nil
+		}
+	}
+
+	rest, err := readPipe(pair, dest[m:n])
+	splice.Done(pair)
+	return m + rest, nil, err
+}
+
+// readNoSplice reads a request without a pipe, after readSplice
+// failed to use one.
+func (ms *Server) readNoSplice(t Transport, dest []byte) (int, *WritePipe, error) {
+	n, err := t.ReadRequest(dest)
+	return n, nil, err
+}
+
+// readPipe fills buf from the pipe.
+func readPipe(pair *splice.Pair, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := pair.Read(buf[n:])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if m == 0 {
+			return n, fmt.Errorf("short splice: read %d, want %d", n, len(buf))
+		}
+		n += m
+	}
+	return n, nil
 }
 
 // trySplice:  Zero-copy read from fdData.Fd into /dev/fuse
@@ -88,9 +183,51 @@ func (ms *Server) trySplice(req *request, fdData *readResultFd) error {
 	}
 
 	// Write header + data to /dev/fuse
-	_, err = pair2.WriteTo(uintptr(ms.mountFd), total)
+	_, err = pair2.WriteTo(uintptr(ms.replyTransport(req).SpliceFd()), total)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// minVmsplice is the smallest ReadResultVmsplice reply that is
+// spliced. Below it, setting up the pipe costs more than the copy.
+const minVmsplice = 32 * 1024
+
+// tryVmsplice writes the reply for req through a pipe, mapping
+// req.outPayload into it rather than copying. The pipe is drained or
+// discarded before it returns, so the kernel no longer refers to
+// req.outPayload afterwards.
+func (ms *Server) tryVmsplice(req *request) error {
+	pair, err := splice.Get()
+	if err != nil {
+		return err
+	}
+	defer splice.Done(pair)
+
+	// Header and payload must go to the device in a single
+	// splice, so the pipe must hold both. Without the extra page
+	// the kernel will block once the pipe is almost full.
+	total := len(req.outputBuf) + len(req.outPayload)
+	if err := pair.Grow(total + os.Getpagesize()); err != nil {
+		return err
+	}
+	n, err := pair.Write(req.outputBuf)
+	if err != nil {
+		return err
+	}
+	if n != len(req.outputBuf) {
+		return fmt.Errorf("Short write into splice: wrote %d, want %d", n, len(req.outputBuf))
+	}
+	if _, err := pair.Vmsplice(req.outPayload); err != nil {
+		return err
+	}
+	_, err = pair.WriteTo(uintptr(ms.replyTransport(req).SpliceFd()), total)
+	return err
+}
+
+// splicePairs returns the number of pipe pairs in the splice pool,
+// and how many of them are in use.
+func splicePairs() (total, used int) {
+	return splice.Total(), splice.Used()
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Tracer observes the requests a Server handles. Set it in
+// MountOptions.Tracer.
+//
+// The methods are called from the goroutine that handles the
+// request, so they should be fast.
+type Tracer interface {
+	// StartRequest is called before the request is passed to the
+	// file system. ctx carries the Caller (see FromContext). The
+	// returned context, typically ctx with a span added, backs
+	// the Value method of the Context that Server.Context returns
+	// for the request, so spans started by the file system become
+	// its children.
+	StartRequest(ctx context.Context, info *RequestInfo) context.Context
+
+	// EndRequest is called after the reply was sent, with the
+	// context returned by StartRequest. info.Status and
+	// info.Duration are filled in.
+	EndRequest(ctx context.Context, info *RequestInfo)
+}
+
+// RequestInfo describes a request for a Tracer.
+type RequestInfo struct {
+	Unique uint64
+	Opcode uint32
+
+	// Op is the name of the opcode, eg. "LOOKUP".
+	Op     string
+	NodeId uint64
+	Caller Caller
+
+	// Names holds the file names in the request, eg. the name
+	// to look up, or the old and new names for RENAME.
+	Names []string
+
+	Start time.Time
+
+	// Status and Duration are set for EndRequest.
+	Status   Status
+	Duration time.Duration
+
+	req *request
+}
+
+// Args prints the request arguments, like the debug output does. It
+// may only be called from Tracer methods.
+func (ri *RequestInfo) Args() string {
+	h := getHandler(ri.Opcode)
+	if ri.req == nil || h == nil || h.InType == nil {
+		return ""
+	}
+	return Print(asType(ri.req.inData(), h.InType))
+}
+
+// Reply prints the reply data, like the debug output does. It may
+// only be called from Tracer.EndRequest.
+func (ri *RequestInfo) Reply() string {
+	h := getHandler(ri.Opcode)
+	r := ri.req
+	if r == nil || h == nil || h.OutType == nil || r.status != OK || len(r.outputBuf) <= int(sizeOfOutHeader) {
+		return ""
+	}
+	return Print(asType(r.outData(), h.OutType))
+}
+
+// setContext sets the context of req, which Server.Context passes
+// on to the file system.
+func (ms *protocolServer) setContext(req *request, ctx context.Context) {
+	ms.contextMu.Lock()
+	defer ms.contextMu.Unlock()
+	if req.ctx == nil {
+		if ms.contextReqs == nil {
+			ms.contextReqs = map[uint64]*request{}
+		}
+		ms.contextReqs[req.inHeader().Unique] = req
+		ms.contexts.Add(1)
+	}
+	req.ctx = ctx
+}
+
+// dropContext forgets the context of a finished request.
+func (ms *protocolServer) dropContext(req *request) {
+	if req.ctx == nil {
+		return
+	}
+	ms.contextMu.Lock()
+	delete(ms.contextReqs, req.inHeader().Unique)
+	ms.contextMu.Unlock()
+	ms.contexts.Add(-1)
+}
+
+// requestContext returns the context of the in-flight request with
+// the given unique ID and cancel channel, or nil if it has none.
+func (ms *protocolServer) requestContext(unique uint64, cancel <-chan struct{}) context.Context {
+	if ms.contexts.Load() == 0 {
+		return nil
+	}
+	ms.contextMu.Lock()
+	defer ms.contextMu.Unlock()
+	if req := ms.contextReqs[unique]; req != nil && req.cancel == cancel {
+		return req.ctx
+	}
+	return nil
+}
+
+// newRequestContext returns the context for a request that needs
+// one.
+func newRequestContext(req *request) context.Context {
+	ctx := NewContext(context.Background(), &req.inHeader().Caller)
+	if req.secctx != nil {
+		ctx = WithSecurityContext(ctx, req.secctx)
+	}
+	if req.createGroups != nil {
+		ctx = context.WithValue(ctx, createGroupsKey, req.createGroups)
+	}
+	if !req.deadline.IsZero() {
+		ctx = &deadlineContext{ctx, req.deadline}
+	}
+	return ctx
+}
+
+// startTrace calls the tracer for a new request.
+func (ms *protocolServer) startTrace(h *operationHandler, req *request) {
+	hdr := req.inHeader()
+	info := &RequestInfo{
+		Unique: hdr.Unique,
+		Opcode: hdr.Opcode,
+		Op:     operationName(hdr.Opcode),
+		NodeId: hdr.NodeId,
+		Caller: hdr.Caller,
+		Start:  time.Now(),
+		req:    req,
+	}
+	if h.FileNames == 1 {
+		info.Names = []string{req.filename()}
+	} else if h.FileNames == 2 {
+		n1, n2 := req.filenames()
+		info.Names = []string{n1, n2}
+	}
+	ctx := req.ctx
+	if ctx == nil {
+		ctx = newRequestContext(req)
+	}
+	ctx = ms.opts.Tracer.StartRequest(ctx, info)
+	req.trace = info
+	ms.setContext(req, ctx)
+}
+
+// endTrace calls the tracer for a finished request.
+func (ms *protocolServer) endTrace(req *request) {
+	info := req.trace
+	if info == nil {
+		return
+	}
+	info.Status = req.status
+	info.Duration = time.Since(info.Start)
+	ms.opts.Tracer.EndRequest(req.ctx, info)
+	info.req = nil
+}
+
+// slogTracer logs requests through log/slog.
+type slogTracer struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// NewSlogTracer returns a Tracer that logs every request and reply
+// to logger at the given level, with structured fields rather than
+// the text of MountOptions.Debug.
+func NewSlogTracer(logger *slog.Logger, level slog.Level) Tracer {
+	return &slogTracer{logger, level}
+}
+
+func (t *slogTracer) StartRequest(ctx context.Context, info *RequestInfo) context.Context {
+	if !t.logger.Enabled(ctx, t.level) {
+		return ctx
+	}
+	attrs := []slog.Attr{
+		slog.Uint64("unique", info.Unique),
+		slog.String("op", info.Op),
+		slog.Uint64("nodeid", info.NodeId),
+		slog.Group("caller",
+			slog.Uint64("uid", uint64(info.Caller.Uid)),
+			slog.Uint64("gid", uint64(info.Caller.Gid)),
+			slog.Uint64("pid", uint64(info.Caller.Pid))),
+	}
+	if len(info.Names) > 0 {
+		attrs = append(attrs, slog.Any("names", info.Names))
+	}
+	if args := info.Args(); args != "" {
+		attrs = append(attrs, slog.String("args", args))
+	}
+	t.logger.LogAttrs(ctx, t.level, "fuse request", attrs...)
+	return ctx
+}
+
+func (t *slogTracer) EndRequest(ctx context.Context, info *RequestInfo) {
+	if !t.logger.Enabled(ctx, t.level) {
+		return
+	}
+	status := "OK"
+	if info.Status != OK {
+		status = errnoName(info.Status)
+	}
+	attrs := []slog.Attr{
+		slog.Uint64("unique", info.Unique),
+		slog.String("op", info.Op),
+		slog.String("status", status),
+		slog.Duration("duration", info.Duration),
+	}
+	if reply := info.Reply(); reply != "" {
+		attrs = append(attrs, slog.String("reply", reply))
+	}
+	t.logger.LogAttrs(ctx, t.level, "fuse reply", attrs...)
+}
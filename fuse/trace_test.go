@@ -0,0 +1,140 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+type spanKey struct{}
+
+type testSpan struct {
+	op   string
+	name string
+}
+
+// recordingTracer adds a testSpan to the request context, and
+// records finished requests.
+type recordingTracer struct {
+	mu   sync.Mutex
+	done []RequestInfo
+}
+
+func (t *recordingTracer) StartRequest(ctx context.Context, info *RequestInfo) context.Context {
+	span := &testSpan{op: info.Op}
+	if len(info.Names) > 0 {
+		span.name = info.Names[0]
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *recordingTracer) EndRequest(ctx context.Context, info *RequestInfo) {
+	if ctx.Value(spanKey{}) == nil {
+		panic("span missing in EndRequest")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = append(t.done, *info)
+}
+
+// contextFS keeps the server, so file systems embedding it can get
+// the Context of a request.
+type contextFS struct {
+	readFS
+	srv *Server
+}
+
+func (fs *contextFS) Init(s *Server) {
+	fs.srv = s
+}
+
+// spanFS checks that LOOKUP sees the span of the request.
+type spanFS struct {
+	contextFS
+	seen chan *testSpan
+}
+
+func (fs *spanFS) Lookup(cancel <-chan struct{}, header *InHeader, name string, out *EntryOut) Status {
+	ctx := fs.srv.Context(cancel, header)
+	span, _ := ctx.Value(spanKey{}).(*testSpan)
+	fs.seen <- span
+	return fs.readFS.Lookup(cancel, header, name, out)
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	fs := &spanFS{seen: make(chan *testSpan, 2)}
+	client, srv := startStreamServer(t, fs, &MountOptions{Tracer: tracer})
+	go srv.Serve()
+
+	if out, _ := streamLookup(t, client, 2, "file"); out.Status != 0 {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	if out, _ := streamLookup(t, client, 3, "missing"); out.Status != -int32(ENOENT) {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	for _, want := range []string{"file", "missing"} {
+		if span := <-fs.seen; span == nil || span.op != "LOOKUP" || span.name != want {
+			t.Errorf("got span %+v, want LOOKUP %q", span, want)
+		}
+	}
+
+	client.Close()
+	srv.Wait()
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	// INIT is traced too.
+	if len(tracer.done) != 3 || tracer.done[0].Op != "INIT" {
+		t.Fatalf("got requests %+v, want INIT and 2 LOOKUPs", tracer.done)
+	}
+	if info := tracer.done[2]; info.Op != "LOOKUP" || info.Unique != 3 || info.NodeId != FUSE_ROOT_ID || info.Status != ENOENT {
+		t.Errorf("got %+v", info)
+	}
+	if n := srv.contexts.Load(); n != 0 {
+		t.Errorf("%d request contexts leaked", n)
+	}
+}
+
+func TestSlogTracer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client, srv := startStreamServer(t, &readFS{}, &MountOptions{
+		Tracer: NewSlogTracer(logger, slog.LevelDebug),
+	})
+	go srv.Serve()
+	if out, _ := streamLookup(t, client, 2, "missing"); out.Status != -int32(ENOENT) {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	client.Close()
+	srv.Wait()
+
+	var recs []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]any
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("Unmarshal(%q): %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	// INIT, then LOOKUP.
+	if len(recs) != 4 {
+		t.Fatalf("got %d records: %s", len(recs), buf.String())
+	}
+	recs = recs[2:]
+	if r := recs[0]; r["msg"] != "fuse request" || r["op"] != "LOOKUP" || r["unique"] != 2.0 {
+		t.Errorf("got request %v", r)
+	} else if names, _ := r["names"].([]any); len(names) != 1 || names[0] != "missing" {
+		t.Errorf("got names %v", r["names"])
+	}
+	if r := recs[1]; r["msg"] != "fuse reply" || r["status"] != "ENOENT" {
+		t.Errorf("got reply %v", r)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Transport carries FUSE messages between the server and its
+// client, usually the kernel. A Server reads requests from the
+// transport, possibly from several goroutines at once, and writes
+// replies and notifications back.
+type Transport interface {
+	// ReadRequest reads a single request into buf, and returns
+	// its size. Once the client has gone away, it should return
+	// syscall.ENODEV.
+	ReadRequest(buf []byte) (int, error)
+
+	// WriteReply writes a single reply or notification, which is
+	// the concatenation of the given slices.
+	WriteReply(data [][]byte) error
+
+	// SpliceFd returns a file descriptor that replies can be
+	// spliced into, or -1 if the transport does not support
+	// splicing.
+	SpliceFd() int
+
+	// Close releases the resources of the transport.
+	Close() error
+}
+
+// devTransport is the transport for a file descriptor of the FUSE
+// device.
+type devTransport struct {
+	fd int
+}
+
+// NewDevTransport returns a Transport for a file descriptor of an
+// opened and mounted /dev/fuse.
+func NewDevTransport(fd int) Transport {
+	return &devTransport{fd: fd}
+}
+
+func (t *devTransport) ReadRequest(buf []byte) (n int, err error) {
+	err = handleEINTR(func() error {
+		var err error
+		n, err = syscall.Read(t.fd, buf)
+		return err
+	})
+	return n, err
+}
+
+func (t *devTransport) WriteReply(data [][]byte) error {
+	return handleEINTR(func() error {
+		var err error
+		if len(data) == 1 {
+			_, err = syscall.Write(t.fd, data[0])
+		} else {
+			_, err = writev(t.fd, data)
+		}
+		return err
+	})
+}
+
+func (t *devTransport) SpliceFd() int {
+	return t.fd
+}
+
+func (t *devTransport) Close() error {
+	return syscall.Close(t.fd)
+}
+
+// streamTransport frames FUSE messages on a byte stream, using the
+// length in the message header.
+type streamTransport struct {
+	rw io.ReadWriter
+
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+}
+
+// NewStreamTransport returns a Transport that exchanges FUSE
+// messages over a byte stream, for example a unix socket. The
+// messages have the same layout as on /dev/fuse, in host byte
+// order. The peer takes the role of the kernel, so it must start
+// by sending INIT. When reading from rw returns io.EOF, the
+// connection is considered closed. If rw implements io.Closer, it
+// is closed along with the transport.
+func NewStreamTransport(rw io.ReadWriter) Transport {
+	return &streamTransport{rw: rw}
+}
+
+func (t *streamTransport) ReadRequest(buf []byte) (int, error) {
+	t.readMu.Lock()
+	defer t.readMu.Unlock()
+
+	hdrSize := int(unsafe.Sizeof(InHeader{}))
+	if len(buf) < hdrSize {
+		return 0, syscall.EINVAL
+	}
+	if _, err := io.ReadFull(t.rw, buf[:hdrSize]); err != nil {
+		return 0, streamError(err)
+	}
+	length := int((*InHeader)(unsafe.Pointer(&buf[0])).Length)
+	if length < hdrSize || length > len(buf) {
+		// We cannot find the next frame, so the stream is
+		// unusable.
+		return 0, syscall.EPROTO
+	}
+	if _, err := io.ReadFull(t.rw, buf[hdrSize:length]); err != nil {
+		return 0, streamError(err)
+	}
+	return length, nil
+}
+
+// streamError translates a closed stream into ENODEV, which the
+// server treats as an unmount. Other errors become an errno, so the
+// server can report them.
+func streamError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		return syscall.ENODEV
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	return syscall.EIO
+}
+
+func (t *streamTransport) WriteReply(data [][]byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	// net.Buffers uses writev if rw is a connection.
+	bufs := net.Buffers(data)
+	_, err := bufs.WriteTo(t.rw)
+	return streamError(err)
+}
+
+func (t *streamTransport) SpliceFd() int {
+	return -1
+}
+
+func (t *streamTransport) Close() error {
+	if c, ok := t.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"unsafe"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// streamExchange writes a request to conn, and reads back the
+// reply. Unlike streamRoundTrip, it may be called from any goroutine.
+func streamExchange(conn net.Conn, req []byte) (*OutHeader, []byte, error) {
+	if _, err := conn.Write(req); err != nil {
+		return nil, nil, fmt.Errorf("Write: %v", err)
+	}
+	var out OutHeader
+	hdr := unsafe.Slice((*byte)(unsafe.Pointer(&out)), unsafe.Sizeof(out))
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return nil, nil, fmt.Errorf("ReadFull: %v", err)
+	}
+	data := make([]byte, int(out.Length)-len(hdr))
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, nil, fmt.Errorf("ReadFull: %v", err)
+	}
+	if in := (*InHeader)(unsafe.Pointer(&req[0])); out.Unique != in.Unique {
+		return nil, nil, fmt.Errorf("got unique %d for request %v", out.Unique, in)
+	}
+	return &out, data, nil
+}
+
+// streamRoundTrip writes a request to conn, and reads back the
+// reply.
+func streamRoundTrip(t *testing.T, conn net.Conn, req []byte) (*OutHeader, []byte) {
+	t.Helper()
+	out, data, err := streamExchange(conn, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out, data
+}
+
+// startStreamServer starts a server for fs on a stream transport,
+// and completes INIT.
+func startStreamServer(t *testing.T, fs RawFileSystem, opts *MountOptions) (net.Conn, *Server) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close() })
+
+	init := InitIn{
+		InHeader: InHeader{
+			Opcode: _OP_INIT,
+			Unique: 1,
+		},
+		Major: _FUSE_KERNEL_VERSION,
+		Minor: _OUR_MINOR_VERSION,
+	}
+	init.Length = uint32(unsafe.Sizeof(init))
+	initDone := make(chan error, 1)
+	go func() {
+		_, _, err := streamExchange(client, structBytes(&init))
+		initDone <- err
+	}()
+	if opts == nil {
+		opts = &MountOptions{}
+	}
+	opts.Debug = testutil.VerboseTest()
+	srv, err := NewTransportServer(fs, NewStreamTransport(server), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-initDone; err != nil {
+		t.Fatalf("INIT: %v", err)
+	}
+	return client, srv
+}
+
+// lookupRequest returns a LOOKUP request for name in the root.
+func lookupRequest(unique uint64, name string) []byte {
+	name += "\000"
+	in := InHeader{
+		Length: uint32(int(unsafe.Sizeof(InHeader{})) + len(name)),
+		Opcode: _OP_LOOKUP,
+		Unique: unique,
+		NodeId: FUSE_ROOT_ID,
+	}
+	return append(structBytes(&in), name...)
+}
+
+// streamLookup sends a LOOKUP for name in the root.
+func streamLookup(t *testing.T, conn net.Conn, unique uint64, name string) (*OutHeader, []byte) {
+	t.Helper()
+	return streamRoundTrip(t, conn, lookupRequest(unique, name))
+}
+
+func structBytes[T any](v *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(v)), unsafe.Sizeof(*v))
+}
+
+func TestStreamTransport(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	init := InitIn{
+		InHeader: InHeader{
+			Opcode: _OP_INIT,
+			Unique: 1,
+		},
+		Major:        _FUSE_KERNEL_VERSION,
+		Minor:        _OUR_MINOR_VERSION,
+		MaxReadAhead: 128 * 1024,
+	}
+	init.Length = uint32(unsafe.Sizeof(init))
+
+	type initResult struct {
+		out  *OutHeader
+		data []byte
+		err  error
+	}
+	initDone := make(chan initResult, 1)
+	go func() {
+		out, data, err := streamExchange(client, structBytes(&init))
+		initDone <- initResult{out, data, err}
+	}()
+
+	opts := &MountOptions{Debug: testutil.VerboseTest()}
+	srv, err := NewTransportServer(&readFS{}, NewStreamTransport(server), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := <-initDone
+	if res.err != nil {
+		t.Fatalf("INIT: %v", res.err)
+	}
+	if res.out.Status != 0 {
+		t.Fatalf("INIT: status %d", res.out.Status)
+	}
+	initOut := (*InitOut)(unsafe.Pointer(&res.data[0]))
+	if initOut.Major != _FUSE_KERNEL_VERSION {
+		t.Errorf("INIT: got major %d", initOut.Major)
+	}
+
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "file\000"
+	lookup := InHeader{
+		Length: uint32(int(unsafe.Sizeof(InHeader{})) + len(name)),
+		Opcode: _OP_LOOKUP,
+		Unique: 2,
+		NodeId: FUSE_ROOT_ID,
+	}
+	out, data := streamRoundTrip(t, client, append(structBytes(&lookup), name...))
+	if out.Status != 0 {
+		t.Fatalf("LOOKUP: status %d", out.Status)
+	}
+	entry := (*EntryOut)(unsafe.Pointer(&data[0]))
+	if entry.NodeId != 2 {
+		t.Fatalf("LOOKUP: got node %d, want 2", entry.NodeId)
+	}
+
+	read := ReadIn{
+		InHeader: InHeader{
+			Opcode: _OP_READ,
+			Unique: 3,
+			NodeId: entry.NodeId,
+		},
+		Size: 4096,
+	}
+	read.Length = uint32(unsafe.Sizeof(read))
+	out, data = streamRoundTrip(t, client, structBytes(&read))
+	if out.Status != 0 {
+		t.Fatalf("READ: status %d", out.Status)
+	}
+	if want := bytes.Repeat([]byte{'x'}, 4096); !bytes.Equal(data, want) {
+		t.Errorf("READ: got %q", data)
+	}
+
+	// Closing the connection is the equivalent of unmounting.
+	client.Close()
+	srv.Wait()
+}
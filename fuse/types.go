@@ -310,6 +310,7 @@ const (
 	CAP_NO_EXPORT_SUPPORT    = (1 << 38)
 	CAP_HAS_RESEND           = (1 << 39)
 	CAP_ALLOW_IDMAP          = (1 << 40)
+	CAP_OVER_IO_URING        = (1 << 41)
 )
 
 type InitIn struct {
@@ -486,10 +487,17 @@ type NotifyInvalInodeOut struct {
 	Length int64
 }
 
+// NotifyInvalEntryOut.Flags
+const (
+	// Mark the entry as expired, rather than dropping it. See
+	// Server.EntryNotifyExpire.
+	FUSE_EXPIRE_ONLY = (1 << 0)
+)
+
 type NotifyInvalEntryOut struct {
 	Parent  uint64
 	NameLen uint32
-	Padding uint32
+	Flags   uint32
 }
 
 type NotifyInvalDeleteOut struct {
@@ -644,7 +652,11 @@ type InHeader struct {
 	Unique uint64
 	NodeId uint64
 	Caller
-	Padding uint32
+
+	// TotalExtlen is the size of the request extensions (see
+	// SecurityContextFromContext) in units of 8 bytes.
+	TotalExtlen uint16
+	Padding     uint16
 }
 
 type StatfsOut struct {
@@ -0,0 +1,566 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// FUSE-over-io_uring (Linux 6.14 and later).
+//
+// The server registers one io_uring queue per possible CPU. Each
+// queue has a number of entries, and each entry has a header and a
+// payload buffer that the kernel fills with a request. The reply is
+// written into the same buffers, and submitted together with the
+// fetch of the next request (COMMIT_AND_FETCH). This avoids a
+// read/write syscall pair per request.
+//
+// FORGET, INTERRUPT and notification replies are still delivered
+// through the /dev/fuse file descriptor, so the classic read loop
+// keeps running alongside the queues.
+
+const (
+	_IORING_SETUP_SQE128    = 1 << 10
+	_IORING_OP_READ         = 22
+	_IORING_OP_URING_CMD    = 46
+	_IORING_ENTER_GETEVENTS = 1
+	_IORING_OFF_SQ_RING     = 0
+	_IORING_OFF_CQ_RING     = 0x8000000
+	_IORING_OFF_SQES        = 0x10000000
+
+	_FUSE_IO_URING_CMD_REGISTER         = 1
+	_FUSE_IO_URING_CMD_COMMIT_AND_FETCH = 2
+
+	// defaultIOUringQueueDepth is the default for
+	// MountOptions.IOUringQueueDepth.
+	defaultIOUringQueueDepth = 8
+
+	// user_data for the eventfd read that wakes up a queue.
+	uringWakeupTag = ^uint64(0)
+)
+
+type ioSqringOffsets struct {
+	Head        uint32
+	Tail        uint32
+	RingMask    uint32
+	RingEntries uint32
+	Flags       uint32
+	Dropped     uint32
+	Array       uint32
+	Resv1       uint32
+	UserAddr    uint64
+}
+
+type ioCqringOffsets struct {
+	Head        uint32
+	Tail        uint32
+	RingMask    uint32
+	RingEntries uint32
+	Overflow    uint32
+	Cqes        uint32
+	Flags       uint32
+	Resv1       uint32
+	UserAddr    uint64
+}
+
+type ioUringParams struct {
+	SqEntries    uint32
+	CqEntries    uint32
+	Flags        uint32
+	SqThreadCPU  uint32
+	SqThreadIdle uint32
+	Features     uint32
+	WqFd         uint32
+	Resv         [3]uint32
+	SqOff        ioSqringOffsets
+	CqOff        ioCqringOffsets
+}
+
+// ioUringSqe is a 128-byte submission entry (IORING_SETUP_SQE128).
+type ioUringSqe struct {
+	Opcode      uint8
+	Flags       uint8
+	Ioprio      uint16
+	Fd          int32
+	Off         uint64 // cmd_op for IORING_OP_URING_CMD
+	Addr        uint64
+	Len         uint32
+	OpFlags     uint32
+	UserData    uint64
+	BufIndex    uint16
+	Personality uint16
+	SpliceFdIn  int32
+	Cmd         [80]byte
+}
+
+type ioUringCqe struct {
+	UserData uint64
+	Res      int32
+	Flags    uint32
+}
+
+// fuseUringEntInOut is struct fuse_uring_ent_in_out.
+type fuseUringEntInOut struct {
+	Flags     uint64
+	CommitID  uint64
+	PayloadSz uint32
+	Padding   uint32
+	Reserved  uint64
+}
+
+// fuseUringReqHeader is struct fuse_uring_req_header.
+type fuseUringReqHeader struct {
+	// InHeader or OutHeader
+	InOut [128]byte
+	// Opcode specific input struct
+	OpIn     [128]byte
+	EntInOut fuseUringEntInOut
+}
+
+// fuseUringCmdReq is struct fuse_uring_cmd_req, stored in the Cmd
+// area of the submission entry.
+type fuseUringCmdReq struct {
+	Flags    uint64
+	CommitID uint64
+	Qid      uint16
+	Padding  [6]uint8
+}
+
+// ioRing is a minimal io_uring instance. It must only be used from
+// a single goroutine.
+type ioRing struct {
+	fd int
+
+	sqMem, cqMem, sqeMem []byte
+
+	sqHead, sqTail, sqMask *uint32
+	sqArray                []uint32
+	sqes                   []ioUringSqe
+
+	cqHead, cqTail, cqMask *uint32
+	cqes                   []ioUringCqe
+
+	// Number of entries queued but not yet submitted.
+	pending uint32
+}
+
+func newIoRing(entries uint32) (*ioRing, error) {
+	var p ioUringParams
+	p.Flags = _IORING_SETUP_SQE128
+	fd, _, errno := syscall.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("io_uring_setup", errno)
+	}
+	r := &ioRing{fd: int(fd)}
+
+	mmap := func(off int64, size int) ([]byte, error) {
+		return syscall.Mmap(r.fd, off, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	}
+	var err error
+	if r.sqMem, err = mmap(_IORING_OFF_SQ_RING, int(p.SqOff.Array+p.SqEntries*4)); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.cqMem, err = mmap(_IORING_OFF_CQ_RING, int(p.CqOff.Cqes)+int(p.CqEntries)*int(unsafe.Sizeof(ioUringCqe{}))); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.sqeMem, err = mmap(_IORING_OFF_SQES, int(p.SqEntries)*int(unsafe.Sizeof(ioUringSqe{}))); err != nil {
+		r.close()
+		return nil, err
+	}
+
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sqMem[p.SqOff.Head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sqMem[p.SqOff.Tail]))
+	r.sqMask = (*uint32)(unsafe.Pointer(&r.sqMem[p.SqOff.RingMask]))
+	r.sqArray = unsafe.Slice((*uint32)(unsafe.Pointer(&r.sqMem[p.SqOff.Array])), p.SqEntries)
+	r.sqes = unsafe.Slice((*ioUringSqe)(unsafe.Pointer(&r.sqeMem[0])), p.SqEntries)
+
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cqMem[p.CqOff.Head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cqMem[p.CqOff.Tail]))
+	r.cqMask = (*uint32)(unsafe.Pointer(&r.cqMem[p.CqOff.RingMask]))
+	r.cqes = unsafe.Slice((*ioUringCqe)(unsafe.Pointer(&r.cqMem[p.CqOff.Cqes])), p.CqEntries)
+	return r, nil
+}
+
+// getSqe returns a cleared submission entry, or nil if the
+// submission queue is full.
+func (r *ioRing) getSqe() *ioUringSqe {
+	head := atomic.LoadUint32(r.sqHead)
+	tail := *r.sqTail + r.pending
+	if tail-head >= uint32(len(r.sqes)) {
+		return nil
+	}
+	idx := tail & *r.sqMask
+	r.sqArray[idx] = idx
+	r.pending++
+	sqe := &r.sqes[idx]
+	*sqe = ioUringSqe{}
+	return sqe
+}
+
+// submitAndWait submits the pending entries, and waits for at least
+// one completion.
+func (r *ioRing) submitAndWait() error {
+	toSubmit := r.pending
+	atomic.StoreUint32(r.sqTail, *r.sqTail+toSubmit)
+	r.pending = 0
+	_, _, errno := syscall.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(toSubmit), 1, _IORING_ENTER_GETEVENTS, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// reap calls fn for all available completions.
+func (r *ioRing) reap(fn func(*ioUringCqe)) {
+	head := *r.cqHead
+	tail := atomic.LoadUint32(r.cqTail)
+	for ; head != tail; head++ {
+		cqe := r.cqes[head&*r.cqMask]
+		fn(&cqe)
+	}
+	atomic.StoreUint32(r.cqHead, head)
+}
+
+func (r *ioRing) close() {
+	for _, m := range [][]byte{r.sqeMem, r.cqMem, r.sqMem} {
+		if m != nil {
+			syscall.Munmap(m)
+		}
+	}
+	syscall.Close(r.fd)
+}
+
+// uringEntry is a request slot in a uringQueue.
+type uringEntry struct {
+	queue *uringQueue
+	index int
+
+	header  *fuseUringReqHeader
+	payload []byte
+	iov     [2]syscall.Iovec
+}
+
+// uringQueue is the io_uring queue for a single CPU.
+type uringQueue struct {
+	server *Server
+	qid    uint16
+	ring   *ioRing
+
+	entries []*uringEntry
+
+	// number of entries that are registered with the kernel.
+	live int
+
+	// eventfd to wake up the queue goroutine when entries are
+	// ready to be committed.
+	wakeFd  int
+	wakeBuf [8]byte
+
+	mu     sync.Mutex
+	done   []*uringEntry
+	closed bool
+}
+
+// possibleCPUs returns the number of CPUs the kernel expects queues
+// for.
+func possibleCPUs() int {
+	data, err := os.ReadFile("/sys/devices/system/cpu/possible")
+	if err != nil {
+		return runtime.NumCPU()
+	}
+	n := 0
+	for _, r := range strings.Split(strings.TrimSpace(string(data)), ",") {
+		// ranges look like "0-3" or "5"
+		_, hi, found := strings.Cut(r, "-")
+		if !found {
+			hi = r
+		}
+		h, err := strconv.Atoi(hi)
+		if err != nil {
+			return runtime.NumCPU()
+		}
+		if h+1 > n {
+			n = h + 1
+		}
+	}
+	return n
+}
+
+// newRingQueues sets up io_uring queues for all CPUs. This must
+// happen before the INIT reply, because once the kernel was told
+// we use io_uring, it holds requests until all queues are
+// registered.
+func (ms *Server) newRingQueues() error {
+	depth := ms.opts.IOUringQueueDepth
+	if depth <= 0 {
+		depth = defaultIOUringQueueDepth
+	}
+	payloadSize := ms.opts.MaxWrite
+	if payloadSize < _FUSE_MIN_READ_BUFFER {
+		payloadSize = _FUSE_MIN_READ_BUFFER
+	}
+	payloadSize = (payloadSize + pageSize - 1) / pageSize * pageSize
+
+	var queues []*uringQueue
+	for qid := 0; qid < possibleCPUs(); qid++ {
+		q, err := ms.newRingQueue(uint16(qid), depth, payloadSize)
+		if err != nil {
+			for _, q := range queues {
+				q.close()
+			}
+			return err
+		}
+		queues = append(queues, q)
+	}
+	ms.rings = queues
+	ms.ioUring = true
+	return nil
+}
+
+func (ms *Server) newRingQueue(qid uint16, depth, payloadSize int) (*uringQueue, error) {
+	// Room for committing all entries and rearming the wakeup.
+	size := uint32(1)
+	for size < uint32(depth+1) {
+		size *= 2
+	}
+	ring, err := newIoRing(size)
+	if err != nil {
+		return nil, err
+	}
+	wakeFd, err := unix.Eventfd(0, unix.EFD_CLOEXEC)
+	if err != nil {
+		ring.close()
+		return nil, err
+	}
+	q := &uringQueue{
+		server: ms,
+		qid:    qid,
+		ring:   ring,
+		wakeFd: wakeFd,
+	}
+	for i := 0; i < depth; i++ {
+		e := &uringEntry{
+			queue:   q,
+			index:   i,
+			header:  &fuseUringReqHeader{},
+			payload: make([]byte, payloadSize),
+		}
+		e.iov[0].Base = (*byte)(unsafe.Pointer(e.header))
+		e.iov[0].SetLen(int(unsafe.Sizeof(*e.header)))
+		e.iov[1].Base = &e.payload[0]
+		e.iov[1].SetLen(len(e.payload))
+		q.entries = append(q.entries, e)
+	}
+	return q, nil
+}
+
+// closeRings releases the io_uring queues, if the kernel did not
+// accept FUSE over io_uring, or INIT failed.
+func (ms *Server) closeRings() {
+	for _, q := range ms.rings {
+		q.close()
+	}
+	ms.rings = nil
+	ms.ioUring = false
+}
+
+// startRings starts the goroutines serving the io_uring queues. It
+// must only be called if usesIOUring returns true.
+func (ms *Server) startRings() {
+	for _, q := range ms.rings {
+		ms.loops.Add(1)
+		go q.loop()
+	}
+}
+
+func (q *uringQueue) submitCmd(e *uringEntry, cmdOp uint32) {
+	sqe := q.ring.getSqe()
+	sqe.Opcode = _IORING_OP_URING_CMD
+	sqe.Fd = int32(q.server.mountFd)
+	sqe.Off = uint64(cmdOp)
+	sqe.Addr = uint64(uintptr(unsafe.Pointer(&e.iov[0])))
+	sqe.Len = uint32(len(e.iov))
+	sqe.UserData = uint64(e.index)
+
+	cmd := (*fuseUringCmdReq)(unsafe.Pointer(&sqe.Cmd[0]))
+	cmd.Qid = q.qid
+	if cmdOp == _FUSE_IO_URING_CMD_COMMIT_AND_FETCH {
+		cmd.CommitID = e.header.EntInOut.CommitID
+	}
+}
+
+func (q *uringQueue) armWakeup() {
+	sqe := q.ring.getSqe()
+	sqe.Opcode = _IORING_OP_READ
+	sqe.Fd = int32(q.wakeFd)
+	sqe.Addr = uint64(uintptr(unsafe.Pointer(&q.wakeBuf[0])))
+	sqe.Len = uint32(len(q.wakeBuf))
+	sqe.UserData = uringWakeupTag
+}
+
+// loop registers the entries of the queue, and dispatches incoming
+// requests until the connection goes away. Completions are
+// delivered to the thread that submitted the command, so the
+// goroutine is locked to its thread.
+func (q *uringQueue) loop() {
+	ms := q.server
+	defer ms.loops.Done()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer q.close()
+
+	for _, e := range q.entries {
+		q.submitCmd(e, _FUSE_IO_URING_CMD_REGISTER)
+	}
+	q.live = len(q.entries)
+	q.armWakeup()
+
+	for q.live > 0 {
+		if err := q.ring.submitAndWait(); err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			ms.opts.Logger.Printf("io_uring queue %d: %v", q.qid, err)
+			return
+		}
+		q.ring.reap(q.complete)
+	}
+	if ms.opts.Debug {
+		ms.opts.Logger.Printf("io_uring queue %d exiting", q.qid)
+	}
+}
+
+func (q *uringQueue) complete(cqe *ioUringCqe) {
+	ms := q.server
+	if cqe.UserData == uringWakeupTag {
+		q.mu.Lock()
+		done := q.done
+		q.done = nil
+		q.mu.Unlock()
+		for _, e := range done {
+			q.submitCmd(e, _FUSE_IO_URING_CMD_COMMIT_AND_FETCH)
+		}
+		q.armWakeup()
+		return
+	}
+
+	e := q.entries[cqe.UserData]
+	if cqe.Res < 0 {
+		// The kernel rejected the entry, or the connection was
+		// aborted. Requests keep flowing through /dev/fuse
+		// if the queue could not be registered.
+		q.live--
+		errno := syscall.Errno(-cqe.Res)
+		if ms.opts.Debug || (errno != syscall.ENOTCONN && errno != syscall.ECONNABORTED && errno != syscall.ECANCELED) {
+			ms.opts.Logger.Printf("io_uring queue %d entry %d: %v", q.qid, e.index, errno)
+		}
+		return
+	}
+	go ms.handleRingEntry(e)
+}
+
+// commit hands the entry back to the queue goroutine, which submits
+// the reply and fetches the next request.
+func (q *uringQueue) commit(e *uringEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.done = append(q.done, e)
+	one := [8]byte{1}
+	syscall.Write(q.wakeFd, one[:])
+}
+
+func (q *uringQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.ring.close()
+	syscall.Close(q.wakeFd)
+}
+
+// handleRingEntry converts the request in the entry to the format
+// read from /dev/fuse, and runs it through the regular dispatch.
+func (ms *Server) handleRingEntry(e *uringEntry) {
+	h := e.header
+	inHeader := (*InHeader)(unsafe.Pointer(&h.InOut[0]))
+	unique := inHeader.Unique
+	hdrSize := int(unsafe.Sizeof(InHeader{}))
+	payloadSize := int(h.EntInOut.PayloadSz)
+	opSize := int(inHeader.Length) - hdrSize - payloadSize
+
+	ch := ms.channels[0]
+	destIface := ch.readPool.Get()
+	dest := destIface.([]byte)
+	if opSize < 0 || opSize > len(h.OpIn) || payloadSize > len(e.payload) || hdrSize+opSize+payloadSize > len(dest) {
+		ch.readPool.Put(destIface)
+		ms.opts.Logger.Printf("io_uring: malformed request %d: len %d, payload %d", unique, inHeader.Length, payloadSize)
+		e.replyStatus(unique, EIO)
+		return
+	}
+
+	req := ms.reqPool.Get().(*requestAlloc)
+	ms.startStats(&req.request, inHeader.Opcode)
+	n := copy(dest, h.InOut[:hdrSize])
+	n += copy(dest[n:], h.OpIn[:opSize])
+	n += copy(dest[n:], e.payload[:payloadSize])
+	if !req.setInput(dest[:n]) {
+		ch.readPool.Put(destIface)
+	}
+	req.channel = ch
+	req.ringEntry = e
+	req.goroutine = ms.handlerGoroutine()
+	ms.handleRequest(req)
+}
+
+// reply copies the reply for req into the entry, and commits it.
+// Once the entry is committed, the kernel may put the next request
+// in it, so req no longer refers to it afterwards.
+func (e *uringEntry) reply(req *request) Status {
+	req.ringEntry = nil
+	if req.fdData != nil {
+		req.outPayload, req.status = req.fdData.Bytes(req.outPayload)
+		req.serializeHeader(len(req.outPayload))
+	}
+	if req.readResult != nil {
+		defer req.readResult.Done()
+	}
+
+	size := int(req.outHeader().Length) - int(sizeOfOutHeader)
+	if size > len(e.payload) {
+		e.replyStatus(req.inHeader().Unique, ERANGE)
+		return ERANGE
+	}
+	copy(e.header.InOut[:], req.outputBuf[:sizeOfOutHeader])
+	n := copy(e.payload, req.outputBuf[sizeOfOutHeader:])
+	copy(e.payload[n:], req.outPayload)
+	e.header.EntInOut.PayloadSz = uint32(size)
+	e.queue.commit(e)
+	return OK
+}
+
+// replyStatus commits a reply without data.
+func (e *uringEntry) replyStatus(unique uint64, code Status) {
+	o := (*OutHeader)(unsafe.Pointer(&e.header.InOut[0]))
+	*o = OutHeader{
+		Length: uint32(sizeOfOutHeader),
+		Status: -int32(code),
+		Unique: unique,
+	}
+	e.header.EntInOut.PayloadSz = 0
+	e.queue.commit(e)
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+func TestIOUring(t *testing.T) {
+	mnt := t.TempDir()
+	opts := &MountOptions{
+		Debug:         testutil.VerboseTest(),
+		EnableIOUring: true,
+	}
+
+	rfs := readFS{}
+	srv, err := NewServer(&rfs, mnt, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Unmount() })
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !srv.usesIOUring() {
+		t.Skip("kernel does not support FUSE over io_uring")
+	}
+
+	want := bytes.Repeat([]byte{'x'}, 1<<20)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := os.ReadFile(mnt + "/file")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("got %d bytes, want %d", len(got), len(want))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := srv.Unmount(); err != nil {
+		t.Fatal(err)
+	}
+}
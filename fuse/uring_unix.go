@@ -0,0 +1,24 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import "syscall"
+
+// FUSE-over-io_uring is only supported on Linux.
+type uringEntry struct{}
+
+type uringQueue struct{}
+
+func (e *uringEntry) replyStatus(unique uint64, code Status) {}
+
+func (ms *Server) newRingQueues() error {
+	return syscall.ENOSYS
+}
+
+func (ms *Server) closeRings() {}
+
+func (ms *Server) startRings() {}
@@ -0,0 +1,67 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io"
+	"syscall"
+)
+
+// RawSpliceWriter is an optional interface for a RawFileSystem. If
+// MountOptions.EnableSpliceWrite is set, large WRITE requests are
+// passed to SpliceWrite with their data still in a pipe, so it can be
+// spliced into a file descriptor without copying it through user
+// space. Other WRITE requests go to RawFileSystem.Write as usual.
+type RawSpliceWriter interface {
+	// SpliceWrite is like RawFileSystem.Write, but with the data
+	// in a pipe. Data left in the pipe on return is discarded.
+	SpliceWrite(cancel <-chan struct{}, input *WriteIn, data *WritePipe) (written uint32, code Status)
+}
+
+// WritePipe holds the data of a WRITE request in a pipe. It is only
+// valid until SpliceWrite returns.
+type WritePipe struct {
+	fd   int
+	size int
+
+	// done returns the pipe to the pool.
+	done func()
+}
+
+// Size returns the number of bytes left in the pipe.
+func (p *WritePipe) Size() int {
+	return p.size
+}
+
+// Bytes reads the remaining data into buf, if it is large enough, or
+// a newly allocated slice otherwise.
+func (p *WritePipe) Bytes(buf []byte) ([]byte, error) {
+	if cap(buf) < p.size {
+		buf = make([]byte, p.size)
+	}
+	buf = buf[:p.size]
+
+	n := 0
+	for n < len(buf) {
+		m, err := syscall.Read(p.fd, buf[n:])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return buf[:n], err
+		}
+		if m == 0 {
+			return buf[:n], io.ErrUnexpectedEOF
+		}
+		n += m
+		p.size -= m
+	}
+	return buf, nil
+}
+
+func (p *WritePipe) release() {
+	p.done()
+	p.done = nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"io"
+	"syscall"
+)
+
+// SpliceTo splices the remaining data into fd at offset off, and
+// returns the number of bytes written. If fd does not accept splices,
+// eg. because it was opened with O_APPEND or its file system lacks
+// support, it fails with EINVAL without consuming data, so the caller
+// can fall back to Bytes.
+func (p *WritePipe) SpliceTo(fd int, off int64) (int, error) {
+	written := 0
+	for p.size > 0 {
+		n, err := syscall.Splice(p.fd, nil, fd, &off, p.size, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, io.ErrUnexpectedEOF
+		}
+		written += int(n)
+		p.size -= int(n)
+	}
+	return written, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/internal/testutil"
+)
+
+// spliceWriteFS stores the data written to its file in backing.
+type spliceWriteFS struct {
+	readFS
+	backing *os.File
+
+	mu           sync.Mutex
+	writes       int
+	spliceWrites int
+}
+
+func (fs *spliceWriteFS) Write(cancel <-chan struct{}, input *WriteIn, data []byte) (uint32, Status) {
+	fs.mu.Lock()
+	fs.writes++
+	fs.mu.Unlock()
+	n, err := fs.backing.WriteAt(data, int64(input.Offset))
+	return uint32(n), ToStatus(err)
+}
+
+func (fs *spliceWriteFS) SpliceWrite(cancel <-chan struct{}, input *WriteIn, data *WritePipe) (uint32, Status) {
+	fs.mu.Lock()
+	fs.spliceWrites++
+	fs.mu.Unlock()
+	if data.Size() != int(input.Size) {
+		return 0, EIO
+	}
+	n, err := data.SpliceTo(int(fs.backing.Fd()), int64(input.Offset))
+	return uint32(n), ToStatus(err)
+}
+
+func TestSpliceWrite(t *testing.T) {
+	backing, err := os.Create(filepath.Join(t.TempDir(), "backing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backing.Close()
+
+	mnt := t.TempDir()
+	fs := &spliceWriteFS{backing: backing}
+	srv, err := NewServer(fs, mnt, &MountOptions{
+		Debug:             testutil.VerboseTest(),
+		EnableSpliceWrite: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMetrics()
+	srv.RecordMetrics(m)
+	go srv.Serve()
+	if err := srv.WaitMount(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Unmount()
+	if !srv.spliceWrites.Load() {
+		t.Skip("splicing not available")
+	}
+
+	f, err := os.OpenFile(filepath.Join(mnt, "file"), os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	if _, err := f.WriteAt(big, 0); err != nil {
+		t.Fatal(err)
+	}
+	small := []byte("hello")
+	if _, err := f.WriteAt(small, int64(len(big))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	fs.mu.Lock()
+	writes, spliceWrites := fs.writes, fs.spliceWrites
+	fs.mu.Unlock()
+	if writes != 1 || spliceWrites != 1 {
+		t.Errorf("got %d writes and %d splice writes, want 1 and 1", writes, spliceWrites)
+	}
+
+	got, err := os.ReadFile(backing.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(big, small...); !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes of data, want %d", len(got), len(want))
+	}
+
+	// Other requests still work.
+	if _, err := os.ReadFile(filepath.Join(mnt, "file")); err != nil {
+		t.Errorf("ReadFile: %v", err)
+	}
+	if got := m.Snapshot().SpliceWrites; got != 1 {
+		t.Errorf("got SpliceWrites %d, want 1", got)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+// Copyright 2025 the Go-FUSE Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuse
+
+import "syscall"
+
+// SpliceTo is not supported outside Linux. WritePipes are never
+// created there, as splicing is not available.
+func (p *WritePipe) SpliceTo(fd int, off int64) (int, error) {
+	return 0, syscall.ENOSYS
+}
@@ -6,12 +6,21 @@ package internal
 
 import (
 	"os/user"
+	"slices"
 	"strconv"
 )
 
 // HasAccess tests if a caller can access a file with permissions
-// `perm` in mode `mask`
+// `perm` in mode `mask`. The supplementary groups of the caller are
+// looked up in the user database.
 func HasAccess(callerUid, callerGid, fileUid, fileGid uint32, perm uint32, mask uint32) bool {
+	return HasAccessGroups(callerUid, callerGid, nil, fileUid, fileGid, perm, mask)
+}
+
+// HasAccessGroups is like HasAccess, but calls `groups` (eg.
+// fuse.Caller.Groups) for the supplementary groups of the caller. If
+// it is nil or fails, the user database is used.
+func HasAccessGroups(callerUid, callerGid uint32, groups func() ([]uint32, error), fileUid, fileGid uint32, perm uint32, mask uint32) bool {
 	if callerUid == 0 {
 		// root can do anything.
 		return true
@@ -40,6 +49,11 @@ func HasAccess(callerUid, callerGid, fileUid, fileGid uint32, perm uint32, mask
 		// avoid expensive lookup if it's not allowed anyway
 		return false
 	}
+	if groups != nil {
+		if gs, err := groups(); err == nil {
+			return slices.Contains(gs, fileGid)
+		}
+	}
 
 	u, err := user.LookupId(strconv.Itoa(int(callerUid)))
 	if err != nil {
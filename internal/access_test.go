@@ -74,3 +74,16 @@ func TestHasAccess(t *testing.T) {
 		}
 	}
 }
+
+func TestHasAccessGroups(t *testing.T) {
+	groups := func() ([]uint32, error) { return []uint32{100, 200}, nil }
+	if !HasAccessGroups(1000, 1000, groups, 0, 200, 0040, 04) {
+		t.Error("supplementary group 200 has no access")
+	}
+	if HasAccessGroups(1000, 1000, groups, 0, 300, 0040, 04) {
+		t.Error("group 300 has access")
+	}
+	if HasAccessGroups(1000, 1000, groups, 0, 200, 0004, 02) {
+		t.Error("write access without write permission")
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"log"
 	"os"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 func (p *Pair) LoadFromAt(fd uintptr, sz int, off int64) (int, error) {
@@ -37,6 +39,26 @@ func (p *Pair) WriteTo(fd uintptr, n int) (int, error) {
 	return int(m), err
 }
 
+// Vmsplice maps the pages of data into the pipe, without copying
+// them. The pipe refers to the memory of data, so it must not be
+// modified until the data has been read or spliced out of the pipe.
+func (p *Pair) Vmsplice(data []byte) (int, error) {
+	n := 0
+	for n < len(data) {
+		iov := []unix.Iovec{{Base: &data[n]}}
+		iov[0].SetLen(len(data) - n)
+		m, err := unix.Vmsplice(p.w, iov, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return n, os.NewSyscallError("vmsplice", err)
+		}
+		n += m
+	}
+	return n, nil
+}
+
 const _SPLICE_F_NONBLOCK = 0x2
 
 func (p *Pair) discard() {
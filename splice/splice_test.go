@@ -7,6 +7,7 @@
 package splice
 
 import (
+	"bytes"
 	"os"
 	"testing"
 )
@@ -50,3 +51,35 @@ func TestDiscard(t *testing.T) {
 		t.Fatalf("Read: got (%d, %v) want (-1, EAGAIN)", n, err)
 	}
 }
+
+func TestVmsplice(t *testing.T) {
+	p, err := Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Done(p)
+
+	data := make([]byte, 3*os.Getpagesize()+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if err := p.Grow(len(data) + os.Getpagesize()); err != nil {
+		t.Skipf("Grow: %v", err)
+	}
+	if n, err := p.Vmsplice(data[1:]); err != nil || n != len(data)-1 {
+		t.Fatalf("Vmsplice: got (%d, %v), want %d", n, err, len(data)-1)
+	}
+
+	got := make([]byte, len(data))
+	n := 0
+	for n < len(data)-1 {
+		m, err := p.Read(got[n:])
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		n += m
+	}
+	if !bytes.Equal(got[:n], data[1:]) {
+		t.Errorf("data mismatch")
+	}
+}